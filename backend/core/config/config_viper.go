@@ -107,6 +107,8 @@ func setDefaultValue(v *viper.Viper) {
 	v.SetDefault("RESUME_PIPELINES", true)
 	// v.SetDefault("CORS_ALLOW_ORIGIN", "*")
 	v.SetDefault("CONSUME_PIPELINES", true)
+	v.SetDefault("SECRETREF_FILE_DIR", "/run/secrets")
+	v.SetDefault("SECRETREF_ENV_PREFIX", "DEVLAKE_SECRET_")
 }
 
 func init() {
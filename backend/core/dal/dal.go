@@ -364,6 +364,11 @@ func Lock(write bool, nowait bool) Clause {
 	return Clause{Type: LockClause, Data: []bool{write, nowait}}
 }
 
+// LockSkipLocked creates a new Lock clause which skips the rows locked by others instead of waiting for them
+func LockSkipLocked(write bool) Clause {
+	return Clause{Type: LockClause, Data: []bool{write, false, true}}
+}
+
 func Expr(expr string, params ...interface{}) DalClause {
 	return DalClause{Expr: expr, Params: params}
 }
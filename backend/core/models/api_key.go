@@ -27,12 +27,56 @@ type ApiKey struct {
 	common.Model
 	common.Creator
 	common.Updater
-	Name        string     `json:"name"`
-	ApiKey      string     `json:"apiKey,omitempty"`
-	ExpiredAt   *time.Time `json:"expiredAt"`
-	AllowedPath string     `json:"allowedPath"`
-	Type        string     `json:"type"`
-	Extra       string     `json:"extra"`
+	Name      string     `json:"name"`
+	ApiKey    string     `json:"apiKey,omitempty"`
+	ExpiredAt *time.Time `json:"expiredAt"`
+	// AllowedPath is the regex restricting keys created before Scopes existed, ignored once Scopes is set
+	AllowedPath string        `json:"allowedPath,omitempty"`
+	Scopes      *ApiKeyScopes `json:"scopes" gorm:"type:json;serializer:json"`
+	// RateLimitPerMinute caps the requests made with the key on each DevLake instance, 0 means unlimited
+	RateLimitPerMinute int        `json:"rateLimitPerMinute"`
+	LastUsedAt         *time.Time `json:"lastUsedAt"`
+	LastUsedIp         string     `json:"lastUsedIp"`
+	Type               string     `json:"type"`
+	Extra              string     `json:"extra"`
+}
+
+// ApiKeyScopes restricts what an api key may do, a request must match every non-empty scope.
+// A key needs at least one scope, or All to be unrestricted, and the ApiKeyAdminResourceTypes
+// are only reachable when named in Resources.
+type ApiKeyScopes struct {
+	// All marks a key left unrestricted on purpose, it can't be combined with the other scopes
+	All bool `json:"all,omitempty"`
+	// Methods are the allowed HTTP methods, i.e. ["GET"] for a read-only key
+	Methods []string `json:"methods,omitempty"`
+	// Resources are the allowed resource types, i.e. "projects", "blueprints", "plugins" or "plugins/github"
+	Resources []string `json:"resources,omitempty"`
+	// Projects are the projects whose resources are reachable
+	Projects []string `json:"projects,omitempty"`
+	// WebhookConnectionIds are the webhook connections whose endpoints are reachable
+	WebhookConnectionIds []uint64 `json:"webhookConnectionIds,omitempty"`
+}
+
+// ApiKeyResourceTypes are the resource types, besides "plugins/<plugin name>", an api key can be scoped to
+var ApiKeyResourceTypes = []string{
+	"blueprints",
+	"domainlayer",
+	"pipelines",
+	"plugininfo",
+	"plugins",
+	"projects",
+	"push",
+	"store",
+	"tasks",
+}
+
+// ApiKeyAdminResourceTypes hold credentials or settings shared by every project, an api key
+// reaches them only when its Resources name them
+var ApiKeyAdminResourceTypes = []string{
+	"api-keys",
+	"audit-logs",
+	"encryption-secret",
+	"notification-channels",
 }
 
 func (apiKey *ApiKey) TableName() string {
@@ -44,10 +88,13 @@ func (apiKey *ApiKey) RemoveHashedApiKey() {
 }
 
 type ApiInputApiKey struct {
-	Name        string     `json:"name" validate:"required,max=255"`
-	Type        string     `json:"type" validate:"required"`
-	AllowedPath string     `json:"allowedPath" validate:"required"`
-	ExpiredAt   *time.Time `json:"expiredAt" `
+	Name               string        `json:"name" validate:"required,max=255"`
+	Type               string        `json:"type" validate:"required"`
+	Scopes             *ApiKeyScopes `json:"scopes" validate:"required"`
+	RateLimitPerMinute int           `json:"rateLimitPerMinute" validate:"min=0"`
+	ExpiredAt          *time.Time    `json:"expiredAt" `
+	// AllowedPath was replaced by Scopes, it is only decoded to reject the clients still sending it
+	AllowedPath *string `json:"allowedPath,omitempty" swaggerignore:"true"`
 }
 
 type ApiOutputApiKey = ApiKey
@@ -0,0 +1,67 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AUDIT_ACTOR_USER      = "user"
+	AUDIT_ACTOR_API_KEY   = "api-key"
+	AUDIT_ACTOR_ANONYMOUS = "anonymous"
+)
+
+// AuditLog records a request which may have changed something, who made it and what it changed
+type AuditLog struct {
+	ID         uint64    `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ActorType  string    `json:"actorType"`
+	Actor      string    `json:"actor"`
+	ActorEmail string    `json:"actorEmail"`
+	ApiKeyId   uint64    `json:"apiKeyId,omitempty"`
+	ClientIp   string    `json:"clientIp"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	// Route is the matched route, i.e. `/blueprints/:blueprintId`
+	Route string `json:"route"`
+	// ResourceType is `projects`, `blueprints`, `plugins/github`...
+	ResourceType string `json:"resourceType"`
+	// ResourceId lists the path parameters identifying the target, i.e. `blueprintId=1`
+	ResourceId string `json:"resourceId"`
+	// Before and After are the JSON representations of the target with secrets redacted
+	Before     json.RawMessage  `json:"before" gorm:"type:json;serializer:json"`
+	After      json.RawMessage  `json:"after" gorm:"type:json;serializer:json"`
+	Diff       []AuditLogChange `json:"diff" gorm:"type:json;serializer:json"`
+	Status     int              `json:"status"`
+	Success    bool             `json:"success"`
+	Message    string           `json:"message"`
+	DurationMs int64            `json:"durationMs"`
+}
+
+func (AuditLog) TableName() string {
+	return "_devlake_audit_logs"
+}
+
+// AuditLogChange is a field whose value differs between Before and After
+type AuditLogChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
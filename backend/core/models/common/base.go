@@ -30,6 +30,8 @@ const (
 type User struct {
 	Name  string
 	Email string
+	// Groups the user belongs to in the identity provider, used for role based access control
+	Groups []string
 }
 
 type Model struct {
@@ -0,0 +1,57 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package code
+
+import (
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+// CodeOwnerRule is one owner of a path pattern declared in the CODEOWNERS file found at the head of the default
+// branch. A pattern without any owner is stored with an empty Owner, as it removes the ownership of the paths
+// matched by earlier rules.
+type CodeOwnerRule struct {
+	common.NoPKModel
+	RepoId    string `json:"repoId" gorm:"primaryKey;type:varchar(255)"`
+	LineNo    int    `json:"lineNo" gorm:"primaryKey"`
+	Owner     string `json:"owner" gorm:"primaryKey;type:varchar(255)"`
+	Pattern   string `json:"pattern" gorm:"type:text"`
+	FilePath  string `json:"filePath" gorm:"type:varchar(255)"`
+	CommitSha string `json:"commitSha" gorm:"type:varchar(40)"`
+}
+
+func (CodeOwnerRule) TableName() string {
+	return "code_owner_rules"
+}
+
+// PullRequestCodeOwnerReview tells whether a merged pull request touching the paths of a CODEOWNERS rule was
+// reviewed by at least one of the owners of the rule
+type PullRequestCodeOwnerReview struct {
+	common.NoPKModel
+	PullRequestId  string `json:"pullRequestId" gorm:"primaryKey;type:varchar(255)"`
+	RuleLineNo     int    `json:"ruleLineNo" gorm:"primaryKey"`
+	RepoId         string `json:"repoId" gorm:"index;type:varchar(255)"`
+	Pattern        string `json:"pattern" gorm:"type:text"`
+	Owners         string `json:"owners" gorm:"type:text"`
+	FileCount      int    `json:"fileCount"`
+	OwnerReviewed  bool   `json:"ownerReviewed"`
+	OwnerReviewers string `json:"ownerReviewers" gorm:"type:text"`
+}
+
+func (PullRequestCodeOwnerReview) TableName() string {
+	return "pull_request_code_owner_reviews"
+}
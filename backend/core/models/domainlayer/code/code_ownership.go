@@ -0,0 +1,78 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package code
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+const (
+	OWNERSHIP_SCOPE_FILE      = "FILE"
+	OWNERSHIP_SCOPE_COMPONENT = "COMPONENT"
+)
+
+// CodeOwnership is the number of lines a person authored in a file or a
+// component, according to the blame data of the latest repo snapshot.
+// File paths might be too long for a primary key, so the scope is keyed by
+// the sha256 of its name, see OwnershipScopeKey
+type CodeOwnership struct {
+	common.NoPKModel
+	RepoId    string  `json:"repoId" gorm:"primaryKey;type:varchar(255)"`
+	ScopeType string  `json:"scopeType" gorm:"primaryKey;type:varchar(20)"`
+	ScopeKey  string  `json:"scopeKey" gorm:"primaryKey;type:varchar(64)"`
+	ScopeName string  `json:"scopeName" gorm:"type:text"`
+	OwnerId   string  `json:"ownerId" gorm:"primaryKey;type:varchar(255)"`
+	OwnerName string  `json:"ownerName" gorm:"type:varchar(255)"`
+	Lines     int     `json:"lines"`
+	Share     float64 `json:"share"`
+	IsPrimary bool    `json:"isPrimary"`
+}
+
+// OwnershipScopeKey returns the key of a file or a component in the ownership tables
+func OwnershipScopeKey(scopeName string) string {
+	sum := sha256.Sum256([]byte(scopeName))
+	return hex.EncodeToString(sum[:])
+}
+
+func (CodeOwnership) TableName() string {
+	return "code_ownerships"
+}
+
+// CodeOwnershipSummary holds the primary owner and the bus factor of a file or
+// a component. The bus factor is the smallest number of owners who together
+// authored more than half of the lines.
+type CodeOwnershipSummary struct {
+	common.NoPKModel
+	RepoId            string  `json:"repoId" gorm:"primaryKey;type:varchar(255)"`
+	ScopeType         string  `json:"scopeType" gorm:"primaryKey;type:varchar(20)"`
+	ScopeKey          string  `json:"scopeKey" gorm:"primaryKey;type:varchar(64)"`
+	ScopeName         string  `json:"scopeName" gorm:"type:text"`
+	TotalLines        int     `json:"totalLines"`
+	OwnerCount        int     `json:"ownerCount"`
+	PrimaryOwnerId    string  `json:"primaryOwnerId" gorm:"type:varchar(255)"`
+	PrimaryOwnerName  string  `json:"primaryOwnerName" gorm:"type:varchar(255)"`
+	PrimaryOwnerShare float64 `json:"primaryOwnerShare"`
+	BusFactor         int     `json:"busFactor"`
+}
+
+func (CodeOwnershipSummary) TableName() string {
+	return "code_ownership_summaries"
+}
@@ -0,0 +1,56 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package code
+
+import (
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+// CommitTrailer is a `Key: value` line of the trailer block at the end of a commit message, e.g. `Co-authored-by`,
+// `Signed-off-by`, `Reviewed-by` or `Change-Id`. When the value is an identity like `Name <email>`, the email is
+// also the id of the account it resolves to, the same way commit authors are.
+type CommitTrailer struct {
+	common.NoPKModel
+	CommitSha string `json:"commitSha" gorm:"primaryKey;type:varchar(40)"`
+	Idx       int    `json:"idx" gorm:"primaryKey"`
+	Key       string `json:"key" gorm:"index;type:varchar(100)"`
+	Value     string `json:"value" gorm:"type:text"`
+	Name      string `json:"name" gorm:"type:varchar(255)"`
+	Email     string `json:"email" gorm:"type:varchar(255)"`
+	AccountId string `json:"accountId" gorm:"type:varchar(255)"`
+}
+
+func (CommitTrailer) TableName() string {
+	return "commit_trailers"
+}
+
+// CommitClassification is the Conventional Commits header of a commit message, commits not following the
+// specification have IsConventional set to false and an empty Type, or the type of their header if it is unknown
+type CommitClassification struct {
+	common.NoPKModel
+	CommitSha      string `json:"commitSha" gorm:"primaryKey;type:varchar(40)"`
+	IsConventional bool   `json:"isConventional"`
+	Type           string `json:"type" gorm:"index;type:varchar(50)"`
+	Scope          string `json:"scope" gorm:"type:varchar(255)"`
+	IsBreaking     bool   `json:"isBreaking"`
+	Description    string `json:"description" gorm:"type:text"`
+}
+
+func (CommitClassification) TableName() string {
+	return "commit_classifications"
+}
@@ -0,0 +1,90 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package code
+
+import (
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+const (
+	SUB_PROJECT_SOURCE_CONFIG       = "CONFIG"
+	SUB_PROJECT_SOURCE_GO_MOD       = "GO_MOD"
+	SUB_PROJECT_SOURCE_PACKAGE_JSON = "PACKAGE_JSON"
+	SUB_PROJECT_SOURCE_POM_XML      = "POM_XML"
+)
+
+// RepoSubProject is a directory of a monorepo holding a project on its own, either defined in the scope config
+// or detected from a go.mod, package.json or pom.xml file at the head of the default branch
+type RepoSubProject struct {
+	common.NoPKModel
+	RepoId       string `json:"repoId" gorm:"primaryKey;type:varchar(255)"`
+	Name         string `json:"name" gorm:"primaryKey;type:varchar(255)"`
+	Path         string `json:"path" gorm:"type:varchar(255)"`
+	Source       string `json:"source" gorm:"type:varchar(20)"`
+	ManifestName string `json:"manifestName" gorm:"type:varchar(255)"`
+}
+
+func (RepoSubProject) TableName() string {
+	return "repo_sub_projects"
+}
+
+// CommitSubProject sums up the files and lines a commit changed in a sub-project, a commit changing several
+// sub-projects has a row for each of them
+type CommitSubProject struct {
+	common.NoPKModel
+	RepoId         string `json:"repoId" gorm:"primaryKey;type:varchar(255)"`
+	CommitSha      string `json:"commitSha" gorm:"primaryKey;type:varchar(40)"`
+	SubProjectName string `json:"subProjectName" gorm:"primaryKey;type:varchar(255)"`
+	FileCount      int    `json:"fileCount"`
+	Additions      int    `json:"additions"`
+	Deletions      int    `json:"deletions"`
+}
+
+func (CommitSubProject) TableName() string {
+	return "commit_sub_projects"
+}
+
+// CommitFileSubProject attributes a file changed by a commit to its sub-project. The diff lines and the refdiff
+// results are attributed per file through it: commit_line_change joins commit_files on commit_sha and new_file_path,
+// commits_diffs joins commit_files on commit_sha, then commit_files joins this table on its id
+type CommitFileSubProject struct {
+	common.NoPKModel
+	CommitFileId   string `json:"commitFileId" gorm:"primaryKey;type:varchar(255)"`
+	RepoId         string `json:"repoId" gorm:"index;type:varchar(255)"`
+	CommitSha      string `json:"commitSha" gorm:"index;type:varchar(40)"`
+	SubProjectName string `json:"subProjectName" gorm:"type:varchar(255)"`
+}
+
+func (CommitFileSubProject) TableName() string {
+	return "commit_file_sub_projects"
+}
+
+// SubmoduleUpdate is a change of the commit a submodule points to, i.e. a dependency update. OldCommitSha is empty
+// when the submodule was added and NewCommitSha is empty when it was removed.
+type SubmoduleUpdate struct {
+	common.NoPKModel
+	CommitSha    string `json:"commitSha" gorm:"primaryKey;type:varchar(40)"`
+	Path         string `json:"path" gorm:"primaryKey;type:varchar(255)"`
+	RepoId       string `json:"repoId" gorm:"index;type:varchar(255)"`
+	OldCommitSha string `json:"oldCommitSha" gorm:"type:varchar(40)"`
+	NewCommitSha string `json:"newCommitSha" gorm:"type:varchar(40)"`
+}
+
+func (SubmoduleUpdate) TableName() string {
+	return "submodule_updates"
+}
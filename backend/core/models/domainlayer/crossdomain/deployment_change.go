@@ -0,0 +1,53 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crossdomain
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+const (
+	DEPLOYMENT_CHANGE_COMMIT       = "COMMIT"
+	DEPLOYMENT_CHANGE_PULL_REQUEST = "PULL_REQUEST"
+	DEPLOYMENT_CHANGE_ISSUE        = "ISSUE"
+)
+
+// DeploymentChange is a commit, a pull request or an issue shipped by a successful deployment commit, i.e. in
+// the diff since the previous successful deployment of the same repo to the same environment
+type DeploymentChange struct {
+	common.NoPKModel
+	DeploymentCommitId     string `gorm:"primaryKey;type:varchar(255)"`
+	ChangeType             string `gorm:"primaryKey;type:varchar(50)"`
+	ChangeId               string `gorm:"primaryKey;type:varchar(255)"`
+	ChangeKey              string `gorm:"type:varchar(255)"`
+	Title                  string
+	Url                    string
+	PrevDeploymentCommitId string `gorm:"type:varchar(255)"`
+	CicdScopeId            string `gorm:"index;type:varchar(255)"`
+	RepoUrl                string `gorm:"type:varchar(255)"`
+	Environment            string `gorm:"type:varchar(255)"`
+	CommitSha              string `gorm:"type:varchar(255)"`
+	PrevCommitSha          string `gorm:"type:varchar(255)"`
+	DeployedDate           *time.Time
+}
+
+func (DeploymentChange) TableName() string {
+	return "deployment_changes"
+}
@@ -0,0 +1,69 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crossdomain
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+const (
+	DORA_GRANULARITY_DAY   = "DAY"
+	DORA_GRANULARITY_WEEK  = "WEEK"
+	DORA_GRANULARITY_MONTH = "MONTH"
+)
+
+const (
+	DORA_LEVEL_ELITE  = "elite"
+	DORA_LEVEL_HIGH   = "high"
+	DORA_LEVEL_MEDIUM = "medium"
+	DORA_LEVEL_LOW    = "low"
+)
+
+// ProjectDoraMetric is a snapshot of the four DORA metrics of a project for one day, week or month.
+// Durations are in minutes, levels are empty when there is no data to compute the metric.
+type ProjectDoraMetric struct {
+	ProjectName string    `gorm:"primaryKey;type:varchar(100)"`
+	Granularity string    `gorm:"primaryKey;type:varchar(20)"`
+	PeriodStart time.Time `gorm:"primaryKey"`
+	PeriodEnd   time.Time
+	DoraReport  string `gorm:"type:varchar(20)"`
+
+	DeploymentCount          int
+	DeploymentDays           int
+	DeploymentFrequencyLevel string `gorm:"type:varchar(20)"`
+
+	PrCount               int
+	MedianLeadTimeMinutes *int64
+	LeadTimeLevel         string `gorm:"type:varchar(20)"`
+
+	FailedDeploymentCount  int
+	ChangeFailureRate      *float64
+	ChangeFailureRateLevel string `gorm:"type:varchar(20)"`
+
+	IncidentCount             int
+	MedianRecoveryTimeMinutes *int64
+	RecoveryTimeLevel         string `gorm:"type:varchar(20)"`
+
+	common.NoPKModel
+}
+
+func (ProjectDoraMetric) TableName() string {
+	return "project_dora_metrics"
+}
@@ -21,10 +21,20 @@ import (
 	"github.com/apache/incubator-devlake/core/models/domainlayer"
 )
 
+const (
+	// ATTRIBUTION_EXPLICIT_LINK means the incident references the deployment id or the commit sha in its description or labels
+	ATTRIBUTION_EXPLICIT_LINK = "EXPLICIT_LINK"
+	// ATTRIBUTION_SAME_SERVICE means the deployment is the last one before the incident among the cicd scopes of the incident's service
+	ATTRIBUTION_SAME_SERVICE = "SAME_SERVICE"
+	// ATTRIBUTION_LAST_DEPLOYMENT means the deployment is the last one before the incident in the project
+	ATTRIBUTION_LAST_DEPLOYMENT = "LAST_DEPLOYMENT"
+)
+
 type ProjectIncidentDeploymentRelationship struct {
 	domainlayer.DomainEntity
-	ProjectName  string `gorm:"primaryKey;type:varchar(100)"`
-	DeploymentId string
+	ProjectName         string `gorm:"primaryKey;type:varchar(100)"`
+	DeploymentId        string
+	AttributionStrategy string `gorm:"type:varchar(50)"`
 }
 
 func (ProjectIncidentDeploymentRelationship) TableName() string {
@@ -0,0 +1,67 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crossdomain
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/common"
+)
+
+const (
+	LEAD_TIME_STAGE_CODING = "CODING"
+	LEAD_TIME_STAGE_PICKUP = "PICKUP"
+	LEAD_TIME_STAGE_REVIEW = "REVIEW"
+	LEAD_TIME_STAGE_DEPLOY = "DEPLOY"
+)
+
+// ProjectLeadTimeStageMetric holds the percentiles of one lead time stage of the pull requests merged
+// within a week, for the whole project when RepoId is empty or for a single repo otherwise.
+type ProjectLeadTimeStageMetric struct {
+	ProjectName string    `gorm:"primaryKey;type:varchar(100)"`
+	RepoId      string    `gorm:"primaryKey;type:varchar(255)"`
+	WeekStart   time.Time `gorm:"primaryKey"`
+	Stage       string    `gorm:"primaryKey;type:varchar(20)"`
+	PrCount     int
+	P50Minutes  *int64
+	P75Minutes  *int64
+	P90Minutes  *int64
+	common.NoPKModel
+}
+
+func (ProjectLeadTimeStageMetric) TableName() string {
+	return "project_lead_time_stage_metrics"
+}
+
+// ProjectLeadTimeOutlier is a pull request whose stage took longer than the p90 of that stage in the project
+type ProjectLeadTimeOutlier struct {
+	ProjectName   string `gorm:"primaryKey;type:varchar(100)"`
+	PullRequestId string `gorm:"primaryKey;type:varchar(255)"`
+	Stage         string `gorm:"primaryKey;type:varchar(20)"`
+	RepoId        string `gorm:"type:varchar(255)"`
+	WeekStart     time.Time
+	StageMinutes  int64
+	P90Minutes    int64
+	// share of the time spent in the stage by all outliers of the project taken by this pull request
+	TailShare float64
+	common.NoPKModel
+}
+
+func (ProjectLeadTimeOutlier) TableName() string {
+	return "project_lead_time_outliers"
+}
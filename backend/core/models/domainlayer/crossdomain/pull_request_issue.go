@@ -21,11 +21,23 @@ import (
 	"github.com/apache/incubator-devlake/core/models/common"
 )
 
+const (
+	PR_ISSUE_SOURCE_PR_TEXT           = "PR_TEXT"
+	PR_ISSUE_SOURCE_BRANCH_NAME       = "BRANCH_NAME"
+	PR_ISSUE_SOURCE_COMMIT_MESSAGE    = "COMMIT_MESSAGE"
+	PR_ISSUE_SOURCE_DEVELOPMENT_PANEL = "DEVELOPMENT_PANEL"
+	PR_ISSUE_SOURCE_REMOTE_LINK       = "REMOTE_LINK"
+)
+
 type PullRequestIssue struct {
 	PullRequestId  string `json:"id" gorm:"primaryKey;type:varchar(255);comment:This key is generated based on details from the original plugin"` // format: <Plugin>:<Entity>:<PK0>:<PK1>
 	IssueId        string `gorm:"primaryKey;type:varchar(255)"`
 	PullRequestKey int
 	IssueKey       string `gorm:"type:varchar(255)"`
+	// Source is how the link was found and Confidence how likely it is right, from 0 to 1.
+	// Both are left empty by the plugins reading links from the data source directly.
+	Source     string `gorm:"type:varchar(50)"`
+	Confidence float64
 	common.NoPKModel
 }
 
@@ -29,9 +29,10 @@ const (
 )
 
 const (
-	PRODUCTION = "PRODUCTION"
-	STAGING    = "STAGING"
-	TESTING    = "TESTING"
+	PRODUCTION  = "PRODUCTION"
+	STAGING     = "STAGING"
+	TESTING     = "TESTING"
+	DEVELOPMENT = "DEVELOPMENT"
 )
 
 const ENV_NAME_PATTERN = "ENV_NAME_PATTERN"
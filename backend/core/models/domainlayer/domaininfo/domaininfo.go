@@ -33,7 +33,14 @@ func GetDomainTablesInfo() []dal.Tabler {
 		&code.Commit{},
 		&code.CommitFile{},
 		&code.CommitFileComponent{},
+		&code.CodeOwnership{},
+		&code.CodeOwnershipSummary{},
+		&code.CodeOwnerRule{},
 		&code.CommitParent{},
+		&code.CommitTrailer{},
+		&code.CommitClassification{},
+		&code.CommitSubProject{},
+		&code.CommitFileSubProject{},
 		&code.Component{},
 		&code.CommitLineChange{},
 		&code.PullRequest{},
@@ -42,6 +49,7 @@ func GetDomainTablesInfo() []dal.Tabler {
 		&code.PullRequestLabel{},
 		&code.PullRequestReviewer{},
 		&code.PullRequestAssignee{},
+		&code.PullRequestCodeOwnerReview{},
 		&code.Ref{},
 		&code.CommitsDiff{},
 		&code.RefCommit{},
@@ -50,6 +58,8 @@ func GetDomainTablesInfo() []dal.Tabler {
 		&code.RepoCommit{},
 		&code.RepoLanguage{},
 		&code.RepoSnapshot{},
+		&code.RepoSubProject{},
+		&code.SubmoduleUpdate{},
 		// codequality
 		&codequality.CqFileMetrics{},
 		&codequality.CqIssueCodeBlock{},
@@ -59,10 +69,14 @@ func GetDomainTablesInfo() []dal.Tabler {
 		// crossdomain
 		&crossdomain.Account{},
 		&crossdomain.BoardRepo{},
+		&crossdomain.DeploymentChange{},
 		&crossdomain.IssueCommit{},
 		&crossdomain.IssueRepoCommit{},
 		&crossdomain.ProjectMapping{},
 		&crossdomain.ProjectIncidentDeploymentRelationship{},
+		&crossdomain.ProjectDoraMetric{},
+		&crossdomain.ProjectLeadTimeStageMetric{},
+		&crossdomain.ProjectLeadTimeOutlier{},
 		&crossdomain.ProjectPrMetric{},
 		&crossdomain.PullRequestIssue{},
 		&crossdomain.RefsIssuesDiffs{},
@@ -87,6 +101,7 @@ func GetDomainTablesInfo() []dal.Tabler {
 		&ticket.IssueChangelogs{},
 		&ticket.IssueComment{},
 		&ticket.IssueLabel{},
+		&ticket.IssueRemoteLink{},
 		&ticket.IssueWorklog{},
 		&ticket.Sprint{},
 		&ticket.SprintIssue{},
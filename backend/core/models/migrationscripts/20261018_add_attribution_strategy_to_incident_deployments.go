@@ -0,0 +1,58 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+)
+
+var _ plugin.MigrationScript = (*addAttributionStrategyToIncidentDeployments)(nil)
+
+type projectIncidentDeploymentRelationship20261018 struct {
+	AttributionStrategy string `gorm:"type:varchar(50)"`
+}
+
+func (projectIncidentDeploymentRelationship20261018) TableName() string {
+	return "project_incident_deployment_relationships"
+}
+
+type addAttributionStrategyToIncidentDeployments struct{}
+
+func (*addAttributionStrategyToIncidentDeployments) Up(basicRes context.BasicRes) errors.Error {
+	db := basicRes.GetDal()
+	if err := db.AutoMigrate(&projectIncidentDeploymentRelationship20261018{}); err != nil {
+		return err
+	}
+	// existing links were all produced by the last deployment strategy
+	return db.UpdateColumn(
+		&projectIncidentDeploymentRelationship20261018{},
+		"attribution_strategy", "LAST_DEPLOYMENT",
+		dal.Where("attribution_strategy IS NULL OR attribution_strategy = ''"),
+	)
+}
+
+func (*addAttributionStrategyToIncidentDeployments) Version() uint64 {
+	return 20261018000003
+}
+
+func (*addAttributionStrategyToIncidentDeployments) Name() string {
+	return "add attribution_strategy to project_incident_deployment_relationships"
+}
@@ -0,0 +1,73 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addAuditLogs)(nil)
+
+type auditLog20261018 struct {
+	ID           uint64    `gorm:"primaryKey"`
+	CreatedAt    time.Time `gorm:"index"`
+	ActorType    string    `gorm:"type:varchar(20)"`
+	Actor        string    `gorm:"type:varchar(255);index"`
+	ActorEmail   string    `gorm:"type:varchar(255)"`
+	ApiKeyId     uint64
+	ClientIp     string          `gorm:"type:varchar(255)"`
+	Method       string          `gorm:"type:varchar(10)"`
+	Path         string          `gorm:"type:varchar(255)"`
+	Route        string          `gorm:"type:varchar(255)"`
+	ResourceType string          `gorm:"type:varchar(100);index"`
+	ResourceId   string          `gorm:"type:varchar(255)"`
+	Before       json.RawMessage `gorm:"type:json;serializer:json"`
+	After        json.RawMessage `gorm:"type:json;serializer:json"`
+	Diff         json.RawMessage `gorm:"type:json;serializer:json"`
+	Status       int
+	Success      bool
+	Message      string `gorm:"type:text"`
+	DurationMs   int64
+}
+
+func (auditLog20261018) TableName() string {
+	return "_devlake_audit_logs"
+}
+
+type addAuditLogs struct{}
+
+func (*addAuditLogs) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(auditLog20261018),
+	)
+}
+
+func (*addAuditLogs) Version() uint64 {
+	return 20261018000013
+}
+
+func (*addAuditLogs) Name() string {
+	return "add audit logs"
+}
@@ -0,0 +1,76 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addCodeOwnerRules)(nil)
+
+type codeOwnerRule20261018 struct {
+	archived.NoPKModel
+	RepoId    string `gorm:"primaryKey;type:varchar(255)"`
+	LineNo    int    `gorm:"primaryKey"`
+	Owner     string `gorm:"primaryKey;type:varchar(255)"`
+	Pattern   string `gorm:"type:text"`
+	FilePath  string `gorm:"type:varchar(255)"`
+	CommitSha string `gorm:"type:varchar(40)"`
+}
+
+func (codeOwnerRule20261018) TableName() string {
+	return "code_owner_rules"
+}
+
+type pullRequestCodeOwnerReview20261018 struct {
+	archived.NoPKModel
+	PullRequestId  string `gorm:"primaryKey;type:varchar(255)"`
+	RuleLineNo     int    `gorm:"primaryKey"`
+	RepoId         string `gorm:"index;type:varchar(255)"`
+	Pattern        string `gorm:"type:text"`
+	Owners         string `gorm:"type:text"`
+	FileCount      int
+	OwnerReviewed  bool
+	OwnerReviewers string `gorm:"type:text"`
+}
+
+func (pullRequestCodeOwnerReview20261018) TableName() string {
+	return "pull_request_code_owner_reviews"
+}
+
+type addCodeOwnerRules struct{}
+
+func (*addCodeOwnerRules) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(codeOwnerRule20261018),
+		new(pullRequestCodeOwnerReview20261018),
+	)
+}
+
+func (*addCodeOwnerRules) Version() uint64 {
+	return 20261018000007
+}
+
+func (*addCodeOwnerRules) Name() string {
+	return "add code_owner_rules and pull_request_code_owner_reviews tables"
+}
@@ -0,0 +1,82 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addCodeOwnerships)(nil)
+
+// the scopes are keyed by the sha256 of their names, long file paths wouldn't fit the primary key
+type codeOwnership20261018 struct {
+	archived.NoPKModel
+	RepoId    string `gorm:"primaryKey;type:varchar(255)"`
+	ScopeType string `gorm:"primaryKey;type:varchar(20)"`
+	ScopeKey  string `gorm:"primaryKey;type:varchar(64)"`
+	ScopeName string `gorm:"type:text"`
+	OwnerId   string `gorm:"primaryKey;type:varchar(255)"`
+	OwnerName string `gorm:"type:varchar(255)"`
+	Lines     int
+	Share     float64
+	IsPrimary bool
+}
+
+func (codeOwnership20261018) TableName() string {
+	return "code_ownerships"
+}
+
+type codeOwnershipSummary20261018 struct {
+	archived.NoPKModel
+	RepoId            string `gorm:"primaryKey;type:varchar(255)"`
+	ScopeType         string `gorm:"primaryKey;type:varchar(20)"`
+	ScopeKey          string `gorm:"primaryKey;type:varchar(64)"`
+	ScopeName         string `gorm:"type:text"`
+	TotalLines        int
+	OwnerCount        int
+	PrimaryOwnerId    string `gorm:"type:varchar(255)"`
+	PrimaryOwnerName  string `gorm:"type:varchar(255)"`
+	PrimaryOwnerShare float64
+	BusFactor         int
+}
+
+func (codeOwnershipSummary20261018) TableName() string {
+	return "code_ownership_summaries"
+}
+
+type addCodeOwnerships struct{}
+
+func (*addCodeOwnerships) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(codeOwnership20261018),
+		new(codeOwnershipSummary20261018),
+	)
+}
+
+func (*addCodeOwnerships) Version() uint64 {
+	return 20261018000006
+}
+
+func (*addCodeOwnerships) Name() string {
+	return "add code_ownerships and code_ownership_summaries tables"
+}
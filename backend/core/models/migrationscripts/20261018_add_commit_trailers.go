@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addCommitTrailers)(nil)
+
+type commitTrailer20261018 struct {
+	archived.NoPKModel
+	CommitSha string `gorm:"primaryKey;type:varchar(40)"`
+	Idx       int    `gorm:"primaryKey"`
+	Key       string `gorm:"index;type:varchar(100)"`
+	Value     string `gorm:"type:text"`
+	Name      string `gorm:"type:varchar(255)"`
+	Email     string `gorm:"type:varchar(255)"`
+	AccountId string `gorm:"type:varchar(255)"`
+}
+
+func (commitTrailer20261018) TableName() string {
+	return "commit_trailers"
+}
+
+type commitClassification20261018 struct {
+	archived.NoPKModel
+	CommitSha      string `gorm:"primaryKey;type:varchar(40)"`
+	IsConventional bool
+	Type           string `gorm:"index;type:varchar(50)"`
+	Scope          string `gorm:"type:varchar(255)"`
+	IsBreaking     bool
+	Description    string `gorm:"type:text"`
+}
+
+func (commitClassification20261018) TableName() string {
+	return "commit_classifications"
+}
+
+type addCommitTrailers struct{}
+
+func (*addCommitTrailers) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(commitTrailer20261018),
+		new(commitClassification20261018),
+	)
+}
+
+func (*addCommitTrailers) Version() uint64 {
+	return 20261018000008
+}
+
+func (*addCommitTrailers) Name() string {
+	return "add commit_trailers and commit_classifications tables"
+}
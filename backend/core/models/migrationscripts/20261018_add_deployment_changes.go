@@ -0,0 +1,68 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addDeploymentChanges)(nil)
+
+type deploymentChange20261018 struct {
+	archived.NoPKModel
+	DeploymentCommitId     string `gorm:"primaryKey;type:varchar(255)"`
+	ChangeType             string `gorm:"primaryKey;type:varchar(50)"`
+	ChangeId               string `gorm:"primaryKey;type:varchar(255)"`
+	ChangeKey              string `gorm:"type:varchar(255)"`
+	Title                  string
+	Url                    string
+	PrevDeploymentCommitId string `gorm:"type:varchar(255)"`
+	CicdScopeId            string `gorm:"index;type:varchar(255)"`
+	RepoUrl                string `gorm:"type:varchar(255)"`
+	Environment            string `gorm:"type:varchar(255)"`
+	CommitSha              string `gorm:"type:varchar(255)"`
+	PrevCommitSha          string `gorm:"type:varchar(255)"`
+	DeployedDate           *time.Time
+}
+
+func (deploymentChange20261018) TableName() string {
+	return "deployment_changes"
+}
+
+type addDeploymentChanges struct{}
+
+func (*addDeploymentChanges) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(deploymentChange20261018),
+	)
+}
+
+func (*addDeploymentChanges) Version() uint64 {
+	return 20261018000011
+}
+
+func (*addDeploymentChanges) Name() string {
+	return "add deployment_changes table"
+}
@@ -0,0 +1,80 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addLeadTimeStageMetrics)(nil)
+
+type projectLeadTimeStageMetric20261018 struct {
+	ProjectName string    `gorm:"primaryKey;type:varchar(100)"`
+	RepoId      string    `gorm:"primaryKey;type:varchar(255)"`
+	WeekStart   time.Time `gorm:"primaryKey"`
+	Stage       string    `gorm:"primaryKey;type:varchar(20)"`
+	PrCount     int
+	P50Minutes  *int64
+	P75Minutes  *int64
+	P90Minutes  *int64
+	archived.NoPKModel
+}
+
+func (projectLeadTimeStageMetric20261018) TableName() string {
+	return "project_lead_time_stage_metrics"
+}
+
+type projectLeadTimeOutlier20261018 struct {
+	ProjectName   string `gorm:"primaryKey;type:varchar(100)"`
+	PullRequestId string `gorm:"primaryKey;type:varchar(255)"`
+	Stage         string `gorm:"primaryKey;type:varchar(20)"`
+	RepoId        string `gorm:"type:varchar(255)"`
+	WeekStart     time.Time
+	StageMinutes  int64
+	P90Minutes    int64
+	TailShare     float64
+	archived.NoPKModel
+}
+
+func (projectLeadTimeOutlier20261018) TableName() string {
+	return "project_lead_time_outliers"
+}
+
+type addLeadTimeStageMetrics struct{}
+
+func (*addLeadTimeStageMetrics) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(projectLeadTimeStageMetric20261018),
+		new(projectLeadTimeOutlier20261018),
+	)
+}
+
+func (*addLeadTimeStageMetrics) Version() uint64 {
+	return 20261018000004
+}
+
+func (*addLeadTimeStageMetrics) Name() string {
+	return "add project_lead_time_stage_metrics and project_lead_time_outliers tables"
+}
@@ -0,0 +1,88 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addNotificationChannels)(nil)
+
+type notification20261018 struct {
+	ChannelId  uint64 `gorm:"index"`
+	PipelineId uint64 `gorm:"index"`
+	Status     string `gorm:"type:varchar(20)"`
+	Attempts   int
+	Error      string
+}
+
+func (notification20261018) TableName() string {
+	return "_devlake_notifications"
+}
+
+type notificationChannel20261018 struct {
+	archived.Model
+	Name       string   `gorm:"type:varchar(255);uniqueIndex"`
+	Type       string   `gorm:"type:varchar(20)"`
+	Endpoint   string   `gorm:"serializer:encdec"`
+	Secret     string   `gorm:"serializer:encdec"`
+	Username   string   `gorm:"type:varchar(255)"`
+	From       string   `gorm:"type:varchar(255)"`
+	Recipients []string `gorm:"type:json;serializer:json"`
+	Template   string   `gorm:"type:text"`
+	MaxRetries int
+}
+
+func (notificationChannel20261018) TableName() string {
+	return "_devlake_notification_channels"
+}
+
+type notificationSubscription20261018 struct {
+	archived.Model
+	ChannelId   uint64   `gorm:"index"`
+	ProjectName string   `gorm:"type:varchar(255);index"`
+	BlueprintId uint64   `gorm:"index"`
+	Statuses    []string `gorm:"type:json;serializer:json"`
+}
+
+func (notificationSubscription20261018) TableName() string {
+	return "_devlake_notification_subscriptions"
+}
+
+type addNotificationChannels struct{}
+
+func (*addNotificationChannels) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(notification20261018),
+		new(notificationChannel20261018),
+		new(notificationSubscription20261018),
+	)
+}
+
+func (*addNotificationChannels) Version() uint64 {
+	return 20261018000001
+}
+
+func (*addNotificationChannels) Name() string {
+	return "add notification channels, subscriptions and delivery log fields"
+}
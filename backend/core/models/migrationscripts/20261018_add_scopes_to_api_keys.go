@@ -0,0 +1,108 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addScopesToApiKeys)(nil)
+
+type apiKeyScopes20261018 struct {
+	Methods              []string `json:"methods,omitempty"`
+	Resources            []string `json:"resources,omitempty"`
+	Projects             []string `json:"projects,omitempty"`
+	WebhookConnectionIds []uint64 `json:"webhookConnectionIds,omitempty"`
+}
+
+type apiKey20261018 struct {
+	Scopes             *apiKeyScopes20261018 `gorm:"type:json;serializer:json"`
+	RateLimitPerMinute int
+	LastUsedAt         *time.Time
+	LastUsedIp         string `gorm:"type:varchar(255)"`
+}
+
+func (apiKey20261018) TableName() string {
+	return "_devlake_api_keys"
+}
+
+type webhookApiKey20261018 struct {
+	ID          uint64 `gorm:"primaryKey"`
+	AllowedPath string
+	Extra       string
+	Scopes      *apiKeyScopes20261018 `gorm:"type:json;serializer:json"`
+}
+
+func (webhookApiKey20261018) TableName() string {
+	return "_devlake_api_keys"
+}
+
+type addScopesToApiKeys struct{}
+
+func (*addScopesToApiKeys) Up(basicRes context.BasicRes) errors.Error {
+	db := basicRes.GetDal()
+	err := migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(apiKey20261018),
+	)
+	if err != nil {
+		return err
+	}
+	// the keys generated for webhook connections have a well known path, turn it into scopes
+	// which like the path only reach the endpoints of the connection, all of them POST
+	var apiKeys []webhookApiKey20261018
+	err = db.All(&apiKeys, dal.Where("type = ?", "plugin:webhook"))
+	if err != nil {
+		return err
+	}
+	for i := range apiKeys {
+		apiKey := &apiKeys[i]
+		var connectionId uint64
+		if _, e := fmt.Sscanf(apiKey.Extra, "connectionId:%d", &connectionId); e != nil {
+			continue
+		}
+		if apiKey.AllowedPath != fmt.Sprintf("/plugins/webhook/connections/%d/.*", connectionId) {
+			continue
+		}
+		apiKey.Scopes = &apiKeyScopes20261018{
+			Methods:              []string{http.MethodPost},
+			Resources:            []string{"plugins/webhook"},
+			WebhookConnectionIds: []uint64{connectionId},
+		}
+		if err = db.Update(apiKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*addScopesToApiKeys) Version() uint64 {
+	return 20261018000012
+}
+
+func (*addScopesToApiKeys) Name() string {
+	return "add scopes, rate limit and usage tracking to api keys"
+}
@@ -0,0 +1,100 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/migrationscripts/archived"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addSubProjects)(nil)
+
+type repoSubProject20261018 struct {
+	archived.NoPKModel
+	RepoId       string `gorm:"primaryKey;type:varchar(255)"`
+	Name         string `gorm:"primaryKey;type:varchar(255)"`
+	Path         string `gorm:"type:varchar(255)"`
+	Source       string `gorm:"type:varchar(20)"`
+	ManifestName string `gorm:"type:varchar(255)"`
+}
+
+func (repoSubProject20261018) TableName() string {
+	return "repo_sub_projects"
+}
+
+type commitSubProject20261018 struct {
+	archived.NoPKModel
+	RepoId         string `gorm:"primaryKey;type:varchar(255)"`
+	CommitSha      string `gorm:"primaryKey;type:varchar(40)"`
+	SubProjectName string `gorm:"primaryKey;type:varchar(255)"`
+	FileCount      int
+	Additions      int
+	Deletions      int
+}
+
+func (commitSubProject20261018) TableName() string {
+	return "commit_sub_projects"
+}
+
+type commitFileSubProject20261018 struct {
+	archived.NoPKModel
+	CommitFileId   string `gorm:"primaryKey;type:varchar(255)"`
+	RepoId         string `gorm:"index;type:varchar(255)"`
+	CommitSha      string `gorm:"index;type:varchar(40)"`
+	SubProjectName string `gorm:"type:varchar(255)"`
+}
+
+func (commitFileSubProject20261018) TableName() string {
+	return "commit_file_sub_projects"
+}
+
+type submoduleUpdate20261018 struct {
+	archived.NoPKModel
+	CommitSha    string `gorm:"primaryKey;type:varchar(40)"`
+	Path         string `gorm:"primaryKey;type:varchar(255)"`
+	RepoId       string `gorm:"index;type:varchar(255)"`
+	OldCommitSha string `gorm:"type:varchar(40)"`
+	NewCommitSha string `gorm:"type:varchar(40)"`
+}
+
+func (submoduleUpdate20261018) TableName() string {
+	return "submodule_updates"
+}
+
+type addSubProjects struct{}
+
+func (*addSubProjects) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		new(repoSubProject20261018),
+		new(commitSubProject20261018),
+		new(commitFileSubProject20261018),
+		new(submoduleUpdate20261018),
+	)
+}
+
+func (*addSubProjects) Version() uint64 {
+	return 20261018000009
+}
+
+func (*addSubProjects) Name() string {
+	return "add repo_sub_projects, commit_sub_projects, commit_file_sub_projects and submodule_updates tables"
+}
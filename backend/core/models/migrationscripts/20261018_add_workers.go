@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addWorkers)(nil)
+
+type worker20261018 struct {
+	Id           string `gorm:"primaryKey;type:varchar(255)"`
+	HostName     string `gorm:"type:varchar(255)"`
+	Pid          int
+	Version      string `gorm:"type:varchar(255)"`
+	Status       string `gorm:"type:varchar(20);index"`
+	MaxParallel  int
+	RunningTasks int
+	HeartbeatAt  time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (worker20261018) TableName() string {
+	return "_devlake_workers"
+}
+
+type task20261018 struct {
+	DispatchedAt   *time.Time
+	WorkerId       string          `gorm:"type:varchar(255);index"`
+	WorkerProgress json.RawMessage `gorm:"type:json"`
+}
+
+func (task20261018) TableName() string {
+	return "_devlake_tasks"
+}
+
+type addWorkers struct{}
+
+func (*addWorkers) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		&worker20261018{},
+		&task20261018{},
+	)
+}
+
+func (*addWorkers) Version() uint64 {
+	return 20261018000005
+}
+
+func (*addWorkers) Name() string {
+	return "add _devlake_workers and task claiming columns"
+}
@@ -145,5 +145,19 @@ func All() []plugin.MigrationScript {
 		new(modifyCicdDeploymentsToText),
 		new(increaseCqIssuesProjectKeyLength),
 		new(addAuthSessions),
+		new(addNotificationChannels),
+		new(addProjectDoraMetrics),
+		new(addAttributionStrategyToIncidentDeployments),
+		new(addLeadTimeStageMetrics),
+		new(addWorkers),
+		new(addCodeOwnerships),
+		new(addCodeOwnerRules),
+		new(addCommitTrailers),
+		new(addSubProjects),
+		new(addSourceToPullRequestIssues),
+		new(addDeploymentChanges),
+		new(addScopesToApiKeys),
+		new(addAuditLogs),
+		new(addIssueRemoteLinks),
 	}
 }
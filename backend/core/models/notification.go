@@ -27,17 +27,72 @@ const (
 	NotificationPipelineStatusChanged NotificationType = "PipelineStatusChanged"
 )
 
-// Notification records notifications sent by lake
+const (
+	NOTIFICATION_CHANNEL_SLACK = "slack"
+	NOTIFICATION_CHANNEL_TEAMS = "teams"
+	NOTIFICATION_CHANNEL_HTTP  = "http"
+	NOTIFICATION_CHANNEL_SMTP  = "smtp"
+)
+
+const (
+	NOTIFICATION_PENDING   = "PENDING"
+	NOTIFICATION_DELIVERED = "DELIVERED"
+	NOTIFICATION_FAILED    = "FAILED"
+)
+
+// Notification records notifications sent by lake, one record per delivery to a channel
 type Notification struct {
 	common.Model
-	Type         NotificationType
-	Endpoint     string
-	Nonce        string
-	ResponseCode int
-	Response     string
-	Data         string
+	Type         NotificationType `json:"type"`
+	Endpoint     string           `json:"endpoint"`
+	Nonce        string           `json:"-"`
+	ResponseCode int              `json:"responseCode"`
+	Response     string           `json:"response"`
+	Data         string           `json:"data"`
+	ChannelId    uint64           `json:"channelId" gorm:"index"`
+	PipelineId   uint64           `json:"pipelineId" gorm:"index"`
+	Status       string           `json:"status" gorm:"type:varchar(20)"`
+	Attempts     int              `json:"attempts"`
+	Error        string           `json:"error"`
 }
 
 func (Notification) TableName() string {
 	return "_devlake_notifications"
 }
+
+// NotificationChannel is an outbound destination of the pipeline notifications
+type NotificationChannel struct {
+	common.Model
+	Name string `json:"name" gorm:"type:varchar(255);uniqueIndex" validate:"required"`
+	Type string `json:"type" gorm:"type:varchar(20)" validate:"required"`
+	// Endpoint is the incoming webhook url for slack and teams, the target url for http and `host:port` for smtp.
+	// Encrypted as the path of the incoming webhooks is a secret on its own
+	Endpoint string `json:"endpoint" gorm:"serializer:encdec" validate:"required"`
+	// Secret signs the payloads sent to a http channel like the default notification does, it is the password of the smtp server otherwise
+	Secret     string   `json:"secret" gorm:"serializer:encdec"`
+	Username   string   `json:"username" gorm:"type:varchar(255)"`
+	From       string   `json:"from" gorm:"type:varchar(255)"`
+	Recipients []string `json:"recipients" gorm:"type:json;serializer:json"`
+	// Template is a go text/template rendering the message (or the body of a http channel), a default one is used if empty
+	Template string `json:"template" gorm:"type:text"`
+	// MaxRetries is how many times a failed delivery is retried with exponential backoff, 0 means the default
+	MaxRetries int `json:"maxRetries" validate:"min=0,max=10"`
+}
+
+func (NotificationChannel) TableName() string {
+	return "_devlake_notification_channels"
+}
+
+// NotificationSubscription subscribes a channel to the pipelines of a project or a blueprint
+type NotificationSubscription struct {
+	common.Model
+	ChannelId   uint64 `json:"channelId" gorm:"index"`
+	ProjectName string `json:"projectName" gorm:"type:varchar(255);index"`
+	BlueprintId uint64 `json:"blueprintId" gorm:"index"`
+	// Statuses lists the pipeline statuses to be notified, e.g. TASK_FAILED and TASK_PARTIAL, all of them if empty
+	Statuses []string `json:"statuses" gorm:"type:json;serializer:json"`
+}
+
+func (NotificationSubscription) TableName() string {
+	return "_devlake_notification_subscriptions"
+}
@@ -27,6 +27,11 @@ type GenericPipelineTask[T any] struct {
 	Plugin   string   `json:"plugin" binding:"required"`
 	Subtasks []string `json:"subtasks"`
 	Options  T        `json:"options"`
+	// Id is optional, it is only needed when other tasks depend on this one
+	Id string `json:"id,omitempty"`
+	// DependsOn lists the ids of the tasks to be finished before this one starts, the task
+	// would wait for all tasks of the previous stage if it was left empty
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 // PipelineTask represents a smallest unit of execution inside a PipelinePlan
@@ -35,7 +40,8 @@ type PipelineTask GenericPipelineTask[map[string]interface{}]
 // PipelineStage consist of multiple PipelineTasks, they will be executed in parallel
 type PipelineStage []*PipelineTask
 
-// PipelinePlan consist of multiple PipelineStages, they will be executed in sequential order
+// PipelinePlan consist of multiple PipelineStages, they will be executed in sequential order unless
+// tasks declare their dependencies explicitly, please check PipelinePlan.ToDag for details
 type PipelinePlan []PipelineStage
 
 // IsEmpty checks if a PipelinePlan is empty
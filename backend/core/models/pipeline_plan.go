@@ -0,0 +1,127 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/apache/incubator-devlake/core/errors"
+)
+
+// PipelineTaskPosition locates a task inside a PipelinePlan, Row and Col are 1-based just like
+// the PipelineRow and PipelineCol of a Task
+type PipelineTaskPosition struct {
+	Row int
+	Col int
+}
+
+// PipelineDag maps every task of a PipelinePlan to the tasks it depends on
+type PipelineDag map[PipelineTaskPosition][]PipelineTaskPosition
+
+// ToDag converts the plan into a dependency graph. A task with `dependsOn` waits for the tasks
+// carrying those ids only, while a task without it waits for all tasks of the previous non-empty stage,
+// which is exactly how the stage format used to be executed.
+func (plan PipelinePlan) ToDag() (PipelineDag, errors.Error) {
+	ids := make(map[string]PipelineTaskPosition)
+	for i, stage := range plan {
+		for j, task := range stage {
+			if task == nil || task.Id == "" {
+				continue
+			}
+			if _, ok := ids[task.Id]; ok {
+				return nil, errors.BadInput.New(fmt.Sprintf("duplicated task id %s in pipeline plan", task.Id))
+			}
+			ids[task.Id] = PipelineTaskPosition{Row: i + 1, Col: j + 1}
+		}
+	}
+	dag := make(PipelineDag)
+	for i, stage := range plan {
+		for j, task := range stage {
+			if task == nil {
+				continue
+			}
+			pos := PipelineTaskPosition{Row: i + 1, Col: j + 1}
+			deps := make([]PipelineTaskPosition, 0)
+			if len(task.DependsOn) > 0 {
+				for _, id := range task.DependsOn {
+					dep, ok := ids[id]
+					if !ok {
+						return nil, errors.BadInput.New(fmt.Sprintf("task %d-%d depends on unknown task id %s", pos.Row, pos.Col, id))
+					}
+					if dep == pos {
+						return nil, errors.BadInput.New(fmt.Sprintf("task %s depends on itself", id))
+					}
+					deps = append(deps, dep)
+				}
+			} else {
+				// empty stages (i.e. whose tasks were all removed by skipCollectors) are skipped
+				for k := i - 1; k >= 0 && len(deps) == 0; k-- {
+					for l, prev := range plan[k] {
+						if prev != nil {
+							deps = append(deps, PipelineTaskPosition{Row: k + 1, Col: l + 1})
+						}
+					}
+				}
+			}
+			dag[pos] = deps
+		}
+	}
+	if cycle := dag.cyclicTasks(); len(cycle) > 0 {
+		return nil, errors.BadInput.New(fmt.Sprintf("circular dependency detected among tasks %v", cycle))
+	}
+	return dag, nil
+}
+
+// cyclicTasks returns the tasks which can never be scheduled because of circular dependencies
+func (dag PipelineDag) cyclicTasks() []PipelineTaskPosition {
+	pending := make(map[PipelineTaskPosition]int, len(dag))
+	dependents := make(map[PipelineTaskPosition][]PipelineTaskPosition)
+	queue := make([]PipelineTaskPosition, 0)
+	for pos, deps := range dag {
+		pending[pos] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], pos)
+		}
+		if len(deps) == 0 {
+			queue = append(queue, pos)
+		}
+	}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		delete(pending, pos)
+		for _, dependent := range dependents[pos] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	cycle := make([]PipelineTaskPosition, 0, len(pending))
+	for pos := range pending {
+		cycle = append(cycle, pos)
+	}
+	sort.Slice(cycle, func(i, j int) bool {
+		if cycle[i].Row != cycle[j].Row {
+			return cycle[i].Row < cycle[j].Row
+		}
+		return cycle[i].Col < cycle[j].Col
+	})
+	return cycle
+}
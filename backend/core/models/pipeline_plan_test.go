@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelinePlan_ToDag(t *testing.T) {
+	// stage format: every task depends on all tasks of the previous stage
+	dag, err := PipelinePlan{
+		{{Plugin: "github"}, {Plugin: "gitlab"}},
+		{{Plugin: "gitextractor"}},
+		{{Plugin: "dora"}},
+	}.ToDag()
+	assert.Nil(t, err)
+	assert.Equal(t, PipelineDag{
+		{Row: 1, Col: 1}: {},
+		{Row: 1, Col: 2}: {},
+		{Row: 2, Col: 1}: {{Row: 1, Col: 1}, {Row: 1, Col: 2}},
+		{Row: 3, Col: 1}: {{Row: 2, Col: 1}},
+	}, dag)
+
+	// explicit dependencies override the stage order
+	dag, err = PipelinePlan{
+		{{Plugin: "github", Id: "gh1"}, {Plugin: "github", Id: "gh2"}},
+		{{Plugin: "gitextractor", DependsOn: []string{"gh1"}}, {Plugin: "gitextractor", DependsOn: []string{"gh2"}}},
+		{{Plugin: "dora"}},
+	}.ToDag()
+	assert.Nil(t, err)
+	assert.Equal(t, []PipelineTaskPosition{{Row: 1, Col: 1}}, dag[PipelineTaskPosition{Row: 2, Col: 1}])
+	assert.Equal(t, []PipelineTaskPosition{{Row: 1, Col: 2}}, dag[PipelineTaskPosition{Row: 2, Col: 2}])
+	assert.Equal(t, []PipelineTaskPosition{{Row: 2, Col: 1}, {Row: 2, Col: 2}}, dag[PipelineTaskPosition{Row: 3, Col: 1}])
+
+	// an empty stage doesn't break the chain
+	dag, err = PipelinePlan{
+		{{Plugin: "github"}},
+		{},
+		{{Plugin: "dora"}},
+	}.ToDag()
+	assert.Nil(t, err)
+	assert.Equal(t, []PipelineTaskPosition{{Row: 1, Col: 1}}, dag[PipelineTaskPosition{Row: 3, Col: 1}])
+
+	_, err = PipelinePlan{
+		{{Plugin: "github", Id: "a"}, {Plugin: "gitlab", Id: "a"}},
+	}.ToDag()
+	assert.NotNil(t, err)
+
+	_, err = PipelinePlan{
+		{{Plugin: "github", DependsOn: []string{"missing"}}},
+	}.ToDag()
+	assert.NotNil(t, err)
+
+	_, err = PipelinePlan{
+		{{Plugin: "github", Id: "a", DependsOn: []string{"b"}}, {Plugin: "gitlab", Id: "b", DependsOn: []string{"a"}}},
+	}.ToDag()
+	assert.NotNil(t, err)
+}
@@ -77,6 +77,11 @@ type Task struct {
 	BeganAt       *time.Time `json:"beganAt"`
 	FinishedAt    *time.Time `json:"finishedAt" gorm:"index"`
 	SpentSeconds  int        `json:"spentSeconds"`
+	// DispatchedAt is set when the task was handed over to the workers instead of running inside the server
+	DispatchedAt *time.Time `json:"dispatchedAt"`
+	WorkerId     string     `json:"workerId" gorm:"type:varchar(255);index"`
+	// WorkerProgress is the progress detail stored by the worker running the task, the server has no running copy of it
+	WorkerProgress *TaskProgressDetail `json:"-" gorm:"type:json;serializer:json"`
 }
 
 func (Task) TableName() string {
@@ -0,0 +1,45 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+const (
+	WORKER_ONLINE  = "ONLINE"
+	WORKER_OFFLINE = "OFFLINE"
+)
+
+// Worker is an extra lake process running with WORKER_MODE=true, it claims the tasks dispatched by the
+// server from the `_devlake_tasks` table and keeps its HeartbeatAt fresh while alive. The tasks held by a
+// worker missing heartbeats for WORKER_TIMEOUT would be handed over to other workers.
+type Worker struct {
+	Id           string    `gorm:"primaryKey;type:varchar(255)" json:"id"`
+	HostName     string    `gorm:"type:varchar(255)" json:"hostName"`
+	Pid          int       `json:"pid"`
+	Version      string    `gorm:"type:varchar(255)" json:"version"`
+	Status       string    `gorm:"type:varchar(20);index" json:"status"`
+	MaxParallel  int       `json:"maxParallel"`
+	RunningTasks int       `json:"runningTasks"`
+	HeartbeatAt  time.Time `json:"heartbeatAt"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+func (Worker) TableName() string {
+	return "_devlake_workers"
+}
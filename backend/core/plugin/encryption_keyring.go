@@ -0,0 +1,149 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/errors"
+)
+
+// EncodeKeyPreviousEnvStr lists the retired encryption secrets, comma separated, still needed to decrypt the values
+// which have not been re-encrypted under ENCRYPTION_SECRET yet
+const EncodeKeyPreviousEnvStr = "ENCRYPTION_SECRET_PREVIOUS"
+
+// encryptionKeyIdSeparator surrounds the key id in front of ciphertexts, it is not part of the base64 alphabet
+const encryptionKeyIdSeparator = "$"
+
+// EncryptionKeyring holds the current encryption secret along with the previous ones. Values are encrypted with the
+// current secret and prefixed with its key id, e.g. `$1a2b3c4d$<base64>`, so they can be decrypted with the right
+// secret after a rotation. Values encrypted before key ids existed have no prefix and are decrypted by trying every
+// secret of the keyring.
+type EncryptionKeyring struct {
+	currentId string
+	secrets   map[string]string
+	ids       []string
+}
+
+// NewEncryptionKeyring creates a keyring encrypting with the current secret and decrypting with any of them
+func NewEncryptionKeyring(currentSecret string, previousSecrets ...string) *EncryptionKeyring {
+	keyring := &EncryptionKeyring{secrets: make(map[string]string)}
+	keyring.currentId = keyring.add(currentSecret)
+	for _, secret := range previousSecrets {
+		secret = strings.TrimSpace(secret)
+		if secret != "" {
+			keyring.add(secret)
+		}
+	}
+	return keyring
+}
+
+// ParseEncryptionSecrets splits the comma separated ENCRYPTION_SECRET_PREVIOUS value
+func ParseEncryptionSecrets(secrets string) []string {
+	if strings.TrimSpace(secrets) == "" {
+		return nil
+	}
+	return strings.Split(secrets, ",")
+}
+
+// EncryptionKeyId returns the id of a secret, the first 8 hex digits of its salted sha256 sum, so the ids are stable
+// without further configuration and tell nothing about the secrets
+func EncryptionKeyId(secret string) string {
+	sum := sha256.Sum256([]byte("devlake-encryption-key-id:" + secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+func (k *EncryptionKeyring) add(secret string) string {
+	id := EncryptionKeyId(secret)
+	if _, ok := k.secrets[id]; !ok {
+		k.secrets[id] = secret
+		k.ids = append(k.ids, id)
+	}
+	return id
+}
+
+// CurrentKeyId returns the id of the secret new values are encrypted with
+func (k *EncryptionKeyring) CurrentKeyId() string {
+	return k.currentId
+}
+
+// CurrentSecret returns the secret new values are encrypted with
+func (k *EncryptionKeyring) CurrentSecret() string {
+	return k.secrets[k.currentId]
+}
+
+// KeyIds returns the ids of all the secrets, the current one first
+func (k *EncryptionKeyring) KeyIds() []string {
+	return append([]string{}, k.ids...)
+}
+
+// Encrypt encrypts the text with the current secret and prefixes it with the key id
+func (k *EncryptionKeyring) Encrypt(plainText string) (string, errors.Error) {
+	if k.CurrentSecret() == "" {
+		return plainText, errors.Default.New("encryptionSecret is required")
+	}
+	encrypted, err := Encrypt(k.CurrentSecret(), plainText)
+	if err != nil {
+		return plainText, err
+	}
+	return encryptionKeyIdSeparator + k.currentId + encryptionKeyIdSeparator + encrypted, nil
+}
+
+// Decrypt decrypts the text with the secret of its key id, or with the first secret able to when it has no key id
+func (k *EncryptionKeyring) Decrypt(encryptedText string) (string, errors.Error) {
+	keyId, encrypted, ok := SplitEncryptionKeyId(encryptedText)
+	if ok {
+		secret, found := k.secrets[keyId]
+		if !found {
+			return encryptedText, errors.Default.New("unknown encryption key id " + keyId + ", add the secret to " + EncodeKeyPreviousEnvStr)
+		}
+		return Decrypt(secret, encrypted)
+	}
+	var err errors.Error
+	for _, id := range k.ids {
+		var decrypted string
+		decrypted, err = Decrypt(k.secrets[id], encryptedText)
+		if err == nil {
+			return decrypted, nil
+		}
+	}
+	if err == nil {
+		err = errors.Default.New("encryptionSecret is required")
+	}
+	return encryptedText, err
+}
+
+// IsCurrent tells whether the text is encrypted with the current secret already
+func (k *EncryptionKeyring) IsCurrent(encryptedText string) bool {
+	keyId, _, ok := SplitEncryptionKeyId(encryptedText)
+	return ok && keyId == k.currentId
+}
+
+// SplitEncryptionKeyId separates the key id from the ciphertext, ok is false for values without key id
+func SplitEncryptionKeyId(encryptedText string) (keyId string, encrypted string, ok bool) {
+	if !strings.HasPrefix(encryptedText, encryptionKeyIdSeparator) {
+		return "", encryptedText, false
+	}
+	parts := strings.SplitN(encryptedText[len(encryptionKeyIdSeparator):], encryptionKeyIdSeparator, 2)
+	if len(parts) != 2 {
+		return "", encryptedText, false
+	}
+	return parts[0], parts[1], true
+}
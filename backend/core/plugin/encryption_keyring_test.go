@@ -0,0 +1,68 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionKeyring(t *testing.T) {
+	oldKeyring := NewEncryptionKeyring("old-secret")
+	oldEncrypted, err := oldKeyring.Encrypt("token")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(oldEncrypted, "$"+EncryptionKeyId("old-secret")+"$"))
+	legacyEncrypted, err := Encrypt("old-secret", "legacy token")
+	assert.Nil(t, err)
+
+	keyring := NewEncryptionKeyring("new-secret", ParseEncryptionSecrets(" old-secret ,")...)
+	assert.Equal(t, EncryptionKeyId("new-secret"), keyring.CurrentKeyId())
+	assert.Equal(t, []string{EncryptionKeyId("new-secret"), EncryptionKeyId("old-secret")}, keyring.KeyIds())
+
+	// values encrypted with a previous key, with or without key id, can still be decrypted
+	decrypted, err := keyring.Decrypt(oldEncrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", decrypted)
+	decrypted, err = keyring.Decrypt(legacyEncrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy token", decrypted)
+	assert.False(t, keyring.IsCurrent(oldEncrypted))
+	assert.False(t, keyring.IsCurrent(legacyEncrypted))
+
+	newEncrypted, err := keyring.Encrypt(decrypted)
+	assert.Nil(t, err)
+	assert.True(t, keyring.IsCurrent(newEncrypted))
+	decrypted, err = keyring.Decrypt(newEncrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy token", decrypted)
+
+	// the new key alone knows nothing about the old values
+	_, err = NewEncryptionKeyring("new-secret").Decrypt(oldEncrypted)
+	assert.NotNil(t, err)
+	_, err = NewEncryptionKeyring("new-secret").Decrypt(legacyEncrypted)
+	assert.NotNil(t, err)
+
+	// the migration scripts decrypting with the secret alone can read the values of the keyring too
+	decrypted, err = Decrypt("new-secret", newEncrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy token", decrypted)
+	_, err = Decrypt("old-secret", newEncrypted)
+	assert.NotNil(t, err)
+}
@@ -30,6 +30,7 @@ type ApiResourceInput struct {
 	Params  map[string]string      // path variables
 	Query   url.Values             // query string
 	Body    map[string]interface{} // json body
+	RawBody []byte                 // raw json body, kept for signature verification
 	Request *http.Request
 
 	User *common.User
@@ -52,9 +52,17 @@ func Decrypt(encryptionSecret, encryptedText string) (string, errors.Error) {
 		// return error message
 		return encryptedText, errors.Default.New("encryptionSecret is required")
 	}
+	// values encrypted by an EncryptionKeyring are prefixed with the key id of the secret
+	cipherText := encryptedText
+	if keyId, encrypted, ok := SplitEncryptionKeyId(encryptedText); ok {
+		if keyId != EncryptionKeyId(encryptionSecret) {
+			return encryptedText, errors.Default.New("the value is encrypted with key " + keyId + " instead of the given encryptionSecret")
+		}
+		cipherText = encrypted
+	}
 
 	// Decode Base64
-	decodingFromBase64, err1 := base64.StdEncoding.DecodeString(encryptedText)
+	decodingFromBase64, err1 := base64.StdEncoding.DecodeString(cipherText)
 	if err1 != nil {
 		return encryptedText, errors.Convert(err1)
 	}
@@ -0,0 +1,158 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/apache/incubator-devlake/core/errors"
+)
+
+// SecretRefPrefix marks a credential of a connection as a reference to a secret kept outside of DevLake,
+// i.e. `secretref://file/github/3/token` or `secretref://env/DEVLAKE_SECRET_GITHUB_3_TOKEN`
+const SecretRefPrefix = "secretref://"
+
+const (
+	SecretRefFileDirEnvStr   = "SECRETREF_FILE_DIR"
+	SecretRefEnvPrefixEnvStr = "SECRETREF_ENV_PREFIX"
+)
+
+// SecretProvider resolves the secret referenced by `path`, which is whatever follows `secretref://<provider>/` in
+// the reference. A connection may only read the secrets of its own namespace, see SecretNamespace, so providers
+// must refuse the paths outside of it. Plugins may register providers for external vaults.
+type SecretProvider interface {
+	Resolve(namespace, path string) (string, errors.Error)
+}
+
+var (
+	secretProviders     map[string]SecretProvider
+	secretProviderMutex sync.RWMutex
+)
+
+// RegisterSecretProvider makes the provider available under `secretref://<name>`
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretProviderMutex.Lock()
+	defer secretProviderMutex.Unlock()
+	if secretProviders == nil {
+		secretProviders = make(map[string]SecretProvider)
+	}
+	secretProviders[name] = provider
+}
+
+// IsSecretRef tells whether the value is a secret reference rather than the secret itself
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefPrefix)
+}
+
+// SecretNamespace returns the namespace of the secrets a connection may refer to, i.e. `github/3`. Connection ids
+// never change nor get reused, so a user able to edit connections can't point one at the secrets of another.
+func SecretNamespace(pluginName string, connectionId uint64) string {
+	return fmt.Sprintf("%s/%d", pluginName, connectionId)
+}
+
+// ResolveSecretRef returns the secret referenced by `ref` from the registered provider, `ref` must be inside the
+// namespace of the connection
+func ResolveSecretRef(namespace, ref string) (string, errors.Error) {
+	if !IsSecretRef(ref) {
+		return "", errors.BadInput.New("not a secret reference")
+	}
+	if namespace == "" {
+		return "", errors.BadInput.New("secret references are only allowed in the credentials of saved connections")
+	}
+	name, path, _ := strings.Cut(strings.TrimPrefix(ref, SecretRefPrefix), "/")
+	secretProviderMutex.RLock()
+	provider, ok := secretProviders[name]
+	secretProviderMutex.RUnlock()
+	if !ok {
+		return "", errors.BadInput.New(fmt.Sprintf("secret provider `%s` is not registered", name))
+	}
+	secret, err := provider.Resolve(namespace, path)
+	if err != nil {
+		return "", errors.Default.Wrap(err, fmt.Sprintf("failed to resolve secret from provider `%s`", name))
+	}
+	return secret, nil
+}
+
+// FileSecretProvider reads secrets from files under a base directory, i.e. docker or kubernetes secret mounts.
+// Paths are relative to the base directory and must be inside the directory of the namespace,
+// i.e. `github/3/token` for the connection 3 of github.
+type FileSecretProvider struct {
+	baseDir string
+}
+
+func NewFileSecretProvider(baseDir string) *FileSecretProvider {
+	return &FileSecretProvider{baseDir: baseDir}
+}
+
+func (p *FileSecretProvider) Resolve(namespace, path string) (string, errors.Error) {
+	if p.baseDir == "" {
+		return "", errors.BadInput.New(fmt.Sprintf("%s is not set", SecretRefFileDirEnvStr))
+	}
+	baseDir, err := filepath.Abs(p.baseDir)
+	if err != nil {
+		return "", errors.Convert(err)
+	}
+	namespaceDir := filepath.Join(baseDir, filepath.Clean("/"+namespace))
+	file := filepath.Join(baseDir, filepath.Clean("/"+path))
+	if !strings.HasPrefix(file, namespaceDir+string(filepath.Separator)) {
+		return "", errors.BadInput.New(fmt.Sprintf("secret file must be inside %s", namespaceDir))
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", errors.NotFound.Wrap(errors.Convert(err), "failed to read secret file")
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// EnvSecretProvider reads secrets from environment variables. Only variables starting with the prefix followed by
+// the namespace are readable, i.e. `DEVLAKE_SECRET_GITHUB_3_` for the connection 3 of github, so that a connection
+// can neither leak DevLake's own settings like ENCRYPTION_SECRET nor the secrets of other connections.
+type EnvSecretProvider struct {
+	prefix string
+}
+
+func NewEnvSecretProvider(prefix string) *EnvSecretProvider {
+	return &EnvSecretProvider{prefix: prefix}
+}
+
+func (p *EnvSecretProvider) Resolve(namespace, path string) (string, errors.Error) {
+	name := strings.Trim(path, "/")
+	prefix := p.prefix + envSecretNamespace(namespace) + "_"
+	if !strings.HasPrefix(name, prefix) || name == prefix {
+		return "", errors.BadInput.New(fmt.Sprintf("environment variable name must start with `%s`", prefix))
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.NotFound.New(fmt.Sprintf("environment variable `%s` is not set", name))
+	}
+	return value, nil
+}
+
+// envSecretNamespace turns `azuredevops_go/3` into `AZUREDEVOPS_GO_3`
+func envSecretNamespace(namespace string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(namespace))
+}
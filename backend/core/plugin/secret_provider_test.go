@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecretRef(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "github", "3"), 0700))
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "github", "4"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "github", "3", "token"), []byte("ghp_file\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "github", "4", "token"), []byte("ghp_other\n"), 0600))
+	t.Setenv("DEVLAKE_SECRET_GITLAB_3_TOKEN", "glpat_env")
+	t.Setenv("DEVLAKE_SECRET_GITLAB_31_TOKEN", "glpat_other")
+	t.Setenv("DEVLAKE_SECRET_AZUREDEVOPS_GO_3_TOKEN", "azdo_env")
+	t.Setenv("ENCRYPTION_SECRET", "do not leak")
+	RegisterSecretProvider("file", NewFileSecretProvider(dir))
+	RegisterSecretProvider("env", NewEnvSecretProvider("DEVLAKE_SECRET_"))
+
+	assert.False(t, IsSecretRef("ghp_plain"))
+	secret, err := ResolveSecretRef(SecretNamespace("github", 3), "secretref://file/github/3/token")
+	assert.Nil(t, err)
+	assert.Equal(t, "ghp_file", secret)
+	secret, err = ResolveSecretRef(SecretNamespace("gitlab", 3), "secretref://env/DEVLAKE_SECRET_GITLAB_3_TOKEN")
+	assert.Nil(t, err)
+	assert.Equal(t, "glpat_env", secret)
+	secret, err = ResolveSecretRef(SecretNamespace("azuredevops_go", 3), "secretref://env/DEVLAKE_SECRET_AZUREDEVOPS_GO_3_TOKEN")
+	assert.Nil(t, err)
+	assert.Equal(t, "azdo_env", secret)
+
+	for namespace, refs := range map[string][]string{
+		SecretNamespace("github", 3): {
+			"secretref://file/github/4/token",
+			"secretref://file/github/3/../4/token",
+			"secretref://file/../etc/passwd",
+			"secretref://file/github/3/missing",
+			"secretref://file/github/3",
+			"secretref://vault/devlake/github",
+		},
+		SecretNamespace("gitlab", 3): {
+			"secretref://env/ENCRYPTION_SECRET",
+			"secretref://env/DEVLAKE_SECRET_GITLAB_31_TOKEN",
+			"secretref://env/DEVLAKE_SECRET_GITLAB_3_MISSING",
+			"secretref://env/DEVLAKE_SECRET_GITLAB_3_",
+		},
+		// the connection is not saved yet
+		"": {"secretref://env/DEVLAKE_SECRET_GITLAB_3_TOKEN"},
+	} {
+		for _, ref := range refs {
+			_, err = ResolveSecretRef(namespace, ref)
+			assert.NotNil(t, err, ref)
+		}
+	}
+}
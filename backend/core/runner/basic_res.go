@@ -49,7 +49,12 @@ func CreateAppBasicRes() context.BasicRes {
 	if err != nil {
 		panic(err)
 	}
-	dalgorm.Init(cfg.GetString(plugin.EncodeKeyEnvStr))
+	dalgorm.Init(plugin.NewEncryptionKeyring(
+		cfg.GetString(plugin.EncodeKeyEnvStr),
+		plugin.ParseEncryptionSecrets(cfg.GetString(plugin.EncodeKeyPreviousEnvStr))...,
+	))
+	plugin.RegisterSecretProvider("file", plugin.NewFileSecretProvider(cfg.GetString(plugin.SecretRefFileDirEnvStr)))
+	plugin.RegisterSecretProvider("env", plugin.NewEnvSecretProvider(cfg.GetString(plugin.SecretRefEnvPrefixEnvStr)))
 	return CreateBasicRes(cfg, logger, db)
 }
 
@@ -19,6 +19,7 @@ package runner
 
 import (
 	gocontext "context"
+	"sort"
 	"time"
 
 	"github.com/apache/incubator-devlake/core/context"
@@ -27,7 +28,8 @@ import (
 	"github.com/apache/incubator-devlake/core/models"
 )
 
-// RunPipeline FIXME ...
+// RunPipeline executes the pending tasks of the pipeline, a task starts as soon as all tasks it depends on
+// are finished, please check models.PipelinePlan.ToDag for how dependencies are resolved
 func RunPipeline(
 	basicRes context.BasicRes,
 	pipelineId uint64,
@@ -44,20 +46,13 @@ func RunPipeline(
 	if err != nil {
 		return err
 	}
-	taskIds := make([][]uint64, 0)
-	for _, task := range tasks {
-		for len(taskIds) < task.PipelineRow {
-			taskIds = append(taskIds, make([]uint64, 0))
-		}
-		taskIds[task.PipelineRow-1] = append(taskIds[task.PipelineRow-1], task.ID)
-	}
-	return runPipelineTasks(basicRes, pipelineId, taskIds, runTasks)
+	return runPipelineTasks(basicRes, pipelineId, tasks, runTasks)
 }
 
 func runPipelineTasks(
 	basicRes context.BasicRes,
 	pipelineId uint64,
-	taskIds [][]uint64,
+	tasks []models.Task,
 	runTasks func([]uint64) errors.Error,
 ) errors.Error {
 	db := basicRes.GetDal()
@@ -74,28 +69,11 @@ func runPipelineTasks(
 		return nil
 	}
 
-	// This double for loop executes each set of tasks sequentially while
-	// executing the set of tasks concurrently.
-	for i, row := range taskIds {
-		// update stage
-		err = db.UpdateColumns(dbPipeline, []dal.DalSet{
-			{ColumnName: "status", Value: models.TASK_RUNNING},
-			{ColumnName: "stage", Value: i + 1},
-		})
-		if err != nil {
-			log.Error(err, "update pipeline state failed")
-			break
-		}
-		// run tasks in parallel
-		err = runTasks(row)
-		if err != nil {
-			log.Error(err, "run tasks failed")
-			if errors.Is(err, gocontext.Canceled) || !dbPipeline.SkipOnFail {
-				log.Info("return error")
-				return err
-			}
-		}
+	dag, err := dbPipeline.Plan.ToDag()
+	if err != nil {
+		return errors.Default.Wrap(err, "invalid pipeline plan")
 	}
+	err = newPipelineScheduler(basicRes, dbPipeline, dag, tasks, runTasks).run()
 	if dbPipeline.BeganAt != nil {
 		log.Info("pipeline finished in %d ms: %v", time.Now().UnixMilli()-dbPipeline.BeganAt.UnixMilli(), err)
 	} else {
@@ -103,3 +81,170 @@ func runPipelineTasks(
 	}
 	return err
 }
+
+type pipelineTaskResult struct {
+	pos models.PipelineTaskPosition
+	err errors.Error
+}
+
+// pipelineScheduler starts the tasks of a pipeline in dependency order
+type pipelineScheduler struct {
+	basicRes   context.BasicRes
+	pipeline   *models.Pipeline
+	runTasks   func([]uint64) errors.Error
+	taskIds    map[models.PipelineTaskPosition][]uint64
+	waiting    map[models.PipelineTaskPosition]int
+	dependents map[models.PipelineTaskPosition][]models.PipelineTaskPosition
+	results    chan pipelineTaskResult
+	running    int
+	stage      int
+}
+
+func newPipelineScheduler(
+	basicRes context.BasicRes,
+	pipeline *models.Pipeline,
+	dag models.PipelineDag,
+	tasks []models.Task,
+	runTasks func([]uint64) errors.Error,
+) *pipelineScheduler {
+	s := &pipelineScheduler{
+		basicRes:   basicRes,
+		pipeline:   pipeline,
+		runTasks:   runTasks,
+		taskIds:    make(map[models.PipelineTaskPosition][]uint64),
+		waiting:    make(map[models.PipelineTaskPosition]int),
+		dependents: make(map[models.PipelineTaskPosition][]models.PipelineTaskPosition),
+		results:    make(chan pipelineTaskResult),
+	}
+	for _, task := range tasks {
+		pos := models.PipelineTaskPosition{Row: task.PipelineRow, Col: task.PipelineCol}
+		s.taskIds[pos] = append(s.taskIds[pos], task.ID)
+	}
+	// tasks finished by previous runs are not loaded, the dependencies on them are satisfied already
+	for pos := range s.taskIds {
+		deps, ok := dag[pos]
+		if !ok {
+			// the task is missing from the plan, fallback to the stage behavior
+			for dep := range s.taskIds {
+				if dep.Row == pos.Row-1 {
+					deps = append(deps, dep)
+				}
+			}
+		}
+		for _, dep := range deps {
+			if _, pending := s.taskIds[dep]; pending {
+				s.waiting[pos]++
+				s.dependents[dep] = append(s.dependents[dep], pos)
+			}
+		}
+	}
+	return s
+}
+
+// run starts the tasks as soon as their dependencies are finished and waits for all of them. A failure stops
+// the scheduling of new tasks unless the pipeline is SkipOnFail, the tasks left behind are reported in the log
+// and all failures are combined into the returned error, a cancellation is returned as is.
+func (s *pipelineScheduler) run() errors.Error {
+	log := s.basicRes.GetLogger()
+	ready := make([]models.PipelineTaskPosition, 0)
+	for pos := range s.taskIds {
+		if s.waiting[pos] == 0 {
+			ready = append(ready, pos)
+		}
+	}
+	sortTaskPositions(ready)
+	var errs []error
+	var cancelled errors.Error
+	started := make(map[models.PipelineTaskPosition]bool)
+	stopped := false
+	startAll := func(positions []models.PipelineTaskPosition) {
+		for _, pos := range positions {
+			if err := s.start(pos); err != nil {
+				errs = append(errs, err)
+				stopped = true
+				return
+			}
+			started[pos] = true
+		}
+	}
+	startAll(ready)
+	for s.running > 0 {
+		result := <-s.results
+		s.running--
+		if result.err != nil {
+			log.Error(result.err, "run tasks at stage %d column %d failed", result.pos.Row, result.pos.Col)
+			if errors.Is(result.err, gocontext.Canceled) {
+				cancelled = result.err
+				stopped = true
+			} else {
+				errs = append(errs, result.err)
+				if !s.pipeline.SkipOnFail {
+					stopped = true
+				}
+			}
+		}
+		if stopped {
+			// wait for the running tasks without starting new ones
+			continue
+		}
+		next := make([]models.PipelineTaskPosition, 0)
+		for _, dependent := range s.dependents[result.pos] {
+			s.waiting[dependent]--
+			if s.waiting[dependent] == 0 {
+				next = append(next, dependent)
+			}
+		}
+		sortTaskPositions(next)
+		startAll(next)
+	}
+	notStarted := make([]models.PipelineTaskPosition, 0)
+	for pos := range s.taskIds {
+		if !started[pos] {
+			notStarted = append(notStarted, pos)
+		}
+	}
+	if len(notStarted) > 0 {
+		sortTaskPositions(notStarted)
+		log.Warn(nil, "%d task(s) were not started due to the failure(s) above: %v", len(notStarted), notStarted)
+	}
+	if cancelled != nil {
+		return cancelled
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errors.Convert(errs[0])
+	default:
+		return errors.Default.Combine(errs)
+	}
+}
+
+func (s *pipelineScheduler) start(pos models.PipelineTaskPosition) errors.Error {
+	// the stage of the pipeline indicates the furthest stage being executed
+	if pos.Row > s.stage {
+		err := s.basicRes.GetDal().UpdateColumns(s.pipeline, []dal.DalSet{
+			{ColumnName: "status", Value: models.TASK_RUNNING},
+			{ColumnName: "stage", Value: pos.Row},
+		})
+		if err != nil {
+			s.basicRes.GetLogger().Error(err, "update pipeline state failed")
+			return err
+		}
+		s.stage = pos.Row
+	}
+	s.running++
+	go func(pos models.PipelineTaskPosition, taskIds []uint64) {
+		s.results <- pipelineTaskResult{pos: pos, err: s.runTasks(taskIds)}
+	}(pos, s.taskIds[pos])
+	return nil
+}
+
+func sortTaskPositions(positions []models.PipelineTaskPosition) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Row != positions[j].Row {
+			return positions[i].Row < positions[j].Row
+		}
+		return positions[i].Col < positions[j].Col
+	})
+}
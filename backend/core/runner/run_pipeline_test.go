@@ -0,0 +1,169 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	gocontext "context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// scheduledTasks runs a plan through the pipelineScheduler, the task ids are numbered row by row starting from 1
+type scheduledTasks struct {
+	sync.Mutex
+	finished []uint64
+	warnings []string
+}
+
+func runScheduler(
+	t *testing.T,
+	plan models.PipelinePlan,
+	skipOnFail bool,
+	runTask func(taskId uint64, finished func(uint64) <-chan struct{}) errors.Error,
+) (*scheduledTasks, errors.Error) {
+	result := &scheduledTasks{}
+	logger := unithelper.DummyLogger()
+	logger.On("Warn", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		result.Lock()
+		defer result.Unlock()
+		result.warnings = append(result.warnings, fmt.Sprintf(args.String(1), args.Get(2).([]interface{})...))
+	}).Maybe()
+	mockDal := mockdal.NewDal(t)
+	mockDal.On("UpdateColumns", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRes := mockcontext.NewBasicRes(t)
+	mockRes.On("GetDal").Return(mockDal).Maybe()
+	mockRes.On("GetLogger").Return(logger).Maybe()
+
+	var tasks []models.Task
+	done := make(map[uint64]chan struct{})
+	for i, stage := range plan {
+		for j := range stage {
+			id := uint64(len(tasks) + 1)
+			tasks = append(tasks, models.Task{Model: common.Model{ID: id}, PipelineRow: i + 1, PipelineCol: j + 1})
+			done[id] = make(chan struct{})
+		}
+	}
+	finished := func(id uint64) <-chan struct{} {
+		return done[id]
+	}
+	dag, err := plan.ToDag()
+	assert.Nil(t, err)
+	pipeline := &models.Pipeline{}
+	pipeline.SkipOnFail = skipOnFail
+	err = newPipelineScheduler(mockRes, pipeline, dag, tasks, func(taskIds []uint64) errors.Error {
+		e := runTask(taskIds[0], finished)
+		result.Lock()
+		result.finished = append(result.finished, taskIds[0])
+		result.Unlock()
+		close(done[taskIds[0]])
+		return e
+	}).run()
+	return result, err
+}
+
+// waitFor blocks until the task is finished, it gives up after a while to keep a broken scheduler from hanging the test
+func waitFor(ch <-chan struct{}) errors.Error {
+	select {
+	case <-ch:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.Default.New("timed out")
+	}
+}
+
+func TestPipelineSchedulerDependencies(t *testing.T) {
+	plan := models.PipelinePlan{
+		{{Plugin: "github", Id: "a"}, {Plugin: "gitlab", Id: "b"}},
+		{{Plugin: "dora", DependsOn: []string{"a"}}, {Plugin: "dora", DependsOn: []string{"b"}}},
+	}
+	// task 2 (b) blocks until task 3 is done which proves task 3 doesn't wait for the whole first stage
+	result, err := runScheduler(t, plan, false, func(id uint64, finished func(uint64) <-chan struct{}) errors.Error {
+		if id == 2 {
+			return waitFor(finished(3))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(result.finished))
+	assert.Equal(t, uint64(4), result.finished[3])
+	assert.Empty(t, result.warnings)
+}
+
+func TestPipelineSchedulerStopOnFailure(t *testing.T) {
+	plan := models.PipelinePlan{
+		{{Plugin: "github", Id: "a"}},
+		{{Plugin: "dora", DependsOn: []string{"a"}}, {Plugin: "refdiff"}},
+	}
+	result, err := runScheduler(t, plan, false, func(id uint64, _ func(uint64) <-chan struct{}) errors.Error {
+		return errors.Default.New(fmt.Sprintf("task %d failed", id))
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "task 1 failed")
+	assert.Equal(t, []uint64{1}, result.finished)
+	if assert.Len(t, result.warnings, 1) {
+		assert.Contains(t, result.warnings[0], "2 task(s) were not started")
+	}
+}
+
+func TestPipelineSchedulerSkipOnFail(t *testing.T) {
+	plan := models.PipelinePlan{
+		{{Plugin: "github", Id: "a"}, {Plugin: "gitlab", Id: "b"}},
+		{{Plugin: "dora", DependsOn: []string{"a", "b"}}},
+	}
+	result, err := runScheduler(t, plan, true, func(id uint64, _ func(uint64) <-chan struct{}) errors.Error {
+		if id == 3 {
+			return nil
+		}
+		return errors.Default.New(fmt.Sprintf("task %d failed", id))
+	})
+	assert.NotNil(t, err)
+	// every failure is reported, not only the first one
+	assert.Contains(t, err.Error(), "task 1 failed")
+	assert.Contains(t, err.Error(), "task 2 failed")
+	assert.Len(t, result.finished, 3)
+	assert.Empty(t, result.warnings)
+}
+
+func TestPipelineSchedulerCancelled(t *testing.T) {
+	plan := models.PipelinePlan{
+		{{Plugin: "github", Id: "a"}, {Plugin: "gitlab", Id: "b"}},
+		{{Plugin: "dora", DependsOn: []string{"a"}}},
+	}
+	result, err := runScheduler(t, plan, true, func(id uint64, finished func(uint64) <-chan struct{}) errors.Error {
+		if id == 1 {
+			return errors.Convert(gocontext.Canceled)
+		}
+		return errors.Default.New("failed")
+	})
+	assert.True(t, errors.Is(err, gocontext.Canceled))
+	assert.Len(t, result.finished, 2)
+	if assert.Len(t, result.warnings, 1) {
+		assert.Contains(t, result.warnings[0], "1 task(s) were not started")
+	}
+}
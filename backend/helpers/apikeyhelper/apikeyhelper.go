@@ -30,14 +30,16 @@ import (
 	common "github.com/apache/incubator-devlake/core/models/common"
 	"github.com/apache/incubator-devlake/core/utils"
 	"github.com/spf13/viper"
-	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	EncodeKeyEnvStr = "ENCRYPTION_SECRET"
 	apiKeyLen       = 128
+	// lastUsedThrottle bounds the DB writes tracking the usage of a key
+	lastUsedThrottle = time.Minute
 )
 
 type ApiKeyHelper struct {
@@ -45,6 +47,9 @@ type ApiKeyHelper struct {
 	cfg              *viper.Viper
 	logger           log.Logger
 	encryptionSecret string
+
+	lastUsedMu sync.Mutex
+	lastUsed   map[uint64]time.Time
 }
 
 func NewApiKeyHelper(basicRes context.BasicRes, logger log.Logger) *ApiKeyHelper {
@@ -58,13 +63,16 @@ func NewApiKeyHelper(basicRes context.BasicRes, logger log.Logger) *ApiKeyHelper
 		cfg:              cfg,
 		logger:           logger,
 		encryptionSecret: encryptionSecret,
+		lastUsed:         map[uint64]time.Time{},
 	}
 }
 
-func (c *ApiKeyHelper) Create(tx dal.Transaction, user *common.User, name string, expiredAt *time.Time, allowedPath string, apiKeyType string, extra string) (*models.ApiKey, errors.Error) {
-	if _, err := regexp.Compile(allowedPath); err != nil {
-		c.logger.Error(err, "Compile allowed path")
-		return nil, errors.Default.Wrap(err, fmt.Sprintf("compile allowed path: %s", allowedPath))
+func (c *ApiKeyHelper) Create(tx dal.Transaction, user *common.User, name string, expiredAt *time.Time, scopes *models.ApiKeyScopes, rateLimitPerMinute int, apiKeyType string, extra string) (*models.ApiKey, errors.Error) {
+	if err := c.ValidateScopes(tx, scopes); err != nil {
+		return nil, err
+	}
+	if rateLimitPerMinute < 0 {
+		return nil, errors.BadInput.New("rateLimitPerMinute must not be negative")
 	}
 	apiKey, hashedApiKey, err := c.generateApiKey()
 	if err != nil {
@@ -77,12 +85,13 @@ func (c *ApiKeyHelper) Create(tx dal.Transaction, user *common.User, name string
 			CreatedAt: now,
 			UpdatedAt: now,
 		},
-		Name:        name,
-		ApiKey:      hashedApiKey,
-		ExpiredAt:   expiredAt,
-		AllowedPath: allowedPath,
-		Type:        apiKeyType,
-		Extra:       extra,
+		Name:               name,
+		ApiKey:             hashedApiKey,
+		ExpiredAt:          expiredAt,
+		Scopes:             scopes,
+		RateLimitPerMinute: rateLimitPerMinute,
+		Type:               apiKeyType,
+		Extra:              extra,
 	}
 	if user != nil {
 		apiKeyRecord.Creator = common.Creator{
@@ -105,8 +114,8 @@ func (c *ApiKeyHelper) Create(tx dal.Transaction, user *common.User, name string
 	return apiKeyRecord, nil
 }
 
-func (c *ApiKeyHelper) CreateForPlugin(tx dal.Transaction, user *common.User, name string, pluginName string, allowedPath string, extra string) (*models.ApiKey, errors.Error) {
-	return c.Create(tx, user, name, nil, allowedPath, fmt.Sprintf("plugin:%s", pluginName), extra)
+func (c *ApiKeyHelper) CreateForPlugin(tx dal.Transaction, user *common.User, name string, pluginName string, scopes *models.ApiKeyScopes, extra string) (*models.ApiKey, errors.Error) {
+	return c.Create(tx, user, name, nil, scopes, 0, fmt.Sprintf("plugin:%s", pluginName), extra)
 }
 
 func (c *ApiKeyHelper) Put(user *common.User, id uint64) (*models.ApiKey, errors.Error) {
@@ -0,0 +1,244 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikeyhelper
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/plugin"
+)
+
+const webhookConnectionTable = "_tool_webhook_connections"
+
+var apiKeyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// ValidateScopes normalizes the scopes and makes sure they refer to existing resources,
+// so a typo can't silently produce a key which is useless or broader than intended.
+func (c *ApiKeyHelper) ValidateScopes(tx dal.Dal, scopes *models.ApiKeyScopes) errors.Error {
+	if scopes == nil {
+		return errors.BadInput.New("api key's scopes are missing")
+	}
+	restricted := len(scopes.Methods) > 0 || len(scopes.Resources) > 0 || len(scopes.Projects) > 0 || len(scopes.WebhookConnectionIds) > 0
+	if scopes.All && restricted {
+		return errors.BadInput.New("api key's scopes can't restrict the key and set all at the same time")
+	}
+	if !scopes.All && !restricted {
+		return errors.BadInput.New("api key's scopes are empty, set all to true for a key allowed to do anything but the admin resources")
+	}
+	for i, method := range scopes.Methods {
+		scopes.Methods[i] = strings.ToUpper(strings.TrimSpace(method))
+		if !contains(apiKeyMethods, scopes.Methods[i]) {
+			return errors.BadInput.New(fmt.Sprintf("invalid method %s, must be one of %s", method, strings.Join(apiKeyMethods, ",")))
+		}
+	}
+	for _, resource := range scopes.Resources {
+		if pluginName, ok := strings.CutPrefix(resource, "plugins/"); ok {
+			if _, err := plugin.GetPlugin(pluginName); err != nil {
+				return errors.BadInput.Wrap(err, fmt.Sprintf("invalid resource %s", resource))
+			}
+		} else if !contains(models.ApiKeyResourceTypes, resource) && !contains(models.ApiKeyAdminResourceTypes, resource) {
+			resourceTypes := append(append([]string{}, models.ApiKeyResourceTypes...), models.ApiKeyAdminResourceTypes...)
+			return errors.BadInput.New(fmt.Sprintf("invalid resource %s, must be one of %s or plugins/<plugin name>", resource, strings.Join(resourceTypes, ",")))
+		}
+	}
+	if len(scopes.Projects) > 0 {
+		var found []string
+		err := tx.Pluck("name", &found, dal.From(&models.Project{}), dal.Where("name IN ?", scopes.Projects))
+		if err != nil {
+			return errors.Default.Wrap(err, "error verifying the projects of the api key")
+		}
+		for _, project := range scopes.Projects {
+			if !contains(found, project) {
+				return errors.BadInput.New(fmt.Sprintf("project %s doesn't exist", project))
+			}
+		}
+	}
+	if len(scopes.WebhookConnectionIds) > 0 {
+		if len(scopes.Resources) > 0 && !contains(scopes.Resources, "plugins") && !contains(scopes.Resources, "plugins/webhook") {
+			return errors.BadInput.New("webhookConnectionIds requires the plugins/webhook resource")
+		}
+		var found []uint64
+		err := tx.Pluck("id", &found, dal.From(webhookConnectionTable), dal.Where("id IN ?", scopes.WebhookConnectionIds))
+		if err != nil {
+			return errors.Default.Wrap(err, "error verifying the webhook connections of the api key")
+		}
+		for _, id := range scopes.WebhookConnectionIds {
+			if !contains(found, id) {
+				return errors.BadInput.New(fmt.Sprintf("webhook connection %d doesn't exist", id))
+			}
+		}
+	}
+	return nil
+}
+
+// ScopedRequest describes what a request made with an api key acts on
+type ScopedRequest struct {
+	Method string
+	// Resource is the resource type of the route, i.e. "projects" or "plugins/github"
+	Resource string
+	// Admin is set for the routes RBAC reserves to admins, which like the ApiKeyAdminResourceTypes
+	// are only allowed when the key names their resource
+	Admin bool
+	// Projects the target belongs to, empty when it doesn't belong to any
+	Projects []string
+	// WebhookConnectionId is set for the endpoints of a webhook connection
+	WebhookConnectionId uint64
+}
+
+// CheckScopes returns a Forbidden error unless the request matches every scope of the key
+func CheckScopes(scopes *models.ApiKeyScopes, req *ScopedRequest) errors.Error {
+	if len(scopes.Methods) > 0 && !contains(scopes.Methods, req.Method) {
+		return errors.Forbidden.New(fmt.Sprintf("method %s is not allowed by the api key's scopes", req.Method))
+	}
+	admin := req.Admin || contains(models.ApiKeyAdminResourceTypes, req.Resource)
+	if admin && !GrantsResource(scopes, req.Resource) {
+		return errors.Forbidden.New(fmt.Sprintf("resource %s is reserved to admins and must be named by the api key's scopes", req.Resource))
+	}
+	if len(scopes.Resources) > 0 && !GrantsResource(scopes, req.Resource) {
+		return errors.Forbidden.New(fmt.Sprintf("resource %s is not allowed by the api key's scopes", req.Resource))
+	}
+	if len(scopes.Projects) > 0 {
+		if len(req.Projects) == 0 {
+			return errors.Forbidden.New("only the resources of the api key's projects are allowed")
+		}
+		for _, project := range req.Projects {
+			if !contains(scopes.Projects, project) {
+				return errors.Forbidden.New(fmt.Sprintf("project %s is not allowed by the api key's scopes", project))
+			}
+		}
+	}
+	if len(scopes.WebhookConnectionIds) > 0 && !contains(scopes.WebhookConnectionIds, req.WebhookConnectionId) {
+		return errors.Forbidden.New("only the api key's webhook connections are allowed")
+	}
+	return nil
+}
+
+// GrantsResource tells whether the key's resources name the resource type, "plugins" standing for every plugin
+func GrantsResource(scopes *models.ApiKeyScopes, resource string) bool {
+	for _, granted := range scopes.Resources {
+		if granted == resource || (granted == "plugins" && strings.HasPrefix(resource, "plugins/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteResource returns the resource type of a route, i.e. `projects` for
+// `/projects/:projectName` and `plugins/github` for plugin routes of github
+func RouteResource(route string) string {
+	segments := strings.SplitN(strings.TrimPrefix(route, "/"), "/", 3)
+	if segments[0] == "plugins" && len(segments) > 1 {
+		return "plugins/" + segments[1]
+	}
+	return segments[0]
+}
+
+// RecordUsage saves when and from where the key was last used, at most once per
+// lastUsedThrottle unless the address changes, off the request path.
+func (c *ApiKeyHelper) RecordUsage(apiKey *models.ApiKey, ip string) {
+	now := time.Now()
+	c.lastUsedMu.Lock()
+	if last, ok := c.lastUsed[apiKey.ID]; ok && now.Sub(last) < lastUsedThrottle && apiKey.LastUsedIp == ip {
+		c.lastUsedMu.Unlock()
+		return
+	}
+	c.lastUsed[apiKey.ID] = now
+	c.lastUsedMu.Unlock()
+
+	go func() {
+		err := c.basicRes.GetDal().UpdateColumns(&models.ApiKey{}, []dal.DalSet{
+			{ColumnName: "last_used_at", Value: now},
+			{ColumnName: "last_used_ip", Value: ip},
+		}, dal.Where("id = ?", apiKey.ID))
+		if err != nil {
+			c.logger.Warn(err, "record usage of api key %d", apiKey.ID)
+		}
+	}()
+}
+
+// RateLimiter counts the requests made with each api key in fixed one minute windows.
+// Counts are kept in memory, so the limit applies to each DevLake instance: behind a
+// load balancer spreading the requests over N instances, a key may make up to N times
+// its limit. The windows of the keys no longer in use are evicted once expired.
+type RateLimiter struct {
+	mu        sync.Mutex
+	windows   map[uint64]*rateWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{windows: map[uint64]*rateWindow{}, now: time.Now}
+}
+
+// Allow records a request made with the key. When the key's limit is reached it
+// returns false and how long to wait for the next window.
+func (l *RateLimiter) Allow(apiKey *models.ApiKey) (bool, time.Duration) {
+	if apiKey.RateLimitPerMinute <= 0 {
+		return true, 0
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	w, ok := l.windows[apiKey.ID]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &rateWindow{start: now}
+		l.windows[apiKey.ID] = w
+	}
+	if w.count >= apiKey.RateLimitPerMinute {
+		return false, w.start.Add(time.Minute).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep evicts the expired windows, at most once a minute so the cost stays
+// proportional to the keys used meanwhile
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	for id, w := range l.windows {
+		if now.Sub(w.start) >= time.Minute {
+			delete(l.windows, id)
+		}
+	}
+	l.lastSweep = now
+}
+
+func contains[T comparable](list []T, target T) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikeyhelper
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckScopes(t *testing.T) {
+	readOnly := &models.ApiKeyScopes{Methods: []string{http.MethodGet}}
+	assert.Nil(t, CheckScopes(readOnly, &ScopedRequest{Method: http.MethodGet, Resource: "projects"}))
+	assert.NotNil(t, CheckScopes(readOnly, &ScopedRequest{Method: http.MethodDelete, Resource: "projects"}))
+
+	plugins := &models.ApiKeyScopes{Resources: []string{"plugins", "pipelines"}}
+	assert.Nil(t, CheckScopes(plugins, &ScopedRequest{Method: http.MethodPost, Resource: "plugins/github"}))
+	assert.Nil(t, CheckScopes(plugins, &ScopedRequest{Method: http.MethodPost, Resource: "pipelines"}))
+	assert.NotNil(t, CheckScopes(plugins, &ScopedRequest{Method: http.MethodPost, Resource: "projects"}))
+
+	project := &models.ApiKeyScopes{Projects: []string{"a"}}
+	assert.Nil(t, CheckScopes(project, &ScopedRequest{Method: http.MethodPost, Resource: "blueprints", Projects: []string{"a"}}))
+	assert.NotNil(t, CheckScopes(project, &ScopedRequest{Method: http.MethodPost, Resource: "blueprints", Projects: []string{"a", "b"}}))
+	assert.NotNil(t, CheckScopes(project, &ScopedRequest{Method: http.MethodGet, Resource: "projects"}))
+
+	webhook := &models.ApiKeyScopes{Resources: []string{"plugins/webhook"}, WebhookConnectionIds: []uint64{1}}
+	assert.Nil(t, CheckScopes(webhook, &ScopedRequest{Method: http.MethodPost, Resource: "plugins/webhook", WebhookConnectionId: 1}))
+	assert.NotNil(t, CheckScopes(webhook, &ScopedRequest{Method: http.MethodPost, Resource: "plugins/webhook", WebhookConnectionId: 2}))
+	assert.NotNil(t, CheckScopes(webhook, &ScopedRequest{Method: http.MethodPost, Resource: "plugins/github"}))
+
+	// admin resources must be named, whatever the other scopes
+	all := &models.ApiKeyScopes{All: true}
+	assert.Nil(t, CheckScopes(all, &ScopedRequest{Method: http.MethodDelete, Resource: "projects"}))
+	assert.NotNil(t, CheckScopes(all, &ScopedRequest{Method: http.MethodGet, Resource: "api-keys"}))
+	assert.NotNil(t, CheckScopes(readOnly, &ScopedRequest{Method: http.MethodGet, Resource: "audit-logs"}))
+	assert.Nil(t, CheckScopes(plugins, &ScopedRequest{Method: http.MethodPost, Resource: "pipelines", Admin: true}))
+	assert.NotNil(t, CheckScopes(all, &ScopedRequest{Method: http.MethodPost, Resource: "pipelines", Admin: true}))
+	admin := &models.ApiKeyScopes{Resources: []string{"api-keys", "audit-logs"}}
+	assert.Nil(t, CheckScopes(admin, &ScopedRequest{Method: http.MethodGet, Resource: "audit-logs"}))
+	assert.NotNil(t, CheckScopes(admin, &ScopedRequest{Method: http.MethodPost, Resource: "encryption-secret"}))
+}
+
+func TestValidateScopes(t *testing.T) {
+	helper := &ApiKeyHelper{}
+	assert.NotNil(t, helper.ValidateScopes(nil, nil))
+	assert.NotNil(t, helper.ValidateScopes(nil, &models.ApiKeyScopes{}))
+	assert.NotNil(t, helper.ValidateScopes(nil, &models.ApiKeyScopes{All: true, Methods: []string{http.MethodGet}}))
+	assert.Nil(t, helper.ValidateScopes(nil, &models.ApiKeyScopes{All: true}))
+
+	scopes := &models.ApiKeyScopes{Methods: []string{" get"}, Resources: []string{"projects", "api-keys"}}
+	assert.Nil(t, helper.ValidateScopes(nil, scopes))
+	assert.Equal(t, []string{http.MethodGet}, scopes.Methods)
+	assert.NotNil(t, helper.ValidateScopes(nil, &models.ApiKeyScopes{Methods: []string{"TRACE"}}))
+	assert.NotNil(t, helper.ValidateScopes(nil, &models.ApiKeyScopes{Resources: []string{"project"}}))
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter()
+	limited := &models.ApiKey{Model: common.Model{ID: 1}, RateLimitPerMinute: 2}
+	unlimited := &models.ApiKey{Model: common.Model{ID: 2}}
+	for i := 0; i < 2; i++ {
+		allowed, _ := limiter.Allow(limited)
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter := limiter.Allow(limited)
+	assert.False(t, allowed)
+	assert.True(t, retryAfter > 0)
+	for i := 0; i < 10; i++ {
+		allowed, _ = limiter.Allow(unlimited)
+		assert.True(t, allowed)
+	}
+}
+
+func TestRateLimiterEvictsExpiredWindows(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+	for id := uint64(1); id <= 3; id++ {
+		allowed, _ := limiter.Allow(&models.ApiKey{Model: common.Model{ID: id}, RateLimitPerMinute: 1})
+		assert.True(t, allowed)
+	}
+	assert.Len(t, limiter.windows, 3)
+
+	// only the key still in use keeps a window
+	now = now.Add(time.Minute)
+	allowed, _ := limiter.Allow(&models.ApiKey{Model: common.Model{ID: 1}, RateLimitPerMinute: 1})
+	assert.True(t, allowed)
+	assert.Len(t, limiter.windows, 1)
+	allowed, retryAfter := limiter.Allow(&models.ApiKey{Model: common.Model{ID: 1}, RateLimitPerMinute: 1})
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+}
@@ -72,6 +72,8 @@ type Config struct {
 
 	CookieDomain string
 	CookieSecure bool
+
+	RBAC RBACConfig
 }
 
 // ProviderNames returns the configured provider names in stable order.
@@ -129,6 +131,11 @@ func LoadConfig(basicRes context.BasicRes) (*Config, error) {
 		CookieDomain:   strings.TrimSpace(cfg.GetString("COOKIE_DOMAIN")),
 		CookieSecure:   cookieSecure,
 	}
+	rbac, err := loadRBACConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	out.RBAC = rbac
 
 	if !out.OIDCEnabled {
 		return out, nil
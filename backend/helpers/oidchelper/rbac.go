@@ -0,0 +1,186 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidchelper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/config"
+)
+
+// Role is what a user may do in DevLake, mapped from the groups claim of the IdP.
+type Role string
+
+const (
+	// RoleAdmin can do everything, including managing connections, api keys and notification channels
+	RoleAdmin Role = "admin"
+	// RoleProjectMaintainer can read everything but the admin-only resources, manage projects and
+	// normal mode blueprints and run their pipelines. Limited to the granted projects when the grant has any.
+	RoleProjectMaintainer Role = "project-maintainer"
+	// RoleViewer can only browse non-sensitive resources
+	RoleViewer Role = "viewer"
+)
+
+const defaultGroupsClaim = "groups"
+
+// Permission is what a route requires, from the least to the most privileged
+type Permission int
+
+const (
+	PermissionRead Permission = iota
+	PermissionReadSensitive
+	PermissionWrite
+	PermissionAdmin
+)
+
+func (r Role) allows(p Permission) bool {
+	switch r {
+	case RoleAdmin:
+		return true
+	case RoleProjectMaintainer:
+		return p <= PermissionWrite
+	case RoleViewer:
+		return p == PermissionRead
+	}
+	return false
+}
+
+// Grant gives a role to a user, limited to the listed projects if there are any
+type Grant struct {
+	Role     Role     `json:"role"`
+	Projects []string `json:"projects,omitempty"`
+}
+
+// RoleMapping grants the role to every member of the group
+type RoleMapping struct {
+	Group string
+	Grant
+}
+
+// RBACConfig is the typed view of the RBAC_* env vars
+type RBACConfig struct {
+	Enabled     bool
+	GroupsClaim string
+	Mappings    []RoleMapping
+	// DefaultRole is granted to authenticated users matching no mapping, nothing is granted when empty
+	DefaultRole Role
+}
+
+// GrantsFor returns the grants of a user being member of the groups
+func (c *RBACConfig) GrantsFor(groups []string) []Grant {
+	var grants []Grant
+	for _, m := range c.Mappings {
+		for _, g := range groups {
+			if g == m.Group {
+				grants = append(grants, m.Grant)
+				break
+			}
+		}
+	}
+	if len(grants) == 0 && c.DefaultRole != "" {
+		grants = append(grants, Grant{Role: c.DefaultRole})
+	}
+	return grants
+}
+
+// Allowed tells whether the grants permit `perm`. `projects` lists the projects a project
+// scoped resource belongs to, all of them must be granted for project limited grants to apply.
+func Allowed(grants []Grant, perm Permission, projects ...string) bool {
+	for _, grant := range grants {
+		if !grant.Role.allows(perm) {
+			continue
+		}
+		if len(grant.Projects) == 0 || perm <= PermissionReadSensitive {
+			return true
+		}
+		if perm == PermissionWrite && len(projects) > 0 && containsAll(grant.Projects, projects) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(granted, projects []string) bool {
+	for _, p := range projects {
+		found := false
+		for _, g := range granted {
+			if g == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func loadRBACConfig(cfg config.ConfigReader) (RBACConfig, error) {
+	out := RBACConfig{
+		Enabled:     cfg.GetBool("RBAC_ENABLED"),
+		GroupsClaim: valueOr(strings.TrimSpace(cfg.GetString("RBAC_GROUPS_CLAIM")), defaultGroupsClaim),
+		DefaultRole: Role(strings.TrimSpace(cfg.GetString("RBAC_DEFAULT_ROLE"))),
+	}
+	if !out.Enabled {
+		return out, nil
+	}
+	if out.DefaultRole != "" && !validRole(out.DefaultRole) {
+		return out, fmt.Errorf("invalid RBAC_DEFAULT_ROLE %q", out.DefaultRole)
+	}
+	mappings, err := ParseRoleMappings(cfg.GetString("RBAC_ROLE_MAPPINGS"))
+	if err != nil {
+		return out, err
+	}
+	out.Mappings = mappings
+	return out, nil
+}
+
+// ParseRoleMappings parses `group=role[:project,...];...`, i.e.
+// `devlake-admins=admin;team-a=project-maintainer:project-a,project-b;staff=viewer`
+func ParseRoleMappings(raw string) ([]RoleMapping, error) {
+	var out []RoleMapping
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, grant, ok := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("invalid RBAC_ROLE_MAPPINGS entry %q, expected group=role[:project,...]", entry)
+		}
+		role, projects, _ := strings.Cut(grant, ":")
+		m := RoleMapping{Group: group, Grant: Grant{Role: Role(strings.TrimSpace(role))}}
+		if !validRole(m.Role) {
+			return nil, fmt.Errorf("invalid role %q for group %q", m.Role, group)
+		}
+		for _, p := range strings.Split(projects, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				m.Projects = append(m.Projects, p)
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func validRole(r Role) bool {
+	return r == RoleAdmin || r == RoleProjectMaintainer || r == RoleViewer
+}
@@ -0,0 +1,96 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidchelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoleMappings(t *testing.T) {
+	got, err := ParseRoleMappings(" devlake-admins=admin; team-a=project-maintainer:project-a, project-b ;staff=viewer;")
+	if err != nil {
+		t.Fatalf("ParseRoleMappings: %v", err)
+	}
+	want := []RoleMapping{
+		{Group: "devlake-admins", Grant: Grant{Role: RoleAdmin}},
+		{Group: "team-a", Grant: Grant{Role: RoleProjectMaintainer, Projects: []string{"project-a", "project-b"}}},
+		{Group: "staff", Grant: Grant{Role: RoleViewer}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRoleMappings = %+v, want %+v", got, want)
+	}
+	for _, invalid := range []string{"admin", "=admin", "team-a=owner"} {
+		if _, err := ParseRoleMappings(invalid); err == nil {
+			t.Errorf("ParseRoleMappings(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestGrantsFor(t *testing.T) {
+	cfg := &RBACConfig{
+		Mappings: []RoleMapping{
+			{Group: "admins", Grant: Grant{Role: RoleAdmin}},
+			{Group: "team-a", Grant: Grant{Role: RoleProjectMaintainer, Projects: []string{"a"}}},
+		},
+	}
+	if got := cfg.GrantsFor([]string{"team-a", "others"}); !reflect.DeepEqual(got, []Grant{cfg.Mappings[1].Grant}) {
+		t.Errorf("GrantsFor(team-a) = %+v", got)
+	}
+	if got := cfg.GrantsFor([]string{"others"}); got != nil {
+		t.Errorf("GrantsFor(others) = %+v, want nothing", got)
+	}
+	cfg.DefaultRole = RoleViewer
+	if got := cfg.GrantsFor(nil); !reflect.DeepEqual(got, []Grant{{Role: RoleViewer}}) {
+		t.Errorf("GrantsFor(nil) with default role = %+v", got)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	admin := []Grant{{Role: RoleAdmin}}
+	maintainer := []Grant{{Role: RoleProjectMaintainer}}
+	teamLead := []Grant{{Role: RoleProjectMaintainer, Projects: []string{"a"}}}
+	viewer := []Grant{{Role: RoleViewer}}
+	cases := []struct {
+		name     string
+		grants   []Grant
+		perm     Permission
+		projects []string
+		want     bool
+	}{
+		{"admin does admin things", admin, PermissionAdmin, nil, true},
+		{"maintainer can't do admin things", maintainer, PermissionAdmin, nil, false},
+		{"maintainer writes any project", maintainer, PermissionWrite, []string{"b"}, true},
+		{"maintainer reads connections", maintainer, PermissionReadSensitive, nil, true},
+		{"team lead reads connections", teamLead, PermissionReadSensitive, nil, true},
+		{"team lead writes own project", teamLead, PermissionWrite, []string{"a"}, true},
+		{"team lead can't write other project", teamLead, PermissionWrite, []string{"b"}, false},
+		{"team lead can't move to other project", teamLead, PermissionWrite, []string{"a", "b"}, false},
+		{"team lead can't write outside projects", teamLead, PermissionWrite, nil, false},
+		{"viewer reads", viewer, PermissionRead, nil, true},
+		{"viewer can't read connections", viewer, PermissionReadSensitive, nil, false},
+		{"viewer can't write", viewer, PermissionWrite, []string{"a"}, false},
+		{"no grant no access", nil, PermissionRead, nil, false},
+		{"grants add up", append(viewer, teamLead...), PermissionWrite, []string{"a"}, true},
+	}
+	for _, c := range cases {
+		if got := Allowed(c.grants, c.perm, c.projects...); got != c.want {
+			t.Errorf("%s: Allowed = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
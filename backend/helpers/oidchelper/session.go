@@ -37,19 +37,23 @@ type SessionClaims struct {
 	Provider string `json:"prv,omitempty"`
 	Email    string `json:"email,omitempty"`
 	Name     string `json:"name,omitempty"`
+	// Groups is the groups claim of the IdP, mapped to roles on each request so that
+	// changes of RBAC_ROLE_MAPPINGS apply without logging in again
+	Groups []string `json:"groups,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // IssueSession signs a session JWT carrying the jti, the provider name (so
 // /auth/logout can find the right end_session_endpoint), and the user-facing
 // claims. The jti lets the server-side revocation table address one session.
-func IssueSession(cfg *Config, jti, provider, sub, email, name string) (string, time.Time, error) {
+func IssueSession(cfg *Config, jti, provider, sub, email, name string, groups []string) (string, time.Time, error) {
 	now := time.Now()
 	expiresAt := now.Add(cfg.SessionTTL)
 	claims := SessionClaims{
 		Provider: provider,
 		Email:    email,
 		Name:     name,
+		Groups:   groups,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        jti,
 			Issuer:    sessionIssuer,
@@ -28,7 +28,7 @@ func newTestCfg(secret string, ttl time.Duration) *Config {
 
 func TestSessionRoundTrip(t *testing.T) {
 	cfg := newTestCfg("session-test-secret-32-bytes!!", time.Hour)
-	jwt, exp, err := IssueSession(cfg, "jti-1", "entra", "user-1", "u@example.com", "Alice")
+	jwt, exp, err := IssueSession(cfg, "jti-1", "entra", "user-1", "u@example.com", "Alice", nil)
 	if err != nil {
 		t.Fatalf("issue: %v", err)
 	}
@@ -52,7 +52,7 @@ func TestSessionRoundTrip(t *testing.T) {
 
 func TestSessionRejectsExpired(t *testing.T) {
 	cfg := newTestCfg("session-test-secret-32-bytes!!", -1*time.Second)
-	jwt, _, err := IssueSession(cfg, "jti-1", "entra", "user-1", "u@example.com", "Alice")
+	jwt, _, err := IssueSession(cfg, "jti-1", "entra", "user-1", "u@example.com", "Alice", nil)
 	if err != nil {
 		t.Fatalf("issue: %v", err)
 	}
@@ -64,7 +64,7 @@ func TestSessionRejectsExpired(t *testing.T) {
 func TestSessionRejectsWrongSecret(t *testing.T) {
 	a := newTestCfg("session-test-secret-32-bytes!a", time.Hour)
 	b := newTestCfg("session-test-secret-32-bytes!b", time.Hour)
-	jwt, _, err := IssueSession(a, "jti-1", "entra", "user-1", "", "")
+	jwt, _, err := IssueSession(a, "jti-1", "entra", "user-1", "", "", nil)
 	if err != nil {
 		t.Fatalf("issue: %v", err)
 	}
@@ -75,7 +75,7 @@ func TestSessionRejectsWrongSecret(t *testing.T) {
 
 func TestSessionRejectsTampered(t *testing.T) {
 	cfg := newTestCfg("session-test-secret-32-bytes!!", time.Hour)
-	jwt, _, err := IssueSession(cfg, "jti-1", "entra", "user-1", "", "")
+	jwt, _, err := IssueSession(cfg, "jti-1", "entra", "user-1", "", "", nil)
 	if err != nil {
 		t.Fatalf("issue: %v", err)
 	}
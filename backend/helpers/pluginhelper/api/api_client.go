@@ -78,6 +78,11 @@ func NewApiClientFromConnection(
 	if reflect.ValueOf(connection).Kind() != reflect.Ptr {
 		panic(fmt.Errorf("connection is not a pointer"))
 	}
+	// credentials might be kept in external secret backends, they are resolved on a copy of the connection
+	connection, err := ResolveSecretRefs(connection)
+	if err != nil {
+		return nil, err
+	}
 	apiClient, err := NewApiClient(ctx, connection.GetEndpoint(), nil, 0, connection.GetProxy(), br)
 	if err != nil {
 		return nil, err
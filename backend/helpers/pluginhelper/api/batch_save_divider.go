@@ -96,6 +96,18 @@ func (d *BatchSaveDivider) ForType(rowType reflect.Type) (*BatchSave, errors.Err
 	return batch, nil
 }
 
+// Flush saves the cached records of all batches into db, so they become
+// visible to subsequent queries before the divider gets closed
+func (d *BatchSaveDivider) Flush() errors.Error {
+	for _, batch := range d.batches {
+		err := batch.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close all batches so the rest records get saved into db
 func (d *BatchSaveDivider) Close() errors.Error {
 	for _, batch := range d.batches {
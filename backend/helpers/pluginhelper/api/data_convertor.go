@@ -43,6 +43,9 @@ type DataConverterArgs struct {
 	Input        dal.Rows
 	Convert      DataConvertHandler
 	BatchSize    int
+	// Incremental skips deleting the records previously converted with the same raw data params,
+	// the caller is then in charge of removing the outdated ones
+	Incremental bool
 }
 
 // DataConverter helps you convert Data from Tool Layer Tables to Domain Layer Tables
@@ -82,6 +85,7 @@ func (converter *DataConverter) Execute() errors.Error {
 	// batch save divider
 	RAW_DATA_ORIGIN := "RawDataOrigin"
 	divider := NewBatchSaveDivider(converter.args.Ctx, converter.args.BatchSize, converter.table, converter.params)
+	divider.SetIncrementalMode(converter.args.Incremental)
 
 	// set progress
 	converter.args.Ctx.SetProgress(0, -1)
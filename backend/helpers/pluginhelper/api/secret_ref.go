@@ -0,0 +1,115 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+)
+
+// ResolveSecretRefs returns the connection with every credential holding a `secretref://` reference replaced by
+// the secret it refers to, so Authenticators and PrepareApiClient work on the real credentials. Credentials are the
+// fields encrypted by the `encdec` serializer, references anywhere else are refused so that the secrets can't
+// end up in endpoints or other fields sent to a server. The reference must also be inside the namespace of the
+// connection, see plugin.SecretNamespace, so a saved connection is required.
+// The connection passed in is never modified: the secrets are resolved on a copy, so they can't be saved along with
+// the connection nor end up in pipeline plans. A connection holding no reference is returned as is.
+func ResolveSecretRefs[C any](connection C) (C, errors.Error) {
+	v := reflect.ValueOf(connection)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return connection, errors.Default.New("connection is not a pointer")
+	}
+	resolved := reflect.New(v.Elem().Type())
+	resolved.Elem().Set(v.Elem())
+	changed, err := resolveSecretRefs(resolved.Elem(), v.Elem().Type().Name(), secretNamespaceOf(connection), false)
+	if err != nil || !changed {
+		return connection, err
+	}
+	return resolved.Interface().(C), nil
+}
+
+// secretNamespaceOf returns the secret namespace of a saved connection of a `_tool_<plugin>_connections` table
+func secretNamespaceOf(connection interface{}) string {
+	tabler, ok := connection.(dal.Tabler)
+	if !ok {
+		return ""
+	}
+	identified, ok := connection.(interface{ ConnectionId() uint64 })
+	if !ok || identified.ConnectionId() == 0 {
+		return ""
+	}
+	table := tabler.TableName()
+	if !strings.HasPrefix(table, "_tool_") || !strings.HasSuffix(table, "_connections") {
+		return ""
+	}
+	pluginName := strings.TrimSuffix(strings.TrimPrefix(table, "_tool_"), "_connections")
+	return plugin.SecretNamespace(pluginName, identified.ConnectionId())
+}
+
+// resolveSecretRefs resolves the references found under `v` and tells whether there were any, the structs pointed
+// to are copied before being changed as they are shared with the original connection
+func resolveSecretRefs(v reflect.Value, path string, namespace string, credential bool) (bool, errors.Error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !v.CanSet() {
+			return false, nil
+		}
+		pointee := reflect.New(v.Elem().Type())
+		pointee.Elem().Set(v.Elem())
+		changed, err := resolveSecretRefs(pointee.Elem(), path, namespace, credential)
+		if changed {
+			v.Set(pointee)
+		}
+		return changed, err
+	case reflect.Struct:
+		changed := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			isCredential := strings.Contains(field.Tag.Get("gorm"), "serializer:encdec")
+			fieldChanged, err := resolveSecretRefs(v.Field(i), path+"."+field.Name, namespace, isCredential)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || fieldChanged
+		}
+		return changed, nil
+	case reflect.String:
+		if !v.CanSet() || !plugin.IsSecretRef(v.String()) {
+			return false, nil
+		}
+		if !credential {
+			return false, errors.BadInput.New(fmt.Sprintf("%s is not a credential, secret references are not allowed", path))
+		}
+		secret, err := plugin.ResolveSecretRef(namespace, v.String())
+		if err != nil {
+			return false, errors.Default.Wrap(err, fmt.Sprintf("failed to resolve %s", path))
+		}
+		v.SetString(secret)
+		return true, nil
+	}
+	return false, nil
+}
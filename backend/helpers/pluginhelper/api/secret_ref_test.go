@@ -0,0 +1,105 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+type secretRefTestConnection struct {
+	BaseConnection
+	RestConnection
+	AccessToken
+	Basic *BasicAuth
+}
+
+func (secretRefTestConnection) TableName() string {
+	return "_tool_example_connections"
+}
+
+func TestResolveSecretRefs(t *testing.T) {
+	t.Setenv("DEVLAKE_SECRET_EXAMPLE_3_TOKEN", "token")
+	t.Setenv("DEVLAKE_SECRET_EXAMPLE_3_PASSWORD", "password")
+	t.Setenv("DEVLAKE_SECRET_EXAMPLE_4_TOKEN", "token of connection 4")
+	plugin.RegisterSecretProvider("env", plugin.NewEnvSecretProvider("DEVLAKE_SECRET_"))
+
+	newConnection := func() *secretRefTestConnection {
+		c := &secretRefTestConnection{
+			RestConnection: RestConnection{Endpoint: "https://example.com/api/"},
+			AccessToken:    AccessToken{Token: "secretref://env/DEVLAKE_SECRET_EXAMPLE_3_TOKEN"},
+			Basic:          &BasicAuth{Username: "user", Password: "secretref://env/DEVLAKE_SECRET_EXAMPLE_3_PASSWORD"},
+		}
+		c.ID = 3
+		return c
+	}
+	c := newConnection()
+	basic := c.Basic
+	resolved, err := ResolveSecretRefs(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/api/", resolved.Endpoint)
+	assert.Equal(t, "token", resolved.Token)
+	assert.Equal(t, "user", resolved.Basic.Username)
+	assert.Equal(t, "password", resolved.Basic.Password)
+	// the connection keeps its references, so saving it or building plans from it leaks nothing
+	assert.Equal(t, "secretref://env/DEVLAKE_SECRET_EXAMPLE_3_TOKEN", c.Token)
+	assert.Same(t, basic, c.Basic)
+	assert.Equal(t, "secretref://env/DEVLAKE_SECRET_EXAMPLE_3_PASSWORD", c.Basic.Password)
+
+	// connections without references are used as they are
+	c = newConnection()
+	c.Token = "token"
+	c.Basic = nil
+	resolved, err = ResolveSecretRefs(c)
+	assert.Nil(t, err)
+	assert.Same(t, c, resolved)
+
+	// and so are the connections known by their interfaces
+	var apiConnection interface{} = newConnection()
+	resolvedApiConnection, err := ResolveSecretRefs(apiConnection)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", resolvedApiConnection.(*secretRefTestConnection).Token)
+
+	c = newConnection()
+	c.Token = "secretref://env/DEVLAKE_SECRET_EXAMPLE_3_MISSING"
+	_, err = ResolveSecretRefs(c)
+	assert.NotNil(t, err)
+
+	// the secrets of another connection are out of reach
+	c = newConnection()
+	c.Token = "secretref://env/DEVLAKE_SECRET_EXAMPLE_4_TOKEN"
+	_, err = ResolveSecretRefs(c)
+	assert.NotNil(t, err)
+
+	// so are the secrets for an unsaved connection
+	c = newConnection()
+	c.ID = 0
+	_, err = ResolveSecretRefs(c)
+	assert.NotNil(t, err)
+
+	// the endpoint is not a credential, it would send the secret anywhere
+	c = newConnection()
+	c.Endpoint = "secretref://env/DEVLAKE_SECRET_EXAMPLE_3_TOKEN"
+	_, err = ResolveSecretRefs(c)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, errors.BadInput, err.GetType())
+	}
+}
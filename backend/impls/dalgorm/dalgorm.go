@@ -128,6 +128,8 @@ func buildTx(tx *gorm.DB, clauses []dal.Clause) *gorm.DB {
 			nowait := params[1]
 			if nowait {
 				locking.Options = "NOWAIT"
+			} else if len(params) > 2 && params[2] {
+				locking.Options = "SKIP LOCKED"
 			}
 			tx = tx.Clauses(locking)
 		}
@@ -20,7 +20,10 @@ package dalgorm
 import (
 	"testing"
 
+	"github.com/apache/incubator-devlake/core/dal"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 )
 
 func Test_validateQuery(t *testing.T) {
@@ -48,3 +51,18 @@ func Test_validateQuery(t *testing.T) {
 		assert.Nil(t, validateQuery(target), "failed text: `%s`", target)
 	}
 }
+
+func TestBuildTxLocking(t *testing.T) {
+	// a dry run session renders the sql without connecting to the database
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/devlake", SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	assert.Nil(t, err)
+	toSql := func(clauses ...dal.Clause) string {
+		return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return buildTx(tx, append([]dal.Clause{dal.From("_devlake_tasks")}, clauses...)).Find(&[]map[string]interface{}{})
+		})
+	}
+	assert.Contains(t, toSql(dal.LockSkipLocked(true)), "FOR UPDATE SKIP LOCKED")
+	assert.Contains(t, toSql(dal.Lock(true, true)), "FOR UPDATE NOWAIT")
+	assert.NotContains(t, toSql(dal.Lock(true, false)), "LOCKED")
+	assert.NotContains(t, toSql(), "FOR UPDATE")
+}
@@ -22,7 +22,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
 	"github.com/apache/incubator-devlake/core/models"
 	"github.com/apache/incubator-devlake/core/plugin"
 
@@ -34,7 +38,7 @@ var _ schema.SerializerInterface = (*EncDecSerializer)(nil)
 // EncDecSerializer is responsible for field encryption/decryption in Application Level
 // Ref: https://gorm.io/docs/serializer.html
 type EncDecSerializer struct {
-	encryptionSecret string
+	keyring *plugin.EncryptionKeyring
 }
 
 // Scan implements serializer interface
@@ -52,7 +56,7 @@ func (es *EncDecSerializer) Scan(ctx context.Context, field *schema.Field, dst r
 			return fmt.Errorf("failed to decrypt value: %#v", dbValue)
 		}
 
-		decrypted, err := plugin.Decrypt(es.encryptionSecret, base64str)
+		decrypted, err := es.keyring.Decrypt(base64str)
 		if err != nil {
 			return err
 		}
@@ -96,10 +100,27 @@ func (es *EncDecSerializer) Value(ctx context.Context, field *schema.Field, dst
 	// 	gormTag, ok := field.Tag.Lookup("gorm")
 	// 	println(ok, gormTag)
 	// }
-	return plugin.Encrypt(es.encryptionSecret, target)
+	return es.keyring.Encrypt(target)
 }
 
 // Init the encdec serializer
-func Init(encryptionSecret string) {
-	schema.RegisterSerializer("encdec", &EncDecSerializer{encryptionSecret: encryptionSecret})
+func Init(keyring *plugin.EncryptionKeyring) {
+	schema.RegisterSerializer("encdec", &EncDecSerializer{keyring: keyring})
+}
+
+// GetEncDecColumns returns the primary key columns and the columns serialized by encdec of the model
+func GetEncDecColumns(tabler dal.Tabler) (primaryKeys []string, columns []string, err errors.Error) {
+	s, parseErr := schema.Parse(tabler, &sync.Map{}, schema.NamingStrategy{})
+	if parseErr != nil {
+		return nil, nil, errors.Convert(parseErr)
+	}
+	for _, field := range s.PrimaryFields {
+		primaryKeys = append(primaryKeys, field.DBName)
+	}
+	for _, field := range s.Fields {
+		if field.DBName != "" && strings.EqualFold(field.TagSettings["SERIALIZER"], "encdec") {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return primaryKeys, columns, nil
 }
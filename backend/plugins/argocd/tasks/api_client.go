@@ -25,6 +25,11 @@ import (
 )
 
 func NewArgocdApiClient(taskCtx plugin.TaskContext, connection *models.ArgocdConnection) (*api.ApiAsyncClient, errors.Error) {
+	// the token is handed to the subtasks as well, so it is resolved here
+	connection, err := api.ResolveSecretRefs(connection)
+	if err != nil {
+		return nil, err
+	}
 	apiClient, err := api.NewApiClientFromConnection(
 		taskCtx.GetContext(),
 		taskCtx,
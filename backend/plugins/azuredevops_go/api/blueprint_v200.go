@@ -190,6 +190,8 @@ func makePipelinePlanV200(
 					"repoId":         didgen.NewDomainIdGenerator(&models.AzuredevopsRepo{}).Generate(connection.ID, azuredevopsRepo.Id),
 					"proxy":          connection.Proxy,
 					"noShallowClone": true,
+					"connectionId":   connection.ID,
+					"pluginName":     "azuredevops_go",
 				},
 			})
 		}
@@ -124,11 +124,13 @@ func makeDataSourcePipelinePlanV200(
 			stage = append(stage, &coreModels.PipelineTask{
 				Plugin: "gitextractor",
 				Options: map[string]interface{}{
-					"url":      cloneUrl.String(),
-					"name":     bitbucketRepo.BitbucketId,
-					"fullName": bitbucketRepo.BitbucketId,
-					"repoId":   didgen.NewDomainIdGenerator(&models.BitbucketServerRepo{}).Generate(connection.ID, bitbucketRepo.BitbucketId),
-					"proxy":    connection.Proxy,
+					"url":          cloneUrl.String(),
+					"name":         bitbucketRepo.BitbucketId,
+					"fullName":     bitbucketRepo.BitbucketId,
+					"repoId":       didgen.NewDomainIdGenerator(&models.BitbucketServerRepo{}).Generate(connection.ID, bitbucketRepo.BitbucketId),
+					"proxy":        connection.Proxy,
+					"connectionId": connection.ID,
+					"pluginName":   "bitbucket_server",
 				},
 			})
 
@@ -0,0 +1,129 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/dora/tasks"
+)
+
+type LeadTimeStageMetric struct {
+	RepoId     string    `json:"repoId"`
+	WeekStart  time.Time `json:"weekStart"`
+	Stage      string    `json:"stage"`
+	PrCount    int       `json:"prCount"`
+	P50Minutes *int64    `json:"p50Minutes"`
+	P75Minutes *int64    `json:"p75Minutes"`
+	P90Minutes *int64    `json:"p90Minutes"`
+}
+
+type LeadTimeOutlier struct {
+	PullRequestId  string    `json:"pullRequestId"`
+	PullRequestKey int       `json:"pullRequestKey"`
+	Title          string    `json:"title"`
+	Url            string    `json:"url"`
+	Stage          string    `json:"stage"`
+	RepoId         string    `json:"repoId"`
+	WeekStart      time.Time `json:"weekStart"`
+	StageMinutes   int64     `json:"stageMinutes"`
+	P90Minutes     int64     `json:"p90Minutes"`
+	TailShare      float64   `json:"tailShare"`
+}
+
+type LeadTimeStagesOutput struct {
+	ProjectName string                `json:"projectName"`
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Stages      []LeadTimeStageMetric `json:"stages"`
+	Outliers    []LeadTimeOutlier     `json:"outliers"`
+}
+
+// GetProjectLeadTimeStages returns the weekly percentiles of each lead time stage of a project
+// @Summary get lead time stages of a project
+// @Description Return p50/p75/p90 of the coding, pickup, review and deploy stages of the pull requests merged
+// @Description each week overlapping [from, to), for the whole project (empty repoId) and for every repo, along with
+// @Description the pull requests above the project's p90 of each stage.
+// @Tags plugins/dora
+// @Param projectName path string true "project name"
+// @Param from query string false "start of the range, RFC3339 or YYYY-MM-DD, defaults to 6 months before to"
+// @Param to query string false "end of the range (exclusive), RFC3339 or YYYY-MM-DD, defaults to now"
+// @Param repoId query string false "only return the stages of this repo"
+// @Success 200  {object} LeadTimeStagesOutput
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 404  {string} errcode.Error "Not Found"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/dora/projects/{projectName}/lead-time-stages [GET]
+func GetProjectLeadTimeStages(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	db := basicRes.GetDal()
+	projectName := input.Params["projectName"]
+	err := checkProject(db, projectName)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := parseMetricsRange(input.Query)
+	if err != nil {
+		return nil, err
+	}
+	repoId := input.Query.Get("repoId")
+	// the week containing from is only partially within the range, it is included as a whole
+	weekFrom := tasks.PeriodStart(from, crossdomain.DORA_GRANULARITY_WEEK)
+
+	output := &LeadTimeStagesOutput{
+		ProjectName: projectName,
+		From:        from,
+		To:          to,
+		Stages:      make([]LeadTimeStageMetric, 0),
+		Outliers:    make([]LeadTimeOutlier, 0),
+	}
+	clauses := []dal.Clause{
+		dal.Select("repo_id, week_start, stage, pr_count, p50_minutes, p75_minutes, p90_minutes"),
+		dal.From("project_lead_time_stage_metrics"),
+		dal.Where("project_name = ? AND week_start >= ? AND week_start < ?", projectName, weekFrom, to),
+	}
+	if repoId != "" {
+		clauses = append(clauses, dal.Where("repo_id = ?", repoId))
+	}
+	clauses = append(clauses, dal.Orderby("repo_id, week_start, stage"))
+	err = db.All(&output.Stages, clauses...)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error getting project_lead_time_stage_metrics")
+	}
+
+	clauses = []dal.Clause{
+		dal.Select(`o.pull_request_id, pr.pull_request_key, pr.title, pr.url, o.stage, o.repo_id, o.week_start,
+			o.stage_minutes, o.p90_minutes, o.tail_share`),
+		dal.From("project_lead_time_outliers o"),
+		dal.Join("LEFT JOIN pull_requests pr ON pr.id = o.pull_request_id"),
+		dal.Where("o.project_name = ? AND o.week_start >= ? AND o.week_start < ?", projectName, weekFrom, to),
+	}
+	if repoId != "" {
+		clauses = append(clauses, dal.Where("o.repo_id = ?", repoId))
+	}
+	clauses = append(clauses, dal.Orderby("o.stage, o.stage_minutes DESC"))
+	err = db.All(&output.Outliers, clauses...)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error getting project_lead_time_outliers")
+	}
+	return &plugin.ApiResourceOutput{Body: output, Status: http.StatusOK}, nil
+}
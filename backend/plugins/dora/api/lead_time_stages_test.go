@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProjectLeadTimeStagesRepoFilter(t *testing.T) {
+	// stageFilters returns the conditions of the stages query run for the given query string
+	stageFilters := func(query url.Values) []string {
+		var filters []string
+		mockMetricsDal(t, func(dst interface{}, clauses []dal.Clause) {
+			if _, ok := dst.(*[]LeadTimeStageMetric); !ok {
+				return
+			}
+			for _, clause := range clauses {
+				if clause.Type == dal.WhereClause {
+					filters = append(filters, clause.Data.(dal.DalClause).Expr)
+				}
+			}
+		})
+		_, err := GetProjectLeadTimeStages(&plugin.ApiResourceInput{
+			Params: map[string]string{"projectName": "p1"},
+			Query:  query,
+		})
+		assert.Nil(t, err)
+		return filters
+	}
+	// the project level rows and the ones of every repo
+	assert.NotContains(t, stageFilters(url.Values{}), "repo_id = ?")
+	assert.Contains(t, stageFilters(url.Values{"repoId": {"github:GithubRepo:1:1"}}), "repo_id = ?")
+}
+
+func TestGetProjectLeadTimeStagesPartialWeek(t *testing.T) {
+	var params [][]interface{}
+	mockMetricsDal(t, func(dst interface{}, clauses []dal.Clause) {
+		for _, clause := range clauses {
+			if clause.Type == dal.WhereClause {
+				params = append(params, clause.Data.(dal.DalClause).Params)
+			}
+		}
+	})
+	// 2024-03-13 is a Wednesday, the rows of the week starting on Monday 2024-03-11 cover it
+	_, err := GetProjectLeadTimeStages(&plugin.ApiResourceInput{
+		Params: map[string]string{"projectName": "p1"},
+		Query:  url.Values{"from": {"2024-03-13"}, "to": {"2024-04-01"}},
+	})
+	assert.Nil(t, err)
+	weekStart := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	// both the stages and the outliers
+	assert.Equal(t, [][]interface{}{{"p1", weekStart, to}, {"p1", weekStart, to}}, params)
+}
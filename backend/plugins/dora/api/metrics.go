@@ -0,0 +1,323 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/dora/tasks"
+)
+
+const defaultMetricsRange = 6 * 30 * 24 * time.Hour
+
+type DoraMetric struct {
+	PeriodStart               time.Time `json:"periodStart"`
+	PeriodEnd                 time.Time `json:"periodEnd"`
+	DeploymentCount           int       `json:"deploymentCount"`
+	DeploymentDays            int       `json:"deploymentDays"`
+	DeploymentFrequencyLevel  string    `json:"deploymentFrequencyLevel"`
+	PrCount                   int       `json:"prCount"`
+	MedianLeadTimeMinutes     *int64    `json:"medianLeadTimeMinutes"`
+	LeadTimeLevel             string    `json:"leadTimeLevel"`
+	FailedDeploymentCount     int       `json:"failedDeploymentCount"`
+	ChangeFailureRate         *float64  `json:"changeFailureRate"`
+	ChangeFailureRateLevel    string    `json:"changeFailureRateLevel"`
+	IncidentCount             int       `json:"incidentCount"`
+	MedianRecoveryTimeMinutes *int64    `json:"medianRecoveryTimeMinutes"`
+	RecoveryTimeLevel         string    `json:"recoveryTimeLevel"`
+}
+
+type DoraDeployment struct {
+	Id           string     `json:"id"`
+	Name         string     `json:"name"`
+	Url          string     `json:"url"`
+	FinishedDate *time.Time `json:"finishedDate"`
+	CommitCount  int        `json:"commitCount"`
+}
+
+type DoraIncident struct {
+	Id                  string     `json:"id"`
+	IncidentKey         string     `json:"incidentKey"`
+	Title               string     `json:"title"`
+	Url                 string     `json:"url"`
+	Status              string     `json:"status"`
+	CreatedDate         *time.Time `json:"createdDate"`
+	ResolutionDate      *time.Time `json:"resolutionDate"`
+	LeadTimeMinutes     *uint      `json:"leadTimeMinutes"`
+	DeploymentId        string     `json:"deploymentId"`
+	AttributionStrategy string     `json:"attributionStrategy"`
+}
+
+type DoraCommit struct {
+	PullRequestId      string     `json:"pullRequestId"`
+	PullRequestKey     int        `json:"pullRequestKey"`
+	Title              string     `json:"title"`
+	Url                string     `json:"url"`
+	MergeCommitSha     string     `json:"mergeCommitSha"`
+	FirstCommitSha     string     `json:"firstCommitSha"`
+	DeploymentId       string     `json:"deploymentId"`
+	DeployedDate       *time.Time `json:"deployedDate"`
+	PrCycleTimeMinutes *int64     `json:"prCycleTimeMinutes"`
+}
+
+type DoraMetricsOutput struct {
+	ProjectName string           `json:"projectName"`
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Granularity string           `json:"granularity"`
+	DoraReport  string           `json:"doraReport"`
+	Summary     *DoraMetric      `json:"summary"`
+	Metrics     []*DoraMetric    `json:"metrics"`
+	Deployments []DoraDeployment `json:"deployments"`
+	Incidents   []DoraIncident   `json:"incidents"`
+	Commits     []DoraCommit     `json:"commits"`
+}
+
+// GetProjectMetrics returns the DORA metrics of a project
+// @Summary get DORA metrics of a project
+// @Description Return the four DORA metrics of the project over [from, to) along with their benchmark levels,
+// @Description the daily/weekly/monthly snapshots calculated by the last dora run, leveled against the requested report, and the deployments,
+// @Description incidents and commits behind them.
+// @Tags plugins/dora
+// @Param projectName path string true "project name"
+// @Param from query string false "start of the range, RFC3339 or YYYY-MM-DD, defaults to 6 months before to"
+// @Param to query string false "end of the range (exclusive), RFC3339 or YYYY-MM-DD, defaults to now"
+// @Param granularity query string false "day, week or month, defaults to week"
+// @Param doraReport query string false "2021 or 2023, defaults to 2023"
+// @Success 200  {object} DoraMetricsOutput
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 404  {string} errcode.Error "Not Found"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/dora/projects/{projectName}/metrics [GET]
+func GetProjectMetrics(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	db := basicRes.GetDal()
+	projectName := input.Params["projectName"]
+	err := checkProject(db, projectName)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := parseMetricsRange(input.Query)
+	if err != nil {
+		return nil, err
+	}
+	granularity := strings.ToUpper(input.Query.Get("granularity"))
+	if granularity == "" {
+		granularity = crossdomain.DORA_GRANULARITY_WEEK
+	}
+	if granularity != crossdomain.DORA_GRANULARITY_DAY &&
+		granularity != crossdomain.DORA_GRANULARITY_WEEK &&
+		granularity != crossdomain.DORA_GRANULARITY_MONTH {
+		return nil, errors.BadInput.New("granularity must be one of day, week or month")
+	}
+	doraReport := input.Query.Get("doraReport")
+	if doraReport == "" {
+		doraReport = tasks.DORA_REPORT_2023
+	}
+	if doraReport != tasks.DORA_REPORT_2021 && doraReport != tasks.DORA_REPORT_2023 {
+		return nil, errors.BadInput.New("doraReport must be either 2021 or 2023")
+	}
+
+	output := &DoraMetricsOutput{
+		ProjectName: projectName,
+		From:        from,
+		To:          to,
+		Granularity: strings.ToLower(granularity),
+		DoraReport:  doraReport,
+	}
+	summary, err := tasks.SummarizeDoraMetrics(db, projectName, doraReport, from, to)
+	if err != nil {
+		return nil, err
+	}
+	output.Summary = toDoraMetric(summary)
+
+	var snapshots []crossdomain.ProjectDoraMetric
+	err = db.All(
+		&snapshots,
+		dal.Where(
+			"project_name = ? AND granularity = ? AND period_end > ? AND period_start < ?",
+			projectName, granularity, from, to,
+		),
+		dal.Orderby("period_start"),
+	)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error getting project_dora_metrics")
+	}
+	output.Metrics = make([]*DoraMetric, 0, len(snapshots))
+	for i := range snapshots {
+		// the snapshots carry the levels of the report configured for the last run, which may differ
+		if snapshots[i].DoraReport != doraReport {
+			tasks.ApplyDoraLevels(&snapshots[i], doraReport)
+		}
+		output.Metrics = append(output.Metrics, toDoraMetric(&snapshots[i]))
+	}
+
+	if output.Deployments, err = getDoraDeployments(db, projectName, from, to); err != nil {
+		return nil, err
+	}
+	if output.Incidents, err = getDoraIncidents(db, projectName, from, to); err != nil {
+		return nil, err
+	}
+	if output.Commits, err = getDoraCommits(db, projectName, from, to); err != nil {
+		return nil, err
+	}
+	return &plugin.ApiResourceOutput{Body: output, Status: http.StatusOK}, nil
+}
+
+// checkProject makes sure the project exists
+func checkProject(db dal.Dal, projectName string) errors.Error {
+	if projectName == "" {
+		return errors.BadInput.New("missing projectName")
+	}
+	err := db.First(&models.Project{}, dal.Where("name = ?", projectName))
+	if err != nil {
+		if db.IsErrorNotFound(err) {
+			return errors.NotFound.New("project not found: " + projectName)
+		}
+		return errors.Default.Wrap(err, "error finding project")
+	}
+	return nil
+}
+
+// parseMetricsRange reads the [from, to) range from the query, it defaults to the last 6 months
+func parseMetricsRange(query url.Values) (from time.Time, to time.Time, err errors.Error) {
+	to = time.Now()
+	if v := query.Get("to"); v != "" {
+		to, err = parseMetricsTime(v)
+		if err != nil {
+			return from, to, errors.BadInput.Wrap(err, "invalid to")
+		}
+	}
+	from = to.Add(-defaultMetricsRange)
+	if v := query.Get("from"); v != "" {
+		from, err = parseMetricsTime(v)
+		if err != nil {
+			return from, to, errors.BadInput.Wrap(err, "invalid from")
+		}
+	}
+	if !from.Before(to) {
+		return from, to, errors.BadInput.New("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseMetricsTime(v string) (time.Time, errors.Error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return t, errors.BadInput.New("expecting RFC3339 or YYYY-MM-DD, got " + v)
+	}
+	return t, nil
+}
+
+func toDoraMetric(m *crossdomain.ProjectDoraMetric) *DoraMetric {
+	return &DoraMetric{
+		PeriodStart:               m.PeriodStart,
+		PeriodEnd:                 m.PeriodEnd,
+		DeploymentCount:           m.DeploymentCount,
+		DeploymentDays:            m.DeploymentDays,
+		DeploymentFrequencyLevel:  m.DeploymentFrequencyLevel,
+		PrCount:                   m.PrCount,
+		MedianLeadTimeMinutes:     m.MedianLeadTimeMinutes,
+		LeadTimeLevel:             m.LeadTimeLevel,
+		FailedDeploymentCount:     m.FailedDeploymentCount,
+		ChangeFailureRate:         m.ChangeFailureRate,
+		ChangeFailureRateLevel:    m.ChangeFailureRateLevel,
+		IncidentCount:             m.IncidentCount,
+		MedianRecoveryTimeMinutes: m.MedianRecoveryTimeMinutes,
+		RecoveryTimeLevel:         m.RecoveryTimeLevel,
+	}
+}
+
+// getDoraDeployments returns the successful production deployments of the project finished within [from, to)
+func getDoraDeployments(db dal.Dal, projectName string, from, to time.Time) ([]DoraDeployment, errors.Error) {
+	deployments := make([]DoraDeployment, 0)
+	err := db.All(
+		&deployments,
+		dal.Select("cdc.cicd_deployment_id AS id, MAX(cdc.name) AS name, MAX(cdc.url) AS url, MAX(cdc.finished_date) AS finished_date, COUNT(*) AS commit_count"),
+		dal.From("cicd_deployment_commits cdc"),
+		dal.Join("LEFT JOIN project_mapping pm ON cdc.cicd_scope_id = pm.row_id"),
+		dal.Where(
+			"pm.table = ? AND pm.project_name = ? AND cdc.result = ? AND cdc.environment = ?",
+			"cicd_scopes", projectName, devops.RESULT_SUCCESS, devops.PRODUCTION,
+		),
+		dal.Groupby("cdc.cicd_deployment_id"),
+		dal.Having("MAX(cdc.finished_date) >= ? AND MAX(cdc.finished_date) < ?", from, to),
+		dal.Orderby("finished_date"),
+	)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error getting deployments")
+	}
+	return deployments, nil
+}
+
+// getDoraIncidents returns the incidents caused by the deployments finished within [from, to), a deployment finishes
+// when its last commit does, same as in getDoraDeployments and the summary
+func getDoraIncidents(db dal.Dal, projectName string, from, to time.Time) ([]DoraIncident, errors.Error) {
+	incidents := make([]DoraIncident, 0)
+	err := db.All(
+		&incidents,
+		dal.Select("i.id, i.incident_key, i.title, i.url, i.status, i.created_date, i.resolution_date, i.lead_time_minutes, pidr.deployment_id, pidr.attribution_strategy"),
+		dal.From("project_incident_deployment_relationships pidr"),
+		dal.Join("JOIN incidents i ON pidr.id = i.id"),
+		dal.Join(`JOIN (SELECT cicd_deployment_id, MAX(finished_date) AS finished_date FROM cicd_deployment_commits
+			GROUP BY cicd_deployment_id) d ON d.cicd_deployment_id = pidr.deployment_id`),
+		dal.Where(
+			"pidr.project_name = ? AND d.finished_date >= ? AND d.finished_date < ?",
+			projectName, from, to,
+		),
+		dal.Orderby("i.created_date"),
+	)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error getting incidents")
+	}
+	return incidents, nil
+}
+
+// getDoraCommits returns the pull requests deployed within [from, to) along with their lead time for changes
+func getDoraCommits(db dal.Dal, projectName string, from, to time.Time) ([]DoraCommit, errors.Error) {
+	commits := make([]DoraCommit, 0)
+	err := db.All(
+		&commits,
+		dal.Select(`pr.id AS pull_request_id, pr.pull_request_key, pr.title, pr.url, pr.merge_commit_sha,
+			ppm.first_commit_sha, cdc.cicd_deployment_id AS deployment_id, cdc.finished_date AS deployed_date,
+			ppm.pr_cycle_time AS pr_cycle_time_minutes`),
+		dal.From("project_pr_metrics ppm"),
+		dal.Join("JOIN pull_requests pr ON pr.id = ppm.id"),
+		dal.Join("JOIN cicd_deployment_commits cdc ON ppm.deployment_commit_id = cdc.id"),
+		dal.Where(
+			"ppm.project_name = ? AND cdc.finished_date >= ? AND cdc.finished_date < ?",
+			projectName, from, to,
+		),
+		dal.Orderby("cdc.finished_date"),
+	)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error getting commits")
+	}
+	return commits, nil
+}
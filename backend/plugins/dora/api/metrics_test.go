@@ -0,0 +1,140 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/plugin"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseMetricsRange(t *testing.T) {
+	from, to, err := parseMetricsRange(url.Values{"from": {"2024-01-01"}, "to": {"2024-02-01T12:00:00Z"}})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC), to)
+
+	from, to, err = parseMetricsRange(url.Values{"to": {"2024-07-01"}})
+	assert.Nil(t, err)
+	assert.Equal(t, defaultMetricsRange, to.Sub(from))
+
+	_, _, err = parseMetricsRange(url.Values{"from": {"2024-02-01"}, "to": {"2024-01-01"}})
+	assert.NotNil(t, err)
+	_, _, err = parseMetricsRange(url.Values{"from": {"01/02/2024"}})
+	assert.NotNil(t, err)
+}
+
+// mockMetricsDal serves project p1, the queries are passed to onAll and return no rows unless onAll fills them
+func mockMetricsDal(t *testing.T, onAll func(dst interface{}, clauses []dal.Clause)) {
+	notFound := errors.Default.New("record not found")
+	db := mockdal.NewDal(t)
+	db.On("First", mock.AnythingOfType("*models.Project"), mock.Anything).Return(func(dst interface{}, clauses ...dal.Clause) errors.Error {
+		if clauses[0].Data.(dal.DalClause).Params[0] != "p1" {
+			return notFound
+		}
+		dst.(*models.Project).Name = "p1"
+		return nil
+	}).Maybe()
+	db.On("IsErrorNotFound", mock.Anything).Return(func(err error) bool {
+		return err == notFound
+	}).Maybe()
+	db.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if onAll != nil {
+			onAll(args.Get(0), args.Get(1).([]dal.Clause))
+		}
+	}).Return(nil).Maybe()
+	br := mockcontext.NewBasicRes(t)
+	br.On("GetDal").Return(db)
+	basicRes = br
+}
+
+func TestGetProjectMetrics(t *testing.T) {
+	week := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	leadTime := int64(2 * 60)
+	snapshots := []crossdomain.ProjectDoraMetric{
+		{
+			ProjectName:           "p1",
+			Granularity:           crossdomain.DORA_GRANULARITY_WEEK,
+			PeriodStart:           week,
+			PeriodEnd:             week.AddDate(0, 0, 7),
+			DoraReport:            "2021",
+			DeploymentCount:       1,
+			DeploymentDays:        1,
+			MedianLeadTimeMinutes: &leadTime,
+			LeadTimeLevel:         crossdomain.DORA_LEVEL_HIGH,
+		},
+	}
+	mockMetricsDal(t, func(dst interface{}, _ []dal.Clause) {
+		if metrics, ok := dst.(*[]crossdomain.ProjectDoraMetric); ok {
+			*metrics = append(*metrics, snapshots...)
+		}
+	})
+
+	output, err := GetProjectMetrics(&plugin.ApiResourceInput{
+		Params: map[string]string{"projectName": "p1"},
+		Query:  url.Values{"from": {"2024-03-01"}, "to": {"2024-04-01"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, output.Status)
+	metrics := output.Body.(*DoraMetricsOutput)
+	assert.Equal(t, "week", metrics.Granularity)
+	assert.Equal(t, "2023", metrics.DoraReport)
+	assert.Equal(t, 0, metrics.Summary.DeploymentCount)
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, metrics.Summary.DeploymentFrequencyLevel)
+	if assert.Len(t, metrics.Metrics, 1) {
+		// a lead time of 2 hours is high in the 2021 report and elite in the 2023 one
+		assert.Equal(t, crossdomain.DORA_LEVEL_ELITE, metrics.Metrics[0].LeadTimeLevel)
+		assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, metrics.Metrics[0].DeploymentFrequencyLevel)
+	}
+	assert.Empty(t, metrics.Deployments)
+}
+
+func TestGetProjectMetricsInvalidInput(t *testing.T) {
+	mockMetricsDal(t, nil)
+	for name, tc := range map[string]struct {
+		projectName string
+		query       url.Values
+		errType     *errors.Type
+	}{
+		"unknown project":     {projectName: "p2", errType: errors.NotFound},
+		"invalid granularity": {projectName: "p1", query: url.Values{"granularity": {"year"}}, errType: errors.BadInput},
+		"invalid report":      {projectName: "p1", query: url.Values{"doraReport": {"2019"}}, errType: errors.BadInput},
+		"invalid range":       {projectName: "p1", query: url.Values{"from": {"2024-02-01"}, "to": {"2024-01-01"}}, errType: errors.BadInput},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := GetProjectMetrics(&plugin.ApiResourceInput{
+				Params: map[string]string{"projectName": tc.projectName},
+				Query:  tc.query,
+			})
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.errType, err.GetType())
+			}
+		})
+	}
+}
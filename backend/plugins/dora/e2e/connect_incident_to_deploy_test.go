@@ -51,3 +51,36 @@ func TestConnectIncidentToDeploymentDataFlow(t *testing.T) {
 		IgnoreTypes: []interface{}{common.NoPKModel{}},
 	})
 }
+
+func TestConnectIncidentToDeploymentStrategiesDataFlow(t *testing.T) {
+	var plugin impl.Dora
+	dataflowTester := e2ehelper.NewDataFlowTester(t, "dora", plugin)
+
+	taskData := &tasks.DoraTaskData{
+		Options: &tasks.DoraOptions{
+			ProjectName: "project1",
+			IncidentAttribution: &tasks.IncidentAttributionOptions{
+				Strategies: []string{
+					crossdomain.ATTRIBUTION_EXPLICIT_LINK,
+					crossdomain.ATTRIBUTION_SAME_SERVICE,
+					crossdomain.ATTRIBUTION_LAST_DEPLOYMENT,
+				},
+				WindowHours:     48,
+				ServiceMappings: map[string][]string{"payments": {"cicd1"}},
+			},
+		},
+	}
+	// import raw data table
+	dataflowTester.ImportCsvIntoTabler("./connect_incident_to_deployment/prev_success_deployment_commit/cicd_deployment_commits_after.csv", &devops.CicdDeploymentCommit{})
+	dataflowTester.ImportCsvIntoTabler("./connect_incident_to_deployment/raw_tables/project_mapping.csv", &crossdomain.ProjectMapping{})
+	dataflowTester.ImportCsvIntoTabler("./connect_incident_to_deployment/attribution/incidents.csv", &ticket.Incident{})
+	dataflowTester.ImportCsvIntoTabler("./connect_incident_to_deployment/attribution/issue_labels.csv", &ticket.IssueLabel{})
+
+	// 1 and 2 are linked by their description and label, 3 by its component, 4 has no deployment within the window
+	dataflowTester.FlushTabler(&crossdomain.ProjectIncidentDeploymentRelationship{})
+	dataflowTester.Subtask(tasks.ConnectIncidentToDeploymentMeta, taskData)
+	dataflowTester.VerifyTableWithOptions(&crossdomain.ProjectIncidentDeploymentRelationship{}, e2ehelper.TableOptions{
+		CSVRelPath:  "./connect_incident_to_deployment/attribution/project_incident_deployment_relationships.csv",
+		IgnoreTypes: []interface{}{common.NoPKModel{}},
+	})
+}
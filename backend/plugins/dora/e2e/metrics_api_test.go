@@ -0,0 +1,74 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/e2ehelper"
+	contextimpl "github.com/apache/incubator-devlake/impls/context"
+	"github.com/apache/incubator-devlake/plugins/dora/api"
+	"github.com/apache/incubator-devlake/plugins/dora/impl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProjectMetricsIncidents(t *testing.T) {
+	var dora impl.Dora
+	dataflowTester := e2ehelper.NewDataFlowTester(t, "dora", dora)
+	api.Init(contextimpl.NewDefaultBasicRes(dataflowTester.Cfg, dataflowTester.Log, dataflowTester.Dal))
+
+	dataflowTester.ImportCsvIntoTabler("./metrics_api/projects.csv", &models.Project{})
+	dataflowTester.ImportCsvIntoTabler("./metrics_api/project_mapping.csv", &crossdomain.ProjectMapping{})
+	dataflowTester.ImportCsvIntoTabler("./metrics_api/cicd_deployment_commits.csv", &devops.CicdDeploymentCommit{})
+	dataflowTester.ImportCsvIntoTabler("./metrics_api/incidents.csv", &ticket.Incident{})
+	dataflowTester.ImportCsvIntoTabler("./metrics_api/project_incident_deployment_relationships.csv", &crossdomain.ProjectIncidentDeploymentRelationship{})
+	dataflowTester.FlushTabler(&crossdomain.ProjectPrMetric{})
+	dataflowTester.FlushTabler(&code.PullRequest{})
+	dataflowTester.FlushTabler(&crossdomain.ProjectDoraMetric{})
+
+	output, err := api.GetProjectMetrics(&plugin.ApiResourceInput{
+		Params: map[string]string{"projectName": "project1"},
+		Query:  url.Values{"from": {"2024-03-01"}, "to": {"2024-04-01"}},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	metrics := output.Body.(*api.DoraMetricsOutput)
+
+	// a deployment is dated by its last commit: deployment2 started in february but finished within the range,
+	// deployment3 has a commit within the range but finished after it, deployment4 finished before it and
+	// deployment5 belongs to another project
+	var incidentIds []string
+	for _, incident := range metrics.Incidents {
+		incidentIds = append(incidentIds, incident.Id)
+	}
+	assert.Equal(t, []string{"incident2", "incident1"}, incidentIds)
+	assert.Equal(t, 2, metrics.Summary.IncidentCount)
+	var deploymentIds []string
+	for _, deployment := range metrics.Deployments {
+		deploymentIds = append(deploymentIds, deployment.Id)
+	}
+	assert.Equal(t, []string{"deployment2", "deployment1"}, deploymentIds)
+}
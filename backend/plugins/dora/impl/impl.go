@@ -20,10 +20,12 @@ package impl
 import (
 	"encoding/json"
 
+	"github.com/apache/incubator-devlake/core/context"
 	"github.com/apache/incubator-devlake/core/dal"
 	"github.com/apache/incubator-devlake/core/errors"
 	coreModels "github.com/apache/incubator-devlake/core/models"
 	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/dora/api"
 	"github.com/apache/incubator-devlake/plugins/dora/models/migrationscripts"
 	"github.com/apache/incubator-devlake/plugins/dora/tasks"
 )
@@ -31,7 +33,9 @@ import (
 // make sure interface is implemented
 var _ interface {
 	plugin.PluginMeta
+	plugin.PluginInit
 	plugin.PluginTask
+	plugin.PluginApi
 	plugin.PluginModel
 	plugin.PluginMetric
 	plugin.PluginMigration
@@ -40,6 +44,11 @@ var _ interface {
 
 type Dora struct{}
 
+func (p Dora) Init(basicRes context.BasicRes) errors.Error {
+	api.Init(basicRes)
+	return nil
+}
+
 func (p Dora) Description() string {
 	return "collect some Dora data"
 }
@@ -94,8 +103,10 @@ func (p Dora) SubTaskMetas() []plugin.SubTaskMeta {
 		tasks.EnrichPrevSuccessDeploymentCommitMeta,
 		tasks.EnrichTaskEnvMeta,
 		tasks.CalculateChangeLeadTimeMeta,
+		tasks.CalculateLeadTimeStagesMeta,
 		tasks.IssuesToIncidentsMeta,
 		tasks.ConnectIncidentToDeploymentMeta,
+		tasks.CalculateDoraMetricsMeta,
 	}
 }
 
@@ -114,6 +125,17 @@ func (p Dora) RootPkgPath() string {
 	return "github.com/apache/incubator-devlake/plugins/dora"
 }
 
+func (p Dora) ApiResources() map[string]map[string]plugin.ApiResourceHandler {
+	return map[string]map[string]plugin.ApiResourceHandler{
+		"projects/:projectName/metrics": {
+			"GET": api.GetProjectMetrics,
+		},
+		"projects/:projectName/lead-time-stages": {
+			"GET": api.GetProjectLeadTimeStages,
+		},
+	}
+}
+
 func (p Dora) MigrationScripts() []plugin.MigrationScript {
 	return migrationscripts.All()
 }
@@ -127,6 +149,23 @@ func (p Dora) MakeMetricPluginPipelinePlanV200(projectName string, options json.
 		}
 	}
 
+	metricsOptions := map[string]interface{}{
+		"projectName": projectName,
+	}
+	if op.DoraReport != "" {
+		metricsOptions["doraReport"] = op.DoraReport
+	}
+	if op.IncidentAttribution != nil {
+		metricsOptions["incidentAttribution"] = op.IncidentAttribution
+	}
+
+	refdiffOptions := map[string]interface{}{
+		"projectName": projectName,
+	}
+	if op.ProductionOnlyChanges {
+		refdiffOptions["productionOnly"] = true
+	}
+
 	plan := coreModels.PipelinePlan{
 		{
 			{
@@ -143,25 +182,24 @@ func (p Dora) MakeMetricPluginPipelinePlanV200(projectName string, options json.
 		},
 		{
 			{
-				Plugin: "refdiff",
-				Options: map[string]interface{}{
-					"projectName": projectName,
-				},
+				Plugin:  "refdiff",
+				Options: refdiffOptions,
 				Subtasks: []string{
 					"calculateDeploymentCommitsDiff",
+					"calculateDeploymentChanges",
 				},
 			},
 		},
 		{
 			{
-				Plugin: "dora",
-				Options: map[string]interface{}{
-					"projectName": projectName,
-				},
+				Plugin:  "dora",
+				Options: metricsOptions,
 				Subtasks: []string{
 					"calculateChangeLeadTime",
+					tasks.CalculateLeadTimeStagesMeta.Name,
 					tasks.IssuesToIncidentsMeta.Name,
 					"ConnectIncidentToDeployment",
+					tasks.CalculateDoraMetricsMeta.Name,
 				},
 			},
 		},
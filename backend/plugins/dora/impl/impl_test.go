@@ -52,7 +52,7 @@ func TestMakeMetricPluginPipelinePlanV200(t *testing.T) {
 		coreModels.PipelineStage{
 			{
 				Plugin:   "refdiff",
-				Subtasks: []string{"calculateDeploymentCommitsDiff"},
+				Subtasks: []string{"calculateDeploymentCommitsDiff", "calculateDeploymentChanges"},
 				Options:  map[string]interface{}{"projectName": projectName},
 			},
 		},
@@ -61,8 +61,10 @@ func TestMakeMetricPluginPipelinePlanV200(t *testing.T) {
 				Plugin: "dora",
 				Subtasks: []string{
 					"calculateChangeLeadTime",
+					tasks.CalculateLeadTimeStagesMeta.Name,
 					tasks.IssuesToIncidentsMeta.Name,
 					"ConnectIncidentToDeployment",
+					tasks.CalculateDoraMetricsMeta.Name,
 				},
 				Options: map[string]interface{}{"projectName": projectName},
 			},
@@ -0,0 +1,131 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/core/utils"
+)
+
+const defaultBackfillChunkDays = 30
+
+// BackfillOptions makes the dora subtasks recompute [From, To) chunk by chunk instead of the whole project at once
+type BackfillOptions struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	ChunkDays int       `json:"chunkDays"`
+}
+
+// backfillWindow is the time range a subtask works on, a nil window means the whole history of the project
+type backfillWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// backfillChunks splits [from, to) into chunks of chunkDays days, starting after done when it is within the range
+func backfillChunks(backfill *BackfillOptions, done *time.Time) []backfillWindow {
+	chunkDays := backfill.ChunkDays
+	if chunkDays <= 0 {
+		chunkDays = defaultBackfillChunkDays
+	}
+	start := backfill.From
+	if done != nil && done.After(start) {
+		start = *done
+	}
+	var chunks []backfillWindow
+	for start.Before(backfill.To) {
+		end := start.AddDate(0, 0, chunkDays)
+		if end.After(backfill.To) {
+			end = backfill.To
+		}
+		chunks = append(chunks, backfillWindow{From: start, To: end})
+		start = end
+	}
+	return chunks
+}
+
+// runBackfill calls process for every chunk of the backfill window. The end of the last completed chunk is
+// checkpointed into _devlake_subtask_states (as TimeAfter, PrevConfig being the backfill options), so
+// re-running the same backfill resumes where the previous run stopped. Changing the window or the chunk
+// size starts over.
+func runBackfill(taskCtx plugin.SubTaskContext, process func(window *backfillWindow) errors.Error) errors.Error {
+	db := taskCtx.GetDal()
+	logger := taskCtx.GetLogger()
+	data := taskCtx.GetData().(*DoraTaskData)
+	backfill := data.Options.Backfill
+
+	config := utils.ToJsonString(backfill)
+	state := &models.SubtaskState{}
+	err := db.First(state, dal.Where(
+		"plugin = ? AND subtask = ? AND params = ?",
+		taskCtx.TaskContext().GetName(), taskCtx.GetName(), utils.ToJsonString(DoraApiParams{ProjectName: data.Options.ProjectName}),
+	))
+	if err != nil {
+		if !db.IsErrorNotFound(err) {
+			return errors.Default.Wrap(err, "failed to load the backfill checkpoint")
+		}
+		state = &models.SubtaskState{
+			Plugin:  taskCtx.TaskContext().GetName(),
+			Subtask: taskCtx.GetName(),
+			Params:  utils.ToJsonString(DoraApiParams{ProjectName: data.Options.ProjectName}),
+		}
+	}
+	var done *time.Time
+	if state.PrevConfig == config {
+		done = state.TimeAfter
+	}
+	state.PrevConfig = config
+
+	chunks := backfillChunks(backfill, done)
+	if len(chunks) == 0 {
+		logger.Info("backfill of [%s, %s) is already completed", backfill.From, backfill.To)
+		return nil
+	}
+	taskCtx.SetProgress(0, len(chunks))
+	for i := range chunks {
+		chunk := &chunks[i]
+		logger.Info("backfilling [%s, %s)", chunk.From, chunk.To)
+		err = process(chunk)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		state.TimeAfter = &chunk.To
+		state.PrevStartedAt = &now
+		err = db.CreateOrUpdate(state)
+		if err != nil {
+			return errors.Default.Wrap(err, "failed to save the backfill checkpoint")
+		}
+		taskCtx.IncProgress(1)
+	}
+	return nil
+}
+
+// runWithBackfill runs process once over the whole history, or chunk by chunk when a backfill is requested
+func runWithBackfill(taskCtx plugin.SubTaskContext, process func(window *backfillWindow) errors.Error) errors.Error {
+	data := taskCtx.GetData().(*DoraTaskData)
+	if data.Options.Backfill == nil {
+		return process(nil)
+	}
+	return runBackfill(taskCtx, process)
+}
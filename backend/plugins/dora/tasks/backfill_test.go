@@ -0,0 +1,197 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	mockplugin "github.com/apache/incubator-devlake/mocks/core/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBackfillChunks(t *testing.T) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	backfill := &BackfillOptions{From: from, To: to, ChunkDays: 25}
+
+	chunks := backfillChunks(backfill, nil)
+	assert.Equal(t, []backfillWindow{
+		{From: from, To: time.Date(2020, 1, 26, 0, 0, 0, 0, time.UTC)},
+		{From: time.Date(2020, 1, 26, 0, 0, 0, 0, time.UTC), To: time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)},
+		{From: time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC), To: to},
+	}, chunks)
+
+	// resume after the first chunk
+	done := time.Date(2020, 1, 26, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, chunks[1:], backfillChunks(backfill, &done))
+
+	// a checkpoint before the window is ignored, a completed one leaves nothing to do
+	before := from.AddDate(-1, 0, 0)
+	assert.Equal(t, chunks, backfillChunks(backfill, &before))
+	assert.Empty(t, backfillChunks(backfill, &to))
+}
+
+func TestRunBackfillResume(t *testing.T) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	backfill := &BackfillOptions{From: from, To: from.AddDate(0, 0, 30), ChunkDays: 10}
+	data := &DoraTaskData{Options: &DoraOptions{ProjectName: "project1", Backfill: backfill}}
+
+	// the checkpoint survives the runs like the row of _devlake_subtask_states
+	var checkpoint *models.SubtaskState
+	notFound := errors.NotFound.New("record not found")
+	db := mockdal.NewDal(t)
+	db.On("First", mock.AnythingOfType("*models.SubtaskState"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		if checkpoint == nil {
+			return notFound
+		}
+		*dst.(*models.SubtaskState) = *checkpoint
+		return nil
+	})
+	db.On("IsErrorNotFound", mock.Anything).Return(func(err error) bool {
+		return err == notFound
+	}).Maybe()
+	db.On("CreateOrUpdate", mock.AnythingOfType("*models.SubtaskState"), mock.Anything).Return(func(entity interface{}, _ ...dal.Clause) errors.Error {
+		saved := *entity.(*models.SubtaskState)
+		timeAfter := *saved.TimeAfter
+		saved.TimeAfter = &timeAfter
+		checkpoint = &saved
+		return nil
+	})
+	taskCtx := mockplugin.NewTaskContext(t)
+	taskCtx.On("GetName").Return("dora")
+	subtaskCtx := mockplugin.NewSubTaskContext(t)
+	subtaskCtx.On("GetDal").Return(db)
+	subtaskCtx.On("GetLogger").Return(unithelper.DummyLogger())
+	subtaskCtx.On("GetData").Return(data)
+	subtaskCtx.On("GetName").Return("calculateChangeLeadTime")
+	subtaskCtx.On("TaskContext").Return(taskCtx)
+	subtaskCtx.On("SetProgress", mock.Anything, mock.Anything)
+	subtaskCtx.On("IncProgress", mock.Anything)
+
+	// the run is interrupted while processing the second chunk
+	var processed []backfillWindow
+	err := runBackfill(subtaskCtx, func(window *backfillWindow) errors.Error {
+		if len(processed) == 1 {
+			return errors.Default.New("interrupted")
+		}
+		processed = append(processed, *window)
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []backfillWindow{{From: from, To: from.AddDate(0, 0, 10)}}, processed)
+	if assert.NotNil(t, checkpoint) {
+		assert.Equal(t, from.AddDate(0, 0, 10), *checkpoint.TimeAfter)
+	}
+
+	// the next run resumes from the checkpoint instead of starting over
+	processed = nil
+	err = runBackfill(subtaskCtx, func(window *backfillWindow) errors.Error {
+		processed = append(processed, *window)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []backfillWindow{
+		{From: from.AddDate(0, 0, 10), To: from.AddDate(0, 0, 20)},
+		{From: from.AddDate(0, 0, 20), To: from.AddDate(0, 0, 30)},
+	}, processed)
+	assert.Equal(t, backfill.To, *checkpoint.TimeAfter)
+
+	// a completed backfill has nothing left to do
+	processed = nil
+	assert.Nil(t, runBackfill(subtaskCtx, func(window *backfillWindow) errors.Error {
+		processed = append(processed, *window)
+		return nil
+	}))
+	assert.Empty(t, processed)
+
+	// another chunk size starts over
+	backfill.ChunkDays = 15
+	assert.Nil(t, runBackfill(subtaskCtx, func(window *backfillWindow) errors.Error {
+		processed = append(processed, *window)
+		return nil
+	}))
+	assert.Equal(t, []backfillWindow{
+		{From: from, To: from.AddDate(0, 0, 15)},
+		{From: from.AddDate(0, 0, 15), To: from.AddDate(0, 0, 30)},
+	}, processed)
+}
+
+func TestDecodeAndValidateBackfill(t *testing.T) {
+	op, err := DecodeAndValidateTaskOptions(map[string]interface{}{
+		"projectName": "project1",
+		"backfill": map[string]interface{}{
+			"from": "2020-01-01T00:00:00Z",
+			"to":   "2021-01-01T00:00:00Z",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), op.Backfill.From.UTC())
+	assert.Equal(t, defaultBackfillChunkDays, op.Backfill.ChunkDays)
+
+	_, err = DecodeAndValidateTaskOptions(map[string]interface{}{
+		"projectName": "project1",
+		"backfill": map[string]interface{}{
+			"from": "2021-01-01T00:00:00Z",
+			"to":   "2020-01-01T00:00:00Z",
+		},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestBatchFetchScopedToWindow(t *testing.T) {
+	window := &backfillWindow{
+		From: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC),
+	}
+	// clauseParams collects the parameters of the clauses a query was made with
+	clauseParams := func(call mock.Call) []interface{} {
+		var params []interface{}
+		for _, clause := range call.Arguments.Get(1).([]dal.Clause) {
+			if data, ok := clause.Data.(dal.DalClause); ok {
+				params = append(params, data.Params...)
+			}
+		}
+		return params
+	}
+	for _, w := range []*backfillWindow{window, nil} {
+		db := new(mockdal.Dal)
+		db.On("All", mock.Anything, mock.Anything).Return(nil)
+		_, err := batchFetchFirstCommits("project1", db, w)
+		assert.Nil(t, err)
+		_, err = batchFetchFirstReviews("project1", db, w)
+		assert.Nil(t, err)
+		_, err = batchFetchDeployments("project1", db, w)
+		assert.Nil(t, err)
+		assert.Len(t, db.Calls, 3)
+		for _, call := range db.Calls {
+			if w != nil {
+				// every query is bounded by the merged dates of the window
+				assert.Subset(t, clauseParams(call), []interface{}{window.From, window.To})
+			} else {
+				assert.NotContains(t, clauseParams(call), window.From)
+			}
+		}
+	}
+}
@@ -18,6 +18,7 @@ limitations under the License.
 package tasks
 
 import (
+	"fmt"
 	"math"
 	"reflect"
 	"time"
@@ -46,37 +47,51 @@ func CalculateChangeLeadTime(taskCtx plugin.SubTaskContext) errors.Error {
 	db := taskCtx.GetDal()
 	logger := taskCtx.GetLogger()
 	data := taskCtx.GetData().(*DoraTaskData)
-	// Clear previous results from the project
-	err := db.Exec("DELETE FROM project_pr_metrics WHERE project_name = ? ", data.Options.ProjectName)
-	if err != nil {
-		return errors.Default.Wrap(err, "error deleting previous project_pr_metrics")
-	}
 
-	// Batch fetch all required data upfront for better performance
-	startTime := time.Now()
-	logger.Info("Batch fetching data for project: %s", data.Options.ProjectName)
+	return runWithBackfill(taskCtx, func(window *backfillWindow) errors.Error {
+		// Batch fetch the data of the pull requests merged within the window upfront for better performance,
+		// a backfill loads it chunk by chunk to bound the memory
+		startTime := time.Now()
+		logger.Info("Batch fetching data for project: %s", data.Options.ProjectName)
 
-	firstCommitsMap, err := batchFetchFirstCommits(data.Options.ProjectName, db)
-	if err != nil {
-		return errors.Default.Wrap(err, "failed to batch fetch first commits")
-	}
-	logger.Info("Fetched %d first commits in %v", len(firstCommitsMap), time.Since(startTime))
+		firstCommitsMap, err := batchFetchFirstCommits(data.Options.ProjectName, db, window)
+		if err != nil {
+			return errors.Default.Wrap(err, "failed to batch fetch first commits")
+		}
+		logger.Info("Fetched %d first commits in %v", len(firstCommitsMap), time.Since(startTime))
 
-	reviewStartTime := time.Now()
-	firstReviewsMap, err := batchFetchFirstReviews(data.Options.ProjectName, db)
-	if err != nil {
-		return errors.Default.Wrap(err, "failed to batch fetch first reviews")
-	}
-	logger.Info("Fetched %d first reviews in %v", len(firstReviewsMap), time.Since(reviewStartTime))
+		reviewStartTime := time.Now()
+		firstReviewsMap, err := batchFetchFirstReviews(data.Options.ProjectName, db, window)
+		if err != nil {
+			return errors.Default.Wrap(err, "failed to batch fetch first reviews")
+		}
+		logger.Info("Fetched %d first reviews in %v", len(firstReviewsMap), time.Since(reviewStartTime))
 
-	deploymentStartTime := time.Now()
-	deploymentsMap, err := batchFetchDeployments(data.Options.ProjectName, db)
-	if err != nil {
-		return errors.Default.Wrap(err, "failed to batch fetch deployments")
-	}
-	logger.Info("Fetched %d deployments in %v", len(deploymentsMap), time.Since(deploymentStartTime))
-	logger.Info("Total batch fetch time: %v", time.Since(startTime))
+		deploymentStartTime := time.Now()
+		deploymentsMap, err := batchFetchDeployments(data.Options.ProjectName, db, window)
+		if err != nil {
+			return errors.Default.Wrap(err, "failed to batch fetch deployments")
+		}
+		logger.Info("Fetched %d deployments in %v", len(deploymentsMap), time.Since(deploymentStartTime))
+		logger.Info("Total batch fetch time: %v", time.Since(startTime))
 
+		return calculateChangeLeadTime(taskCtx, window, firstCommitsMap, firstReviewsMap, deploymentsMap)
+	})
+}
+
+// calculateChangeLeadTime recalculates the metrics of the pull requests merged within window, or all of them when window is nil
+func calculateChangeLeadTime(
+	taskCtx plugin.SubTaskContext,
+	window *backfillWindow,
+	firstCommitsMap map[string]*code.PullRequestCommit,
+	firstReviewsMap map[string]*code.PullRequestComment,
+	deploymentsMap map[string]*devops.CicdDeploymentCommit,
+) errors.Error {
+	db := taskCtx.GetDal()
+	logger := taskCtx.GetLogger()
+	data := taskCtx.GetData().(*DoraTaskData)
+	// Clear previous results from the project
+	deleteClauses := []dal.Clause{dal.Where("project_name = ?", data.Options.ProjectName)}
 	// Get pull requests by repo project_name
 	var clauses = []dal.Clause{
 		dal.Select("pr.id, pr.pull_request_key, pr.author_id, pr.merge_commit_sha, pr.created_date, pr.merged_date"),
@@ -84,6 +99,14 @@ func CalculateChangeLeadTime(taskCtx plugin.SubTaskContext) errors.Error {
 		dal.Join(`LEFT JOIN project_mapping pm ON (pm.row_id = pr.base_repo_id)`),
 		dal.Where("pr.merged_date IS NOT NULL AND pm.project_name = ? AND pm.table = 'repos'", data.Options.ProjectName),
 	}
+	if window != nil {
+		deleteClauses = append(deleteClauses, dal.Where("pr_merged_date >= ? AND pr_merged_date < ?", window.From, window.To))
+		clauses = append(clauses, dal.Where("pr.merged_date >= ? AND pr.merged_date < ?", window.From, window.To))
+	}
+	err := db.Delete(&crossdomain.ProjectPrMetric{}, deleteClauses...)
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous project_pr_metrics")
+	}
 	cursor, err := db.Cursor(clauses...)
 	if err != nil {
 		return err
@@ -102,6 +125,7 @@ func CalculateChangeLeadTime(taskCtx plugin.SubTaskContext) errors.Error {
 		BatchSize:    100,
 		InputRowType: reflect.TypeOf(code.PullRequest{}),
 		Input:        cursor,
+		Incremental:  window != nil,
 		Convert: func(inputRow interface{}) ([]interface{}, errors.Error) {
 			pr := inputRow.(*code.PullRequest)
 			// Initialize a new ProjectPrMetric
@@ -187,27 +211,39 @@ type deploymentCommitWithMergeSha struct {
 	MergeSha string `gorm:"column:merge_sha"`
 }
 
-// batchFetchFirstCommits retrieves the first commit for all pull requests in the given project.
+// mergedWithin restricts the pull requests aliased as alias to those merged within the window, or
+// lets all of them through when window is nil
+func mergedWithin(alias string, window *backfillWindow) (string, []interface{}) {
+	if window == nil {
+		return "1 = 1", nil
+	}
+	return fmt.Sprintf("%s.merged_date >= ? AND %s.merged_date < ?", alias, alias), []interface{}{window.From, window.To}
+}
+
+// batchFetchFirstCommits retrieves the first commit for all pull requests in the given project, merged within window unless it is nil.
 // Returns a map indexed by PR ID for O(1) lookup performance.
 //
 // The query uses a subquery to find the minimum commit_authored_date for each PR,
 // then joins back to get the full commit record. This is more efficient than
 // fetching all commits and filtering in memory.
-func batchFetchFirstCommits(projectName string, db dal.Dal) (map[string]*code.PullRequestCommit, errors.Error) {
+func batchFetchFirstCommits(projectName string, db dal.Dal, window *backfillWindow) (map[string]*code.PullRequestCommit, errors.Error) {
 	var results []*code.PullRequestCommit
 
 	// Use a subquery to find the earliest commit for each PR, then join to get full commit details.
 	// This avoids scanning all commits and is optimized by the database engine.
+	windowCondition, windowParams := mergedWithin("pr2", window)
 	err := db.All(
 		&results,
 		dal.Select("prc.*"),
 		dal.From("pull_request_commits prc"),
 		dal.Join(`INNER JOIN (
-			SELECT pull_request_id, MIN(commit_authored_date) as min_date
-			FROM pull_request_commits
-			GROUP BY pull_request_id
+			SELECT prc2.pull_request_id, MIN(prc2.commit_authored_date) as min_date
+			FROM pull_request_commits prc2
+			INNER JOIN pull_requests pr2 ON pr2.id = prc2.pull_request_id
+			WHERE `+windowCondition+`
+			GROUP BY prc2.pull_request_id
 		) first_commits ON prc.pull_request_id = first_commits.pull_request_id
-		AND prc.commit_authored_date = first_commits.min_date`),
+		AND prc.commit_authored_date = first_commits.min_date`, windowParams...),
 		dal.Join("INNER JOIN pull_requests pr ON pr.id = prc.pull_request_id"),
 		dal.Join("LEFT JOIN project_mapping pm ON pm.row_id = pr.base_repo_id AND pm.table = 'repos'"),
 		dal.Where("pm.project_name = ?", projectName),
@@ -230,16 +266,17 @@ func batchFetchFirstCommits(projectName string, db dal.Dal) (map[string]*code.Pu
 	return commitMap, nil
 }
 
-// batchFetchFirstReviews retrieves the first review comment for all pull requests in the given project.
+// batchFetchFirstReviews retrieves the first review comment for all pull requests in the given project, merged within window unless it is nil.
 // Returns a map indexed by PR ID for O(1) lookup performance.
 //
 // The query uses a subquery to find the minimum created_date for each PR (excluding the PR author),
 // then joins back to get the full comment record.
-func batchFetchFirstReviews(projectName string, db dal.Dal) (map[string]*code.PullRequestComment, errors.Error) {
+func batchFetchFirstReviews(projectName string, db dal.Dal, window *backfillWindow) (map[string]*code.PullRequestComment, errors.Error) {
 	var results []*code.PullRequestComment
 
 	// Use a subquery to find the earliest review comment for each PR (excluding author's comments),
 	// then join to get full comment details.
+	windowCondition, windowParams := mergedWithin("pr2", window)
 	err := db.All(
 		&results,
 		dal.Select("prc.*"),
@@ -249,9 +286,10 @@ func batchFetchFirstReviews(projectName string, db dal.Dal) (map[string]*code.Pu
 			FROM pull_request_comments prc2
 			INNER JOIN pull_requests pr2 ON pr2.id = prc2.pull_request_id
 			WHERE (pr2.author_id IS NULL OR pr2.author_id = '' OR prc2.account_id != pr2.author_id)
+			AND `+windowCondition+`
 			GROUP BY prc2.pull_request_id
 		) first_reviews ON prc.pull_request_id = first_reviews.pull_request_id
-		AND prc.created_date = first_reviews.min_date`),
+		AND prc.created_date = first_reviews.min_date`, windowParams...),
 		dal.Join("INNER JOIN pull_requests pr ON pr.id = prc.pull_request_id"),
 		dal.Join("LEFT JOIN project_mapping pm ON pm.row_id = pr.base_repo_id AND pm.table = 'repos'"),
 		dal.Where("pm.project_name = ? AND (pr.author_id IS NULL OR pr.author_id = '' OR prc.account_id != pr.author_id)", projectName),
@@ -274,7 +312,8 @@ func batchFetchFirstReviews(projectName string, db dal.Dal) (map[string]*code.Pu
 	return reviewMap, nil
 }
 
-// batchFetchDeployments retrieves deployment commits for all merge commits in the given project.
+// batchFetchDeployments retrieves deployment commits for all merge commits in the given project, those of the
+// pull requests merged within window unless it is nil.
 // Returns a map indexed by merge commit SHA for O(1) lookup performance.
 //
 // The query finds the first successful production deployment for each merge commit by:
@@ -285,13 +324,12 @@ func batchFetchFirstReviews(projectName string, db dal.Dal) (map[string]*code.Pu
 //
 // The map is indexed by merge_sha (from commits_diffs), not by deployment commit_sha,
 // because the caller needs to look up deployments by PR merge_commit_sha.
-func batchFetchDeployments(projectName string, db dal.Dal) (map[string]*devops.CicdDeploymentCommit, errors.Error) {
+func batchFetchDeployments(projectName string, db dal.Dal, window *backfillWindow) (map[string]*devops.CicdDeploymentCommit, errors.Error) {
 	var results []*deploymentCommitWithMergeSha
 
 	// Query finds the first deployment for each merge commit by using a window function
 	// to rank deployments by started_date, then filtering to keep only rank 1.
-	err := db.All(
-		&results,
+	clauses := []dal.Clause{
 		dal.Select("dc.*, cd.commit_sha as merge_sha"),
 		dal.From("cicd_deployment_commits dc"),
 		dal.Join("LEFT JOIN cicd_deployment_commits p ON dc.prev_success_deployment_commit_id = p.id"),
@@ -300,8 +338,16 @@ func batchFetchDeployments(projectName string, db dal.Dal) (map[string]*devops.C
 		dal.Where("dc.prev_success_deployment_commit_id <> ''"),
 		dal.Where("dc.environment = 'PRODUCTION'"), // TODO: remove this when multi-environment is supported
 		dal.Where("dc.result = ? AND pm.project_name = ?", devops.RESULT_SUCCESS, projectName),
-		dal.Orderby("cd.commit_sha, dc.started_date ASC, dc.id ASC"),
-	)
+	}
+	if window != nil {
+		windowCondition, windowParams := mergedWithin("pr", window)
+		clauses = append(clauses, dal.Where(
+			"EXISTS (SELECT 1 FROM pull_requests pr WHERE pr.merge_commit_sha = cd.commit_sha AND "+windowCondition+")",
+			windowParams...,
+		))
+	}
+	clauses = append(clauses, dal.Orderby("cd.commit_sha, dc.started_date ASC, dc.id ASC"))
+	err := db.All(&results, clauses...)
 
 	if err != nil {
 		return nil, errors.Default.Wrap(err, "failed to batch fetch deployments")
@@ -60,6 +60,13 @@ type pipelineCommitEx struct {
 }
 
 func GenerateDeploymentCommits(taskCtx plugin.SubTaskContext) errors.Error {
+	return runWithBackfill(taskCtx, func(window *backfillWindow) errors.Error {
+		return generateDeploymentCommits(taskCtx, window)
+	})
+}
+
+// generateDeploymentCommits regenerates the deployment commits of the pipelines finished within window, or all of them when window is nil
+func generateDeploymentCommits(taskCtx plugin.SubTaskContext, window *backfillWindow) errors.Error {
 	db := taskCtx.GetDal()
 	data := taskCtx.GetData().(*DoraTaskData)
 	// select all cicd_pipeline_commits from all "Deployments" in the project
@@ -103,11 +110,19 @@ func GenerateDeploymentCommits(taskCtx plugin.SubTaskContext) errors.Error {
 			noneSkippedResult,
 		),
 	}
+	// only the deployment commits finished within the window get regenerated when backfilling
+	windowSql := ""
+	var windowParams []interface{}
+	if window != nil {
+		clauses = append(clauses, dal.Where(`p.finished_date >= ? AND p.finished_date < ?`, window.From, window.To))
+		windowSql = " AND finished_date >= ? AND finished_date < ?"
+		windowParams = []interface{}{window.From, window.To}
+	}
 	if data.Options.ScopeId != nil {
 		clauses = append(clauses, dal.Where(`p.cicd_scope_id = ?`, data.Options.ScopeId))
 		// Clear previous results from the project
-		deleteSql := `DELETE FROM cicd_deployment_commits WHERE cicd_scope_id = ? and subtask_name = ?;`
-		err := db.Exec(deleteSql, data.Options.ScopeId, DORAGenerateDeploymentCommits)
+		deleteSql := `DELETE FROM cicd_deployment_commits WHERE cicd_scope_id = ? and subtask_name = ?` + windowSql
+		err := db.Exec(deleteSql, append([]interface{}{data.Options.ScopeId, DORAGenerateDeploymentCommits}, windowParams...)...)
 		if err != nil {
 			return errors.Default.Wrap(err, "error deleting previous cicd_deployment_commits")
 		}
@@ -126,8 +141,8 @@ func GenerateDeploymentCommits(taskCtx plugin.SubTaskContext) errors.Error {
 				LEFT JOIN project_mapping pm ON (pm.table = 'cicd_scopes' AND pm.row_id = cdc.cicd_scope_id)
 				WHERE pm.project_name = ?
 			) AS subquery
-			) AND subtask_name = ?;`
-		err := db.Exec(deleteSql, data.Options.ProjectName, DORAGenerateDeploymentCommits)
+			) AND subtask_name = ?` + windowSql
+		err := db.Exec(deleteSql, append([]interface{}{data.Options.ProjectName, DORAGenerateDeploymentCommits}, windowParams...)...)
 		if err != nil {
 			return errors.Default.Wrap(err, "error deleting previous cicd_deployment_commits")
 		}
@@ -148,6 +163,7 @@ func GenerateDeploymentCommits(taskCtx plugin.SubTaskContext) errors.Error {
 		},
 		InputRowType: reflect.TypeOf(pipelineCommitEx{}),
 		Input:        cursor,
+		Incremental:  window != nil,
 		Convert: func(inputRow interface{}) ([]interface{}, errors.Error) {
 			pipelineCommit := inputRow.(*pipelineCommitEx)
 
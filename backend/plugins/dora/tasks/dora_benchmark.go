@@ -0,0 +1,189 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"sort"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+)
+
+const (
+	DORA_REPORT_2021 = "2021"
+	DORA_REPORT_2023 = "2023"
+)
+
+// the thresholds below mirror the ones used by the DORA dashboards, see dora_benchmarks table
+const (
+	minutesPerHour = 60
+	minutesPerDay  = 24 * minutesPerHour
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// PeriodStart returns the beginning of the day, week (starting on Monday) or month that t belongs to, in UTC
+func PeriodStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case crossdomain.DORA_GRANULARITY_WEEK:
+		weekday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -weekday)
+	case crossdomain.DORA_GRANULARITY_MONTH:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriodStart returns the beginning of the period following the one starting at start
+func nextPeriodStart(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case crossdomain.DORA_GRANULARITY_WEEK:
+		return start.AddDate(0, 0, 7)
+	case crossdomain.DORA_GRANULARITY_MONTH:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// percentileMinutes returns the p-th percentile the same way the dashboards do: the largest value whose percent rank <= p
+func percentileMinutes(values []int64, p float64) *int64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	value := sorted[int(float64(len(sorted)-1)*p)]
+	return &value
+}
+
+// medianMinutes returns the 50th percentile of values
+func medianMinutes(values []int64) *int64 {
+	return percentileMinutes(values, .5)
+}
+
+// deploymentFrequencyLevel buckets the number of days with a production deployment within a period of periodDays days.
+// The benchmark is expressed in deployment days per week/month/six months, so the count is scaled to the period length.
+func deploymentFrequencyLevel(doraReport string, deploymentDays int, periodDays int) string {
+	if periodDays <= 0 {
+		return ""
+	}
+	perWeek := float64(deploymentDays) * 7 / float64(periodDays)
+	perMonth := float64(deploymentDays) * 30 / float64(periodDays)
+	perSixMonths := float64(deploymentDays) * 182 / float64(periodDays)
+	if doraReport == DORA_REPORT_2021 {
+		switch {
+		case perWeek >= 5:
+			return crossdomain.DORA_LEVEL_ELITE
+		case perMonth >= 1:
+			return crossdomain.DORA_LEVEL_HIGH
+		case perSixMonths >= 1:
+			return crossdomain.DORA_LEVEL_MEDIUM
+		default:
+			return crossdomain.DORA_LEVEL_LOW
+		}
+	}
+	switch {
+	case perWeek >= 5:
+		return crossdomain.DORA_LEVEL_ELITE
+	case perWeek >= 1:
+		return crossdomain.DORA_LEVEL_HIGH
+	case perMonth >= 1:
+		return crossdomain.DORA_LEVEL_MEDIUM
+	default:
+		return crossdomain.DORA_LEVEL_LOW
+	}
+}
+
+// leadTimeLevel buckets the median lead time for changes in minutes
+func leadTimeLevel(doraReport string, minutes *int64) string {
+	if minutes == nil {
+		return ""
+	}
+	if doraReport == DORA_REPORT_2021 {
+		switch {
+		case *minutes < minutesPerHour:
+			return crossdomain.DORA_LEVEL_ELITE
+		case *minutes < minutesPerWeek:
+			return crossdomain.DORA_LEVEL_HIGH
+		case *minutes < 180*minutesPerDay:
+			return crossdomain.DORA_LEVEL_MEDIUM
+		default:
+			return crossdomain.DORA_LEVEL_LOW
+		}
+	}
+	switch {
+	case *minutes < minutesPerDay:
+		return crossdomain.DORA_LEVEL_ELITE
+	case *minutes < minutesPerWeek:
+		return crossdomain.DORA_LEVEL_HIGH
+	case *minutes < 30*minutesPerDay:
+		return crossdomain.DORA_LEVEL_MEDIUM
+	default:
+		return crossdomain.DORA_LEVEL_LOW
+	}
+}
+
+// changeFailureRateLevel buckets the ratio of deployments causing incidents
+func changeFailureRateLevel(doraReport string, rate *float64) string {
+	if rate == nil {
+		return ""
+	}
+	if doraReport == DORA_REPORT_2021 {
+		switch {
+		case *rate <= .15:
+			return crossdomain.DORA_LEVEL_ELITE
+		case *rate <= .20:
+			return crossdomain.DORA_LEVEL_HIGH
+		case *rate <= .30:
+			return crossdomain.DORA_LEVEL_MEDIUM
+		default:
+			return crossdomain.DORA_LEVEL_LOW
+		}
+	}
+	switch {
+	case *rate <= .05:
+		return crossdomain.DORA_LEVEL_ELITE
+	case *rate <= .10:
+		return crossdomain.DORA_LEVEL_HIGH
+	case *rate <= .15:
+		return crossdomain.DORA_LEVEL_MEDIUM
+	default:
+		return crossdomain.DORA_LEVEL_LOW
+	}
+}
+
+// recoveryTimeLevel buckets the median time to restore service (2021) or failed deployment recovery time (2023),
+// both reports share the same thresholds
+func recoveryTimeLevel(minutes *int64) string {
+	if minutes == nil {
+		return ""
+	}
+	switch {
+	case *minutes < minutesPerHour:
+		return crossdomain.DORA_LEVEL_ELITE
+	case *minutes < minutesPerDay:
+		return crossdomain.DORA_LEVEL_HIGH
+	case *minutes < minutesPerWeek:
+		return crossdomain.DORA_LEVEL_MEDIUM
+	default:
+		return crossdomain.DORA_LEVEL_LOW
+	}
+}
@@ -0,0 +1,346 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+)
+
+// CalculateDoraMetricsMeta contains metadata for the CalculateDoraMetrics subtask.
+var CalculateDoraMetricsMeta = plugin.SubTaskMeta{
+	Name:             "calculateDoraMetrics",
+	EntryPoint:       CalculateDoraMetrics,
+	EnabledByDefault: true,
+	Description:      "Calculate daily, weekly and monthly DORA metrics snapshots of the project",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CICD, plugin.DOMAIN_TYPE_CODE, plugin.DOMAIN_TYPE_TICKET},
+}
+
+var doraGranularities = []string{
+	crossdomain.DORA_GRANULARITY_DAY,
+	crossdomain.DORA_GRANULARITY_WEEK,
+	crossdomain.DORA_GRANULARITY_MONTH,
+}
+
+// doraDeployment is a production deployment, multiple deployment commits of the same deployment count as one
+type doraDeployment struct {
+	Id           string
+	FinishedDate *time.Time
+}
+
+// doraPrLeadTime is the cycle time of a pull request along with the deployment that shipped it
+type doraPrLeadTime struct {
+	Id           string
+	PrCycleTime  int64
+	FinishedDate *time.Time
+}
+
+// doraIncident is an incident caused by a deployment
+type doraIncident struct {
+	Id              string
+	DeploymentId    string
+	LeadTimeMinutes *uint
+}
+
+// CalculateDoraMetrics computes deployment frequency, lead time for changes, change failure rate and
+// failed deployment recovery time per day, week and month and saves them into project_dora_metrics.
+// It relies on project_pr_metrics and project_incident_deployment_relationships, so it must run after
+// calculateChangeLeadTime and ConnectIncidentToDeployment.
+func CalculateDoraMetrics(taskCtx plugin.SubTaskContext) errors.Error {
+	db := taskCtx.GetDal()
+	logger := taskCtx.GetLogger()
+	data := taskCtx.GetData().(*DoraTaskData)
+	projectName := data.Options.ProjectName
+
+	deployments, err := fetchDoraDeployments(projectName, db, nil)
+	if err != nil {
+		return err
+	}
+	prs, err := fetchDoraPrLeadTimes(projectName, db, nil)
+	if err != nil {
+		return err
+	}
+	incidents, err := fetchDoraIncidents(projectName, db, nil)
+	if err != nil {
+		return err
+	}
+	logger.Info("calculating dora metrics of %d deployments, %d pull requests and %d incidents", len(deployments), len(prs), len(incidents))
+
+	// Clear previous results from the project
+	err = db.Delete(&crossdomain.ProjectDoraMetric{}, dal.Where("project_name = ?", projectName))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous project_dora_metrics")
+	}
+
+	batchSave, err := api.NewBatchSave(taskCtx, reflect.TypeOf(&crossdomain.ProjectDoraMetric{}), 500)
+	if err != nil {
+		return err
+	}
+	defer batchSave.Close()
+	now := time.Now()
+	for _, granularity := range doraGranularities {
+		metrics := computeDoraMetrics(projectName, data.Options.DoraReport, granularity, deployments, prs, incidents, now)
+		for _, metric := range metrics {
+			err = batchSave.Add(metric)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return batchSave.Flush()
+}
+
+// doraRange limits the inputs to the deployments finished within [from, to), nil means the whole history
+type doraRange struct {
+	from time.Time
+	to   time.Time
+}
+
+func fetchDoraDeployments(projectName string, db dal.Dal, r *doraRange) ([]doraDeployment, errors.Error) {
+	var deployments []doraDeployment
+	clauses := []dal.Clause{
+		dal.Select("cdc.cicd_deployment_id AS id, MAX(cdc.finished_date) AS finished_date"),
+		dal.From("cicd_deployment_commits cdc"),
+		dal.Join("LEFT JOIN project_mapping pm ON cdc.cicd_scope_id = pm.row_id"),
+		dal.Where(
+			"pm.table = ? AND pm.project_name = ? AND cdc.result = ? AND cdc.environment = ? AND cdc.finished_date IS NOT NULL",
+			"cicd_scopes", projectName, devops.RESULT_SUCCESS, devops.PRODUCTION,
+		),
+		dal.Groupby("cdc.cicd_deployment_id"),
+	}
+	if r != nil {
+		clauses = append(clauses, dal.Having("MAX(cdc.finished_date) >= ? AND MAX(cdc.finished_date) < ?", r.from, r.to))
+	}
+	err := db.All(&deployments, clauses...)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "failed to fetch production deployments")
+	}
+	return deployments, nil
+}
+
+func fetchDoraPrLeadTimes(projectName string, db dal.Dal, r *doraRange) ([]doraPrLeadTime, errors.Error) {
+	var prs []doraPrLeadTime
+	clauses := []dal.Clause{
+		dal.Select("ppm.id, ppm.pr_cycle_time, cdc.finished_date"),
+		dal.From("project_pr_metrics ppm"),
+		dal.Join("JOIN cicd_deployment_commits cdc ON ppm.deployment_commit_id = cdc.id"),
+		dal.Where("ppm.project_name = ? AND ppm.pr_cycle_time IS NOT NULL AND cdc.finished_date IS NOT NULL", projectName),
+	}
+	if r != nil {
+		clauses = append(clauses, dal.Where("cdc.finished_date >= ? AND cdc.finished_date < ?", r.from, r.to))
+	}
+	err := db.All(&prs, clauses...)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "failed to fetch pull request lead times")
+	}
+	return prs, nil
+}
+
+func fetchDoraIncidents(projectName string, db dal.Dal, r *doraRange) ([]doraIncident, errors.Error) {
+	var incidents []doraIncident
+	clauses := []dal.Clause{
+		dal.Select("i.id, pidr.deployment_id, i.lead_time_minutes"),
+		dal.From("project_incident_deployment_relationships pidr"),
+		dal.Join("JOIN incidents i ON pidr.id = i.id"),
+		dal.Where("pidr.project_name = ?", projectName),
+	}
+	if r != nil {
+		// incidents are attributed to the period of their deployment, see aggregateDoraStats
+		clauses = append(clauses, dal.Where(
+			"pidr.deployment_id IN (SELECT cicd_deployment_id FROM cicd_deployment_commits WHERE finished_date >= ? AND finished_date < ?)",
+			r.from, r.to,
+		))
+	}
+	err := db.All(&incidents, clauses...)
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "failed to fetch incidents caused by deployments")
+	}
+	return incidents, nil
+}
+
+// doraPeriodStats accumulates the inputs of the DORA metrics within one period
+type doraPeriodStats struct {
+	deployments       int
+	deploymentDays    map[time.Time]bool
+	failedDeployments map[string]bool
+	leadTimes         []int64
+	incidents         int
+	recoveryTimes     []int64
+}
+
+// aggregateDoraStats groups deployments, lead times and incidents by the period returned by bucket,
+// inputs for which bucket returns false are ignored. Incidents are attributed to the period of the
+// deployment that caused them.
+func aggregateDoraStats(
+	deployments []doraDeployment,
+	prs []doraPrLeadTime,
+	incidents []doraIncident,
+	bucket func(t time.Time) (time.Time, bool),
+) map[time.Time]*doraPeriodStats {
+	stats := make(map[time.Time]*doraPeriodStats)
+	getStats := func(t time.Time) *doraPeriodStats {
+		start, ok := bucket(t)
+		if !ok {
+			return nil
+		}
+		s := stats[start]
+		if s == nil {
+			s = &doraPeriodStats{
+				deploymentDays:    make(map[time.Time]bool),
+				failedDeployments: make(map[string]bool),
+			}
+			stats[start] = s
+		}
+		return s
+	}
+
+	deploymentDates := make(map[string]time.Time, len(deployments))
+	for _, deployment := range deployments {
+		if deployment.FinishedDate == nil {
+			continue
+		}
+		finishedDate := *deployment.FinishedDate
+		deploymentDates[deployment.Id] = finishedDate
+		if s := getStats(finishedDate); s != nil {
+			s.deployments++
+			s.deploymentDays[PeriodStart(finishedDate, crossdomain.DORA_GRANULARITY_DAY)] = true
+		}
+	}
+	for _, pr := range prs {
+		if pr.FinishedDate == nil {
+			continue
+		}
+		if s := getStats(*pr.FinishedDate); s != nil {
+			s.leadTimes = append(s.leadTimes, pr.PrCycleTime)
+		}
+	}
+	for _, incident := range incidents {
+		finishedDate, ok := deploymentDates[incident.DeploymentId]
+		if !ok {
+			continue
+		}
+		if s := getStats(finishedDate); s != nil {
+			s.incidents++
+			s.failedDeployments[incident.DeploymentId] = true
+			if incident.LeadTimeMinutes != nil {
+				s.recoveryTimes = append(s.recoveryTimes, int64(*incident.LeadTimeMinutes))
+			}
+		}
+	}
+	return stats
+}
+
+// newDoraMetric builds the snapshot of [start, end) out of the aggregated stats, s may be nil
+func newDoraMetric(projectName, doraReport, granularity string, start, end time.Time, s *doraPeriodStats) *crossdomain.ProjectDoraMetric {
+	metric := &crossdomain.ProjectDoraMetric{
+		ProjectName: projectName,
+		Granularity: granularity,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		DoraReport:  doraReport,
+	}
+	if s != nil {
+		metric.DeploymentCount = s.deployments
+		metric.DeploymentDays = len(s.deploymentDays)
+		metric.PrCount = len(s.leadTimes)
+		metric.MedianLeadTimeMinutes = medianMinutes(s.leadTimes)
+		metric.IncidentCount = s.incidents
+		metric.MedianRecoveryTimeMinutes = medianMinutes(s.recoveryTimes)
+		if s.deployments > 0 {
+			metric.FailedDeploymentCount = len(s.failedDeployments)
+			rate := float64(metric.FailedDeploymentCount) / float64(s.deployments)
+			metric.ChangeFailureRate = &rate
+		}
+	}
+	ApplyDoraLevels(metric, doraReport)
+	return metric
+}
+
+// ApplyDoraLevels sets the benchmark levels of the metric according to the given DORA report, so that
+// the snapshots calculated for a report can be presented along with another one
+func ApplyDoraLevels(metric *crossdomain.ProjectDoraMetric, doraReport string) {
+	periodDays := int(metric.PeriodEnd.Sub(metric.PeriodStart).Hours() / 24)
+	metric.DoraReport = doraReport
+	metric.DeploymentFrequencyLevel = deploymentFrequencyLevel(doraReport, metric.DeploymentDays, periodDays)
+	metric.LeadTimeLevel = leadTimeLevel(doraReport, metric.MedianLeadTimeMinutes)
+	metric.ChangeFailureRateLevel = changeFailureRateLevel(doraReport, metric.ChangeFailureRate)
+	metric.RecoveryTimeLevel = recoveryTimeLevel(metric.MedianRecoveryTimeMinutes)
+}
+
+// computeDoraMetrics aggregates the inputs into one snapshot per period, from the period of the first
+// deployment to the period containing until. Periods without any deployment are kept since they
+// matter to the deployment frequency.
+func computeDoraMetrics(
+	projectName string,
+	doraReport string,
+	granularity string,
+	deployments []doraDeployment,
+	prs []doraPrLeadTime,
+	incidents []doraIncident,
+	until time.Time,
+) []*crossdomain.ProjectDoraMetric {
+	if len(deployments) == 0 {
+		return nil
+	}
+	first := until
+	for _, deployment := range deployments {
+		if deployment.FinishedDate != nil && deployment.FinishedDate.Before(first) {
+			first = *deployment.FinishedDate
+		}
+	}
+	stats := aggregateDoraStats(deployments, prs, incidents, func(t time.Time) (time.Time, bool) {
+		return PeriodStart(t, granularity), true
+	})
+
+	var metrics []*crossdomain.ProjectDoraMetric
+	last := PeriodStart(until, granularity)
+	for start := PeriodStart(first, granularity); !start.After(last); start = nextPeriodStart(start, granularity) {
+		end := nextPeriodStart(start, granularity)
+		metrics = append(metrics, newDoraMetric(projectName, doraReport, granularity, start, end, stats[start]))
+	}
+	return metrics
+}
+
+// SummarizeDoraMetrics computes the DORA metrics of the project over [from, to) as a whole, the
+// granularity of the returned metric is left empty. Only the inputs within the range are loaded.
+func SummarizeDoraMetrics(db dal.Dal, projectName, doraReport string, from, to time.Time) (*crossdomain.ProjectDoraMetric, errors.Error) {
+	r := &doraRange{from: from, to: to}
+	deployments, err := fetchDoraDeployments(projectName, db, r)
+	if err != nil {
+		return nil, err
+	}
+	prs, err := fetchDoraPrLeadTimes(projectName, db, r)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := fetchDoraIncidents(projectName, db, r)
+	if err != nil {
+		return nil, err
+	}
+	stats := aggregateDoraStats(deployments, prs, incidents, func(t time.Time) (time.Time, bool) {
+		return from, !t.Before(from) && t.Before(to)
+	})
+	return newDoraMetric(projectName, doraReport, "", from, to, stats[from]), nil
+}
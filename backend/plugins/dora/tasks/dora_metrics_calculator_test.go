@@ -0,0 +1,118 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// 2024-03-14 is a Thursday
+	ts := time.Date(2024, 3, 14, 15, 4, 5, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), PeriodStart(ts, crossdomain.DORA_GRANULARITY_DAY))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), PeriodStart(ts, crossdomain.DORA_GRANULARITY_WEEK))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), PeriodStart(ts, crossdomain.DORA_GRANULARITY_MONTH))
+	// sunday belongs to the week started on the previous monday
+	sunday := time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), PeriodStart(sunday, crossdomain.DORA_GRANULARITY_WEEK))
+}
+
+func TestMedianMinutes(t *testing.T) {
+	assert.Nil(t, medianMinutes(nil))
+	assert.Equal(t, int64(3), *medianMinutes([]int64{5, 3, 1}))
+	assert.Equal(t, int64(2), *medianMinutes([]int64{4, 1, 3, 2}))
+}
+
+func TestDoraLevels(t *testing.T) {
+	minutes := func(m int64) *int64 { return &m }
+	rate := func(r float64) *float64 { return &r }
+
+	assert.Equal(t, crossdomain.DORA_LEVEL_ELITE, deploymentFrequencyLevel(DORA_REPORT_2023, 5, 7))
+	assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, deploymentFrequencyLevel(DORA_REPORT_2023, 1, 7))
+	assert.Equal(t, crossdomain.DORA_LEVEL_MEDIUM, deploymentFrequencyLevel(DORA_REPORT_2023, 1, 30))
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, deploymentFrequencyLevel(DORA_REPORT_2023, 0, 30))
+	assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, deploymentFrequencyLevel(DORA_REPORT_2021, 1, 30))
+
+	assert.Equal(t, "", leadTimeLevel(DORA_REPORT_2023, nil))
+	assert.Equal(t, crossdomain.DORA_LEVEL_ELITE, leadTimeLevel(DORA_REPORT_2023, minutes(120)))
+	assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, leadTimeLevel(DORA_REPORT_2021, minutes(120)))
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, leadTimeLevel(DORA_REPORT_2023, minutes(31*minutesPerDay)))
+
+	assert.Equal(t, crossdomain.DORA_LEVEL_ELITE, changeFailureRateLevel(DORA_REPORT_2023, rate(0.05)))
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, changeFailureRateLevel(DORA_REPORT_2023, rate(0.2)))
+	assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, changeFailureRateLevel(DORA_REPORT_2021, rate(0.2)))
+
+	assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, recoveryTimeLevel(minutes(90)))
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, recoveryTimeLevel(minutes(minutesPerWeek)))
+}
+
+func TestComputeDoraMetrics(t *testing.T) {
+	date := func(day, hour int) *time.Time {
+		d := time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC)
+		return &d
+	}
+	leadTime := uint(30)
+	deployments := []doraDeployment{
+		{Id: "d1", FinishedDate: date(11, 10)},
+		{Id: "d2", FinishedDate: date(11, 18)},
+		{Id: "d3", FinishedDate: date(13, 9)},
+	}
+	prs := []doraPrLeadTime{
+		{Id: "pr1", PrCycleTime: 100, FinishedDate: date(11, 10)},
+		{Id: "pr2", PrCycleTime: 300, FinishedDate: date(11, 18)},
+		{Id: "pr3", PrCycleTime: 200, FinishedDate: date(13, 9)},
+	}
+	incidents := []doraIncident{
+		{Id: "i1", DeploymentId: "d2", LeadTimeMinutes: &leadTime},
+		{Id: "i2", DeploymentId: "d2"},
+		{Id: "i3", DeploymentId: "unknown"},
+	}
+
+	daily := computeDoraMetrics("p", DORA_REPORT_2023, crossdomain.DORA_GRANULARITY_DAY, deployments, prs, incidents, *date(14, 0))
+	assert.Len(t, daily, 4)
+	assert.Equal(t, 2, daily[0].DeploymentCount)
+	assert.Equal(t, 1, daily[0].DeploymentDays)
+	assert.Equal(t, int64(100), *daily[0].MedianLeadTimeMinutes)
+	assert.Equal(t, 1, daily[0].FailedDeploymentCount)
+	assert.Equal(t, 0.5, *daily[0].ChangeFailureRate)
+	assert.Equal(t, 2, daily[0].IncidentCount)
+	assert.Equal(t, int64(30), *daily[0].MedianRecoveryTimeMinutes)
+	assert.Equal(t, crossdomain.DORA_LEVEL_ELITE, daily[0].DeploymentFrequencyLevel)
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, daily[0].ChangeFailureRateLevel)
+	assert.Equal(t, crossdomain.DORA_LEVEL_ELITE, daily[0].RecoveryTimeLevel)
+	// no deployment on the 12th
+	assert.Equal(t, 0, daily[1].DeploymentCount)
+	assert.Nil(t, daily[1].ChangeFailureRate)
+	assert.Equal(t, crossdomain.DORA_LEVEL_LOW, daily[1].DeploymentFrequencyLevel)
+	assert.Equal(t, "", daily[1].LeadTimeLevel)
+
+	weekly := computeDoraMetrics("p", DORA_REPORT_2023, crossdomain.DORA_GRANULARITY_WEEK, deployments, prs, incidents, *date(14, 0))
+	assert.Len(t, weekly, 1)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), weekly[0].PeriodStart)
+	assert.Equal(t, time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC), weekly[0].PeriodEnd)
+	assert.Equal(t, 3, weekly[0].DeploymentCount)
+	assert.Equal(t, 2, weekly[0].DeploymentDays)
+	assert.Equal(t, int64(200), *weekly[0].MedianLeadTimeMinutes)
+	assert.Equal(t, crossdomain.DORA_LEVEL_HIGH, weekly[0].DeploymentFrequencyLevel)
+
+	assert.Nil(t, computeDoraMetrics("p", DORA_REPORT_2023, crossdomain.DORA_GRANULARITY_MONTH, nil, prs, incidents, *date(14, 0)))
+}
@@ -19,6 +19,8 @@ package tasks
 
 import (
 	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/apache/incubator-devlake/core/dal"
@@ -44,13 +46,143 @@ type simpleCicdDeploymentCommit struct {
 	FinishedDate *time.Time
 }
 
+// defaultExplicitLinkRegex matches references such as "deployment: github:GithubRun:1:123" or "commit 3f2a9c1".
+// Without a separator only values shaped like a sha or a domain id are taken, so that prose like "the deployment of"
+// doesn't cost a lookup.
+var defaultExplicitLinkRegex = regexp.MustCompile(
+	`(?i)\b(?:deploy(?:ment)?|commit|sha)(?:[ \t]*id)?(?:[ \t]*[:=#][ \t]*([\w.:/-]+)|[ \t]+([0-9a-f]{7,40}|\w+:\w+:[\w.:/-]+)\b)`,
+)
+
+var shortShaRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// productionDeploymentClauses selects the successful production deployments of the project
+func productionDeploymentClauses(projectName string) []dal.Clause {
+	return []dal.Clause{
+		dal.Select("cicd_deployment_commits.cicd_deployment_id as id, cicd_deployment_commits.finished_date as finished_date"),
+		dal.From(&devops.CicdDeploymentCommit{}),
+		dal.Join("left join project_mapping pm on cicd_deployment_commits.cicd_scope_id = pm.row_id"),
+		dal.Where(
+			`cicd_deployment_commits.result = ?
+				and cicd_deployment_commits.environment = ?
+				and pm.table = ?
+				and pm.project_name = ?`,
+			devops.RESULT_SUCCESS, devops.PRODUCTION, "cicd_scopes", projectName,
+		),
+	}
+}
+
+// findLastDeployment returns the last successful production deployment finished before the incident,
+// optionally restricted to the given cicd scopes and to windowHours before the incident
+func findLastDeployment(db dal.Dal, projectName string, incident *ticket.Incident, scopeIds []string, windowHours int) (string, errors.Error) {
+	clauses := append(
+		productionDeploymentClauses(projectName),
+		dal.Where("cicd_deployment_commits.finished_date < ?", incident.CreatedDate),
+	)
+	if len(scopeIds) > 0 {
+		clauses = append(clauses, dal.Where("cicd_deployment_commits.cicd_scope_id IN ?", scopeIds))
+	}
+	if windowHours > 0 && incident.CreatedDate != nil {
+		clauses = append(clauses, dal.Where(
+			"cicd_deployment_commits.finished_date >= ?",
+			incident.CreatedDate.Add(-time.Duration(windowHours)*time.Hour),
+		))
+	}
+	clauses = append(clauses, dal.Orderby("finished_date DESC"), dal.Limit(1))
+	scdc := &simpleCicdDeploymentCommit{}
+	err := db.All(scdc, clauses...)
+	if err != nil && !db.IsErrorNotFound(err) {
+		return "", err
+	}
+	return scdc.Id, nil
+}
+
+// findExplicitlyLinkedDeployment returns the deployment referenced by the incident's description or labels,
+// either by its id or by the sha of one of its commits
+func findExplicitlyLinkedDeployment(db dal.Dal, projectName string, incident *ticket.Incident, pattern *regexp.Regexp) (string, errors.Error) {
+	texts := []string{incident.Description}
+	var labels []string
+	err := db.Pluck("label_name", &labels, dal.From(&ticket.IssueLabel{}), dal.Where("issue_id = ?", incident.Id))
+	if err != nil {
+		return "", err
+	}
+	texts = append(texts, labels...)
+	checked := make(map[string]bool)
+	for _, text := range texts {
+		for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+			ref := strings.TrimRight(explicitLinkRef(match), ".:/-")
+			if ref == "" || checked[ref] {
+				continue
+			}
+			checked[ref] = true
+			condition := dal.Where("cicd_deployment_commits.cicd_deployment_id = ?", ref)
+			if shortShaRegex.MatchString(ref) {
+				condition = dal.Where(
+					"(cicd_deployment_commits.cicd_deployment_id = ? or cicd_deployment_commits.commit_sha like ?)",
+					ref, strings.ToLower(ref)+"%",
+				)
+			}
+			clauses := append(
+				productionDeploymentClauses(projectName),
+				condition,
+				dal.Orderby("finished_date ASC"),
+				dal.Limit(1),
+			)
+			scdc := &simpleCicdDeploymentCommit{}
+			err = db.All(scdc, clauses...)
+			if err != nil && !db.IsErrorNotFound(err) {
+				return "", err
+			}
+			if scdc.Id != "" {
+				return scdc.Id, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// explicitLinkRef returns the first non-empty group of the match
+func explicitLinkRef(match []string) string {
+	for _, group := range match[1:] {
+		if group != "" {
+			return group
+		}
+	}
+	return ""
+}
+
+// serviceScopeIds returns the cicd scopes mapped to the incident's scope or component
+func serviceScopeIds(serviceMappings map[string][]string, incident *ticket.Incident) []string {
+	var scopeIds []string
+	scopeIds = append(scopeIds, serviceMappings[incident.ScopeId]...)
+	if incident.Component != "" {
+		scopeIds = append(scopeIds, serviceMappings[incident.Component]...)
+	}
+	return scopeIds
+}
+
 // ConnectIncidentToDeployment will generate data to crossdomain.ProjectIncidentDeploymentRelationship.
 func ConnectIncidentToDeployment(taskCtx plugin.SubTaskContext) errors.Error {
+	return runWithBackfill(taskCtx, func(window *backfillWindow) errors.Error {
+		return connectIncidentToDeployment(taskCtx, window)
+	})
+}
+
+// connectIncidentToDeployment links the incidents created within window, or all of them when window is nil
+func connectIncidentToDeployment(taskCtx plugin.SubTaskContext, window *backfillWindow) errors.Error {
 	db := taskCtx.GetDal()
 	data := taskCtx.GetData().(*DoraTaskData)
 	logger := taskCtx.GetLogger()
 	// Clear previous results from the project
-	err := db.Exec("DELETE FROM project_incident_deployment_relationships WHERE project_name = ?", data.Options.ProjectName)
+	var err errors.Error
+	if window == nil {
+		err = db.Exec("DELETE FROM project_incident_deployment_relationships WHERE project_name = ?", data.Options.ProjectName)
+	} else {
+		err = db.Exec(
+			`DELETE FROM project_incident_deployment_relationships WHERE project_name = ?
+				AND id IN (SELECT id FROM incidents WHERE created_date >= ? AND created_date < ?)`,
+			data.Options.ProjectName, window.From, window.To,
+		)
+	}
 	if err != nil {
 		return errors.Default.Wrap(err, "error deleting previous project_incident_deployment_relationships")
 	}
@@ -61,6 +193,9 @@ func ConnectIncidentToDeployment(taskCtx plugin.SubTaskContext) errors.Error {
 		dal.Join(`left join project_mapping pm on pm.row_id = i.scope_id and pm.table = i.table`),
 		dal.Where("pm.project_name = ?", data.Options.ProjectName),
 	}
+	if window != nil {
+		clauses = append(clauses, dal.Where("i.created_date >= ? AND i.created_date < ?", window.From, window.To))
+	}
 
 	//count, err := db.Count(
 	//	dal.From(`incidents i`),
@@ -79,6 +214,20 @@ func ConnectIncidentToDeployment(taskCtx plugin.SubTaskContext) errors.Error {
 		return err
 	}
 	defer cursor.Close()
+	attribution := IncidentAttributionOptions{}
+	if data.Options.IncidentAttribution != nil {
+		attribution = *data.Options.IncidentAttribution
+	}
+	if len(attribution.Strategies) == 0 {
+		attribution.Strategies = []string{crossdomain.ATTRIBUTION_LAST_DEPLOYMENT}
+	}
+	explicitLinkRegex := defaultExplicitLinkRegex
+	if attribution.ExplicitLinkPattern != "" {
+		explicitLinkRegex, err = errors.Convert01(regexp.Compile(attribution.ExplicitLinkPattern))
+		if err != nil {
+			return errors.BadInput.Wrap(err, "invalid explicitLinkPattern")
+		}
+	}
 	logger.Info("start enricher")
 	enricher, err := api.NewDataConverter(api.DataConverterArgs{
 		RawDataSubTaskArgs: api.RawDataSubTaskArgs{
@@ -90,6 +239,7 @@ func ConnectIncidentToDeployment(taskCtx plugin.SubTaskContext) errors.Error {
 		},
 		InputRowType: reflect.TypeOf(ticket.Incident{}),
 		Input:        cursor,
+		Incremental:  window != nil,
 		Convert: func(inputRow interface{}) ([]interface{}, errors.Error) {
 			incident := inputRow.(*ticket.Incident)
 			projectIssueMetric := &crossdomain.ProjectIncidentDeploymentRelationship{
@@ -99,39 +249,31 @@ func ConnectIncidentToDeployment(taskCtx plugin.SubTaskContext) errors.Error {
 				ProjectName: data.Options.ProjectName,
 			}
 			logger.Debug("get incident: %+v", incident.Id)
-			cicdDeploymentCommit := &devops.CicdDeploymentCommit{}
-			cicdDeploymentCommitClauses := []dal.Clause{
-				dal.Select("cicd_deployment_commits.cicd_deployment_id as id, cicd_deployment_commits.finished_date as finished_date"),
-				dal.From(cicdDeploymentCommit),
-				dal.Join("left join project_mapping pm on cicd_deployment_commits.cicd_scope_id = pm.row_id"),
-				dal.Where(
-					`cicd_deployment_commits.finished_date < ?
-					    and cicd_deployment_commits.result = ?
-						and cicd_deployment_commits.environment = ?
-						and pm.table = ?
-						and pm.project_name = ?`,
-					incident.CreatedDate, devops.RESULT_SUCCESS, devops.PRODUCTION, "cicd_scopes", data.Options.ProjectName,
-				),
-				dal.Orderby("finished_date DESC"),
-				dal.Limit(1),
-			}
-
-			scdc := &simpleCicdDeploymentCommit{}
-			err = db.All(scdc, cicdDeploymentCommitClauses...)
-			if err != nil {
-				if db.IsErrorNotFound(err) {
-					logger.Warn(err, "deployment commit not found")
-					return nil, nil
-				} else {
-					logger.Error(err, "get all deployment commits")
+			for _, strategy := range attribution.Strategies {
+				var deploymentId string
+				switch strategy {
+				case crossdomain.ATTRIBUTION_EXPLICIT_LINK:
+					deploymentId, err = findExplicitlyLinkedDeployment(db, data.Options.ProjectName, incident, explicitLinkRegex)
+				case crossdomain.ATTRIBUTION_SAME_SERVICE:
+					scopeIds := serviceScopeIds(attribution.ServiceMappings, incident)
+					if len(scopeIds) == 0 {
+						continue
+					}
+					deploymentId, err = findLastDeployment(db, data.Options.ProjectName, incident, scopeIds, attribution.WindowHours)
+				case crossdomain.ATTRIBUTION_LAST_DEPLOYMENT:
+					deploymentId, err = findLastDeployment(db, data.Options.ProjectName, incident, nil, attribution.WindowHours)
+				}
+				if err != nil {
+					logger.Error(err, "get deployment commits with strategy %s", strategy)
 					return nil, err
 				}
+				if deploymentId != "" {
+					projectIssueMetric.DeploymentId = deploymentId
+					projectIssueMetric.AttributionStrategy = strategy
+					return []interface{}{projectIssueMetric}, nil
+				}
 			}
-			if scdc.Id != "" {
-				projectIssueMetric.DeploymentId = scdc.Id
-				return []interface{}{projectIssueMetric}, nil
-			}
-			logger.Debug("scdc.id is empty, incident will be ignored: %+v", incident.Id)
+			logger.Debug("no deployment found, incident will be ignored: %+v", incident.Id)
 			return nil, nil
 		},
 	})
@@ -0,0 +1,85 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultExplicitLinkRegex(t *testing.T) {
+	refs := func(text string) []string {
+		var result []string
+		for _, match := range defaultExplicitLinkRegex.FindAllStringSubmatch(text, -1) {
+			result = append(result, explicitLinkRef(match))
+		}
+		return result
+	}
+	assert.Equal(t, []string{"github:GithubRun:1:123."}, refs("Caused by deployment: github:GithubRun:1:123."))
+	assert.Equal(t, []string{"3f2a9c1"}, refs("rollback of Commit 3f2a9c1 fixed it"))
+	assert.Equal(t, []string{"pipeline7"}, refs("deployment=pipeline7"))
+	assert.Equal(t, []string{"abc"}, refs("deploy id #abc"))
+	assert.Equal(t, []string{"github:GithubRun:1:123"}, refs("broken since deployment github:GithubRun:1:123."))
+	assert.Empty(t, refs("nothing to see here"))
+	// prose mentioning a deployment or a commit without a reference
+	assert.Empty(t, refs("the deployment of the fix failed"))
+	assert.Empty(t, refs("commit message was wrong, deploy later"))
+	assert.Empty(t, refs("the sha changed, deployment failed"))
+}
+
+func TestServiceScopeIds(t *testing.T) {
+	mappings := map[string][]string{
+		"pagerduty:Service:1:P1": {"github:GithubRepo:1:1"},
+		"payments":               {"gitlab:GitlabProject:1:2"},
+	}
+	assert.Equal(t,
+		[]string{"github:GithubRepo:1:1", "gitlab:GitlabProject:1:2"},
+		serviceScopeIds(mappings, &ticket.Incident{ScopeId: "pagerduty:Service:1:P1", Component: "payments"}),
+	)
+	assert.Empty(t, serviceScopeIds(mappings, &ticket.Incident{ScopeId: "jira:JiraBoard:1:1"}))
+}
+
+func TestDecodeAndValidateIncidentAttribution(t *testing.T) {
+	op, err := DecodeAndValidateTaskOptions(map[string]interface{}{
+		"projectName": "project1",
+		"incidentAttribution": map[string]interface{}{
+			"strategies":  []string{"EXPLICIT_LINK", "SAME_SERVICE", "LAST_DEPLOYMENT"},
+			"windowHours": 48,
+			"serviceMappings": map[string]interface{}{
+				"payments": []string{"github:GithubRepo:1:1"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, DORA_REPORT_2023, op.DoraReport)
+	assert.Equal(t, 48, op.IncidentAttribution.WindowHours)
+	assert.Equal(t, []string{"github:GithubRepo:1:1"}, op.IncidentAttribution.ServiceMappings["payments"])
+
+	_, err = DecodeAndValidateTaskOptions(map[string]interface{}{
+		"projectName":         "project1",
+		"incidentAttribution": map[string]interface{}{"strategies": []string{"NEAREST"}},
+	})
+	assert.NotNil(t, err)
+	_, err = DecodeAndValidateTaskOptions(map[string]interface{}{
+		"projectName":         "project1",
+		"incidentAttribution": map[string]interface{}{"explicitLinkPattern": "("},
+	})
+	assert.NotNil(t, err)
+}
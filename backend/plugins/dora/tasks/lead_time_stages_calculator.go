@@ -0,0 +1,222 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+)
+
+// CalculateLeadTimeStagesMeta contains metadata for the CalculateLeadTimeStages subtask.
+var CalculateLeadTimeStagesMeta = plugin.SubTaskMeta{
+	Name:             "calculateLeadTimeStages",
+	EntryPoint:       CalculateLeadTimeStages,
+	EnabledByDefault: true,
+	Description:      "Calculate weekly percentiles of each lead time stage and the outlier pull requests",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE, plugin.DOMAIN_TYPE_CICD},
+}
+
+var leadTimeStages = []string{
+	crossdomain.LEAD_TIME_STAGE_CODING,
+	crossdomain.LEAD_TIME_STAGE_PICKUP,
+	crossdomain.LEAD_TIME_STAGE_REVIEW,
+	crossdomain.LEAD_TIME_STAGE_DEPLOY,
+}
+
+// prStageTimes holds the lead time stages of a pull request calculated by calculateChangeLeadTime
+type prStageTimes struct {
+	Id           string
+	RepoId       string
+	PrMergedDate *time.Time
+	PrCodingTime *int64
+	PrPickupTime *int64
+	PrReviewTime *int64
+	PrDeployTime *int64
+}
+
+func (pr *prStageTimes) stageTime(stage string) *int64 {
+	switch stage {
+	case crossdomain.LEAD_TIME_STAGE_CODING:
+		return pr.PrCodingTime
+	case crossdomain.LEAD_TIME_STAGE_PICKUP:
+		return pr.PrPickupTime
+	case crossdomain.LEAD_TIME_STAGE_REVIEW:
+		return pr.PrReviewTime
+	case crossdomain.LEAD_TIME_STAGE_DEPLOY:
+		return pr.PrDeployTime
+	}
+	return nil
+}
+
+// CalculateLeadTimeStages computes p50/p75/p90 of the coding, pickup, review and deploy stages of the pull
+// requests merged each week, for the whole project and for every repo, then lists the pull requests above
+// the project's p90 of each stage. It relies on project_pr_metrics so it must run after calculateChangeLeadTime.
+func CalculateLeadTimeStages(taskCtx plugin.SubTaskContext) errors.Error {
+	db := taskCtx.GetDal()
+	logger := taskCtx.GetLogger()
+	data := taskCtx.GetData().(*DoraTaskData)
+	projectName := data.Options.ProjectName
+
+	var prs []prStageTimes
+	err := db.All(
+		&prs,
+		dal.Select("ppm.id, pr.base_repo_id AS repo_id, ppm.pr_merged_date, ppm.pr_coding_time, ppm.pr_pickup_time, ppm.pr_review_time, ppm.pr_deploy_time"),
+		dal.From("project_pr_metrics ppm"),
+		dal.Join("JOIN pull_requests pr ON pr.id = ppm.id"),
+		dal.Where("ppm.project_name = ? AND ppm.pr_merged_date IS NOT NULL", projectName),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch project_pr_metrics")
+	}
+	logger.Info("calculating lead time stages of %d pull requests", len(prs))
+
+	// Clear previous results from the project
+	err = db.Delete(&crossdomain.ProjectLeadTimeStageMetric{}, dal.Where("project_name = ?", projectName))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous project_lead_time_stage_metrics")
+	}
+	err = db.Delete(&crossdomain.ProjectLeadTimeOutlier{}, dal.Where("project_name = ?", projectName))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous project_lead_time_outliers")
+	}
+
+	metricSave, err := api.NewBatchSave(taskCtx, reflect.TypeOf(&crossdomain.ProjectLeadTimeStageMetric{}), 500)
+	if err != nil {
+		return err
+	}
+	defer metricSave.Close()
+	for _, metric := range computeLeadTimeStageMetrics(projectName, prs) {
+		if err = metricSave.Add(metric); err != nil {
+			return err
+		}
+	}
+	if err = metricSave.Flush(); err != nil {
+		return err
+	}
+
+	outlierSave, err := api.NewBatchSave(taskCtx, reflect.TypeOf(&crossdomain.ProjectLeadTimeOutlier{}), 500)
+	if err != nil {
+		return err
+	}
+	defer outlierSave.Close()
+	for _, outlier := range computeLeadTimeOutliers(projectName, prs) {
+		if err = outlierSave.Add(outlier); err != nil {
+			return err
+		}
+	}
+	return outlierSave.Flush()
+}
+
+// computeLeadTimeStageMetrics groups stage times by week and stage, once for the project and once per repo
+func computeLeadTimeStageMetrics(projectName string, prs []prStageTimes) []*crossdomain.ProjectLeadTimeStageMetric {
+	type groupKey struct {
+		repoId    string
+		weekStart time.Time
+		stage     string
+	}
+	groups := make(map[groupKey][]int64)
+	var keys []groupKey
+	add := func(key groupKey, value int64) {
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], value)
+	}
+	for i := range prs {
+		pr := &prs[i]
+		weekStart := PeriodStart(*pr.PrMergedDate, crossdomain.DORA_GRANULARITY_WEEK)
+		for _, stage := range leadTimeStages {
+			value := pr.stageTime(stage)
+			if value == nil {
+				continue
+			}
+			add(groupKey{weekStart: weekStart, stage: stage}, *value)
+			if pr.RepoId != "" {
+				add(groupKey{repoId: pr.RepoId, weekStart: weekStart, stage: stage}, *value)
+			}
+		}
+	}
+
+	metrics := make([]*crossdomain.ProjectLeadTimeStageMetric, 0, len(keys))
+	for _, key := range keys {
+		values := groups[key]
+		metrics = append(metrics, &crossdomain.ProjectLeadTimeStageMetric{
+			ProjectName: projectName,
+			RepoId:      key.repoId,
+			WeekStart:   key.weekStart,
+			Stage:       key.stage,
+			PrCount:     len(values),
+			P50Minutes:  percentileMinutes(values, .5),
+			P75Minutes:  percentileMinutes(values, .75),
+			P90Minutes:  percentileMinutes(values, .9),
+		})
+	}
+	return metrics
+}
+
+// computeLeadTimeOutliers returns, for every stage, the pull requests which took longer than the project's p90,
+// sorted by the time they spent in the stage
+func computeLeadTimeOutliers(projectName string, prs []prStageTimes) []*crossdomain.ProjectLeadTimeOutlier {
+	var outliers []*crossdomain.ProjectLeadTimeOutlier
+	for _, stage := range leadTimeStages {
+		var values []int64
+		for i := range prs {
+			if value := prs[i].stageTime(stage); value != nil {
+				values = append(values, *value)
+			}
+		}
+		p90 := percentileMinutes(values, .9)
+		if p90 == nil {
+			continue
+		}
+		var stageOutliers []*crossdomain.ProjectLeadTimeOutlier
+		var tailTotal int64
+		for i := range prs {
+			pr := &prs[i]
+			value := pr.stageTime(stage)
+			if value == nil || *value <= *p90 {
+				continue
+			}
+			tailTotal += *value
+			stageOutliers = append(stageOutliers, &crossdomain.ProjectLeadTimeOutlier{
+				ProjectName:   projectName,
+				PullRequestId: pr.Id,
+				Stage:         stage,
+				RepoId:        pr.RepoId,
+				WeekStart:     PeriodStart(*pr.PrMergedDate, crossdomain.DORA_GRANULARITY_WEEK),
+				StageMinutes:  *value,
+				P90Minutes:    *p90,
+			})
+		}
+		sort.Slice(stageOutliers, func(i, j int) bool {
+			return stageOutliers[i].StageMinutes > stageOutliers[j].StageMinutes
+		})
+		for _, outlier := range stageOutliers {
+			outlier.TailShare = float64(outlier.StageMinutes) / float64(tailTotal)
+		}
+		outliers = append(outliers, stageOutliers...)
+	}
+	return outliers
+}
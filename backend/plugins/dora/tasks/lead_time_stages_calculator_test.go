@@ -0,0 +1,73 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeLeadTimeStages(t *testing.T) {
+	minutes := func(m int64) *int64 { return &m }
+	monday := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	merged := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	var prs []prStageTimes
+	for i := int64(1); i <= 10; i++ {
+		repoId := "repo1"
+		if i > 5 {
+			repoId = "repo2"
+		}
+		prs = append(prs, prStageTimes{
+			Id:           string(rune('a' + i)),
+			RepoId:       repoId,
+			PrMergedDate: &merged,
+			PrCodingTime: minutes(i * 10),
+			PrReviewTime: minutes(i * i),
+		})
+	}
+	// the last one took much longer to be reviewed
+	prs[9].PrReviewTime = minutes(1000)
+
+	metrics := computeLeadTimeStageMetrics("p", prs)
+	// project + 2 repos, for coding and review
+	assert.Len(t, metrics, 6)
+	var project *crossdomain.ProjectLeadTimeStageMetric
+	for _, m := range metrics {
+		if m.RepoId == "" && m.Stage == crossdomain.LEAD_TIME_STAGE_CODING {
+			project = m
+		}
+	}
+	assert.NotNil(t, project)
+	assert.Equal(t, monday, project.WeekStart)
+	assert.Equal(t, 10, project.PrCount)
+	assert.Equal(t, int64(50), *project.P50Minutes)
+	assert.Equal(t, int64(70), *project.P75Minutes)
+	assert.Equal(t, int64(90), *project.P90Minutes)
+
+	outliers := computeLeadTimeOutliers("p", prs)
+	assert.Len(t, outliers, 2)
+	assert.Equal(t, crossdomain.LEAD_TIME_STAGE_CODING, outliers[0].Stage)
+	assert.Equal(t, int64(100), outliers[0].StageMinutes)
+	assert.Equal(t, crossdomain.LEAD_TIME_STAGE_REVIEW, outliers[1].Stage)
+	assert.Equal(t, int64(1000), outliers[1].StageMinutes)
+	assert.Equal(t, int64(81), outliers[1].P90Minutes)
+	assert.Equal(t, 1.0, outliers[1].TailShare)
+}
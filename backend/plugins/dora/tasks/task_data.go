@@ -18,7 +18,10 @@ limitations under the License.
 package tasks
 
 import (
+	"regexp"
+
 	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
 	helper "github.com/apache/incubator-devlake/helpers/pluginhelper/api"
 )
 
@@ -31,6 +34,28 @@ type DoraOptions struct {
 	Since       string
 	ProjectName string  `json:"projectName"`
 	ScopeId     *string `json:"scopeId,omitempty"`
+	// DoraReport selects the benchmark used to bucket metric snapshots, "2021" or "2023" (default)
+	DoraReport string `json:"doraReport,omitempty"`
+	// IncidentAttribution configures how incidents are linked to the deployments causing them
+	IncidentAttribution *IncidentAttributionOptions `json:"incidentAttribution,omitempty"`
+	// ProductionOnlyChanges limits the change sets of deployments calculated by refdiff to PRODUCTION
+	ProductionOnlyChanges bool `json:"productionOnlyChanges,omitempty"`
+	// Backfill recomputes deployment commits, lead times and incident links over a window, chunk by chunk
+	Backfill *BackfillOptions `json:"backfill,omitempty"`
+}
+
+// IncidentAttributionOptions configures ConnectIncidentToDeployment. Strategies are tried in order
+// and the first one finding a deployment wins, they default to LAST_DEPLOYMENT only.
+type IncidentAttributionOptions struct {
+	// Strategies contains EXPLICIT_LINK, SAME_SERVICE and/or LAST_DEPLOYMENT
+	Strategies []string `json:"strategies"`
+	// WindowHours caps the time between the deployment and the incident for SAME_SERVICE and LAST_DEPLOYMENT, 0 means no cap
+	WindowHours int `json:"windowHours"`
+	// ServiceMappings maps the incident's scope id or component to the cicd scope ids of the same service
+	ServiceMappings map[string][]string `json:"serviceMappings"`
+	// ExplicitLinkPattern is matched against the incident's description and labels, its first non-empty
+	// group must capture a deployment id or a commit sha
+	ExplicitLinkPattern string `json:"explicitLinkPattern"`
 }
 
 type DoraTaskData struct {
@@ -40,10 +65,44 @@ type DoraTaskData struct {
 
 func DecodeAndValidateTaskOptions(options map[string]interface{}) (*DoraOptions, errors.Error) {
 	var op DoraOptions
-	err := helper.Decode(options, &op, nil)
+	err := helper.DecodeMapStruct(options, &op, false)
 	if err != nil {
 		return nil, errors.Default.Wrap(err, "error decoding DORA task options")
 	}
+	if op.DoraReport == "" {
+		op.DoraReport = DORA_REPORT_2023
+	}
+	if op.DoraReport != DORA_REPORT_2021 && op.DoraReport != DORA_REPORT_2023 {
+		return nil, errors.BadInput.New("doraReport must be either 2021 or 2023")
+	}
+	if op.Backfill != nil {
+		if op.Backfill.From.IsZero() || op.Backfill.To.IsZero() || !op.Backfill.From.Before(op.Backfill.To) {
+			return nil, errors.BadInput.New("backfill requires from to be before to")
+		}
+		if op.Backfill.ChunkDays < 0 {
+			return nil, errors.BadInput.New("backfill chunkDays must not be negative")
+		}
+		if op.Backfill.ChunkDays == 0 {
+			op.Backfill.ChunkDays = defaultBackfillChunkDays
+		}
+	}
+	if op.IncidentAttribution != nil {
+		for _, strategy := range op.IncidentAttribution.Strategies {
+			if strategy != crossdomain.ATTRIBUTION_EXPLICIT_LINK &&
+				strategy != crossdomain.ATTRIBUTION_SAME_SERVICE &&
+				strategy != crossdomain.ATTRIBUTION_LAST_DEPLOYMENT {
+				return nil, errors.BadInput.New("invalid incident attribution strategy: " + strategy)
+			}
+		}
+		if op.IncidentAttribution.WindowHours < 0 {
+			return nil, errors.BadInput.New("incident attribution windowHours must not be negative")
+		}
+		if op.IncidentAttribution.ExplicitLinkPattern != "" {
+			if _, err := regexp.Compile(op.IncidentAttribution.ExplicitLinkPattern); err != nil {
+				return nil, errors.BadInput.Wrap(err, "invalid incident attribution explicitLinkPattern")
+			}
+		}
+	}
 
 	return &op, nil
 }
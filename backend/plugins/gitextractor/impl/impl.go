@@ -20,11 +20,14 @@ package impl
 import (
 	"fmt"
 	"net/url"
+	"reflect"
 
 	"github.com/apache/incubator-devlake/core/dal"
 	"github.com/apache/incubator-devlake/core/errors"
 	"github.com/apache/incubator-devlake/core/plugin"
 	helper "github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/models"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/models/migrationscripts"
 	"github.com/apache/incubator-devlake/plugins/gitextractor/parser"
 	"github.com/apache/incubator-devlake/plugins/gitextractor/tasks"
 	giturls "github.com/chainguard-dev/git-urls"
@@ -34,6 +37,7 @@ var _ interface {
 	plugin.PluginMeta
 	plugin.PluginTask
 	plugin.PluginModel
+	plugin.PluginMigration
 } = (*GitExtractor)(nil)
 
 type GitExtractor struct{}
@@ -43,7 +47,9 @@ type DynamicGitUrl interface {
 }
 
 func (p GitExtractor) GetTablesInfo() []dal.Tabler {
-	return []dal.Tabler{}
+	return []dal.Tabler{
+		&models.GitRefState{},
+	}
 }
 
 func (p GitExtractor) Description() string {
@@ -59,9 +65,14 @@ func (p GitExtractor) SubTaskMetas() []plugin.SubTaskMeta {
 	return []plugin.SubTaskMeta{
 		tasks.CloneGitRepoMeta,
 		tasks.CollectGitCommitMeta,
+		tasks.CalculateSubProjectsMeta,
 		tasks.CollectGitBranchMeta,
 		tasks.CollectGitTagMeta,
 		tasks.CollectGitDiffLineMeta,
+		tasks.CalculateOwnershipMeta,
+		tasks.CollectGitCodeOwnersMeta,
+		tasks.CheckCodeOwnerReviewsMeta,
+		tasks.SaveGitRefStatesMeta,
 	}
 }
 
@@ -96,6 +107,18 @@ func (p GitExtractor) PrepareTaskData(taskCtx plugin.TaskContext, options map[st
 		return nil, errors.BadInput.Wrap(err, "failed to parse git url")
 	}
 
+	// plans keep the secretref:// references of the connection credentials
+	if parser.HasUrlSecretRef(parsedURL) {
+		endpoint, err := loadConnectionEndpoint(taskCtx, op.PluginName, op.ConnectionId)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := parser.ResolveUrlSecretRef(parsedURL, op.PluginName, op.ConnectionId, endpoint); err != nil {
+			return nil, err
+		}
+		op.Url = parsedURL.String()
+	}
+
 	// append username to the git url
 	if op.User != "" {
 		parsedURL.User = url.UserPassword(op.User, op.Password)
@@ -129,6 +152,31 @@ func (p GitExtractor) PrepareTaskData(taskCtx plugin.TaskContext, options map[st
 	return taskData, nil
 }
 
+// loadConnectionEndpoint returns the endpoint of the connection the git url was built from
+func loadConnectionEndpoint(taskCtx plugin.TaskContext, pluginName string, connectionId uint64) (string, errors.Error) {
+	if pluginName == "" || connectionId == 0 {
+		return "", errors.BadInput.New("secret references are only resolved in the git urls of connections")
+	}
+	pluginInstance, err := plugin.GetPlugin(pluginName)
+	if err != nil {
+		return "", errors.Default.Wrap(err, fmt.Sprintf("failed to get plugin instance for plugin: %s", pluginName))
+	}
+	pluginSource, ok := pluginInstance.(plugin.PluginSource)
+	if !ok {
+		return "", errors.BadInput.New(fmt.Sprintf("plugin %s has no connections", pluginName))
+	}
+	connection := reflect.New(reflect.TypeOf(pluginSource.Connection()).Elem()).Interface()
+	err = taskCtx.GetDal().First(connection, dal.Where("id = ?", connectionId))
+	if err != nil {
+		return "", errors.Default.Wrap(err, fmt.Sprintf("failed to load connection %d of plugin %s", connectionId, pluginName))
+	}
+	apiConnection, ok := connection.(plugin.ApiConnection)
+	if !ok {
+		return "", errors.BadInput.New(fmt.Sprintf("the connections of plugin %s have no endpoint", pluginName))
+	}
+	return apiConnection.GetEndpoint(), nil
+}
+
 func (p GitExtractor) Close(taskCtx plugin.TaskContext) errors.Error {
 	if taskData, ok := taskCtx.GetData().(*parser.GitExtractorTaskData); ok {
 		if !taskCtx.GetConfigReader().GetBool("GIT_EXTRACTOR_KEEP_REPO") {
@@ -142,6 +190,10 @@ func (p GitExtractor) Close(taskCtx plugin.TaskContext) errors.Error {
 	return errors.Default.New("task ctx is not GitExtractorTaskData which is unexpected")
 }
 
+func (p GitExtractor) MigrationScripts() []plugin.MigrationScript {
+	return migrationscripts.All()
+}
+
 func (p GitExtractor) RootPkgPath() string {
 	return "github.com/apache/incubator-devlake/plugins/gitextractor"
 }
@@ -32,5 +32,9 @@ type Store interface {
 	CommitFileComponents(commitFileComponent *code.CommitFileComponent) errors.Error
 	CommitLineChange(commitLineChange *code.CommitLineChange) errors.Error
 	RepoSnapshot(snapshot *code.RepoSnapshot) errors.Error
+	CommitTrailers(trailers []*code.CommitTrailer) errors.Error
+	CommitClassification(classification *code.CommitClassification) errors.Error
+	SubmoduleUpdates(update *code.SubmoduleUpdate) errors.Error
+	Flush() errors.Error
 	Close() errors.Error
 }
@@ -53,6 +53,7 @@ type GitcliCloner struct {
 	since        *time.Time
 	remoteUrl    string
 	localDir     string
+	mirror       *Mirror
 	success      bool
 	syncEnvs     []string
 	syncArgs     []string
@@ -163,13 +164,42 @@ func (g *GitcliCloner) IsIncremental() bool {
 	return false
 }
 
+// SetMirror makes the cloner fetch into the persistent mirror instead of cloning into an empty dir
+func (g *GitcliCloner) SetMirror(mirror *Mirror) {
+	g.mirror = mirror
+}
+
 func (g *GitcliCloner) CloneRepo() errors.Error {
+	if g.mirror != nil {
+		// the mirror always keeps the full history so the following runs could fetch the new objects only,
+		// the commits before since are skipped while walking instead of by a shallow fetch
+		if g.since != nil {
+			g.logger.Info("fetching the full history into the mirror, commits before %s will be skipped", g.since.Format(time.RFC3339))
+			g.taskData.Since = g.since
+		}
+		if err := g.syncMirror(); err != nil {
+			return err
+		}
+		g.success = true
+		return nil
+	}
 	if g.since == nil {
 		// full sync
 		if err := g.fullClone(); err != nil {
 			return err
 		}
 	} else {
+		if g.taskData.Options.NoShallowClone && HasRefStates(g.ctx.GetDal(), g.taskData.Options.RepoId, g.logger) {
+			// data source does not support shallow clone, but the refs recorded by the previous run tell which
+			// commits are new, so the full clone would be walked incrementally just like a mirror
+			g.logger.Info("cloning the full history, commits before %s will be skipped", g.since.Format(time.RFC3339))
+			g.taskData.Since = g.since
+			if err := g.fullClone(); err != nil {
+				return err
+			}
+			g.success = true
+			return nil
+		}
 		if g.taskData.Options.NoShallowClone {
 			// data source does not support shallow clone
 			//   1. perform a full clone to accommodate
@@ -268,6 +298,62 @@ func (g *GitcliCloner) doubleClone() errors.Error {
 	return nil
 }
 
+// syncMirror fetches all branches and tags into the mirror, the remote url is never written into the mirror
+// since it might contain credentials
+func (g *GitcliCloner) syncMirror() errors.Error {
+	if isMirrorInitialized(g.localDir) {
+		err := g.fetchMirror()
+		if err == nil || errors.Is(err, ErrNoData) {
+			return err
+		}
+		g.logger.Warn(err, "failed to fetch into the mirror, recreating it")
+		if err := g.mirror.Reset(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(g.localDir, 0755); err != nil {
+		return errors.Convert(err)
+	}
+	if err := g.gitCmd("init", "--bare"); err != nil {
+		return err
+	}
+	return g.fetchMirror()
+}
+
+func (g *GitcliCloner) fetchMirror() errors.Error {
+	// point HEAD to the default branch of the remote, collectors rely on it
+	output, err := g.gitOutput(g.syncEnvs, "ls-remote", "--symref", g.remoteUrl, "HEAD")
+	if err != nil {
+		return err
+	}
+	head := ""
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "ref: ") && strings.HasSuffix(line, "\tHEAD") {
+			head = strings.TrimSuffix(strings.TrimPrefix(line, "ref: "), "\tHEAD")
+		}
+	}
+	if head == "" {
+		return ErrNoData
+	}
+	err = g.git(g.syncEnvs, g.localDir, "fetch", "--prune", "--force", g.remoteUrl, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+	if err != nil {
+		return err
+	}
+	return g.gitCmd("symbolic-ref", "HEAD", head)
+}
+
+func (g *GitcliCloner) gitOutput(env []string, gitcmd string, args ...string) (string, errors.Error) {
+	g.logger.Debug("git %s %v", gitcmd, sanitizeArgs(args))
+	cmd := exec.CommandContext(g.ctx.GetContext(), "git", append([]string{gitcmd}, args...)...)
+	cmd.Env = env
+	cmd.Dir = g.localDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Default.New(fmt.Sprintf("git cmd %v failed: %s", sanitizeArgs(cmd.Args), generateErrMsg(output, err)))
+	}
+	return string(output), nil
+}
+
 func (g *GitcliCloner) gitClone(args ...string) errors.Error {
 	args = append(args, g.syncArgs...)
 	return g.git(g.syncEnvs, "", "clone", args...)
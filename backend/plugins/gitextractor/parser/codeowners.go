@@ -0,0 +1,225 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+)
+
+// CodeOwnersPaths are the locations of the CODEOWNERS file, in the order GitHub and GitLab look them up
+var CodeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+var codeOwnersSectionPattern = regexp.MustCompile(`^\^?\[[^\]]+\](\[\d+\])?`)
+
+// CodeOwnersRule is a line of a CODEOWNERS file
+type CodeOwnersRule struct {
+	LineNo  int
+	Pattern string
+	Owners  []string
+	matcher *regexp.Regexp
+}
+
+// NewCodeOwnersRule compiles the gitignore-like pattern of a CODEOWNERS line
+func NewCodeOwnersRule(lineNo int, pattern string, owners []string) (*CodeOwnersRule, errors.Error) {
+	matcher, err := compileCodeOwnersPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &CodeOwnersRule{
+		LineNo:  lineNo,
+		Pattern: pattern,
+		Owners:  owners,
+		matcher: matcher,
+	}, nil
+}
+
+// Match tells whether the file path, relative to the root of the repo, is covered by the rule
+func (r *CodeOwnersRule) Match(filePath string) bool {
+	return r.matcher.MatchString(strings.TrimPrefix(filePath, "/"))
+}
+
+// ParseCodeOwners parses the content of a CODEOWNERS file. Comments, GitLab section headers and lines with
+// invalid patterns are skipped, like GitHub and GitLab do.
+func ParseCodeOwners(content string) []*CodeOwnersRule {
+	var rules []*CodeOwnersRule
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripCodeOwnersComment(line))
+		if line == "" || codeOwnersSectionPattern.MatchString(line) {
+			continue
+		}
+		fields := splitCodeOwnersLine(line)
+		rule, err := NewCodeOwnersRule(i+1, fields[0], fields[1:])
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// MatchCodeOwners returns the rule that applies to the file path, the last matching one wins
+func MatchCodeOwners(rules []*CodeOwnersRule, filePath string) *CodeOwnersRule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].Match(filePath) {
+			return rules[i]
+		}
+	}
+	return nil
+}
+
+// stripCodeOwnersComment removes the comment of a line, `\#` being a literal `#`
+func stripCodeOwnersComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '#' {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitCodeOwnersLine splits a line by whitespaces, except the escaped ones in the pattern
+func splitCodeOwnersLine(line string) []string {
+	var fields []string
+	var current strings.Builder
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' && i+1 < len(line) {
+			i++
+			current.WriteByte(line[i])
+			continue
+		}
+		if c == ' ' || c == '\t' {
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// compileCodeOwnersPattern translates a CODEOWNERS pattern into a regexp following the gitignore rules:
+// a pattern containing a slash is relative to the root of the repo, otherwise it matches at any depth, and a
+// pattern matching a directory covers everything inside it, except for `dir/*` which only covers direct children.
+func compileCodeOwnersPattern(pattern string) (*regexp.Regexp, errors.Error) {
+	p := pattern
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, errors.BadInput.New("empty CODEOWNERS pattern")
+	}
+	var re strings.Builder
+	if anchored {
+		re.WriteString("^")
+	} else {
+		re.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	switch {
+	case dirOnly:
+		re.WriteString("/.*$")
+	case strings.HasSuffix(p, "/*"):
+		re.WriteString("$")
+	default:
+		re.WriteString("(?:/.*)?$")
+	}
+	matcher, err := regexp.Compile(re.String())
+	if err != nil {
+		return nil, errors.BadInput.Wrap(err, "invalid CODEOWNERS pattern "+pattern)
+	}
+	return matcher, nil
+}
+
+// storeCodeOwners replaces the CODEOWNERS rules of the repo with the ones parsed from the content, the rules are
+// only removed if no CODEOWNERS file exists anymore (filePath is empty)
+func storeCodeOwners(subtaskCtx plugin.SubTaskContext, repoId, commitSha, filePath, content string) errors.Error {
+	db := subtaskCtx.GetDal()
+	err := db.Delete(&code.CodeOwnerRule{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return err
+	}
+	if filePath == "" {
+		return nil
+	}
+	batch, err := api.NewBatchSave(subtaskCtx, reflect.TypeOf(&code.CodeOwnerRule{}), 500)
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+	rules := ParseCodeOwners(content)
+	for _, rule := range rules {
+		owners := rule.Owners
+		if len(owners) == 0 {
+			owners = []string{""}
+		}
+		seen := make(map[string]bool, len(owners))
+		for _, owner := range owners {
+			if seen[owner] {
+				continue
+			}
+			seen[owner] = true
+			err = batch.Add(&code.CodeOwnerRule{
+				RepoId:    repoId,
+				LineNo:    rule.LineNo,
+				Owner:     owner,
+				Pattern:   rule.Pattern,
+				FilePath:  filePath,
+				CommitSha: commitSha,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	subtaskCtx.GetLogger().Info("stored %d CODEOWNERS rules from %s", len(rules), filePath)
+	return batch.Flush()
+}
@@ -0,0 +1,69 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCodeOwners(t *testing.T) {
+	rules := ParseCodeOwners(`# default owners
+*       @org/core
+
+[Docs][2] @org/writers
+docs/*  @alice docs@example.com # inline comment
+/build/logs/ @bob
+apps/   @carol
+**/vendor
+file\ with\ space.txt @dave
+`)
+	assert.Len(t, rules, 6)
+	assert.Equal(t, 2, rules[0].LineNo)
+	assert.Equal(t, []string{"@org/core"}, rules[0].Owners)
+	assert.Equal(t, []string{"@alice", "docs@example.com"}, rules[1].Owners)
+	assert.Empty(t, rules[4].Owners)
+	assert.Equal(t, "file with space.txt", rules[5].Pattern)
+}
+
+func TestMatchCodeOwners(t *testing.T) {
+	rules := ParseCodeOwners(`*       @org/core
+*.go    @gopher
+docs/*  @alice
+/build/logs/ @bob
+apps/   @carol
+**/vendor
+`)
+	owners := func(filePath string) []string {
+		rule := MatchCodeOwners(rules, filePath)
+		if rule == nil {
+			return nil
+		}
+		return rule.Owners
+	}
+	assert.Equal(t, []string{"@org/core"}, owners("README.md"))
+	assert.Equal(t, []string{"@gopher"}, owners("backend/main.go"))
+	assert.Equal(t, []string{"@alice"}, owners("docs/getting-started.md"))
+	assert.Equal(t, []string{"@org/core"}, owners("docs/build-app/troubleshooting.md"))
+	assert.Equal(t, []string{"@bob"}, owners("build/logs/a/b.log"))
+	assert.Equal(t, []string{"@org/core"}, owners("src/build/logs/b.log"))
+	assert.Equal(t, []string{"@carol"}, owners("web/apps/index.ts"))
+	assert.Empty(t, owners("a/vendor/lib.go"))
+	assert.Nil(t, MatchCodeOwners(nil, "README.md"))
+}
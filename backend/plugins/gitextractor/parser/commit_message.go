@@ -0,0 +1,138 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/models"
+)
+
+var conventionalHeaderPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+var trailerPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*|BREAKING CHANGE): *(.*)$`)
+
+// conventionalTypes are the types a header must have to be conventional, the others like `WIP: ...` or
+// `Note: ...` are stored but not taken as conventional
+var conventionalTypes = map[string]bool{
+	"feat":     true,
+	"fix":      true,
+	"chore":    true,
+	"docs":     true,
+	"refactor": true,
+	"perf":     true,
+	"test":     true,
+	"build":    true,
+	"ci":       true,
+	"style":    true,
+	"revert":   true,
+}
+
+// knownTrailerKeys maps the lowercase key of the trailers we care about to their canonical spelling
+var knownTrailerKeys = map[string]string{
+	"co-authored-by":  "Co-authored-by",
+	"signed-off-by":   "Signed-off-by",
+	"reviewed-by":     "Reviewed-by",
+	"acked-by":        "Acked-by",
+	"tested-by":       "Tested-by",
+	"reported-by":     "Reported-by",
+	"change-id":       "Change-Id",
+	"breaking change": "BREAKING CHANGE",
+	"breaking-change": "BREAKING CHANGE",
+}
+
+// ParseCommitMessage extracts the Conventional Commits header and the git trailers of a commit message
+func ParseCommitMessage(commitSha string, message string) (*code.CommitClassification, []*code.CommitTrailer) {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(message), "\r\n", "\n"), "\n")
+	classification := &code.CommitClassification{
+		CommitSha: commitSha,
+	}
+	if m := conventionalHeaderPattern.FindStringSubmatch(strings.TrimSpace(lines[0])); m != nil {
+		classification.Type = strings.ToLower(m[1])
+		classification.IsConventional = conventionalTypes[classification.Type]
+		classification.Scope = strings.TrimSpace(m[2])
+		classification.IsBreaking = m[3] == "!" && classification.IsConventional
+		classification.Description = strings.TrimSpace(m[4])
+	}
+	trailers := parseTrailers(commitSha, lines[1:])
+	for _, trailer := range trailers {
+		if trailer.Key == "BREAKING CHANGE" && classification.IsConventional {
+			classification.IsBreaking = true
+		}
+	}
+	return classification, trailers
+}
+
+// parseTrailers parses the last paragraph of the message body if all of its lines are trailers, lines starting
+// with a whitespace being the continuation of the previous trailer
+func parseTrailers(commitSha string, body []string) []*code.CommitTrailer {
+	end := len(body)
+	for end > 0 && strings.TrimSpace(body[end-1]) == "" {
+		end--
+	}
+	start := end
+	for start > 0 && strings.TrimSpace(body[start-1]) != "" {
+		start--
+	}
+	// a paragraph not separated from the header by an empty line is not a trailer block
+	if start == end || start == 0 {
+		return nil
+	}
+	var trailers []*code.CommitTrailer
+	for _, line := range body[start:end] {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(trailers) > 0 {
+			last := trailers[len(trailers)-1]
+			last.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		m := trailerPattern.FindStringSubmatch(strings.TrimRight(line, " \t"))
+		if m == nil {
+			return nil
+		}
+		key := m[1]
+		if canonical, ok := knownTrailerKeys[strings.ToLower(key)]; ok {
+			key = canonical
+		}
+		trailers = append(trailers, &code.CommitTrailer{
+			CommitSha: commitSha,
+			Idx:       len(trailers),
+			Key:       key,
+			Value:     strings.TrimSpace(m[2]),
+		})
+	}
+	for _, trailer := range trailers {
+		if address, err := mail.ParseAddress(trailer.Value); err == nil {
+			trailer.Name = address.Name
+			trailer.Email = address.Address
+			trailer.AccountId = address.Address
+		}
+	}
+	return trailers
+}
+
+// storeCommitMessage saves the classification and the trailers parsed from the message of the commit
+func storeCommitMessage(store models.Store, commit *code.Commit) errors.Error {
+	classification, trailers := ParseCommitMessage(commit.Sha, commit.Message)
+	if err := store.CommitClassification(classification); err != nil {
+		return err
+	}
+	return store.CommitTrailers(trailers)
+}
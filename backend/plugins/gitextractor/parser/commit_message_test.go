@@ -0,0 +1,87 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommitMessage(t *testing.T) {
+	classification, trailers := ParseCommitMessage("sha1", `feat(api)!: drop the v1 endpoints
+
+The v1 endpoints were deprecated for a year.
+Reviewed-by: not a trailer since the paragraph is the body
+
+Co-authored-by: Jane Doe <jane@example.com>
+Signed-off-by: John Doe <john@example.com>
+Change-Id: I8473b95934b5732ac55d26311a706c9c2bde9940
+`)
+	assert.True(t, classification.IsConventional)
+	assert.Equal(t, "feat", classification.Type)
+	assert.Equal(t, "api", classification.Scope)
+	assert.True(t, classification.IsBreaking)
+	assert.Equal(t, "drop the v1 endpoints", classification.Description)
+	assert.Len(t, trailers, 3)
+	assert.Equal(t, "Co-authored-by", trailers[0].Key)
+	assert.Equal(t, "Jane Doe", trailers[0].Name)
+	assert.Equal(t, "jane@example.com", trailers[0].AccountId)
+	assert.Equal(t, 1, trailers[1].Idx)
+	assert.Equal(t, "Change-Id", trailers[2].Key)
+	assert.Equal(t, "I8473b95934b5732ac55d26311a706c9c2bde9940", trailers[2].Value)
+	assert.Empty(t, trailers[2].AccountId)
+}
+
+func TestParseCommitMessageBreakingFooter(t *testing.T) {
+	classification, trailers := ParseCommitMessage("sha2", "Fix: handle nil config\n\nBREAKING CHANGE: config is now\n  required\nreviewed-by: alice@example.com")
+	assert.True(t, classification.IsConventional)
+	assert.Equal(t, "fix", classification.Type)
+	assert.Empty(t, classification.Scope)
+	assert.True(t, classification.IsBreaking)
+	assert.Len(t, trailers, 2)
+	assert.Equal(t, "config is now required", trailers[0].Value)
+	assert.Equal(t, "Reviewed-by", trailers[1].Key)
+	assert.Equal(t, "alice@example.com", trailers[1].Email)
+}
+
+func TestParseCommitMessageNotConventional(t *testing.T) {
+	classification, trailers := ParseCommitMessage("sha3", "Merge branch 'main' into feature\n\nSee: the details below\nsome free text")
+	assert.False(t, classification.IsConventional)
+	assert.Empty(t, classification.Type)
+	assert.Empty(t, trailers)
+	classification, trailers = ParseCommitMessage("sha4", "Signed-off-by: only a header")
+	assert.False(t, classification.IsConventional)
+	assert.Empty(t, trailers)
+}
+
+func TestParseCommitMessageUnknownType(t *testing.T) {
+	for _, message := range []string{"WIP: foo", "Note: bar", "Merge: x", "wip!: breaking nothing"} {
+		classification, _ := ParseCommitMessage("sha5", message)
+		assert.False(t, classification.IsConventional, message)
+		assert.False(t, classification.IsBreaking, message)
+		assert.NotEmpty(t, classification.Type, message)
+	}
+	classification, _ := ParseCommitMessage("sha6", "Note: bar")
+	assert.Equal(t, "note", classification.Type)
+	assert.Equal(t, "bar", classification.Description)
+	// the colon must be followed by a space
+	classification, _ = ParseCommitMessage("sha7", "fix:no space")
+	assert.False(t, classification.IsConventional)
+	assert.Empty(t, classification.Type)
+}
@@ -0,0 +1,253 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/config"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/log"
+)
+
+// MirrorCache keeps bare mirrors of the remote repos on disk, so every run only has to fetch the new objects
+// instead of cloning the whole repo. Each mirror is guarded by a file lock since multiple pipelines (or workers
+// on the same host) might collect the same repo at the same time, and the least recently used mirrors would be
+// evicted when the total size exceeds the limit.
+type MirrorCache struct {
+	dir      string
+	maxBytes int64
+	logger   log.Logger
+}
+
+// Mirror is a locked mirror in the cache, it must be released after use
+type Mirror struct {
+	Dir      string
+	cache    *MirrorCache
+	lockFile *os.File
+}
+
+// NewMirrorCache returns nil if GIT_EXTRACTOR_MIRROR_DIR is not set
+func NewMirrorCache(cfg config.ConfigReader, logger log.Logger) (*MirrorCache, errors.Error) {
+	dir := cfg.GetString("GIT_EXTRACTOR_MIRROR_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Default.Wrap(err, fmt.Sprintf("failed to create mirror dir %s", dir))
+	}
+	return &MirrorCache{
+		dir:      dir,
+		maxBytes: cfg.GetInt64("GIT_EXTRACTOR_MIRROR_MAX_SIZE_GB") << 30,
+		logger:   logger.Nested("mirror"),
+	}, nil
+}
+
+// MirrorKey identifies the mirror of a remote repo, the credentials are excluded so rotating them won't
+// invalidate the mirror
+func MirrorKey(taskData *GitExtractorTaskData) string {
+	remote := taskData.Options.Url
+	if taskData.ParsedURL != nil {
+		u := *taskData.ParsedURL
+		u.User = nil
+		remote = u.String()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", taskData.Options.PluginName, taskData.Options.ConnectionId, remote)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Acquire locks the mirror exclusively, it waits until other holders release it or the context is done
+func (c *MirrorCache) Acquire(ctx context.Context, key string) (*Mirror, errors.Error) {
+	lockFile, err := c.lock(ctx, key, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Mirror{
+		Dir:      filepath.Join(c.dir, key),
+		cache:    c,
+		lockFile: lockFile,
+	}, nil
+}
+
+// Reset removes the mirror content, e.g. when it got corrupted
+func (m *Mirror) Reset() errors.Error {
+	if err := os.RemoveAll(m.Dir); err != nil {
+		return errors.Default.Wrap(err, fmt.Sprintf("failed to remove mirror %s", m.Dir))
+	}
+	return nil
+}
+
+// Release marks the mirror as used just now, unlocks it and evicts the least recently used mirrors if needed
+func (m *Mirror) Release() {
+	if m.lockFile == nil {
+		return
+	}
+	now := time.Now()
+	_ = os.Chtimes(m.lockFile.Name(), now, now)
+	unlock(m.lockFile)
+	m.lockFile = nil
+	m.cache.evict(filepath.Base(m.Dir))
+}
+
+type mirrorEntry struct {
+	key      string
+	size     int64
+	lastUsed time.Time
+}
+
+// evict removes the least recently used mirrors until the total size fits the limit, the mirror being
+// used by others or just used by us would be kept
+func (c *MirrorCache) evict(justUsed string) {
+	if c.maxBytes <= 0 {
+		return
+	}
+	entries, e := os.ReadDir(c.dir)
+	if e != nil {
+		c.logger.Warn(e, "failed to list mirrors")
+		return
+	}
+	mirrors := make([]*mirrorEntry, 0)
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		size := dirSize(filepath.Join(c.dir, entry.Name()))
+		total += size
+		lastUsed := time.Time{}
+		if info, e := os.Stat(c.lockPath(entry.Name())); e == nil {
+			lastUsed = info.ModTime()
+		}
+		mirrors = append(mirrors, &mirrorEntry{key: entry.Name(), size: size, lastUsed: lastUsed})
+	}
+	sort.Slice(mirrors, func(i, j int) bool {
+		return mirrors[i].lastUsed.Before(mirrors[j].lastUsed)
+	})
+	for _, mirror := range mirrors {
+		if total <= c.maxBytes {
+			return
+		}
+		if mirror.key == justUsed {
+			continue
+		}
+		lockFile, err := c.lock(context.Background(), mirror.key, false)
+		if err != nil {
+			// being used by someone else
+			continue
+		}
+		if e := os.RemoveAll(filepath.Join(c.dir, mirror.key)); e != nil {
+			c.logger.Warn(e, "failed to evict mirror %s", mirror.key)
+		} else {
+			c.logger.Info("evicted mirror %s (%d bytes, last used at %s)", mirror.key, mirror.size, mirror.lastUsed)
+			total -= mirror.size
+			// removed while being locked, the ones waiting for the old lock file would notice and retry
+			if e := os.Remove(lockFile.Name()); e != nil {
+				c.logger.Warn(e, "failed to remove the lock file of mirror %s", mirror.key)
+			}
+		}
+		unlock(lockFile)
+	}
+}
+
+func (c *MirrorCache) lockPath(key string) string {
+	return filepath.Join(c.dir, key+".lock")
+}
+
+// mirrorLockRetryInterval is how often a locked mirror is checked again while waiting for it
+var mirrorLockRetryInterval = time.Second
+
+// lock obtains the exclusive file lock of the mirror, it returns immediately if wait was false and the
+// mirror was locked by others
+func (c *MirrorCache) lock(ctx context.Context, key string, wait bool) (*os.File, errors.Error) {
+	logged := false
+	for {
+		lockFile, e := os.OpenFile(c.lockPath(key), os.O_CREATE|os.O_RDWR, 0644)
+		if e != nil {
+			return nil, errors.Default.Wrap(e, "failed to open the mirror lock file")
+		}
+		e = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if e == nil {
+			if isLockFileCurrent(lockFile) {
+				return lockFile, nil
+			}
+			// the mirror was evicted while we were waiting, lock the new lock file instead
+			unlock(lockFile)
+			continue
+		}
+		_ = lockFile.Close()
+		if e != syscall.EWOULDBLOCK || !wait {
+			return nil, errors.Default.Wrap(e, fmt.Sprintf("failed to lock mirror %s", key))
+		}
+		if !logged {
+			c.logger.Info("mirror %s is being used by another task, waiting", key)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Convert(ctx.Err())
+		case <-time.After(mirrorLockRetryInterval):
+		}
+	}
+}
+
+// isLockFileCurrent tells whether the locked file is still the lock file of the mirror, it was not if the
+// mirror got evicted in the meantime
+func isLockFileCurrent(lockFile *os.File) bool {
+	locked, e := lockFile.Stat()
+	if e != nil {
+		return false
+	}
+	current, e := os.Stat(lockFile.Name())
+	return e == nil && os.SameFile(locked, current)
+}
+
+func unlock(lockFile *os.File) {
+	_ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+	_ = lockFile.Close()
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			if info, e := d.Info(); e == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// isMirrorInitialized checks if the dir contains a bare repo already
+func isMirrorInitialized(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "HEAD"))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,171 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMirrorCache(t *testing.T, maxBytes int64) *MirrorCache {
+	mirrorLockRetryInterval = 10 * time.Millisecond
+	return &MirrorCache{dir: t.TempDir(), maxBytes: maxBytes, logger: unithelper.DummyLogger()}
+}
+
+// useMirror fills the mirror with size bytes and marks it as used at the given time
+func useMirror(t *testing.T, cache *MirrorCache, key string, size int, lastUsed time.Time) {
+	mirror, err := cache.Acquire(context.Background(), key)
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(mirror.Dir, 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(mirror.Dir, "pack"), []byte(strings.Repeat("x", size)), 0644))
+	unlock(mirror.lockFile)
+	assert.Nil(t, os.Chtimes(cache.lockPath(key), lastUsed, lastUsed))
+}
+
+func TestNewMirrorCache(t *testing.T) {
+	v := viper.New()
+	cache, err := NewMirrorCache(v, unithelper.DummyLogger())
+	assert.Nil(t, err)
+	assert.Nil(t, cache, "mirrors are only kept in the configured dir")
+
+	dir := filepath.Join(t.TempDir(), "mirrors")
+	v.Set("GIT_EXTRACTOR_MIRROR_DIR", dir)
+	v.Set("GIT_EXTRACTOR_MIRROR_MAX_SIZE_GB", 2)
+	cache, err = NewMirrorCache(v, unithelper.DummyLogger())
+	assert.Nil(t, err)
+	assert.Equal(t, dir, cache.dir)
+	assert.Equal(t, int64(2)<<30, cache.maxBytes)
+	assert.DirExists(t, dir)
+}
+
+func TestMirrorCacheEvictLeastRecentlyUsed(t *testing.T) {
+	cache := newTestMirrorCache(t, 200)
+	now := time.Now()
+	useMirror(t, cache, "oldest", 100, now.Add(-3*time.Hour))
+	useMirror(t, cache, "older", 100, now.Add(-2*time.Hour))
+	useMirror(t, cache, "recent", 100, now.Add(-time.Hour))
+
+	mirror, err := cache.Acquire(context.Background(), "current")
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(mirror.Dir, 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(mirror.Dir, "pack"), []byte(strings.Repeat("x", 50)), 0644))
+	mirror.Release()
+
+	// 350 bytes in total, evicting the 2 least recently used ones fits the limit
+	for _, key := range []string{"oldest", "older"} {
+		assert.NoDirExists(t, filepath.Join(cache.dir, key))
+		assert.NoFileExists(t, cache.lockPath(key))
+	}
+	for _, key := range []string{"recent", "current"} {
+		assert.DirExists(t, filepath.Join(cache.dir, key))
+		assert.FileExists(t, cache.lockPath(key))
+	}
+}
+
+func TestMirrorCacheEvictSkipsLockedMirrors(t *testing.T) {
+	cache := newTestMirrorCache(t, 100)
+	now := time.Now()
+	useMirror(t, cache, "oldest", 100, now.Add(-2*time.Hour))
+	useMirror(t, cache, "older", 100, now.Add(-time.Hour))
+
+	// the oldest one is being used by another task
+	locked, err := cache.Acquire(context.Background(), "oldest")
+	assert.Nil(t, err)
+	cache.evict("")
+	assert.DirExists(t, filepath.Join(cache.dir, "oldest"))
+	assert.NoDirExists(t, filepath.Join(cache.dir, "older"))
+	unlock(locked.lockFile)
+
+	// nothing is evicted without a limit
+	unlimited := &MirrorCache{dir: cache.dir, logger: cache.logger}
+	unlimited.evict("")
+	assert.DirExists(t, filepath.Join(cache.dir, "oldest"))
+}
+
+func TestMirrorCacheLock(t *testing.T) {
+	cache := newTestMirrorCache(t, 0)
+	mirror, err := cache.Acquire(context.Background(), "repo")
+	assert.Nil(t, err)
+
+	// not waiting
+	_, err = cache.lock(context.Background(), "repo", false)
+	assert.NotNil(t, err)
+
+	// waiting until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cache.Acquire(ctx, "repo")
+	assert.NotNil(t, err)
+
+	// waiting until the holder releases it
+	acquired := make(chan *Mirror)
+	go func() {
+		m, err := cache.Acquire(context.Background(), "repo")
+		assert.Nil(t, err)
+		acquired <- m
+	}()
+	time.Sleep(50 * time.Millisecond)
+	mirror.Release()
+	select {
+	case m := <-acquired:
+		m.Release()
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the mirror was never acquired")
+	}
+}
+
+func TestMirrorCacheLockAfterEviction(t *testing.T) {
+	cache := newTestMirrorCache(t, 0)
+	holder, err := cache.Acquire(context.Background(), "repo")
+	assert.Nil(t, err)
+	acquired := make(chan *Mirror, 1)
+	go func() {
+		m, err := cache.Acquire(context.Background(), "repo")
+		assert.Nil(t, err)
+		acquired <- m
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the holder evicts the mirror, then someone else locks the new lock file
+	assert.Nil(t, os.Remove(cache.lockPath("repo")))
+	unlock(holder.lockFile)
+	other, err := cache.lock(context.Background(), "repo", false)
+	assert.Nil(t, err)
+
+	// the waiting one must not take the stale lock file as its own
+	select {
+	case <-acquired:
+		assert.Fail(t, "the mirror was acquired twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock(other)
+	select {
+	case m := <-acquired:
+		m.Release()
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the mirror was never acquired")
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/log"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/models"
+)
+
+// CommitWalkPlan tells the collector which commits should be processed
+type CommitWalkPlan struct {
+	// Incremental means only the commits in Shas need to be processed, otherwise all commits in the repo would be walked
+	Incremental bool
+	Shas        []string
+	// FullSync means the collected data might be stale (e.g. some refs were force-pushed) and must be replaced
+	FullSync bool
+	Reason   string
+}
+
+// RefTracker remembers the commit each ref pointed to after a successful collection, so the next run would
+// only walk the commits added since then instead of the whole history.
+type RefTracker struct {
+	ctx      context.Context
+	db       dal.Dal
+	logger   log.Logger
+	repoId   string
+	localDir string
+	previous map[string]string
+	current  map[string]string
+}
+
+// NewRefTracker loads the recorded refs of the repo and reads the current refs from the local clone
+func NewRefTracker(ctx context.Context, db dal.Dal, logger log.Logger, repoId string, localDir string) (*RefTracker, errors.Error) {
+	t := &RefTracker{
+		ctx:      ctx,
+		db:       db,
+		logger:   logger,
+		repoId:   repoId,
+		localDir: localDir,
+		previous: make(map[string]string),
+	}
+	var states []models.GitRefState
+	if err := db.All(&states, dal.Where("repo_id = ?", repoId)); err != nil {
+		return nil, err
+	}
+	for _, state := range states {
+		t.previous[state.RefName] = state.CommitSha
+	}
+	current, err := t.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	t.current = current
+	return t, nil
+}
+
+// Plan compares the current refs with the recorded ones to decide how the commits should be walked
+func (t *RefTracker) Plan() (*CommitWalkPlan, errors.Error) {
+	shallow, err := t.git(nil, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(shallow) == "true" {
+		// the shallow clone contains the new commits only, walking all of them is cheap already
+		return &CommitWalkPlan{Reason: "shallow clone"}, nil
+	}
+	if len(t.previous) == 0 {
+		return &CommitWalkPlan{Reason: "no ref was recorded"}, nil
+	}
+	for _, refName := range sortedKeys(t.previous) {
+		prevSha := t.previous[refName]
+		currentSha, ok := t.current[refName]
+		if !ok || currentSha == prevSha {
+			// deleted refs leave nothing to collect
+			continue
+		}
+		if _, e := t.git(nil, "cat-file", "-e", prevSha+"^{commit}"); e != nil {
+			return &CommitWalkPlan{FullSync: true, Reason: fmt.Sprintf("commit %s of %s is gone", prevSha, refName)}, nil
+		}
+		isAncestor, err := t.isAncestor(prevSha, currentSha)
+		if err != nil {
+			return nil, err
+		}
+		if !isAncestor {
+			return &CommitWalkPlan{FullSync: true, Reason: fmt.Sprintf("%s was force-pushed from %s to %s", refName, prevSha, currentSha)}, nil
+		}
+	}
+	// equivalent to `git rev-list <current tips> --not <previous tips>`
+	var stdin bytes.Buffer
+	for _, sha := range distinctValues(t.current) {
+		stdin.WriteString(sha + "\n")
+	}
+	for _, sha := range distinctValues(t.previous) {
+		// previous tips of deleted refs might have been garbage collected
+		if _, e := t.git(nil, "cat-file", "-e", sha+"^{commit}"); e == nil {
+			stdin.WriteString("^" + sha + "\n")
+		}
+	}
+	output, err := t.git(&stdin, "rev-list", "--stdin")
+	if err != nil {
+		return nil, err
+	}
+	return &CommitWalkPlan{Incremental: true, Shas: strings.Fields(output)}, nil
+}
+
+// PlanSince narrows the plan down to the commits reachable from the current refs and committed after since,
+// it is what a shallow clone would have fetched
+func (t *RefTracker) PlanSince(plan *CommitWalkPlan, since time.Time) (*CommitWalkPlan, errors.Error) {
+	var stdin bytes.Buffer
+	for _, sha := range distinctValues(t.current) {
+		stdin.WriteString(sha + "\n")
+	}
+	output, err := t.git(&stdin, "rev-list", "--stdin", fmt.Sprintf("--since=%s", since.Format(time.RFC3339)))
+	if err != nil {
+		return nil, err
+	}
+	return &CommitWalkPlan{
+		Incremental: true,
+		Shas:        strings.Fields(output),
+		FullSync:    plan.FullSync,
+		Reason:      plan.Reason,
+	}, nil
+}
+
+// Save records the current refs, it should be called after the collected data was flushed into the database
+func (t *RefTracker) Save() errors.Error {
+	err := t.db.Delete(&models.GitRefState{}, dal.Where("repo_id = ?", t.repoId))
+	if err != nil {
+		return err
+	}
+	if len(t.current) == 0 {
+		return nil
+	}
+	states := make([]*models.GitRefState, 0, len(t.current))
+	for _, refName := range sortedKeys(t.current) {
+		states = append(states, &models.GitRefState{
+			RepoId:    t.repoId,
+			RefName:   refName,
+			CommitSha: t.current[refName],
+		})
+	}
+	return t.db.Create(states)
+}
+
+// listRefs returns the commit each branch and tag points to, annotated tags are peeled
+func (t *RefTracker) listRefs() (map[string]string, errors.Error) {
+	output, err := t.git(
+		nil,
+		"for-each-ref",
+		"--format=%(refname) %(objecttype) %(objectname) %(*objecttype) %(*objectname)",
+		"refs/heads", "refs/tags", "refs/remotes",
+	)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[1] == "commit" {
+			refs[fields[0]] = fields[2]
+		} else if len(fields) == 5 && fields[3] == "commit" {
+			refs[fields[0]] = fields[4]
+		}
+	}
+	return refs, nil
+}
+
+func (t *RefTracker) isAncestor(ancestor, descendant string) (bool, errors.Error) {
+	cmd := exec.CommandContext(t.ctx, "git", "merge-base", "--is-ancestor", ancestor, descendant)
+	cmd.Dir = t.localDir
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, errors.Default.Wrap(err, fmt.Sprintf("failed to check if %s is an ancestor of %s", ancestor, descendant))
+}
+
+func (t *RefTracker) git(stdin *bytes.Buffer, args ...string) (string, errors.Error) {
+	cmd := exec.CommandContext(t.ctx, "git", args...)
+	cmd.Dir = t.localDir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Default.New(fmt.Sprintf("git %v in %s failed: %s", args, t.localDir, generateErrMsg(stderr.Bytes(), err)))
+	}
+	return string(output), nil
+}
+
+// HasRefStates tells whether the refs of the repo were recorded by a previous run
+func HasRefStates(db dal.Dal, repoId string, logger log.Logger) bool {
+	count, err := db.Count(
+		dal.From(&models.GitRefState{}),
+		dal.Where("repo_id = ?", repoId),
+	)
+	if err != nil {
+		logger.Warn(err, "failed to count ref states")
+		return false
+	}
+	return count > 0
+}
+
+// prepareCommitWalk creates the RefTracker for the repo and decides how the commits should be walked,
+// the store would be switched to full sync mode if previously collected commits could be stale
+func prepareCommitWalk(subtaskCtx plugin.SubTaskContext, store models.Store, repoId, localDir string, logger log.Logger) (*RefTracker, *CommitWalkPlan, errors.Error) {
+	tracker, err := NewRefTracker(subtaskCtx.GetContext(), subtaskCtx.GetDal(), logger, repoId, localDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	taskData := subtaskCtx.GetData().(*GitExtractorTaskData)
+	plan := &CommitWalkPlan{Reason: "full sync"}
+	if taskData.Incremental {
+		plan, err = tracker.Plan()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if !plan.Incremental && taskData.Since != nil {
+		plan, err = tracker.PlanSince(plan, *taskData.Since)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if plan.Incremental {
+		logger.Info("%d new commits to be collected since last run", len(plan.Shas))
+	} else if plan.FullSync {
+		logger.Warn(nil, "history rewritten (%s), falling back to a full collection", plan.Reason)
+		store.SetIncrementalMode(false)
+	} else {
+		logger.Info("walking all commits in the repo: %s", plan.Reason)
+	}
+	return tracker, plan, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func distinctValues(m map[string]string) []string {
+	seen := make(map[string]bool, len(m))
+	values := make([]string, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		if !seen[m[k]] {
+			seen[m[k]] = true
+			values = append(values, m[k])
+		}
+	}
+	return values
+}
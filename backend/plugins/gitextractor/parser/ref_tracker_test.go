@@ -0,0 +1,182 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// gitRepo is a temporary repo the tests commit into
+type gitRepo struct {
+	t   *testing.T
+	dir string
+	env []string
+}
+
+func newGitRepo(t *testing.T) *gitRepo {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := &gitRepo{t: t, dir: t.TempDir()}
+	repo.git("init", "--initial-branch=main")
+	return repo
+}
+
+func (r *gitRepo) git(args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=devlake", "-c", "user.email=devlake@example.com"}, args...)...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), r.env...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v failed: %s", args, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// commit adds a commit to the current branch and returns its sha
+func (r *gitRepo) commit(message string) string {
+	assert.Nil(r.t, os.WriteFile(filepath.Join(r.dir, "file.txt"), []byte(message), 0644))
+	r.git("add", "file.txt")
+	r.git("commit", "-m", message)
+	return r.git("rev-parse", "HEAD")
+}
+
+// commitAt adds a commit committed at the given date
+func (r *gitRepo) commitAt(message, date string) string {
+	r.env = []string{"GIT_COMMITTER_DATE=" + date}
+	defer func() { r.env = nil }()
+	return r.commit(message)
+}
+
+// tracker returns a RefTracker of the repo as if the recorded refs were loaded from the database
+func (r *gitRepo) tracker(dir string, previous map[string]string) *RefTracker {
+	states := make([]models.GitRefState, 0, len(previous))
+	for refName, sha := range previous {
+		states = append(states, models.GitRefState{RepoId: "repo", RefName: refName, CommitSha: sha})
+	}
+	db := mockdal.NewDal(r.t)
+	db.On("All", mock.Anything, mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		*dst.(*[]models.GitRefState) = states
+		return nil
+	})
+	tracker, err := NewRefTracker(context.Background(), db, unithelper.DummyLogger(), "repo", dir)
+	assert.Nil(r.t, err)
+	return tracker
+}
+
+func TestRefTrackerPlan(t *testing.T) {
+	repo := newGitRepo(t)
+	repo.commit("1")
+	repo.git("tag", "-a", "v1", "-m", "v1")
+	repo.git("branch", "feature")
+
+	plan, err := repo.tracker(repo.dir, nil).Plan()
+	assert.Nil(t, err)
+	assert.False(t, plan.Incremental)
+	assert.False(t, plan.FullSync)
+	assert.Equal(t, "no ref was recorded", plan.Reason)
+
+	// nothing changed since the recorded refs, the annotated tag is peeled to its commit
+	recorded := repo.tracker(repo.dir, nil).current
+	assert.Equal(t, recorded["refs/heads/main"], recorded["refs/tags/v1"])
+	plan, err = repo.tracker(repo.dir, recorded).Plan()
+	assert.Nil(t, err)
+	assert.True(t, plan.Incremental)
+	assert.Empty(t, plan.Shas)
+
+	// new commits on an existing branch and a new branch are walked through rev-list --stdin
+	second := repo.commit("2")
+	third := repo.commit("3")
+	repo.git("checkout", "-b", "bugfix")
+	fourth := repo.commit("4")
+	repo.git("checkout", "main")
+	plan, err = repo.tracker(repo.dir, recorded).Plan()
+	assert.Nil(t, err)
+	assert.True(t, plan.Incremental)
+	assert.ElementsMatch(t, []string{second, third, fourth}, plan.Shas)
+
+	// deleted refs leave nothing to collect
+	repo.git("branch", "-D", "feature")
+	plan, err = repo.tracker(repo.dir, recorded).Plan()
+	assert.Nil(t, err)
+	assert.True(t, plan.Incremental)
+	assert.ElementsMatch(t, []string{second, third, fourth}, plan.Shas)
+}
+
+func TestRefTrackerPlanRewrittenHistory(t *testing.T) {
+	repo := newGitRepo(t)
+	repo.commit("1")
+	repo.commit("2")
+	recorded := repo.tracker(repo.dir, nil).current
+
+	// force-push: the recorded tip is no longer an ancestor of the branch
+	repo.git("reset", "--hard", "HEAD~1")
+	repo.commit("2'")
+	plan, err := repo.tracker(repo.dir, recorded).Plan()
+	assert.Nil(t, err)
+	assert.True(t, plan.FullSync)
+	assert.Contains(t, plan.Reason, "refs/heads/main was force-pushed")
+
+	// the recorded tip doesn't exist in the clone at all
+	plan, err = repo.tracker(repo.dir, map[string]string{"refs/heads/main": strings.Repeat("a", 40)}).Plan()
+	assert.Nil(t, err)
+	assert.True(t, plan.FullSync)
+	assert.Contains(t, plan.Reason, "is gone")
+}
+
+func TestRefTrackerPlanShallowClone(t *testing.T) {
+	repo := newGitRepo(t)
+	repo.commit("1")
+	repo.commit("2")
+	recorded := repo.tracker(repo.dir, nil).current
+	repo.commit("3")
+
+	shallowDir := filepath.Join(t.TempDir(), "shallow")
+	repo.git("clone", "--bare", "--depth=1", "file://"+repo.dir, shallowDir)
+	plan, err := repo.tracker(shallowDir, recorded).Plan()
+	assert.Nil(t, err)
+	assert.False(t, plan.Incremental)
+	assert.False(t, plan.FullSync)
+	assert.Equal(t, "shallow clone", plan.Reason)
+}
+
+func TestRefTrackerPlanSince(t *testing.T) {
+	repo := newGitRepo(t)
+	repo.commitAt("old", "2020-01-01T00:00:00Z")
+	recent := repo.commit("recent")
+
+	plan, err := repo.tracker(repo.dir, nil).PlanSince(&CommitWalkPlan{FullSync: true, Reason: "force-pushed"}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.True(t, plan.Incremental)
+	assert.True(t, plan.FullSync)
+	assert.Equal(t, []string{recent}, plan.Shas)
+}
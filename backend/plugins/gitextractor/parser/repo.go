@@ -31,6 +31,7 @@ const (
 type RepoCollector interface {
 	SetCleanUp(func()) error
 	Close(ctx context.Context) error
+	SaveRefStates() error
 
 	CollectAll(subtaskCtx plugin.SubTaskContext) error
 
@@ -42,4 +43,6 @@ type RepoCollector interface {
 	CollectBranches(subtaskCtx plugin.SubTaskContext) error
 	CollectCommits(subtaskCtx plugin.SubTaskContext) error
 	CollectDiffLine(subtaskCtx plugin.SubTaskContext) error
+	CollectCodeOwners(subtaskCtx plugin.SubTaskContext) error
+	ReadHeadFiles(ctx context.Context, match func(filePath string) bool) (map[string]string, error)
 }
@@ -34,16 +34,19 @@ import (
 	"github.com/apache/incubator-devlake/plugins/gitextractor/models"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
 )
 
 type GogitRepoCollector struct {
-	id      string
-	logger  log.Logger
-	store   models.Store
-	repo    *gogit.Repository
-	cleanUp func()
+	id         string
+	logger     log.Logger
+	store      models.Store
+	repo       *gogit.Repository
+	localDir   string
+	refTracker *RefTracker
+	cleanUp    func()
 }
 
 func NewGogitRepoCollector(localDir string, repoId string, store models.Store, logger log.Logger) (*GogitRepoCollector, errors.Error) {
@@ -52,10 +55,11 @@ func NewGogitRepoCollector(localDir string, repoId string, store models.Store, l
 		return nil, errors.Convert(err)
 	}
 	return &GogitRepoCollector{
-		id:     repoId,
-		logger: logger,
-		store:  store,
-		repo:   repo,
+		id:       repoId,
+		logger:   logger,
+		store:    store,
+		repo:     repo,
+		localDir: localDir,
 	}, nil
 }
 
@@ -76,6 +80,17 @@ func (r *GogitRepoCollector) Close(ctx context.Context) error {
 	return nil
 }
 
+// SaveRefStates records the refs walked by CollectCommits, it should be called only after all subtasks succeeded
+func (r *GogitRepoCollector) SaveRefStates() error {
+	if r.refTracker == nil {
+		return nil
+	}
+	if err := r.store.Flush(); err != nil {
+		return err
+	}
+	return r.refTracker.Save()
+}
+
 // CollectAll The main parser subtask
 func (r *GogitRepoCollector) CollectAll(subtaskCtx plugin.SubTaskContext) error {
 	subtaskCtx.SetProgress(0, -1)
@@ -285,7 +300,29 @@ func (r *GogitRepoCollector) CollectCommits(subtaskCtx plugin.SubTaskContext) (e
 	}
 
 	repo := r.repo
-	store := r.store
+	tracker, plan, err := prepareCommitWalk(subtaskCtx, r.store, r.id, r.localDir, r.logger)
+	if err != nil {
+		return err
+	}
+	if plan.Incremental {
+		subtaskCtx.SetProgress(0, len(plan.Shas))
+		for _, sha := range plan.Shas {
+			select {
+			case <-subtaskCtx.GetContext().Done():
+				return subtaskCtx.GetContext().Err()
+			default:
+			}
+			commit, err := repo.CommitObject(plumbing.NewHash(sha))
+			if err != nil {
+				return err
+			}
+			if err := r.collectCommit(subtaskCtx, taskOpts, componentMap, commit); err != nil {
+				return err
+			}
+		}
+		r.refTracker = tracker
+		return r.store.Flush()
+	}
 
 	commitsObjectsIter, err := repo.CommitObjects()
 	if err != nil {
@@ -298,98 +335,115 @@ func (r *GogitRepoCollector) CollectCommits(subtaskCtx plugin.SubTaskContext) (e
 			return subtaskCtx.GetContext().Err()
 		default:
 		}
-		commitSha := commit.Hash.String()
+		return r.collectCommit(subtaskCtx, taskOpts, componentMap, commit)
+	}); err != nil {
+		return err
+	}
+	r.refTracker = tracker
+	// make commits and commit files visible to the subtasks reading them from the database
+	return r.store.Flush()
+}
 
-		if commit.NumParents() != 0 {
-			_, err := commit.Parents().Next()
-			if err != nil {
-				if err == plumbing.ErrObjectNotFound {
-					// Skip calculating commit statistics when there are parent commits, but the first one cannot be fetched from the ODB.
-					// This usually happens during a shallow clone for incremental collection. Otherwise, we might end up overwriting
-					// the correct addition/deletion data in the database with an absurdly large addition number.
-					r.logger.Info("skip commit %s because it has no parent commit", commitSha)
-					return nil
-				}
-				return err
+func (r *GogitRepoCollector) collectCommit(subtaskCtx plugin.SubTaskContext, taskOpts *GitExtractorOptions, componentMap map[string]*regexp.Regexp, commit *object.Commit) (err error) {
+	store := r.store
+	commitSha := commit.Hash.String()
+
+	if commit.NumParents() != 0 {
+		_, err := commit.Parents().Next()
+		if err != nil {
+			if err == plumbing.ErrObjectNotFound {
+				// Skip calculating commit statistics when there are parent commits, but the first one cannot be fetched from the ODB.
+				// This usually happens during a shallow clone for incremental collection. Otherwise, we might end up overwriting
+				// the correct addition/deletion data in the database with an absurdly large addition number.
+				r.logger.Info("skip commit %s because it has no parent commit", commitSha)
+				return nil
 			}
-		}
-		codeCommit := &code.Commit{
-			Sha:            commitSha,
-			Message:        commit.Message,
-			AuthorName:     commit.Author.Name,
-			AuthorEmail:    commit.Author.Email,
-			AuthorId:       commit.Author.Email,
-			AuthoredDate:   commit.Author.When,
-			CommitterName:  commit.Committer.Name,
-			CommitterEmail: commit.Committer.Email,
-			CommitterId:    commit.Committer.Email,
-			CommittedDate:  commit.Committer.When,
-		}
-		if err = r.storeParentCommits(commitSha, commit); err != nil {
 			return err
 		}
+	}
+	codeCommit := &code.Commit{
+		Sha:            commitSha,
+		Message:        commit.Message,
+		AuthorName:     commit.Author.Name,
+		AuthorEmail:    commit.Author.Email,
+		AuthorId:       commit.Author.Email,
+		AuthoredDate:   commit.Author.When,
+		CommitterName:  commit.Committer.Name,
+		CommitterEmail: commit.Committer.Email,
+		CommitterId:    commit.Committer.Email,
+		CommittedDate:  commit.Committer.When,
+	}
+	if err = r.storeParentCommits(commitSha, commit); err != nil {
+		return err
+	}
 
-		if !*taskOpts.SkipCommitStat {
-			stats, err := commit.StatsContext(subtaskCtx.GetContext())
-			if err != nil {
-				return err
-			} else {
-				excluded := map[string]struct{}{}
-				for _, ext := range taskOpts.ExcludeFileExtensions {
-					e := strings.ToLower(strings.TrimSpace(ext))
-					if e == "" {
-						continue
-					}
-					excluded[e] = struct{}{}
+	if !*taskOpts.SkipCommitStat {
+		stats, err := commit.StatsContext(subtaskCtx.GetContext())
+		if err != nil {
+			return err
+		} else {
+			excluded := map[string]struct{}{}
+			for _, ext := range taskOpts.ExcludeFileExtensions {
+				e := strings.ToLower(strings.TrimSpace(ext))
+				if e == "" {
+					continue
 				}
-				for _, stat := range stats {
-					nameLower := strings.ToLower(stat.Name)
-					skip := false
-					for ext := range excluded {
-						if strings.HasSuffix(nameLower, ext) {
-							skip = true
-							break
-						}
-					}
-					if skip {
-						continue
+				excluded[e] = struct{}{}
+			}
+			for _, stat := range stats {
+				nameLower := strings.ToLower(stat.Name)
+				skip := false
+				for ext := range excluded {
+					if strings.HasSuffix(nameLower, ext) {
+						skip = true
+						break
 					}
-					codeCommit.Additions += stat.Addition
-					// In some repos, deletion may be zero, which is different from git log --stat.
-					// It seems go-git doesn't get the correct changes.
-					// I have run object.DiffTreeWithOptions manually with different diff algorithms,
-					// but get the same result with StatsContext.
-					// I cannot reproduce it with another repo.
-					// A similar issue: https://github.com/go-git/go-git/issues/367
-					codeCommit.Deletions += stat.Deletion
 				}
+				if skip {
+					continue
+				}
+				codeCommit.Additions += stat.Addition
+				// In some repos, deletion may be zero, which is different from git log --stat.
+				// It seems go-git doesn't get the correct changes.
+				// I have run object.DiffTreeWithOptions manually with different diff algorithms,
+				// but get the same result with StatsContext.
+				// I cannot reproduce it with another repo.
+				// A similar issue: https://github.com/go-git/go-git/issues/367
+				codeCommit.Deletions += stat.Deletion
 			}
 		}
+	}
 
-		err = store.Commits(codeCommit)
-		if err != nil {
+	err = store.Commits(codeCommit)
+	if err != nil {
+		return err
+	}
+	if taskOpts.ParseCommitMessages {
+		if err = storeCommitMessage(store, codeCommit); err != nil {
 			return err
 		}
+	}
 
-		codeRepoCommit := &code.RepoCommit{
-			RepoId:    r.id,
-			CommitSha: commitSha,
-		}
-		err = store.RepoCommits(codeRepoCommit)
-		if err != nil {
+	codeRepoCommit := &code.RepoCommit{
+		RepoId:    r.id,
+		CommitSha: commitSha,
+	}
+	err = store.RepoCommits(codeRepoCommit)
+	if err != nil {
+		return err
+	}
+	if !*taskOpts.SkipCommitFiles {
+		if err := r.storeDiffCommitFilesComparedToParent(subtaskCtx, componentMap, commit, taskOpts.ExcludeFileExtensions); err != nil {
 			return err
 		}
-		if !*taskOpts.SkipCommitFiles {
-			if err := r.storeDiffCommitFilesComparedToParent(subtaskCtx, componentMap, commit, taskOpts.ExcludeFileExtensions); err != nil {
-				return err
-			}
+	}
+	if !*taskOpts.SkipCommitStat {
+		if err := r.storeSubmoduleUpdates(subtaskCtx, commit); err != nil {
+			return err
 		}
-		subtaskCtx.IncProgress(1)
-		return nil
-	}); err != nil {
-		return err
 	}
-	return
+	subtaskCtx.IncProgress(1)
+	return nil
 }
 
 func (r *GogitRepoCollector) storeParentCommits(commitSha string, commit *object.Commit) error {
@@ -493,6 +547,104 @@ func (r *GogitRepoCollector) storeDiffCommitFilesComparedToParent(subtaskCtx plu
 	return nil
 }
 
+// storeSubmoduleUpdates compares the commits the submodules declared in .gitmodules point to with the ones of the
+// first parent, since go-git tree diffs skip submodule entries
+func (r *GogitRepoCollector) storeSubmoduleUpdates(subtaskCtx plugin.SubTaskContext, commit *object.Commit) error {
+	commitTree, firstParentTree, err := r.getCurrentAndParentTree(subtaskCtx.GetContext(), commit)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, tree := range []*object.Tree{commitTree, firstParentTree} {
+		if tree == nil {
+			continue
+		}
+		file, err := tree.File(".gitmodules")
+		if err == object.ErrFileNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		for _, path := range parseGitModulesPaths(content) {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			newSha, oldSha := gogitSubmoduleCommit(commitTree, path), gogitSubmoduleCommit(firstParentTree, path)
+			if newSha == oldSha {
+				continue
+			}
+			err = r.store.SubmoduleUpdates(&code.SubmoduleUpdate{
+				CommitSha:    commit.Hash.String(),
+				Path:         path,
+				RepoId:       r.id,
+				OldCommitSha: oldSha,
+				NewCommitSha: newSha,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func gogitSubmoduleCommit(tree *object.Tree, path string) string {
+	if tree == nil {
+		return ""
+	}
+	entry, err := tree.FindEntry(path)
+	if err != nil || entry.Mode != filemode.Submodule {
+		return ""
+	}
+	return entry.Hash.String()
+}
+
+// ReadHeadFiles returns the content of the files matching at the head of the default branch
+func (r *GogitRepoCollector) ReadHeadFiles(ctx context.Context, match func(filePath string) bool) (map[string]string, error) {
+	files := make(map[string]string)
+	head, err := r.repo.Head()
+	if err != nil {
+		if err == plumbing.ErrReferenceNotFound {
+			return files, nil
+		}
+		return nil, err
+	}
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	fileIter, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	err = fileIter.ForEach(func(file *object.File) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if !match(file.Name) {
+			return nil
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		files[file.Name] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // With some long path,the varchar(255) was not enough both ID and file_path
 // So we use the hash to compress the path in ID and add length of file_path.
 // Use commitSha and the sha256 of FilePath to create id
@@ -611,6 +763,38 @@ func (r *GogitRepoCollector) GetCommitList(subtaskCtx plugin.SubTaskContext) ([]
 	return commitList, nil
 }
 
+// CollectCodeOwners stores the rules of the CODEOWNERS file found at the head of the default branch
+func (r *GogitRepoCollector) CollectCodeOwners(subtaskCtx plugin.SubTaskContext) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		if err == plumbing.ErrReferenceNotFound {
+			r.logger.Info("HEAD not found, the repo might be empty")
+			return storeCodeOwners(subtaskCtx, r.id, "", "", "")
+		}
+		return err
+	}
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	for _, path := range CodeOwnersPaths {
+		file, err := commit.File(path)
+		if err == object.ErrFileNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		return storeCodeOwners(subtaskCtx, r.id, head.Hash().String(), path, content)
+	}
+	r.logger.Info("no CODEOWNERS file found at %s", head.Hash().String())
+	return storeCodeOwners(subtaskCtx, r.id, "", "", "")
+}
+
 func (r *GogitRepoCollector) CollectDiffLine(subtaskCtx plugin.SubTaskContext) error {
 	commitList, err := r.GetCommitList(subtaskCtx)
 	if err != nil {
@@ -619,6 +803,10 @@ func (r *GogitRepoCollector) CollectDiffLine(subtaskCtx plugin.SubTaskContext) e
 	if err := r.storeRepoSnapshot(subtaskCtx, commitList); err != nil {
 		return err
 	}
+	// make the snapshot visible to the subtasks reading it from the database
+	if err := r.store.Flush(); err != nil {
+		return err
+	}
 	// fixme: collecting CommitLineChange is not implemented.
 	// There is no way to get such information with go-git, and table commit_line_change is not used by any dashboards
 	// So we just ignore it.
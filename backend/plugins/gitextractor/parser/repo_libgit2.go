@@ -46,9 +46,10 @@ type Libgit2RepoCollector struct {
 	id     string
 	logger log.Logger
 
-	store   models.Store
-	repo    *git.Repository
-	cleanup func()
+	store      models.Store
+	repo       *git.Repository
+	refTracker *RefTracker
+	cleanup    func()
 }
 
 func NewLibgit2RepoCollector(localDir string, repoId string, store models.Store, logger log.Logger) (*Libgit2RepoCollector, errors.Error) {
@@ -103,6 +104,17 @@ func (r *Libgit2RepoCollector) Close(ctx context.Context) error {
 	return r.store.Close()
 }
 
+// SaveRefStates records the refs walked by CollectCommits, it should be called only after all subtasks succeeded
+func (r *Libgit2RepoCollector) SaveRefStates() error {
+	if r.refTracker == nil {
+		return nil
+	}
+	if err := r.store.Flush(); err != nil {
+		return err
+	}
+	return r.refTracker.Save()
+}
+
 // CountTags Count git tags subtask
 func (r *Libgit2RepoCollector) CountTags(ctx context.Context) (int, error) {
 	tags, err := r.repo.Tags.List()
@@ -263,11 +275,38 @@ func (r *Libgit2RepoCollector) CollectCommits(subtaskCtx plugin.SubTaskContext)
 	for _, component := range components {
 		componentMap[component.Name] = regexp.MustCompile(component.PathRegex)
 	}
+	tracker, plan, err := prepareCommitWalk(subtaskCtx, r.store, r.id, r.repo.Path(), r.logger)
+	if err != nil {
+		return err
+	}
+	if plan.Incremental {
+		subtaskCtx.SetProgress(0, len(plan.Shas))
+		for _, sha := range plan.Shas {
+			select {
+			case <-subtaskCtx.GetContext().Done():
+				return errors.Convert(subtaskCtx.GetContext().Err())
+			default:
+			}
+			oid, err1 := git.NewOid(sha)
+			if err1 != nil {
+				return errors.Convert(err1)
+			}
+			commit, err1 := r.repo.LookupCommit(oid)
+			if err1 != nil {
+				return errors.Convert(err1)
+			}
+			if err = r.collectCommit(subtaskCtx, taskOpts, opts, componentMap, commit); err != nil {
+				return err
+			}
+		}
+		r.refTracker = tracker
+		return r.store.Flush()
+	}
 	odb, err := errors.Convert01(r.repo.Odb())
 	if err != nil {
 		return err
 	}
-	return errors.Convert(odb.ForEach(func(id *git.Oid) error {
+	err = errors.Convert(odb.ForEach(func(id *git.Oid) error {
 		select {
 		case <-subtaskCtx.GetContext().Done():
 			return subtaskCtx.GetContext().Err()
@@ -280,68 +319,84 @@ func (r *Libgit2RepoCollector) CollectCommits(subtaskCtx plugin.SubTaskContext)
 		if commit == nil {
 			return nil
 		}
-		var parent *git.Commit
-		if commit.ParentCount() > 0 {
-			parent = commit.Parent(0)
-			// Skip calculating commit statistics when there are parent commits, but the first one cannot be fetched from the ODB.
-			// This usually happens during a shallow clone for incremental collection. Otherwise, we might end up overwriting
-			// the correct addition/deletion data in the database with an absurdly large addition number.
-			if parent == nil {
-				r.logger.Info("skip commit %s because it has no parent commit", commit.Id().String())
-				return nil
-			}
-		}
-		commitSha := commit.Id().String()
-		r.logger.Debug("process commit: %s", commitSha)
-		c := &code.Commit{
-			Sha:     commitSha,
-			Message: commit.Message(),
-		}
-		author := commit.Author()
-		if author != nil {
-			c.AuthorName = author.Name
-			c.AuthorEmail = author.Email
-			c.AuthorId = author.Email
-			c.AuthoredDate = author.When
-		}
-		committer := commit.Committer()
-		if committer != nil {
-			c.CommitterName = committer.Name
-			c.CommitterEmail = committer.Email
-			c.CommitterId = committer.Email
-			c.CommittedDate = committer.When
-		}
-		err = r.storeParentCommits(commitSha, commit)
-		if err != nil {
-			return err
-		}
+		return r.collectCommit(subtaskCtx, taskOpts, opts, componentMap, commit)
+	}))
+	if err != nil {
+		return err
+	}
+	r.refTracker = tracker
+	// make commits and commit files visible to the subtasks reading them from the database
+	return r.store.Flush()
+}
 
-		if !*taskOpts.SkipCommitStat {
-			var stats *git.DiffStats
-			var addIncluded, delIncluded int
-			if stats, addIncluded, delIncluded, err = r.getDiffComparedToParent(taskOpts, c.Sha, commit, parent, opts, componentMap); err != nil {
-				return err
-			}
-			r.logger.Debug("state: %#+v\n", stats.Deletions())
-			c.Additions += addIncluded
-			c.Deletions += delIncluded
+func (r *Libgit2RepoCollector) collectCommit(subtaskCtx plugin.SubTaskContext, taskOpts *GitExtractorOptions, opts *git.DiffOptions, componentMap map[string]*regexp.Regexp, commit *git.Commit) errors.Error {
+	var err errors.Error
+	var parent *git.Commit
+	if commit.ParentCount() > 0 {
+		parent = commit.Parent(0)
+		// Skip calculating commit statistics when there are parent commits, but the first one cannot be fetched from the ODB.
+		// This usually happens during a shallow clone for incremental collection. Otherwise, we might end up overwriting
+		// the correct addition/deletion data in the database with an absurdly large addition number.
+		if parent == nil {
+			r.logger.Info("skip commit %s because it has no parent commit", commit.Id().String())
+			return nil
 		}
+	}
+	commitSha := commit.Id().String()
+	r.logger.Debug("process commit: %s", commitSha)
+	c := &code.Commit{
+		Sha:     commitSha,
+		Message: commit.Message(),
+	}
+	author := commit.Author()
+	if author != nil {
+		c.AuthorName = author.Name
+		c.AuthorEmail = author.Email
+		c.AuthorId = author.Email
+		c.AuthoredDate = author.When
+	}
+	committer := commit.Committer()
+	if committer != nil {
+		c.CommitterName = committer.Name
+		c.CommitterEmail = committer.Email
+		c.CommitterId = committer.Email
+		c.CommittedDate = committer.When
+	}
+	err = r.storeParentCommits(commitSha, commit)
+	if err != nil {
+		return err
+	}
 
-		err = r.store.Commits(c)
-		if err != nil {
+	if !*taskOpts.SkipCommitStat {
+		var stats *git.DiffStats
+		var addIncluded, delIncluded int
+		if stats, addIncluded, delIncluded, err = r.getDiffComparedToParent(taskOpts, c.Sha, commit, parent, opts, componentMap); err != nil {
 			return err
 		}
-		repoCommit := &code.RepoCommit{
-			RepoId:    r.id,
-			CommitSha: c.Sha,
-		}
-		err = r.store.RepoCommits(repoCommit)
-		if err != nil {
+		r.logger.Debug("state: %#+v\n", stats.Deletions())
+		c.Additions += addIncluded
+		c.Deletions += delIncluded
+	}
+
+	err = r.store.Commits(c)
+	if err != nil {
+		return err
+	}
+	if taskOpts.ParseCommitMessages {
+		if err = storeCommitMessage(r.store, c); err != nil {
 			return err
 		}
-		subtaskCtx.IncProgress(1)
-		return nil
-	}))
+	}
+	repoCommit := &code.RepoCommit{
+		RepoId:    r.id,
+		CommitSha: c.Sha,
+	}
+	err = r.store.RepoCommits(repoCommit)
+	if err != nil {
+		return err
+	}
+	subtaskCtx.IncProgress(1)
+	return nil
 }
 
 func (r *Libgit2RepoCollector) storeParentCommits(commitSha string, commit *git.Commit) errors.Error {
@@ -393,6 +448,10 @@ func (r *Libgit2RepoCollector) getDiffComparedToParent(taskOpts *GitExtractorOpt
 			return nil, 0, 0, errors.Convert(err)
 		}
 	}
+	err = r.storeSubmoduleUpdatesFromDiff(commitSha, diff)
+	if err != nil {
+		return nil, 0, 0, errors.Convert(err)
+	}
 	var stats *git.DiffStats
 	stats, err = diff.Stats()
 	if err != nil {
@@ -521,6 +580,47 @@ func (r *Libgit2RepoCollector) storeCommitFilesFromDiff(commitSha string, diff *
 }
 
 // CollectDiffLine get line diff data from a specific branch
+// CollectCodeOwners stores the rules of the CODEOWNERS file found at the head of the default branch
+func (r *Libgit2RepoCollector) CollectCodeOwners(subtaskCtx plugin.SubTaskContext) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeUnbornBranch) || git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			r.logger.Info("HEAD not found, the repo might be empty")
+			return storeCodeOwners(subtaskCtx, r.id, "", "", "")
+		}
+		return err
+	}
+	defer head.Free()
+	commit, err := r.repo.LookupCommit(head.Target())
+	if err != nil {
+		return err
+	}
+	defer commit.Free()
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	defer tree.Free()
+	for _, path := range CodeOwnersPaths {
+		entry, err := tree.EntryByPath(path)
+		if git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		blob, err := r.repo.LookupBlob(entry.Id)
+		if err != nil {
+			return err
+		}
+		content := string(blob.Contents())
+		blob.Free()
+		return storeCodeOwners(subtaskCtx, r.id, commit.Id().String(), path, content)
+	}
+	r.logger.Info("no CODEOWNERS file found at %s", commit.Id().String())
+	return storeCodeOwners(subtaskCtx, r.id, "", "", "")
+}
+
 func (r *Libgit2RepoCollector) CollectDiffLine(subtaskCtx plugin.SubTaskContext) error {
 	//Using this subtask,we can get every line change in every commit.
 	//We maintain a snapshot structure to get which commit each deleted line belongs to
@@ -673,7 +773,8 @@ func (r *Libgit2RepoCollector) CollectDiffLine(subtaskCtx plugin.SubTaskContext)
 	}
 
 	r.logger.Info("collect snapshot finished")
-	return nil
+	// make the snapshot visible to the subtasks reading it from the database
+	return r.store.Flush()
 }
 
 func updateSnapshotFileBlame(currentCommit *git.Commit, deleted models.DiffLines, added models.DiffLines, lastFile string, snapshot map[string]*models.FileBlame) {
@@ -686,6 +787,75 @@ func updateSnapshotFileBlame(currentCommit *git.Commit, deleted models.DiffLines
 	}
 }
 
+// storeSubmoduleUpdatesFromDiff records the changes of the commits submodules point to
+func (r *Libgit2RepoCollector) storeSubmoduleUpdatesFromDiff(commitSha string, diff *git.Diff) error {
+	return diff.ForEach(func(delta git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
+		oldIsSubmodule := delta.OldFile.Mode == uint16(git.FilemodeCommit)
+		newIsSubmodule := delta.NewFile.Mode == uint16(git.FilemodeCommit)
+		if !oldIsSubmodule && !newIsSubmodule {
+			return nil, nil
+		}
+		update := &code.SubmoduleUpdate{
+			CommitSha: commitSha,
+			Path:      delta.NewFile.Path,
+			RepoId:    r.id,
+		}
+		if oldIsSubmodule {
+			update.OldCommitSha = delta.OldFile.Oid.String()
+		}
+		if newIsSubmodule {
+			update.NewCommitSha = delta.NewFile.Oid.String()
+		} else {
+			update.Path = delta.OldFile.Path
+		}
+		return nil, r.store.SubmoduleUpdates(update)
+	}, git.DiffDetailFiles)
+}
+
+// ReadHeadFiles returns the content of the files matching at the head of the default branch
+func (r *Libgit2RepoCollector) ReadHeadFiles(ctx context.Context, match func(filePath string) bool) (map[string]string, error) {
+	files := make(map[string]string)
+	head, err := r.repo.Head()
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeUnbornBranch) || git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			return files, nil
+		}
+		return nil, err
+	}
+	defer head.Free()
+	commit, err := r.repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, err
+	}
+	defer commit.Free()
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Free()
+	err = tree.Walk(func(root string, entry *git.TreeEntry) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if entry.Type != git.ObjectBlob || !match(root+entry.Name) {
+			return nil
+		}
+		blob, err := r.repo.LookupBlob(entry.Id)
+		if err != nil {
+			return err
+		}
+		files[root+entry.Name] = string(blob.Contents())
+		blob.Free()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 func getDiffOpts() (*git.DiffOptions, errors.Error) {
 	opts, err := git.DefaultDiffOptions()
 	if err != nil {
@@ -0,0 +1,72 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+)
+
+// HasUrlSecretRef tells whether the password of the clone url is a `secretref://` reference
+func HasUrlSecretRef(u *url.URL) bool {
+	if u == nil || u.User == nil {
+		return false
+	}
+	password, ok := u.User.Password()
+	return ok && plugin.IsSecretRef(password)
+}
+
+// ResolveUrlSecretRef replaces the password of the clone url with the secret it refers to. Plans keep the
+// `secretref://` references of the connection credentials, so the secrets are only resolved right before cloning,
+// within the namespace of the connection the url was built from and only if the url points to the host of the
+// connection endpoint. It tells whether the url was changed.
+func ResolveUrlSecretRef(u *url.URL, pluginName string, connectionId uint64, endpoint string) (bool, errors.Error) {
+	if !HasUrlSecretRef(u) {
+		return false, nil
+	}
+	if !isEndpointHost(u.Hostname(), endpoint) {
+		return false, errors.BadInput.New(fmt.Sprintf("the git url host %s doesn't match the endpoint of the connection", u.Hostname()))
+	}
+	password, _ := u.User.Password()
+	namespace := ""
+	if pluginName != "" && connectionId != 0 {
+		namespace = plugin.SecretNamespace(pluginName, connectionId)
+	}
+	secret, err := plugin.ResolveSecretRef(namespace, password)
+	if err != nil {
+		return false, errors.Default.Wrap(err, "failed to resolve the password of the git url")
+	}
+	u.User = url.UserPassword(u.User.Username(), secret)
+	return true, nil
+}
+
+// isEndpointHost tells whether the repos are cloned from the host of the api endpoint, the api might be served by
+// the api subdomain of the host, e.g. api.github.com for github.com
+func isEndpointHost(host, endpoint string) bool {
+	endpointUrl, e := url.Parse(endpoint)
+	if e != nil || host == "" {
+		return false
+	}
+	host = strings.ToLower(host)
+	endpointHost := strings.ToLower(endpointUrl.Hostname())
+	return host == endpointHost || "api."+host == endpointHost
+}
@@ -0,0 +1,192 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+)
+
+// subProjectManifests maps the file names marking the root of a sub-project to the source they are reported as,
+// a directory holding several of them is attributed to the first one in this order
+var subProjectManifests = []struct {
+	fileName string
+	source   string
+}{
+	{"go.mod", code.SUB_PROJECT_SOURCE_GO_MOD},
+	{"package.json", code.SUB_PROJECT_SOURCE_PACKAGE_JSON},
+	{"pom.xml", code.SUB_PROJECT_SOURCE_POM_XML},
+}
+
+// directories holding third-party or test code, manifests found inside them are not sub-projects
+var ignoredSubProjectDirs = []string{"node_modules", "vendor", "testdata", "third_party"}
+
+var goModulePattern = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+var gitModulesPathPattern = regexp.MustCompile(`(?m)^\s*path\s*=\s*(.+?)\s*$`)
+
+// IsSubProjectManifest tells whether the file marks the root of a sub-project, manifests at the root of the repo
+// are ignored since they describe the repo itself
+func IsSubProjectManifest(filePath string) bool {
+	dir, fileName := path.Split(filePath)
+	if dir == "" {
+		return false
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(dir, "/"), "/") {
+		for _, ignored := range ignoredSubProjectDirs {
+			if segment == ignored {
+				return false
+			}
+		}
+	}
+	for _, manifest := range subProjectManifests {
+		if fileName == manifest.fileName {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSubProjects merges the sub-projects configured as name→path with the ones detected from the manifests,
+// which are given as path→content. A detected sub-project is skipped if its directory was configured already.
+func ResolveSubProjects(repoId string, configured map[string]string, manifests map[string]string) []*code.RepoSubProject {
+	var subProjects []*code.RepoSubProject
+	paths := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, name := range sortedKeys(configured) {
+		dir := strings.Trim(configured[name], "/")
+		if name == "" || dir == "" {
+			continue
+		}
+		subProjects = append(subProjects, &code.RepoSubProject{
+			RepoId: repoId,
+			Name:   name,
+			Path:   dir,
+			Source: code.SUB_PROJECT_SOURCE_CONFIG,
+		})
+		paths[dir] = true
+		names[name] = true
+	}
+	for _, manifest := range subProjectManifests {
+		for _, filePath := range sortedKeys(manifests) {
+			dir, fileName := path.Split(filePath)
+			dir = strings.TrimSuffix(dir, "/")
+			if fileName != manifest.fileName || paths[dir] || names[dir] {
+				continue
+			}
+			subProjects = append(subProjects, &code.RepoSubProject{
+				RepoId:       repoId,
+				Name:         dir,
+				Path:         dir,
+				Source:       manifest.source,
+				ManifestName: parseManifestName(fileName, manifests[filePath]),
+			})
+			paths[dir] = true
+			names[dir] = true
+		}
+	}
+	return subProjects
+}
+
+// MatchSubProject returns the sub-project the file belongs to, the deepest one wins for nested sub-projects
+func MatchSubProject(subProjects []*code.RepoSubProject, filePath string) *code.RepoSubProject {
+	var matched *code.RepoSubProject
+	for _, subProject := range subProjects {
+		if filePath != subProject.Path && !strings.HasPrefix(filePath, subProject.Path+"/") {
+			continue
+		}
+		if matched == nil || len(subProject.Path) > len(matched.Path) {
+			matched = subProject
+		}
+	}
+	return matched
+}
+
+// AttributeCommitFiles attributes the files changed by a commit to their sub-projects, one by one, and sums
+// them up per sub-project. Files outside of the sub-projects are left out.
+func AttributeCommitFiles(repoId string, subProjects []*code.RepoSubProject, files []*code.CommitFile) ([]*code.CommitSubProject, []*code.CommitFileSubProject) {
+	var commitRows []*code.CommitSubProject
+	var fileRows []*code.CommitFileSubProject
+	bySubProject := make(map[string]*code.CommitSubProject)
+	for _, file := range files {
+		subProject := MatchSubProject(subProjects, file.FilePath)
+		if subProject == nil {
+			continue
+		}
+		fileRows = append(fileRows, &code.CommitFileSubProject{
+			CommitFileId:   file.Id,
+			RepoId:         repoId,
+			CommitSha:      file.CommitSha,
+			SubProjectName: subProject.Name,
+		})
+		row := bySubProject[subProject.Name]
+		if row == nil {
+			row = &code.CommitSubProject{
+				RepoId:         repoId,
+				CommitSha:      file.CommitSha,
+				SubProjectName: subProject.Name,
+			}
+			bySubProject[subProject.Name] = row
+			commitRows = append(commitRows, row)
+		}
+		row.FileCount++
+		row.Additions += file.Additions
+		row.Deletions += file.Deletions
+	}
+	return commitRows, fileRows
+}
+
+// parseManifestName extracts the module path of go.mod, the name of package.json or the artifactId of pom.xml
+func parseManifestName(fileName string, content string) string {
+	switch fileName {
+	case "go.mod":
+		if m := goModulePattern.FindStringSubmatch(content); m != nil {
+			return m[1]
+		}
+	case "package.json":
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal([]byte(content), &pkg) == nil {
+			return pkg.Name
+		}
+	case "pom.xml":
+		var pom struct {
+			ArtifactId string `xml:"artifactId"`
+		}
+		if xml.Unmarshal([]byte(content), &pom) == nil {
+			return pom.ArtifactId
+		}
+	}
+	return ""
+}
+
+// parseGitModulesPaths returns the paths of the submodules declared in a .gitmodules file
+func parseGitModulesPaths(content string) []string {
+	var paths []string
+	for _, m := range gitModulesPathPattern.FindAllStringSubmatch(content, -1) {
+		paths = append(paths, strings.Trim(m[1], `"`))
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,99 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSubProjectManifest(t *testing.T) {
+	assert.True(t, IsSubProjectManifest("services/api/go.mod"))
+	assert.True(t, IsSubProjectManifest("web/package.json"))
+	assert.True(t, IsSubProjectManifest("java/core/pom.xml"))
+	assert.False(t, IsSubProjectManifest("go.mod"))
+	assert.False(t, IsSubProjectManifest("web/node_modules/left-pad/package.json"))
+	assert.False(t, IsSubProjectManifest("services/api/main.go"))
+}
+
+func TestResolveSubProjects(t *testing.T) {
+	subProjects := ResolveSubProjects("repo1", map[string]string{"billing": "/services/billing/"}, map[string]string{
+		"services/billing/go.mod":   "module example.com/billing\n",
+		"services/api/go.mod":       "module example.com/api\n\ngo 1.21\n",
+		"services/api/package.json": `{"name": "api-docs"}`,
+		"web/package.json":          `{"name": "@example/web", "version": "1.0.0"}`,
+		"java/pom.xml":              `<project><parent><artifactId>parent</artifactId></parent><artifactId>java-core</artifactId></project>`,
+	})
+	assert.Equal(t, []*code.RepoSubProject{
+		{RepoId: "repo1", Name: "billing", Path: "services/billing", Source: code.SUB_PROJECT_SOURCE_CONFIG},
+		{RepoId: "repo1", Name: "services/api", Path: "services/api", Source: code.SUB_PROJECT_SOURCE_GO_MOD, ManifestName: "example.com/api"},
+		{RepoId: "repo1", Name: "web", Path: "web", Source: code.SUB_PROJECT_SOURCE_PACKAGE_JSON, ManifestName: "@example/web"},
+		{RepoId: "repo1", Name: "java", Path: "java", Source: code.SUB_PROJECT_SOURCE_POM_XML, ManifestName: "java-core"},
+	}, subProjects)
+}
+
+func TestMatchSubProject(t *testing.T) {
+	subProjects := []*code.RepoSubProject{
+		{Name: "java", Path: "java"},
+		{Name: "java/core", Path: "java/core"},
+	}
+	assert.Equal(t, "java/core", MatchSubProject(subProjects, "java/core/src/Main.java").Name)
+	assert.Equal(t, "java", MatchSubProject(subProjects, "java/pom.xml").Name)
+	assert.Nil(t, MatchSubProject(subProjects, "javascript/index.js"))
+}
+
+func TestAttributeCommitFiles(t *testing.T) {
+	subProjects := []*code.RepoSubProject{
+		{Name: "api", Path: "services/api"},
+		{Name: "web", Path: "web"},
+	}
+	// the commit spans two sub-projects and a file out of them
+	file := func(id, filePath string, additions, deletions int) *code.CommitFile {
+		f := &code.CommitFile{CommitSha: "c1", FilePath: filePath, Additions: additions, Deletions: deletions}
+		f.Id = id
+		return f
+	}
+	commitRows, fileRows := AttributeCommitFiles("repo1", subProjects, []*code.CommitFile{
+		file("c1:1", "services/api/main.go", 10, 2),
+		file("c1:2", "web/index.js", 3, 1),
+		file("c1:3", "services/api/go.mod", 1, 0),
+		file("c1:4", "README.md", 5, 5),
+	})
+	assert.Equal(t, []*code.CommitSubProject{
+		{RepoId: "repo1", CommitSha: "c1", SubProjectName: "api", FileCount: 2, Additions: 11, Deletions: 2},
+		{RepoId: "repo1", CommitSha: "c1", SubProjectName: "web", FileCount: 1, Additions: 3, Deletions: 1},
+	}, commitRows)
+	// every file goes to its own sub-project only
+	assert.Equal(t, []*code.CommitFileSubProject{
+		{CommitFileId: "c1:1", RepoId: "repo1", CommitSha: "c1", SubProjectName: "api"},
+		{CommitFileId: "c1:2", RepoId: "repo1", CommitSha: "c1", SubProjectName: "web"},
+		{CommitFileId: "c1:3", RepoId: "repo1", CommitSha: "c1", SubProjectName: "api"},
+	}, fileRows)
+}
+
+func TestParseGitModulesPaths(t *testing.T) {
+	assert.Equal(t, []string{"libs/b", "third_party/a"}, parseGitModulesPaths(`[submodule "a"]
+	path = third_party/a
+	url = https://example.com/a.git
+[submodule "b"]
+	path = libs/b
+	url = ../b.git
+`))
+}
@@ -19,6 +19,7 @@ package parser
 
 import (
 	"net/url"
+	"time"
 )
 
 type GitExtractorTaskData struct {
@@ -26,6 +27,9 @@ type GitExtractorTaskData struct {
 	ParsedURL       *url.URL
 	GitRepo         RepoCollector
 	SkipAllSubtasks bool // silently skip all tasks without raising errors
+	Incremental     bool // previously collected data are kept, only new commits need to be collected
+	// Since limits the commits to be walked when the repo was not cloned shallowly by the time, i.e. in mirror mode
+	Since *time.Time
 }
 
 type GitExtractorApiParams struct {
@@ -45,8 +49,12 @@ type GitExtractorOptions struct {
 	SkipCommitStat        *bool  `json:"skipCommitStat" mapstructure:"skipCommitStat" comment:"skip all commit stat including added/deleted lines and commit files as well"`
 	SkipCommitFiles       *bool  `json:"skipCommitFiles" mapstructure:"skipCommitFiles"`
 	NoShallowClone        bool   `json:"noShallowClone" mapstructure:"noShallowClone"`
+	ParseCommitMessages   bool   `json:"parseCommitMessages" mapstructure:"parseCommitMessages" comment:"parse Conventional Commits headers and git trailers of commit messages"`
 	ConnectionId          uint64 `json:"connectionId" mapstructure:"connectionId,omitempty"`
 	PluginName            string `json:"pluginName" mapstructure:"pluginName,omitempty"`
 	// Configured by upstream plugin (e.g., GitLab) to exclude file extensions from commit stats
 	ExcludeFileExtensions []string `json:"excludeFileExtensions" mapstructure:"excludeFileExtensions"`
+	// Configured by upstream plugin (e.g., GitHub) to define the sub-projects of a monorepo as name→directory
+	SubProjects       map[string]string `json:"subProjects" mapstructure:"subProjects"`
+	DetectSubProjects bool              `json:"detectSubProjects" mapstructure:"detectSubProjects" comment:"detect sub-projects from go.mod, package.json and pom.xml files"`
 }
@@ -71,6 +71,11 @@ func (w *csvWriter) Write(item interface{}) errors.Error {
 	return errors.Convert(w.w.Write(record))
 }
 
+func (w *csvWriter) Flush() errors.Error {
+	w.w.Flush()
+	return errors.Convert(w.w.Error())
+}
+
 func (w *csvWriter) Close() errors.Error {
 	w.w.Flush()
 	return errors.Convert(w.f.Close())
@@ -86,6 +91,9 @@ type CsvStore struct {
 	commitFileComponentWriter *csvWriter
 	commitLineChangeWriter    *csvWriter
 	snapshotWriter            *csvWriter
+	commitTrailerWriter       *csvWriter
+	classificationWriter      *csvWriter
+	submoduleUpdateWriter     *csvWriter
 }
 
 func NewCsvStore(dir string) (*CsvStore, errors.Error) {
@@ -129,6 +137,18 @@ func NewCsvStore(dir string) (*CsvStore, errors.Error) {
 	if err != nil {
 		return nil, errors.Convert(err)
 	}
+	s.commitTrailerWriter, err = newCsvWriter(filepath.Join(dir, "commit_trailers.csv"), code.CommitTrailer{})
+	if err != nil {
+		return nil, errors.Convert(err)
+	}
+	s.classificationWriter, err = newCsvWriter(filepath.Join(dir, "commit_classifications.csv"), code.CommitClassification{})
+	if err != nil {
+		return nil, errors.Convert(err)
+	}
+	s.submoduleUpdateWriter, err = newCsvWriter(filepath.Join(dir, "submodule_updates.csv"), code.SubmoduleUpdate{})
+	if err != nil {
+		return nil, errors.Convert(err)
+	}
 	return s, nil
 }
 
@@ -174,6 +194,49 @@ func (c *CsvStore) CommitParents(pp []*code.CommitParent) errors.Error {
 	return nil
 }
 
+func (c *CsvStore) CommitTrailers(trailers []*code.CommitTrailer) errors.Error {
+	for _, trailer := range trailers {
+		err := c.commitTrailerWriter.Write(trailer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CsvStore) CommitClassification(classification *code.CommitClassification) errors.Error {
+	return c.classificationWriter.Write(classification)
+}
+
+func (c *CsvStore) SubmoduleUpdates(update *code.SubmoduleUpdate) errors.Error {
+	return c.submoduleUpdateWriter.Write(update)
+}
+
+func (c *CsvStore) Flush() errors.Error {
+	writers := []*csvWriter{
+		c.repoCommitWriter,
+		c.commitWriter,
+		c.refWriter,
+		c.commitFileWriter,
+		c.commitParentWriter,
+		c.snapshotWriter,
+		c.commitFileComponentWriter,
+		c.commitLineChangeWriter,
+		c.commitTrailerWriter,
+		c.classificationWriter,
+		c.submoduleUpdateWriter,
+	}
+	for _, w := range writers {
+		if w == nil {
+			continue
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *CsvStore) Close() errors.Error {
 	if c.repoCommitWriter != nil {
 		c.repoCommitWriter.Close()
@@ -199,5 +262,14 @@ func (c *CsvStore) Close() errors.Error {
 	if c.commitLineChangeWriter != nil {
 		c.commitLineChangeWriter.Close()
 	}
+	if c.commitTrailerWriter != nil {
+		c.commitTrailerWriter.Close()
+	}
+	if c.classificationWriter != nil {
+		c.classificationWriter.Close()
+	}
+	if c.submoduleUpdateWriter != nil {
+		c.submoduleUpdateWriter.Close()
+	}
 	return nil
 }
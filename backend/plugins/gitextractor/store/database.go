@@ -160,6 +160,62 @@ func (d *Database) CommitParents(pp []*code.CommitParent) errors.Error {
 	return nil
 }
 
+func (d *Database) CommitTrailers(trailers []*code.CommitTrailer) errors.Error {
+	if len(trailers) == 0 {
+		return nil
+	}
+	batch, err := d.driver.ForType(reflect.TypeOf(trailers[0]))
+	if err != nil {
+		return err
+	}
+	for _, trailer := range trailers {
+		// co-authors might never author a commit themselves, make sure their accounts exist
+		if trailer.Key == "Co-authored-by" && trailer.AccountId != "" {
+			account := &crossdomain.Account{
+				DomainEntity: domainlayer.DomainEntity{Id: trailer.AccountId},
+				Email:        trailer.Email,
+				FullName:     trailer.Name,
+				UserName:     trailer.Name,
+			}
+			accountBatch, err := d.driver.ForType(reflect.TypeOf(account))
+			if err != nil {
+				return err
+			}
+			d.updateRawDataFields(&account.RawDataOrigin)
+			if err = accountBatch.Add(account); err != nil {
+				return err
+			}
+		}
+		d.updateRawDataFields(&trailer.RawDataOrigin)
+		if err = batch.Add(trailer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Database) CommitClassification(classification *code.CommitClassification) errors.Error {
+	batch, err := d.driver.ForType(reflect.TypeOf(classification))
+	if err != nil {
+		return err
+	}
+	d.updateRawDataFields(&classification.RawDataOrigin)
+	return batch.Add(classification)
+}
+
+func (d *Database) SubmoduleUpdates(update *code.SubmoduleUpdate) errors.Error {
+	batch, err := d.driver.ForType(reflect.TypeOf(update))
+	if err != nil {
+		return err
+	}
+	d.updateRawDataFields(&update.RawDataOrigin)
+	return batch.Add(update)
+}
+
+func (d *Database) Flush() errors.Error {
+	return d.driver.Flush()
+}
+
 func (d *Database) Close() errors.Error {
 	return d.driver.Close()
 }
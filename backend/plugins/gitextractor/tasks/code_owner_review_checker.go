@@ -0,0 +1,249 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/parser"
+)
+
+var CheckCodeOwnerReviewsMeta = plugin.SubTaskMeta{
+	Name:             "Check Code Owner Reviews",
+	EntryPoint:       CheckCodeOwnerReviews,
+	EnabledByDefault: false,
+	Description:      "check whether merged pull requests touching owned paths were reviewed by one of the code owners",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE},
+	Dependencies:     []*plugin.SubTaskMeta{&CollectGitCodeOwnersMeta},
+}
+
+// prFile is a file changed by one of the commits of a pull request
+type prFile struct {
+	PullRequestId string
+	FilePath      string
+}
+
+// prReviewer is a reviewer of a pull request, along with the identities a CODEOWNERS owner could refer to
+type prReviewer struct {
+	PullRequestId string
+	ReviewerId    string
+	Name          string
+	UserName      string
+	Email         string
+	UserId        string
+}
+
+// teamMember is the membership of a user in a team
+type teamMember struct {
+	UserId    string
+	TeamName  string
+	TeamAlias string
+}
+
+// CheckCodeOwnerReviews matches the files changed by every merged pull request of the repo against the CODEOWNERS
+// rules, and records for each rule whether one of its owners reviewed the pull request. Owners are matched by
+// username (`@user`), by email, or by membership in a team whose name or alias equals the team slug (`@org/team`).
+func CheckCodeOwnerReviews(subTaskCtx plugin.SubTaskContext) errors.Error {
+	taskData := subTaskCtx.GetData().(*parser.GitExtractorTaskData)
+	if taskData.SkipAllSubtasks {
+		return nil
+	}
+	db := subTaskCtx.GetDal()
+	logger := subTaskCtx.GetLogger()
+	repoId := taskData.Options.RepoId
+
+	// Clear previous results of the repo
+	err := db.Delete(&code.PullRequestCodeOwnerReview{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous pull_request_code_owner_reviews")
+	}
+
+	var ruleRows []code.CodeOwnerRule
+	err = db.All(&ruleRows, dal.Where("repo_id = ?", repoId), dal.Orderby("line_no, owner"))
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch code_owner_rules")
+	}
+	rules := buildCodeOwnersRules(ruleRows)
+	if len(rules) == 0 {
+		logger.Info("no CODEOWNERS rules found for repo %s", repoId)
+		return nil
+	}
+
+	var files []prFile
+	err = db.All(
+		&files,
+		dal.Select("DISTINCT prc.pull_request_id, cf.file_path"),
+		dal.From("pull_request_commits prc"),
+		dal.Join("JOIN pull_requests pr ON pr.id = prc.pull_request_id"),
+		dal.Join("JOIN commit_files cf ON cf.commit_sha = prc.commit_sha"),
+		dal.Where("pr.base_repo_id = ? AND pr.merged_date IS NOT NULL", repoId),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch files of merged pull requests")
+	}
+	var reviewers []prReviewer
+	err = db.All(
+		&reviewers,
+		dal.Select("prr.pull_request_id, prr.reviewer_id, prr.name, prr.user_name, a.email, ua.user_id"),
+		dal.From("pull_request_reviewers prr"),
+		dal.Join("JOIN pull_requests pr ON pr.id = prr.pull_request_id"),
+		dal.Join("LEFT JOIN accounts a ON a.id = prr.reviewer_id"),
+		dal.Join("LEFT JOIN user_accounts ua ON ua.account_id = prr.reviewer_id"),
+		dal.Where("pr.base_repo_id = ? AND pr.merged_date IS NOT NULL", repoId),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch reviewers of merged pull requests")
+	}
+	var members []teamMember
+	err = db.All(
+		&members,
+		dal.Select("tu.user_id, t.name AS team_name, t.alias AS team_alias"),
+		dal.From("team_users tu"),
+		dal.Join("JOIN teams t ON t.id = tu.team_id"),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch team_users")
+	}
+	teams := make(map[string][]string)
+	for _, m := range members {
+		for _, name := range []string{m.TeamName, m.TeamAlias} {
+			if name != "" {
+				teams[m.UserId] = append(teams[m.UserId], strings.ToLower(name))
+			}
+		}
+	}
+
+	reviewersByPr := make(map[string][]prReviewer)
+	for _, r := range reviewers {
+		reviewersByPr[r.PullRequestId] = append(reviewersByPr[r.PullRequestId], r)
+	}
+	results := make(map[string]map[int]*code.PullRequestCodeOwnerReview)
+	for _, file := range files {
+		rule := parser.MatchCodeOwners(rules, file.FilePath)
+		if rule == nil || len(rule.Owners) == 0 {
+			continue
+		}
+		prResults := results[file.PullRequestId]
+		if prResults == nil {
+			prResults = make(map[int]*code.PullRequestCodeOwnerReview)
+			results[file.PullRequestId] = prResults
+		}
+		if result, ok := prResults[rule.LineNo]; ok {
+			result.FileCount++
+			continue
+		}
+		var ownerReviewers []string
+		for _, r := range reviewersByPr[file.PullRequestId] {
+			if isCodeOwner(rule.Owners, r, teams[r.UserId]) {
+				name := r.UserName
+				if name == "" {
+					name = r.Name
+				}
+				ownerReviewers = append(ownerReviewers, name)
+			}
+		}
+		prResults[rule.LineNo] = &code.PullRequestCodeOwnerReview{
+			PullRequestId:  file.PullRequestId,
+			RuleLineNo:     rule.LineNo,
+			RepoId:         repoId,
+			Pattern:        rule.Pattern,
+			Owners:         strings.Join(rule.Owners, " "),
+			FileCount:      1,
+			OwnerReviewed:  len(ownerReviewers) > 0,
+			OwnerReviewers: strings.Join(ownerReviewers, ","),
+		}
+	}
+
+	batch, err := api.NewBatchSave(subTaskCtx, reflect.TypeOf(&code.PullRequestCodeOwnerReview{}), 500)
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+	unreviewed := 0
+	for _, prResults := range results {
+		for _, result := range prResults {
+			if !result.OwnerReviewed {
+				unreviewed++
+			}
+			if err = batch.Add(result); err != nil {
+				return err
+			}
+		}
+	}
+	logger.Info("%d merged pull requests touched owned paths, %d rules were not reviewed by an owner", len(results), unreviewed)
+	return batch.Flush()
+}
+
+// buildCodeOwnersRules rebuilds the CODEOWNERS rules from the rows of code_owner_rules, in the order of the file
+func buildCodeOwnersRules(rows []code.CodeOwnerRule) []*parser.CodeOwnersRule {
+	owners := make(map[int][]string)
+	patterns := make(map[int]string)
+	for _, row := range rows {
+		patterns[row.LineNo] = row.Pattern
+		if row.Owner != "" {
+			owners[row.LineNo] = append(owners[row.LineNo], row.Owner)
+		}
+	}
+	lineNos := make([]int, 0, len(patterns))
+	for lineNo := range patterns {
+		lineNos = append(lineNos, lineNo)
+	}
+	sort.Ints(lineNos)
+	rules := make([]*parser.CodeOwnersRule, 0, len(lineNos))
+	for _, lineNo := range lineNos {
+		rule, err := parser.NewCodeOwnersRule(lineNo, patterns[lineNo], owners[lineNo])
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isCodeOwner tells whether the reviewer is one of the owners, teams being the lowercase names of the reviewer's teams
+func isCodeOwner(owners []string, reviewer prReviewer, teams []string) bool {
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") {
+			if reviewer.Email != "" && strings.EqualFold(owner, reviewer.Email) {
+				return true
+			}
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(owner, "@"))
+		if slash := strings.Index(name, "/"); slash >= 0 {
+			slug := name[slash+1:]
+			for _, team := range teams {
+				if team == slug || team == name {
+					return true
+				}
+			}
+			continue
+		}
+		if reviewer.UserName != "" && strings.ToLower(reviewer.UserName) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/parser"
+)
+
+const defaultComponentName = "Default"
+
+var CalculateOwnershipMeta = plugin.SubTaskMeta{
+	Name:             "Calculate Ownership",
+	EntryPoint:       CalculateOwnership,
+	EnabledByDefault: false,
+	Description:      "calculate file and component ownership and bus factor from the blame data of repo_snapshot",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE},
+	Dependencies:     []*plugin.SubTaskMeta{&CollectGitDiffLineMeta},
+}
+
+// authorLines is the number of lines of a file last touched by an author
+type authorLines struct {
+	FilePath   string
+	AuthorId   string
+	AuthorName string
+	LineCount  int
+}
+
+// accountOwner is the user an author account was merged into
+type accountOwner struct {
+	AccountId string
+	UserId    string
+	UserName  string
+}
+
+type ownerLines struct {
+	id    string
+	name  string
+	lines int
+}
+
+// CalculateOwnership aggregates the blame of the latest repo snapshot into lines authored per owner, for every
+// file and for every component defined in the components table. Authors are resolved to users through
+// user_accounts when possible, so the lines of a person committing with several emails are counted together.
+func CalculateOwnership(subTaskCtx plugin.SubTaskContext) errors.Error {
+	taskData := subTaskCtx.GetData().(*parser.GitExtractorTaskData)
+	if taskData.SkipAllSubtasks || *taskData.Options.SkipCommitStat {
+		return nil
+	}
+	db := subTaskCtx.GetDal()
+	logger := subTaskCtx.GetLogger()
+	repoId := taskData.Options.RepoId
+
+	var blames []authorLines
+	err := db.All(
+		&blames,
+		dal.Select("rs.file_path, c.author_id, MAX(c.author_name) AS author_name, COUNT(*) AS line_count"),
+		dal.From("repo_snapshot rs"),
+		dal.Join("JOIN commits c ON c.sha = rs.commit_sha"),
+		dal.Where("rs.repo_id = ?", repoId),
+		dal.Groupby("rs.file_path, c.author_id"),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to aggregate repo_snapshot")
+	}
+	logger.Info("calculating ownership of %d file/author pairs", len(blames))
+
+	var accountOwners []accountOwner
+	err = db.All(
+		&accountOwners,
+		dal.Select("ua.account_id, ua.user_id, u.name AS user_name"),
+		dal.From("user_accounts ua"),
+		dal.Join("LEFT JOIN users u ON u.id = ua.user_id"),
+		dal.Where(`ua.account_id IN (
+			SELECT DISTINCT c.author_id FROM repo_commits rc JOIN commits c ON c.sha = rc.commit_sha WHERE rc.repo_id = ?
+		)`, repoId),
+		dal.Orderby("ua.account_id, ua.user_id"),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch user_accounts")
+	}
+	owners := make(map[string]accountOwner, len(accountOwners))
+	for _, owner := range accountOwners {
+		// an account merged into several users is attributed to the first one only
+		if _, ok := owners[owner.AccountId]; !ok {
+			owners[owner.AccountId] = owner
+		}
+	}
+
+	var components []code.Component
+	err = db.All(&components, dal.Where("repo_id = ?", repoId), dal.Orderby("name"))
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch components")
+	}
+	componentRegexps := make([]*regexp.Regexp, len(components))
+	for i, component := range components {
+		componentRegexps[i], err = errors.Convert01(regexp.Compile(component.PathRegex))
+		if err != nil {
+			return errors.BadInput.Wrap(err, "invalid path regex of component "+component.Name)
+		}
+	}
+	componentOf := func(filePath string) string {
+		for i, reg := range componentRegexps {
+			if reg.MatchString(filePath) {
+				return components[i].Name
+			}
+		}
+		return defaultComponentName
+	}
+
+	files := make(map[string]map[string]*ownerLines)
+	componentsLines := make(map[string]map[string]*ownerLines)
+	addLines := func(scopes map[string]map[string]*ownerLines, scopeName, ownerId, ownerName string, lines int) {
+		scope := scopes[scopeName]
+		if scope == nil {
+			scope = make(map[string]*ownerLines)
+			scopes[scopeName] = scope
+		}
+		if scope[ownerId] == nil {
+			scope[ownerId] = &ownerLines{id: ownerId, name: ownerName}
+		}
+		scope[ownerId].lines += lines
+	}
+	for _, blame := range blames {
+		ownerId, ownerName := blame.AuthorId, blame.AuthorName
+		if owner, ok := owners[blame.AuthorId]; ok {
+			ownerId = owner.UserId
+			if owner.UserName != "" {
+				ownerName = owner.UserName
+			}
+		}
+		addLines(files, blame.FilePath, ownerId, ownerName, blame.LineCount)
+		addLines(componentsLines, componentOf(blame.FilePath), ownerId, ownerName, blame.LineCount)
+	}
+
+	// Clear previous results of the repo
+	err = db.Delete(&code.CodeOwnership{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous code_ownerships")
+	}
+	err = db.Delete(&code.CodeOwnershipSummary{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous code_ownership_summaries")
+	}
+
+	ownershipSave, err := api.NewBatchSave(subTaskCtx, reflect.TypeOf(&code.CodeOwnership{}), 500)
+	if err != nil {
+		return err
+	}
+	defer ownershipSave.Close()
+	summarySave, err := api.NewBatchSave(subTaskCtx, reflect.TypeOf(&code.CodeOwnershipSummary{}), 500)
+	if err != nil {
+		return err
+	}
+	defer summarySave.Close()
+	for _, scopes := range []struct {
+		scopeType string
+		lines     map[string]map[string]*ownerLines
+	}{
+		{code.OWNERSHIP_SCOPE_FILE, files},
+		{code.OWNERSHIP_SCOPE_COMPONENT, componentsLines},
+	} {
+		for scopeName, scope := range scopes.lines {
+			ownerships, summary := computeOwnership(repoId, scopes.scopeType, scopeName, scope)
+			for _, ownership := range ownerships {
+				if err = ownershipSave.Add(ownership); err != nil {
+					return err
+				}
+			}
+			if err = summarySave.Add(summary); err != nil {
+				return err
+			}
+		}
+	}
+	if err = ownershipSave.Flush(); err != nil {
+		return err
+	}
+	return summarySave.Flush()
+}
+
+// computeOwnership ranks the owners of a scope by lines, the first one being the primary owner, and counts how
+// many of the top owners are needed to cover more than half of the lines, which is the bus factor of the scope.
+func computeOwnership(repoId, scopeType, scopeName string, scope map[string]*ownerLines) ([]*code.CodeOwnership, *code.CodeOwnershipSummary) {
+	ranked := make([]*ownerLines, 0, len(scope))
+	total := 0
+	for _, owner := range scope {
+		ranked = append(ranked, owner)
+		total += owner.lines
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].lines != ranked[j].lines {
+			return ranked[i].lines > ranked[j].lines
+		}
+		return ranked[i].id < ranked[j].id
+	})
+	summary := &code.CodeOwnershipSummary{
+		RepoId:     repoId,
+		ScopeType:  scopeType,
+		ScopeKey:   code.OwnershipScopeKey(scopeName),
+		ScopeName:  scopeName,
+		TotalLines: total,
+		OwnerCount: len(ranked),
+	}
+	ownerships := make([]*code.CodeOwnership, 0, len(ranked))
+	covered := 0
+	for i, owner := range ranked {
+		share := 0.0
+		if total > 0 {
+			share = float64(owner.lines) / float64(total)
+		}
+		ownerships = append(ownerships, &code.CodeOwnership{
+			RepoId:    repoId,
+			ScopeType: scopeType,
+			ScopeKey:  summary.ScopeKey,
+			ScopeName: scopeName,
+			OwnerId:   owner.id,
+			OwnerName: owner.name,
+			Lines:     owner.lines,
+			Share:     share,
+			IsPrimary: i == 0,
+		})
+		if i == 0 {
+			summary.PrimaryOwnerId = owner.id
+			summary.PrimaryOwnerName = owner.name
+			summary.PrimaryOwnerShare = share
+		}
+		if covered*2 <= total {
+			covered += owner.lines
+			summary.BusFactor++
+		}
+	}
+	return ownerships, summary
+}
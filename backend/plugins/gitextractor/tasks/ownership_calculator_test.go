@@ -0,0 +1,77 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeOwnership(t *testing.T) {
+	ownerships, summary := computeOwnership("repo", code.OWNERSHIP_SCOPE_FILE, "main.go", map[string]*ownerLines{
+		"bob":   {id: "bob", name: "Bob", lines: 30},
+		"alice": {id: "alice", name: "Alice", lines: 50},
+		"carol": {id: "carol", name: "Carol", lines: 20},
+	})
+	if assert.Len(t, ownerships, 3) {
+		assert.Equal(t, []string{"alice", "bob", "carol"}, []string{ownerships[0].OwnerId, ownerships[1].OwnerId, ownerships[2].OwnerId})
+		assert.True(t, ownerships[0].IsPrimary)
+		assert.False(t, ownerships[1].IsPrimary)
+		assert.Equal(t, 0.3, ownerships[1].Share)
+	}
+	assert.Equal(t, 100, summary.TotalLines)
+	assert.Equal(t, 3, summary.OwnerCount)
+	assert.Equal(t, "alice", summary.PrimaryOwnerId)
+	assert.Equal(t, "Alice", summary.PrimaryOwnerName)
+	assert.Equal(t, 0.5, summary.PrimaryOwnerShare)
+	// exactly half of the lines is not more than half
+	assert.Equal(t, 2, summary.BusFactor)
+}
+
+func TestComputeOwnershipBusFactor(t *testing.T) {
+	busFactor := func(lines ...int) int {
+		scope := make(map[string]*ownerLines)
+		for i, l := range lines {
+			id := string(rune('a' + i))
+			scope[id] = &ownerLines{id: id, lines: l}
+		}
+		_, summary := computeOwnership("repo", code.OWNERSHIP_SCOPE_COMPONENT, "Default", scope)
+		return summary.BusFactor
+	}
+	assert.Equal(t, 1, busFactor(51, 49))
+	assert.Equal(t, 1, busFactor(10))
+	assert.Equal(t, 3, busFactor(25, 25, 25, 25))
+	// ties are ranked by owner id so the result is stable
+	assert.Equal(t, 2, busFactor(40, 40, 20))
+	assert.Equal(t, 0, busFactor())
+}
+
+func TestComputeOwnershipLongPath(t *testing.T) {
+	longPath := strings.Repeat("very/deep/directory/", 30) + "file.go"
+	ownerships, summary := computeOwnership("repo", code.OWNERSHIP_SCOPE_FILE, longPath, map[string]*ownerLines{
+		"alice": {id: "alice", lines: 1},
+	})
+	assert.Equal(t, longPath, summary.ScopeName)
+	assert.Len(t, summary.ScopeKey, 64)
+	assert.Equal(t, code.OwnershipScopeKey(longPath), summary.ScopeKey)
+	assert.Equal(t, summary.ScopeKey, ownerships[0].ScopeKey)
+	assert.NotEqual(t, code.OwnershipScopeKey("other.go"), summary.ScopeKey)
+}
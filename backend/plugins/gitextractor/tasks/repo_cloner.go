@@ -47,19 +47,46 @@ func CloneGitRepo(subTaskCtx plugin.SubTaskContext) errors.Error {
 	var err errors.Error
 	logger := subTaskCtx.GetLogger()
 
-	// temporary dir for cloning
-	localDir, e := os.MkdirTemp("", "gitextractor")
-	if e != nil {
-		return errors.Convert(e)
+	// fetch into the persistent mirror if enabled, or clone into a temporary dir
+	mirrorCache, err := parser.NewMirrorCache(subTaskCtx.GetConfigReader(), logger)
+	if err != nil {
+		return err
+	}
+	var mirror *parser.Mirror
+	var localDir string
+	if mirrorCache != nil {
+		mirror, err = mirrorCache.Acquire(subTaskCtx.GetContext(), parser.MirrorKey(taskData))
+		if err != nil {
+			return err
+		}
+		localDir = mirror.Dir
+	} else {
+		var e error
+		localDir, e = os.MkdirTemp("", "gitextractor")
+		if e != nil {
+			return errors.Convert(e)
+		}
+	}
+	release := func() {
+		if mirror != nil {
+			mirror.Release()
+		} else {
+			_ = os.RemoveAll(localDir)
+		}
 	}
 
 	// clone repo
 	repoCloner, err := parser.NewGitcliCloner(subTaskCtx, localDir)
 	if err != nil {
+		release()
 		return err
 	}
+	if mirror != nil {
+		repoCloner.SetMirror(mirror)
+	}
 	err = repoCloner.CloneRepo()
 	if err != nil {
+		release()
 		if errors.Is(err, parser.ErrNoData) {
 			taskData.SkipAllSubtasks = true
 			return nil
@@ -69,6 +96,7 @@ func CloneGitRepo(subTaskCtx plugin.SubTaskContext) errors.Error {
 	if repoCloner.IsIncremental() {
 		storage.SetIncrementalMode(repoCloner.IsIncremental())
 	}
+	taskData.Incremental = repoCloner.IsIncremental()
 	// We have done comparison experiments for git2go and go-git, and the results show that git2go has better performance.
 	var repoCollector parser.RepoCollector
 	if *taskData.Options.UseGoGit {
@@ -77,12 +105,13 @@ func CloneGitRepo(subTaskCtx plugin.SubTaskContext) errors.Error {
 		repoCollector, err = parser.NewLibgit2RepoCollector(localDir, op.RepoId, storage, logger)
 	}
 	if err != nil {
+		release()
 		return err
 	}
 
-	// inject clean up callback to remove the cloned dir
+	// inject clean up callback to remove the cloned dir or release the mirror
 	cleanup := func() {
-		_ = os.RemoveAll(localDir)
+		release()
 		_ = repoCloner.CloseRepo()
 	}
 	if e := repoCollector.SetCleanUp(cleanup); e != nil {
@@ -81,6 +81,22 @@ func CollectGitDiffLines(subTaskCtx plugin.SubTaskContext) errors.Error {
 	return nil
 }
 
+func CollectGitCodeOwners(subTaskCtx plugin.SubTaskContext) errors.Error {
+	if subTaskCtx.TaskContext().GetData().(*parser.GitExtractorTaskData).SkipAllSubtasks {
+		return nil
+	}
+	repo := getGitRepo(subTaskCtx)
+	return errors.Convert(repo.CollectCodeOwners(subTaskCtx))
+}
+
+func SaveGitRefStates(subTaskCtx plugin.SubTaskContext) errors.Error {
+	if subTaskCtx.TaskContext().GetData().(*parser.GitExtractorTaskData).SkipAllSubtasks {
+		return nil
+	}
+	repo := getGitRepo(subTaskCtx)
+	return errors.Convert(repo.SaveRefStates())
+}
+
 func getGitRepo(subTaskCtx plugin.SubTaskContext) parser.RepoCollector {
 	taskData, ok := subTaskCtx.GetData().(*parser.GitExtractorTaskData)
 	if !ok {
@@ -129,3 +145,22 @@ var CollectGitDiffLineMeta = plugin.SubTaskMeta{
 	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE},
 	Dependencies:     []*plugin.SubTaskMeta{&CloneGitRepoMeta},
 }
+
+var CollectGitCodeOwnersMeta = plugin.SubTaskMeta{
+	Name:             "Collect CODEOWNERS",
+	EntryPoint:       CollectGitCodeOwners,
+	EnabledByDefault: false,
+	Description:      "collect the CODEOWNERS rules of the default branch into Domain Layer Tables",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE},
+	Dependencies:     []*plugin.SubTaskMeta{&CloneGitRepoMeta},
+}
+
+var SaveGitRefStatesMeta = plugin.SubTaskMeta{
+	Name:             "Save Ref States",
+	EntryPoint:       SaveGitRefStates,
+	EnabledByDefault: true,
+	Required:         true,
+	Description:      "record the refs walked by this run so the next run collects the new commits only, it runs after all other subtasks succeeded",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE},
+	Dependencies:     []*plugin.SubTaskMeta{&CloneGitRepoMeta},
+}
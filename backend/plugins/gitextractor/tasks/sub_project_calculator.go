@@ -0,0 +1,155 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"reflect"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+	"github.com/apache/incubator-devlake/plugins/gitextractor/parser"
+)
+
+var CalculateSubProjectsMeta = plugin.SubTaskMeta{
+	Name:             "Calculate Sub-Projects",
+	EntryPoint:       CalculateSubProjects,
+	EnabledByDefault: true,
+	Description:      "resolve the sub-projects of a monorepo and attribute the files changed by the commits to them, requires commit files. Diff lines and refdiff results are attributed per file through commit_file_sub_projects",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE},
+	Dependencies:     []*plugin.SubTaskMeta{&CollectGitCommitMeta},
+}
+
+// CalculateSubProjects stores the sub-projects configured for the repo or detected from its manifests, then
+// attributes every file changed by the commits of the repo to its sub-project, and sums them up per commit
+func CalculateSubProjects(subTaskCtx plugin.SubTaskContext) errors.Error {
+	taskData := subTaskCtx.GetData().(*parser.GitExtractorTaskData)
+	if taskData.SkipAllSubtasks {
+		return nil
+	}
+	opts := taskData.Options
+	if len(opts.SubProjects) == 0 && !opts.DetectSubProjects {
+		return nil
+	}
+	db := subTaskCtx.GetDal()
+	logger := subTaskCtx.GetLogger()
+	repoId := opts.RepoId
+
+	manifests := make(map[string]string)
+	if opts.DetectSubProjects {
+		var err errors.Error
+		manifests, err = errors.Convert01(getGitRepo(subTaskCtx).ReadHeadFiles(subTaskCtx.GetContext(), parser.IsSubProjectManifest))
+		if err != nil {
+			return errors.Default.Wrap(err, "failed to read sub-project manifests")
+		}
+	}
+	subProjects := parser.ResolveSubProjects(repoId, opts.SubProjects, manifests)
+	logger.Info("found %d sub-projects in repo %s", len(subProjects), repoId)
+
+	// Clear previous results of the repo
+	err := db.Delete(&code.RepoSubProject{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous repo_sub_projects")
+	}
+	err = db.Delete(&code.CommitSubProject{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous commit_sub_projects")
+	}
+	err = db.Delete(&code.CommitFileSubProject{}, dal.Where("repo_id = ?", repoId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting previous commit_file_sub_projects")
+	}
+	if len(subProjects) == 0 {
+		return nil
+	}
+
+	subProjectSave, err := api.NewBatchSave(subTaskCtx, reflect.TypeOf(&code.RepoSubProject{}), 500)
+	if err != nil {
+		return err
+	}
+	defer subProjectSave.Close()
+	for _, subProject := range subProjects {
+		if err = subProjectSave.Add(subProject); err != nil {
+			return err
+		}
+	}
+	if err = subProjectSave.Flush(); err != nil {
+		return err
+	}
+
+	cursor, err := db.Cursor(
+		dal.Select("cf.id, cf.commit_sha, cf.file_path, cf.additions, cf.deletions"),
+		dal.From("commit_files cf"),
+		dal.Join("JOIN repo_commits rc ON rc.commit_sha = cf.commit_sha"),
+		dal.Where("rc.repo_id = ?", repoId),
+		dal.Orderby("cf.commit_sha"),
+	)
+	if err != nil {
+		return errors.Default.Wrap(err, "failed to fetch commit_files")
+	}
+	defer cursor.Close()
+
+	commitSave, err := api.NewBatchSave(subTaskCtx, reflect.TypeOf(&code.CommitSubProject{}), 500)
+	if err != nil {
+		return err
+	}
+	defer commitSave.Close()
+	fileSave, err := api.NewBatchSave(subTaskCtx, reflect.TypeOf(&code.CommitFileSubProject{}), 500)
+	if err != nil {
+		return err
+	}
+	defer fileSave.Close()
+	// commit files are ordered by commit, so the rows of a commit are saved once its last file is read
+	var files []*code.CommitFile
+	saveCommit := func() errors.Error {
+		commitRows, fileRows := parser.AttributeCommitFiles(repoId, subProjects, files)
+		for _, row := range commitRows {
+			if err := commitSave.Add(row); err != nil {
+				return err
+			}
+		}
+		for _, row := range fileRows {
+			if err := fileSave.Add(row); err != nil {
+				return err
+			}
+		}
+		files = nil
+		return nil
+	}
+	for cursor.Next() {
+		file := &code.CommitFile{}
+		if err = db.Fetch(cursor, file); err != nil {
+			return err
+		}
+		if len(files) > 0 && files[0].CommitSha != file.CommitSha {
+			if err = saveCommit(); err != nil {
+				return err
+			}
+		}
+		files = append(files, file)
+	}
+	if err = saveCommit(); err != nil {
+		return err
+	}
+	if err = commitSave.Flush(); err != nil {
+		return err
+	}
+	return fileSave.Flush()
+}
@@ -57,7 +57,8 @@ func MakeDataSourcePipelinePlanV200(
 	}
 
 	// needed for the connection to populate its access tokens
-	// if AppKey authentication method is selected
+	// if AppKey authentication method is selected, secretref:// tokens are
+	// kept in the clone urls and resolved by GetDynamicGitUrl before cloning
 	_, err = helper.NewApiClientFromConnection(context.TODO(), basicRes, connection)
 	if err != nil {
 		return nil, nil, err
@@ -125,18 +126,25 @@ func makeDataSourcePipelinePlanV200(
 			}
 			token := strings.Split(connection.Token, ",")[0]
 			cloneUrl.User = url.UserPassword("git", token)
+			gitextOpts := map[string]interface{}{
+				"url":                   cloneUrl.String(),
+				"name":                  githubRepo.FullName,
+				"fullName":              githubRepo.FullName,
+				"repoId":                didgen.NewDomainIdGenerator(&models.GithubRepo{}).Generate(connection.ID, githubRepo.GithubId),
+				"proxy":                 connection.Proxy,
+				"connectionId":          githubRepo.ConnectionId,
+				"pluginName":            "github",
+				"excludeFileExtensions": scopeConfig.PrSizeExcludedFileExtensions,
+			}
+			if len(scopeConfig.SubProjects) > 0 {
+				gitextOpts["subProjects"] = scopeConfig.SubProjects
+			}
+			if scopeConfig.DetectSubProjects {
+				gitextOpts["detectSubProjects"] = true
+			}
 			stage = append(stage, &coreModels.PipelineTask{
-				Plugin: "gitextractor",
-				Options: map[string]interface{}{
-					"url":                   cloneUrl.String(),
-					"name":                  githubRepo.FullName,
-					"fullName":              githubRepo.FullName,
-					"repoId":                didgen.NewDomainIdGenerator(&models.GithubRepo{}).Generate(connection.ID, githubRepo.GithubId),
-					"proxy":                 connection.Proxy,
-					"connectionId":          githubRepo.ConnectionId,
-					"pluginName":            "github",
-					"excludeFileExtensions": scopeConfig.PrSizeExcludedFileExtensions,
-				},
+				Plugin:  "gitextractor",
+				Options: gitextOpts,
 			})
 
 		}
@@ -257,6 +257,12 @@ func (p Github) GetDynamicGitUrl(taskCtx plugin.TaskContext, connectionId uint64
 		return "", errors.Default.Wrap(err, "unable to get github connection by the given connection ID")
 	}
 
+	// the plan keeps the secretref:// tokens, they are resolved right before cloning
+	connection, err = helper.ResolveSecretRefs(connection)
+	if err != nil {
+		return "", err
+	}
+
 	apiClient, err := helper.NewApiClient(taskCtx.GetContext(), connection.GetEndpoint(), nil, 0, connection.GetProxy(), taskCtx)
 	if err != nil {
 		return "", err
@@ -41,6 +41,8 @@ type GithubScopeConfig struct {
 	EnvNamePattern               string            `mapstructure:"envNamePattern,omitempty" json:"envNamePattern" gorm:"type:varchar(255)"`
 	Refdiff                      datatypes.JSONMap `mapstructure:"refdiff,omitempty" json:"refdiff" swaggertype:"object" format:"json"`
 	PrSizeExcludedFileExtensions []string          `mapstructure:"prSizeExcludedFileExtensions" json:"prSizeExcludedFileExtensions" gorm:"type:json;serializer:json"`
+	SubProjects                  map[string]string `mapstructure:"subProjects" json:"subProjects" gorm:"type:json;serializer:json"`
+	DetectSubProjects            bool              `mapstructure:"detectSubProjects" json:"detectSubProjects"`
 }
 
 // GetConnectionId implements plugin.ToolLayerScopeConfig.
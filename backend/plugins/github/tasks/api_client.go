@@ -30,6 +30,12 @@ import (
 )
 
 func CreateApiClient(taskCtx plugin.TaskContext, connection *models.GithubConnection) (*api.ApiAsyncClient, errors.Error) {
+	// secretref:// references are resolved on a copy, so only the tokens minted
+	// or refreshed below get persisted, never the secrets they refer to
+	connection, err := api.ResolveSecretRefs(connection)
+	if err != nil {
+		return nil, err
+	}
 	apiClient, err := api.NewApiClientFromConnection(taskCtx.GetContext(), taskCtx, connection)
 	if err != nil {
 		return nil, err
@@ -63,7 +69,7 @@ func CreateApiClient(taskCtx plugin.TaskContext, connection *models.GithubConnec
 	// PrepareApiClient (called by NewApiClientFromConnection) mints the token
 	// in-memory but does not persist it; without this, the DB may contain a stale
 	// or corrupted token that breaks GET /connections.
-	if connection.AuthMethod == models.AppKey && connection.Token != "" {
+	if connection.AuthMethod == models.AppKey && connection.InstallationID != 0 && connection.Token != "" {
 		if err := token.PersistEncryptedTokenColumns(db, connection, encryptionSecret, logger, false); err != nil {
 			logger.Warn(err, "Failed to persist initial token for connection %d", connection.ID)
 		} else {
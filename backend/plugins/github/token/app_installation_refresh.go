@@ -121,7 +121,8 @@ func persistAppToken(d dal.Dal, conn *models.GithubConnection, encryptionSecret
 // If includeRefreshToken is true, refresh_token and refresh_token_expires_at
 // are also written (used by the OAuth refresh path where these values are valid).
 func PersistEncryptedTokenColumns(d dal.Dal, conn *models.GithubConnection, encryptionSecret string, logger log.Logger, includeRefreshToken bool) errors.Error {
-	encToken, err := plugin.Encrypt(encryptionSecret, conn.Token)
+	keyring := plugin.NewEncryptionKeyring(encryptionSecret)
+	encToken, err := keyring.Encrypt(conn.Token)
 	if err != nil {
 		return errors.Default.Wrap(err, "failed to encrypt token for persistence")
 	}
@@ -132,7 +133,7 @@ func PersistEncryptedTokenColumns(d dal.Dal, conn *models.GithubConnection, encr
 	}
 
 	if includeRefreshToken {
-		encRefreshToken, err := plugin.Encrypt(encryptionSecret, conn.RefreshToken)
+		encRefreshToken, err := keyring.Encrypt(conn.RefreshToken)
 		if err != nil {
 			return errors.Default.Wrap(err, "failed to encrypt refresh_token for persistence")
 		}
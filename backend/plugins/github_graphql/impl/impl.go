@@ -169,6 +169,11 @@ func (p GithubGraphql) PrepareTaskData(taskCtx plugin.TaskContext, options map[s
 	if err != nil {
 		return nil, errors.Default.Wrap(err, "unable to get github connection by the given connection ID: %v")
 	}
+	// the graphql client below needs the resolved tokens as well
+	connection, err = helper.ResolveSecretRefs(connection)
+	if err != nil {
+		return nil, err
+	}
 
 	apiClient, err := githubTasks.CreateApiClient(taskCtx, connection)
 	if err != nil {
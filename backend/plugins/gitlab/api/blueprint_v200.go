@@ -151,6 +151,12 @@ func makePipelinePlanV200(
 				// pass excluded file extensions to gitextractor to support PR Size exclusion
 				gitextOpts["excludeFileExtensions"] = scopeConfig.PrSizeExcludedFileExtensions
 			}
+			if len(scopeConfig.SubProjects) > 0 {
+				gitextOpts["subProjects"] = scopeConfig.SubProjects
+			}
+			if scopeConfig.DetectSubProjects {
+				gitextOpts["detectSubProjects"] = true
+			}
 			stage = append(stage, &coreModels.PipelineTask{
 				Plugin:  "gitextractor",
 				Options: gitextOpts,
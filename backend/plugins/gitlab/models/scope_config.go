@@ -39,6 +39,9 @@ type GitlabScopeConfig struct {
 	Refdiff              datatypes.JSONMap `mapstructure:"refdiff,omitempty" json:"refdiff" swaggertype:"object" format:"json"`
 	// A list of file extensions to exclude when calculating PR Size (affects commit additions/deletions used by dashboards)
 	PrSizeExcludedFileExtensions []string `mapstructure:"prSizeExcludedFileExtensions" json:"prSizeExcludedFileExtensions" gorm:"type:json;serializer:json"`
+	// Sub-projects of a monorepo as name→directory, and whether to detect them from go.mod, package.json and pom.xml
+	SubProjects       map[string]string `mapstructure:"subProjects" json:"subProjects" gorm:"type:json;serializer:json"`
+	DetectSubProjects bool              `mapstructure:"detectSubProjects" json:"detectSubProjects"`
 }
 
 func (t GitlabScopeConfig) TableName() string {
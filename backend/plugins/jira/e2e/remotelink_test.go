@@ -20,6 +20,7 @@ package e2e
 import (
 	"testing"
 
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
 	"github.com/apache/incubator-devlake/helpers/e2ehelper"
 	"github.com/apache/incubator-devlake/plugins/jira/impl"
 	"github.com/apache/incubator-devlake/plugins/jira/models"
@@ -76,4 +77,19 @@ func TestRemotelinkDataFlow(t *testing.T) {
 			"commit_url",
 		),
 	)
+
+	// verify remotelink conversion, the links of the issues on other boards are left out
+	dataflowTester.FlushTabler(&ticket.IssueRemoteLink{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/_tool_jira_board_issues_for_remotelink.csv", &models.JiraBoardIssue{})
+	dataflowTester.Subtask(tasks.ConvertRemotelinksMeta, taskData)
+	dataflowTester.VerifyTable(
+		ticket.IssueRemoteLink{},
+		"./snapshot_tables/issue_remote_links.csv",
+		e2ehelper.ColumnWithRawData(
+			"id",
+			"issue_id",
+			"url",
+			"title",
+		),
+	)
 }
@@ -150,6 +150,7 @@ func (p Jira) SubTaskMetas() []plugin.SubTaskMeta {
 		tasks.ConvertWorklogsMeta,
 		tasks.ConvertIssueChangelogsMeta,
 		tasks.ConvertIssueRelationshipsMeta,
+		tasks.ConvertRemotelinksMeta,
 
 		tasks.ConvertSprintsMeta,
 		tasks.ConvertSprintIssuesMeta,
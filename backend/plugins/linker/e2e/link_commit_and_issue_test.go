@@ -0,0 +1,127 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	"github.com/apache/incubator-devlake/helpers/e2ehelper"
+	"github.com/apache/incubator-devlake/plugins/linker/impl"
+	"github.com/apache/incubator-devlake/plugins/linker/tasks"
+)
+
+func TestLinkCommitToIssue(t *testing.T) {
+	var plugin impl.Linker
+	dataflowTester := e2ehelper.NewDataFlowTester(t, "linker", plugin)
+
+	regexpStr := "#(\\d+)"
+	taskData := &tasks.LinkerTaskData{
+		Options: &tasks.LinkerOptions{
+			PrToIssueRegexp: regexpStr,
+			ProjectName:     "GitHub1",
+		},
+		PrToIssueRegexp: regexp.MustCompile(regexpStr),
+	}
+
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/issues.csv", &ticket.Issue{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/project_mapping.csv", &crossdomain.ProjectMapping{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/board_issues.csv", &ticket.BoardIssue{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/repos.csv", &code.Repo{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/commits.csv", &code.Commit{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/repo_commits.csv", &code.RepoCommit{})
+
+	dataflowTester.FlushTabler(&crossdomain.IssueCommit{})
+	dataflowTester.FlushTabler(&crossdomain.IssueRepoCommit{})
+	dataflowTester.Subtask(tasks.LinkCommitToIssueMeta, taskData)
+	dataflowTester.VerifyTable(
+		crossdomain.IssueCommit{},
+		"./snapshot_tables/issue_commits_linked.csv",
+		[]string{
+			"issue_id",
+			"commit_sha",
+			"_raw_data_remark",
+		},
+	)
+	dataflowTester.VerifyTable(
+		crossdomain.IssueRepoCommit{},
+		"./snapshot_tables/issue_repo_commits_linked.csv",
+		[]string{
+			"issue_id",
+			"repo_url",
+			"commit_sha",
+			"host",
+			"namespace",
+			"repo_name",
+			"_raw_data_remark",
+		},
+	)
+}
+
+func TestLinkCommitToIssueWithDefaultRegexp(t *testing.T) {
+	var plugin impl.Linker
+	dataflowTester := e2ehelper.NewDataFlowTester(t, "linker", plugin)
+
+	// the regexp config-ui fills the project settings with
+	regexpStr := `(?mi)(Closes)[\s]*.*(((and )?#\d+[ ]*)+)`
+	taskData := &tasks.LinkerTaskData{
+		Options: &tasks.LinkerOptions{
+			PrToIssueRegexp: regexpStr,
+			ProjectName:     "GitHub1",
+		},
+		PrToIssueRegexp: regexp.MustCompile(regexpStr),
+	}
+
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/issues.csv", &ticket.Issue{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/project_mapping.csv", &crossdomain.ProjectMapping{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/board_issues.csv", &ticket.BoardIssue{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/repos.csv", &code.Repo{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/commits_closing.csv", &code.Commit{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/repo_commits.csv", &code.RepoCommit{})
+
+	// the commit closes #1884 and #1886, #1884 being linked to it by the Jira development panel already
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/issue_commits.csv", &crossdomain.IssueCommit{})
+	dataflowTester.FlushTabler(&crossdomain.IssueRepoCommit{})
+	dataflowTester.Subtask(tasks.LinkCommitToIssueMeta, taskData)
+	dataflowTester.VerifyTable(
+		crossdomain.IssueCommit{},
+		"./snapshot_tables/issue_commits_closing_linked.csv",
+		[]string{
+			"issue_id",
+			"commit_sha",
+			"_raw_data_table",
+			"_raw_data_remark",
+		},
+	)
+	dataflowTester.VerifyTable(
+		crossdomain.IssueRepoCommit{},
+		"./snapshot_tables/issue_repo_commits_closing_linked.csv",
+		[]string{
+			"issue_id",
+			"repo_url",
+			"commit_sha",
+			"host",
+			"namespace",
+			"repo_name",
+			"_raw_data_remark",
+		},
+	)
+}
@@ -52,6 +52,9 @@ func TestLinkPrToIssueWithSharedRepo(t *testing.T) {
 	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/cross_project_project_mapping.csv", &crossdomain.ProjectMapping{})
 	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/cross_project_board_issues.csv", &ticket.BoardIssue{})
 
+	dataflowTester.FlushTabler(&code.PullRequestCommit{})
+	dataflowTester.FlushTabler(&crossdomain.IssueCommit{})
+
 	// Pre-populate pull_request_issues with data from GitHub2's linker and from GitHub converter.
 	// These rows must survive when we run the linker for GitHub1.
 	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/cross_project_pull_request_issues_before.csv", &crossdomain.PullRequestIssue{})
@@ -93,6 +96,8 @@ func TestLinkPrToIssue(t *testing.T) {
 	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/project_mapping.csv", &crossdomain.ProjectMapping{})
 	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/board_issues.csv", &ticket.BoardIssue{})
 
+	dataflowTester.FlushTabler(&code.PullRequestCommit{})
+	dataflowTester.FlushTabler(&crossdomain.IssueCommit{})
 	dataflowTester.FlushTabler(&crossdomain.PullRequestIssue{})
 	dataflowTester.Subtask(tasks.LinkPrToIssueMeta, taskData)
 	dataflowTester.VerifyTable(
@@ -111,3 +116,54 @@ func TestLinkPrToIssue(t *testing.T) {
 	)
 
 }
+
+func TestLinkPrToIssueWithStrategies(t *testing.T) {
+	var plugin impl.Linker
+	dataflowTester := e2ehelper.NewDataFlowTester(t, "linker", plugin)
+
+	prToIssueRegexpStr := "#(\\d+)"
+	branchToIssueRegexpStr := "fix#(\\d+)"
+	taskData := &tasks.LinkerTaskData{
+		Options: &tasks.LinkerOptions{
+			PrToIssueRegexp:     prToIssueRegexpStr,
+			BranchToIssueRegexp: branchToIssueRegexpStr,
+			LinkStrategies: []string{
+				crossdomain.PR_ISSUE_SOURCE_PR_TEXT,
+				crossdomain.PR_ISSUE_SOURCE_BRANCH_NAME,
+				crossdomain.PR_ISSUE_SOURCE_COMMIT_MESSAGE,
+				crossdomain.PR_ISSUE_SOURCE_DEVELOPMENT_PANEL,
+				crossdomain.PR_ISSUE_SOURCE_REMOTE_LINK,
+			},
+			ProjectName: "GitHub1",
+		},
+		PrToIssueRegexp:     regexp.MustCompile(prToIssueRegexpStr),
+		BranchToIssueRegexp: regexp.MustCompile(branchToIssueRegexpStr),
+	}
+
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/issues.csv", &ticket.Issue{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/pull_requests.csv", &code.PullRequest{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/project_mapping.csv", &crossdomain.ProjectMapping{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/board_issues.csv", &ticket.BoardIssue{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/commits.csv", &code.Commit{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/pull_request_commits.csv", &code.PullRequestCommit{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/issue_commits.csv", &crossdomain.IssueCommit{})
+	dataflowTester.ImportCsvIntoTabler("./snapshot_tables/issue_remote_links.csv", &ticket.IssueRemoteLink{})
+
+	// PR 7317 refers to #1884 and #1885 in its title, PR 7318 refers to #1886 in its branch, to #1885 in its commit
+	// message and shares its commit with #1884 in the development panel. #1886 has a remote link to PR 7317 and
+	// #1885 one to PR 7318, which is more confident than the commit message, the issue of another project is left out
+	dataflowTester.FlushTabler(&crossdomain.PullRequestIssue{})
+	dataflowTester.Subtask(tasks.LinkPrToIssueMeta, taskData)
+	dataflowTester.VerifyTable(
+		crossdomain.PullRequestIssue{},
+		"./snapshot_tables/pull_request_issues_with_strategies.csv",
+		[]string{
+			"pull_request_id",
+			"pull_request_key",
+			"issue_id",
+			"issue_key",
+			"source",
+			"confidence",
+		},
+	)
+}
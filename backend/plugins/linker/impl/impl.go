@@ -73,6 +73,7 @@ func (p Linker) Settings() interface{} {
 func (p Linker) SubTaskMetas() []plugin.SubTaskMeta {
 	return []plugin.SubTaskMeta{
 		tasks.LinkPrToIssueMeta,
+		tasks.LinkCommitToIssueMeta,
 	}
 }
 
@@ -91,6 +92,13 @@ func (p Linker) PrepareTaskData(taskCtx plugin.TaskContext, options map[string]i
 		}
 		taskData.PrToIssueRegexp = re
 	}
+	if op.BranchToIssueRegexp != "" {
+		re, err := regexp.Compile(op.BranchToIssueRegexp)
+		if err != nil {
+			return taskData, errors.Convert(err)
+		}
+		taskData.BranchToIssueRegexp = re
+	}
 	return taskData, nil
 }
 
@@ -114,11 +122,14 @@ func (p Linker) MakeMetricPluginPipelinePlanV200(projectName string, options jso
 			{
 				Plugin: "linker",
 				Options: map[string]interface{}{
-					"projectName":     projectName,
-					"prToIssueRegexp": op.PrToIssueRegexp,
+					"projectName":         projectName,
+					"prToIssueRegexp":     op.PrToIssueRegexp,
+					"branchToIssueRegexp": op.BranchToIssueRegexp,
+					"linkStrategies":      op.LinkStrategies,
 				},
 				Subtasks: []string{
 					"LinkPrToIssue",
+					"LinkCommitToIssue",
 				},
 			},
 		},
@@ -0,0 +1,196 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+)
+
+var LinkCommitToIssueMeta = plugin.SubTaskMeta{
+	Name:             "LinkCommitToIssue",
+	EntryPoint:       LinkCommitToIssue,
+	EnabledByDefault: true,
+	Description:      "Try to link commits of all repos in the project to issues, according to commits' message",
+	DependencyTables: []string{code.Commit{}.TableName(), code.RepoCommit{}.TableName(), ticket.Issue{}.TableName()},
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE, plugin.DOMAIN_TYPE_TICKET, plugin.DOMAIN_TYPE_CROSS},
+	ProductTables:    []string{crossdomain.IssueCommit{}.TableName(), crossdomain.IssueRepoCommit{}.TableName()},
+}
+
+// commitUrlPatterns extract the namespace, the repo name and the sha from the commit urls built from repos' url
+var commitUrlPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^https?://[^/]+/(.+)/([^/]+)/(?:-/)?commit/(\w+)$`),
+}
+
+// repoCommit is a commit along with one of the repos of the project containing it
+type repoCommit struct {
+	Sha     string
+	Message string
+	RepoId  string
+	RepoUrl string
+}
+
+func clearCommitHistoryData(db dal.Dal, data *LinkerTaskData) errors.Error {
+	for _, table := range []string{crossdomain.IssueCommit{}.TableName(), crossdomain.IssueRepoCommit{}.TableName()} {
+		sql := `
+	DELETE FROM ` + table + `
+		WHERE commit_sha IN (
+			SELECT rc.commit_sha
+				FROM repo_commits rc
+					INNER JOIN project_mapping pm
+					ON pm.table = 'repos'
+						AND pm.row_id = rc.repo_id
+				WHERE pm.project_name = ?
+		)
+		AND issue_id IN (
+			SELECT bi.issue_id
+				FROM board_issues bi
+					INNER JOIN project_mapping pm2
+					ON pm2.table = 'boards'
+						AND pm2.row_id = bi.board_id
+				WHERE pm2.project_name = ?
+		)
+		AND (_raw_data_table = '' OR _raw_data_table IS NULL)
+		AND _raw_data_remark LIKE '%commits,%'
+`
+		if err := db.Exec(sql, data.Options.ProjectName, data.Options.ProjectName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func LinkCommitToIssue(taskCtx plugin.SubTaskContext) errors.Error {
+	db := taskCtx.GetDal()
+	data := taskCtx.GetData().(*LinkerTaskData)
+	if data.PrToIssueRegexp == nil {
+		taskCtx.GetLogger().Info("prToIssueRegexp is not set, skip linking commits to issues")
+		return nil
+	}
+
+	if err := clearCommitHistoryData(db, data); err != nil {
+		return err
+	}
+
+	var projectIssues []*ticket.Issue
+	if err := db.All(&projectIssues,
+		dal.Select("issues.id, issues.issue_key"),
+		dal.From(&ticket.Issue{}),
+		dal.Join("JOIN board_issues bi ON bi.issue_id = issues.id"),
+		dal.Join("JOIN project_mapping pm ON (pm.table = 'boards' AND pm.row_id = bi.board_id)"),
+		dal.Where("pm.project_name = ?", data.Options.ProjectName),
+	); err != nil {
+		return err
+	}
+	issueIdsByKey := make(map[string][]string)
+	for _, issue := range projectIssues {
+		issueIdsByKey[issue.IssueKey] = append(issueIdsByKey[issue.IssueKey], issue.Id)
+	}
+
+	// the links left after clearing ours come from other sources, like the Jira development panel, and are theirs
+	linkedIssueCommits, err := existingLinks(db, crossdomain.IssueCommit{}.TableName(), data)
+	if err != nil {
+		return err
+	}
+	linkedIssueRepoCommits, err := existingLinks(db, crossdomain.IssueRepoCommit{}.TableName(), data)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := db.Cursor(
+		dal.Select("commits.sha, commits.message, rc.repo_id, repos.url AS repo_url"),
+		dal.From(&code.Commit{}),
+		dal.Join("JOIN repo_commits rc ON rc.commit_sha = commits.sha"),
+		dal.Join("JOIN project_mapping pm ON (pm.table = 'repos' AND pm.row_id = rc.repo_id)"),
+		dal.Join("LEFT JOIN repos ON repos.id = rc.repo_id"),
+		dal.Where("pm.project_name = ?", data.Options.ProjectName),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	enricher, err := api.NewDataEnricher(api.DataEnricherArgs[repoCommit]{
+		Ctx:   taskCtx,
+		Name:  code.Commit{}.TableName(),
+		Input: cursor,
+		Enrich: func(commit *repoCommit) ([]interface{}, errors.Error) {
+			var result []interface{}
+			linked := make(map[string]bool)
+			for _, issueKey := range findIssueKeys(data.PrToIssueRegexp, commit.Message) {
+				for _, issueId := range issueIdsByKey[issueKey] {
+					if linked[issueId] {
+						continue
+					}
+					linked[issueId] = true
+					if !linkedIssueCommits[issueId+":"+commit.Sha] {
+						result = append(result, &crossdomain.IssueCommit{
+							IssueId:   issueId,
+							CommitSha: commit.Sha,
+						})
+					}
+					if commit.RepoUrl == "" || linkedIssueRepoCommits[issueId+":"+commit.Sha] {
+						continue
+					}
+					repoUrl := strings.TrimSuffix(strings.TrimSuffix(commit.RepoUrl, "/"), ".git")
+					issueRepoCommit := &crossdomain.IssueRepoCommit{
+						IssueId:   issueId,
+						CommitSha: commit.Sha,
+						RepoUrl:   repoUrl,
+					}
+					result = append(result, api.RefineIssueRepoCommit(issueRepoCommit, commitUrlPatterns, repoUrl+"/commit/"+commit.Sha))
+				}
+			}
+			return result, nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return enricher.Execute()
+}
+
+// existingLinks returns the `issue_id:commit_sha` pairs of the table linking the issues of the project to commits
+func existingLinks(db dal.Dal, table string, data *LinkerTaskData) (map[string]bool, errors.Error) {
+	var links []crossdomain.IssueCommit
+	err := db.All(
+		&links,
+		dal.Select("DISTINCT l.issue_id, l.commit_sha"),
+		dal.From(table+" l"),
+		dal.Join("JOIN board_issues bi ON bi.issue_id = l.issue_id"),
+		dal.Join("JOIN project_mapping pm ON (pm.table = 'boards' AND pm.row_id = bi.board_id)"),
+		dal.Where("pm.project_name = ?", data.Options.ProjectName),
+	)
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string]bool, len(links))
+	for _, link := range links {
+		linked[link.IssueId+":"+link.CommitSha] = true
+	}
+	return linked, nil
+}
@@ -34,7 +34,7 @@ var LinkPrToIssueMeta = plugin.SubTaskMeta{
 	Name:             "LinkPrToIssue",
 	EntryPoint:       LinkPrToIssue,
 	EnabledByDefault: true,
-	Description:      "Try to link pull requests to issues, according to pull requests' title and description",
+	Description:      "Try to link pull requests to issues, according to pull requests' title, description, branch, commits and the remote links of issues",
 	DependencyTables: []string{code.PullRequest{}.TableName(), ticket.Issue{}.TableName()},
 	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE, plugin.DOMAIN_TYPE_TICKET, plugin.DOMAIN_TYPE_CROSS},
 	ProductTables:    []string{crossdomain.PullRequestIssue{}.TableName()},
@@ -101,47 +101,45 @@ func LinkPrToIssue(taskCtx plugin.SubTaskContext) errors.Error {
 		return err
 	}
 
+	strategies, err := makeLinkStrategies(db, data, &projectIssues{db: db, ids: projectIssueIds})
+	if err != nil {
+		return err
+	}
+
 	enricher, err := api.NewDataEnricher(api.DataEnricherArgs[code.PullRequest]{
 		Ctx:   taskCtx,
 		Name:  code.PullRequest{}.TableName(),
 		Input: cursor,
 		Enrich: func(pullRequest *code.PullRequest) ([]interface{}, errors.Error) {
-
-			var issueKeys []string
-			for _, text := range []string{pullRequest.Title, pullRequest.Description, pullRequest.HeadRef} {
-				foundIssueKeys := data.PrToIssueRegexp.FindAllString(text, -1)
-				if len(foundIssueKeys) > 0 {
-					for _, issueKey := range foundIssueKeys {
-						issueKey = normalizeIssueKey(issueKey)
-						issueKeys = append(issueKeys, issueKey)
-					}
-					break
-				}
-			}
-			var issues []*ticket.Issue
-
-			var clauses = []dal.Clause{
-				dal.From(&ticket.Issue{}),
-				dal.Where("issues.id in ? AND issues.issue_key in ?", projectIssueIds, issueKeys),
-			}
-
-			if err := db.All(&issues, clauses...); err != nil {
-				return nil, err
-			}
-			if len(issues) == 0 {
-				return nil, nil
-			}
 			var result []interface{}
-			for _, issue := range issues {
-				pullRequestIssue := &crossdomain.PullRequestIssue{
-					PullRequestId:  pullRequest.Id,
-					IssueId:        issue.Id,
-					PullRequestKey: pullRequest.PullRequestKey,
-					IssueKey:       issue.IssueKey,
+			linked := make(map[string]*crossdomain.PullRequestIssue)
+			for _, strategy := range strategies {
+				issues, err := strategy.FindIssues(pullRequest)
+				if err != nil {
+					return nil, err
+				}
+				source := strategy.Source()
+				for _, issue := range issues {
+					// keep the most confident source when several strategies find the same issue
+					if pullRequestIssue, ok := linked[issue.Id]; ok {
+						if linkConfidences[source] > pullRequestIssue.Confidence {
+							pullRequestIssue.Source = source
+							pullRequestIssue.Confidence = linkConfidences[source]
+						}
+						continue
+					}
+					pullRequestIssue := &crossdomain.PullRequestIssue{
+						PullRequestId:  pullRequest.Id,
+						IssueId:        issue.Id,
+						PullRequestKey: pullRequest.PullRequestKey,
+						IssueKey:       issue.IssueKey,
+						Source:         source,
+						Confidence:     linkConfidences[source],
+					}
+					linked[issue.Id] = pullRequestIssue
+					result = append(result, pullRequestIssue)
 				}
-				result = append(result, pullRequestIssue)
 			}
-
 			return result, nil
 		},
 	})
@@ -151,3 +149,40 @@ func LinkPrToIssue(taskCtx plugin.SubTaskContext) errors.Error {
 
 	return enricher.Execute()
 }
+
+// makeLinkStrategies returns the strategies enabled by the options. When none is specified, only the pull request
+// text and the branch name are looked at, as before the other strategies existed.
+func makeLinkStrategies(db dal.Dal, data *LinkerTaskData, issues *projectIssues) ([]prIssueLinkStrategy, errors.Error) {
+	enabled := func(source string) bool {
+		if len(data.Options.LinkStrategies) == 0 {
+			return source == crossdomain.PR_ISSUE_SOURCE_PR_TEXT || source == crossdomain.PR_ISSUE_SOURCE_BRANCH_NAME
+		}
+		for _, s := range data.Options.LinkStrategies {
+			if s == source {
+				return true
+			}
+		}
+		return false
+	}
+	var strategies []prIssueLinkStrategy
+	if data.PrToIssueRegexp != nil && enabled(crossdomain.PR_ISSUE_SOURCE_PR_TEXT) {
+		strategies = append(strategies, &prTextStrategy{issues: issues, re: data.PrToIssueRegexp})
+	}
+	if data.BranchToIssueRegexp != nil && enabled(crossdomain.PR_ISSUE_SOURCE_BRANCH_NAME) {
+		strategies = append(strategies, &branchNameStrategy{issues: issues, re: data.BranchToIssueRegexp})
+	}
+	if data.PrToIssueRegexp != nil && enabled(crossdomain.PR_ISSUE_SOURCE_COMMIT_MESSAGE) {
+		strategies = append(strategies, &commitMessageStrategy{issues: issues, re: data.PrToIssueRegexp})
+	}
+	if enabled(crossdomain.PR_ISSUE_SOURCE_DEVELOPMENT_PANEL) {
+		strategies = append(strategies, &developmentPanelStrategy{issues: issues})
+	}
+	if enabled(crossdomain.PR_ISSUE_SOURCE_REMOTE_LINK) {
+		strategy, err := newRemoteLinkStrategy(issues)
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
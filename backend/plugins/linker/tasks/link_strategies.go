@@ -0,0 +1,242 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+)
+
+// linkConfidences is how likely a link found by each strategy is right, the more explicit the reference the higher
+var linkConfidences = map[string]float64{
+	crossdomain.PR_ISSUE_SOURCE_REMOTE_LINK:       1,
+	crossdomain.PR_ISSUE_SOURCE_DEVELOPMENT_PANEL: 0.95,
+	crossdomain.PR_ISSUE_SOURCE_BRANCH_NAME:       0.9,
+	crossdomain.PR_ISSUE_SOURCE_PR_TEXT:           0.8,
+	crossdomain.PR_ISSUE_SOURCE_COMMIT_MESSAGE:    0.6,
+}
+
+func isLinkStrategy(source string) bool {
+	_, ok := linkConfidences[source]
+	return ok
+}
+
+// prIssueLinkStrategy finds the issues of the project a pull request refers to
+type prIssueLinkStrategy interface {
+	Source() string
+	FindIssues(pr *code.PullRequest) ([]*ticket.Issue, errors.Error)
+}
+
+// projectIssues looks up the issues of the project
+type projectIssues struct {
+	db  dal.Dal
+	ids []string
+}
+
+func (p *projectIssues) byKeys(issueKeys []string) ([]*ticket.Issue, errors.Error) {
+	if len(issueKeys) == 0 {
+		return nil, nil
+	}
+	var issues []*ticket.Issue
+	err := p.db.All(
+		&issues,
+		dal.From(&ticket.Issue{}),
+		dal.Where("issues.id in ? AND issues.issue_key in ?", p.ids, issueKeys),
+	)
+	return issues, err
+}
+
+// issueNumberReference is a reference to an issue by its number, like in `Closes #1 and #2`
+var issueNumberReference = regexp.MustCompile(`#\d+`)
+
+// findIssueKeys returns the normalized issue keys matched by PrToIssueRegexp: every match is a key, like `PROJ-1` or
+// `#1`, and so are the issue numbers it refers to when it is a whole sentence, like `Closes #1 and #2`
+func findIssueKeys(re *regexp.Regexp, text string) []string {
+	var issueKeys []string
+	for _, match := range re.FindAllString(text, -1) {
+		issueKeys = append(issueKeys, normalizeIssueKey(match))
+		references := issueNumberReference.FindAllString(match, -1)
+		if len(references) == 1 && references[0] == strings.TrimSpace(match) {
+			continue
+		}
+		for _, reference := range references {
+			issueKeys = append(issueKeys, normalizeIssueKey(reference))
+		}
+	}
+	return issueKeys
+}
+
+// findBranchIssueKeys returns the normalized issue keys matched by BranchToIssueRegexp, the first group being the
+// key if any
+func findBranchIssueKeys(re *regexp.Regexp, branch string) []string {
+	var issueKeys []string
+	for _, match := range re.FindAllStringSubmatch(branch, -1) {
+		issueKey := match[0]
+		if len(match) > 1 && match[1] != "" {
+			issueKey = match[1]
+		}
+		issueKeys = append(issueKeys, normalizeIssueKey(issueKey))
+	}
+	return issueKeys
+}
+
+// prTextStrategy applies PrToIssueRegexp to the title, the description then the head branch of the pull request,
+// and stops at the first one referring to issues
+type prTextStrategy struct {
+	issues *projectIssues
+	re     *regexp.Regexp
+}
+
+func (s *prTextStrategy) Source() string {
+	return crossdomain.PR_ISSUE_SOURCE_PR_TEXT
+}
+
+func (s *prTextStrategy) FindIssues(pr *code.PullRequest) ([]*ticket.Issue, errors.Error) {
+	var issueKeys []string
+	for _, text := range []string{pr.Title, pr.Description, pr.HeadRef} {
+		issueKeys = findIssueKeys(s.re, text)
+		if len(issueKeys) > 0 {
+			break
+		}
+	}
+	return s.issues.byKeys(issueKeys)
+}
+
+// branchNameStrategy applies BranchToIssueRegexp to the head branch of the pull request
+type branchNameStrategy struct {
+	issues *projectIssues
+	re     *regexp.Regexp
+}
+
+func (s *branchNameStrategy) Source() string {
+	return crossdomain.PR_ISSUE_SOURCE_BRANCH_NAME
+}
+
+func (s *branchNameStrategy) FindIssues(pr *code.PullRequest) ([]*ticket.Issue, errors.Error) {
+	return s.issues.byKeys(findBranchIssueKeys(s.re, pr.HeadRef))
+}
+
+// commitMessageStrategy applies PrToIssueRegexp to the messages of all the commits of the pull request
+type commitMessageStrategy struct {
+	issues *projectIssues
+	re     *regexp.Regexp
+}
+
+func (s *commitMessageStrategy) Source() string {
+	return crossdomain.PR_ISSUE_SOURCE_COMMIT_MESSAGE
+}
+
+func (s *commitMessageStrategy) FindIssues(pr *code.PullRequest) ([]*ticket.Issue, errors.Error) {
+	var messages []string
+	err := s.issues.db.Pluck(
+		"c.message",
+		&messages,
+		dal.From("pull_request_commits prc"),
+		dal.Join("JOIN commits c ON c.sha = prc.commit_sha"),
+		dal.Where("prc.pull_request_id = ?", pr.Id),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var issueKeys []string
+	for _, message := range messages {
+		issueKeys = append(issueKeys, findIssueKeys(s.re, message)...)
+	}
+	return s.issues.byKeys(issueKeys)
+}
+
+// developmentPanelStrategy links the pull request to the issues sharing a commit with it in issue_commits, which
+// holds the commits of the Jira development panel. The links made by LinkCommitToIssue have no raw data table
+// and are left out.
+type developmentPanelStrategy struct {
+	issues *projectIssues
+}
+
+func (s *developmentPanelStrategy) Source() string {
+	return crossdomain.PR_ISSUE_SOURCE_DEVELOPMENT_PANEL
+}
+
+func (s *developmentPanelStrategy) FindIssues(pr *code.PullRequest) ([]*ticket.Issue, errors.Error) {
+	var issues []*ticket.Issue
+	err := s.issues.db.All(
+		&issues,
+		dal.Select("DISTINCT issues.id, issues.issue_key"),
+		dal.From(&ticket.Issue{}),
+		dal.Join("JOIN issue_commits ic ON ic.issue_id = issues.id"),
+		dal.Join("JOIN pull_request_commits prc ON prc.commit_sha = ic.commit_sha"),
+		dal.Where("prc.pull_request_id = ? AND issues.id in ? AND ic._raw_data_table != ''", pr.Id, s.issues.ids),
+	)
+	return issues, err
+}
+
+// remoteLinkStrategy links the pull request to the issues having a remote link to its url
+type remoteLinkStrategy struct {
+	issuesByUrl map[string][]*ticket.Issue
+}
+
+// remoteLinkIssue is an issue along with the url of one of its remote links
+type remoteLinkIssue struct {
+	Url      string
+	IssueId  string
+	IssueKey string
+}
+
+func newRemoteLinkStrategy(issues *projectIssues) (*remoteLinkStrategy, errors.Error) {
+	s := &remoteLinkStrategy{issuesByUrl: make(map[string][]*ticket.Issue)}
+	var links []remoteLinkIssue
+	err := issues.db.All(
+		&links,
+		dal.Select("rl.url, issues.id AS issue_id, issues.issue_key"),
+		dal.From("issue_remote_links rl"),
+		dal.Join("JOIN issues ON issues.id = rl.issue_id"),
+		dal.Where("issues.id in ?", issues.ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		url := normalizeLinkUrl(link.Url)
+		s.issuesByUrl[url] = append(s.issuesByUrl[url], &ticket.Issue{
+			DomainEntity: domainlayer.DomainEntity{Id: link.IssueId},
+			IssueKey:     link.IssueKey,
+		})
+	}
+	return s, nil
+}
+
+func (s *remoteLinkStrategy) Source() string {
+	return crossdomain.PR_ISSUE_SOURCE_REMOTE_LINK
+}
+
+func (s *remoteLinkStrategy) FindIssues(pr *code.PullRequest) ([]*ticket.Issue, errors.Error) {
+	if pr.Url == "" {
+		return nil, nil
+	}
+	return s.issuesByUrl[normalizeLinkUrl(pr.Url)], nil
+}
+
+func normalizeLinkUrl(url string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(url), "/"))
+}
@@ -0,0 +1,81 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/stretchr/testify/assert"
+)
+
+// defaultPrToIssueRegexp is the one config-ui fills the project settings with
+const defaultPrToIssueRegexp = `(?mi)(Closes)[\s]*.*(((and )?#\d+[ ]*)+)`
+
+func TestFindIssueKeys(t *testing.T) {
+	cases := []struct {
+		re   string
+		text string
+		want []string
+	}{
+		{`#(\d+)`, "fix: recreate deployment policy, refs #1885 #1886", []string{"1885", "1886"}},
+		{`[A-Z]+-\d+`, "PROJ-12: fix the login", []string{"PROJ-12"}},
+		{defaultPrToIssueRegexp, "fix the login\n\nCloses #1885", []string{"Closes 1885", "1885"}},
+		{defaultPrToIssueRegexp, "Closes #1885 and #1886", []string{"Closes 1885 and 1886", "1885", "1886"}},
+		{defaultPrToIssueRegexp, "refs #1885", nil},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, findIssueKeys(regexp.MustCompile(c.re), c.text), c.text)
+	}
+}
+
+func TestFindBranchIssueKeys(t *testing.T) {
+	assert.Equal(t, []string{"PROJ-12"}, findBranchIssueKeys(regexp.MustCompile(`(?:feature|bugfix)/([A-Z]+-\d+)`), "feature/PROJ-12"))
+	assert.Equal(t, []string{"1886"}, findBranchIssueKeys(regexp.MustCompile(`#\d+`), "fix#1886"))
+}
+
+func TestMakeLinkStrategies(t *testing.T) {
+	sources := func(options *LinkerOptions, branchToIssueRegexp *regexp.Regexp) []string {
+		data := &LinkerTaskData{
+			Options:             options,
+			PrToIssueRegexp:     regexp.MustCompile(defaultPrToIssueRegexp),
+			BranchToIssueRegexp: branchToIssueRegexp,
+		}
+		strategies, err := makeLinkStrategies(nil, data, &projectIssues{})
+		assert.Nil(t, err)
+		var result []string
+		for _, strategy := range strategies {
+			result = append(result, strategy.Source())
+		}
+		return result
+	}
+	// the strategies added later must be asked for, not to change the links of existing projects
+	assert.Equal(t, []string{crossdomain.PR_ISSUE_SOURCE_PR_TEXT}, sources(&LinkerOptions{}, nil))
+	assert.Equal(t,
+		[]string{crossdomain.PR_ISSUE_SOURCE_PR_TEXT, crossdomain.PR_ISSUE_SOURCE_BRANCH_NAME},
+		sources(&LinkerOptions{}, regexp.MustCompile(`fix#(\d+)`)),
+	)
+	assert.Equal(t,
+		[]string{crossdomain.PR_ISSUE_SOURCE_COMMIT_MESSAGE, crossdomain.PR_ISSUE_SOURCE_DEVELOPMENT_PANEL},
+		sources(&LinkerOptions{LinkStrategies: []string{
+			crossdomain.PR_ISSUE_SOURCE_COMMIT_MESSAGE,
+			crossdomain.PR_ISSUE_SOURCE_DEVELOPMENT_PANEL,
+		}}, nil),
+	)
+}
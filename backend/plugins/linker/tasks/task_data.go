@@ -25,12 +25,18 @@ import (
 
 type LinkerOptions struct {
 	PrToIssueRegexp string `json:"prToIssueRegexp"`
-	ProjectName     string `json:"projectName"`
+	// BranchToIssueRegexp extracts issue keys from the head branch of pull requests, the first group is the key
+	// if the regexp has one, e.g. `(?:feature|bugfix)/([A-Z]+-\d+)`
+	BranchToIssueRegexp string `json:"branchToIssueRegexp"`
+	// LinkStrategies are the sources of pull_request_issues to use, PR_TEXT and BRANCH_NAME by default
+	LinkStrategies []string `json:"linkStrategies"`
+	ProjectName    string   `json:"projectName"`
 }
 
 type LinkerTaskData struct {
-	Options         *LinkerOptions
-	PrToIssueRegexp *regexp.Regexp
+	Options             *LinkerOptions
+	PrToIssueRegexp     *regexp.Regexp
+	BranchToIssueRegexp *regexp.Regexp
 }
 
 func DecodeAndValidateTaskOptions(options map[string]interface{}) (*LinkerOptions, errors.Error) {
@@ -39,5 +45,10 @@ func DecodeAndValidateTaskOptions(options map[string]interface{}) (*LinkerOption
 	if err != nil {
 		return nil, errors.Default.Wrap(err, "error decoding linker task options")
 	}
+	for _, strategy := range op.LinkStrategies {
+		if !isLinkStrategy(strategy) {
+			return nil, errors.BadInput.New("unknown link strategy " + strategy)
+		}
+	}
 	return &op, nil
 }
@@ -0,0 +1,72 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/refdiff/tasks"
+)
+
+// GetReleaseNotes returns the release notes between two refs of a repo
+// @Summary get release notes between two refs
+// @Description Return the changes from oldRef to newRef: the linked issues grouped by type, the merged pull requests,
+// @Description the contributors and the breaking changes, as JSON or as Markdown.
+// @Tags plugins/refdiff
+// @Param repoId query string true "repo id"
+// @Param newRef query string true "new ref name, e.g. refs/tags/v1.1.0"
+// @Param oldRef query string true "old ref name, e.g. refs/tags/v1.0.0"
+// @Param format query string false "json or markdown, defaults to json"
+// @Success 200  {object} models.ReleaseNotes
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 404  {string} errcode.Error "Not Found"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/refdiff/release-notes [GET]
+func GetReleaseNotes(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	repoId := input.Query.Get("repoId")
+	newRef := input.Query.Get("newRef")
+	oldRef := input.Query.Get("oldRef")
+	if repoId == "" || newRef == "" || oldRef == "" {
+		return nil, errors.BadInput.New("repoId, newRef and oldRef are required")
+	}
+	format := input.Query.Get("format")
+	if format != "" && format != "json" && format != "markdown" {
+		return nil, errors.BadInput.New("format must be either json or markdown")
+	}
+	ctx := context.Background()
+	if input.Request != nil {
+		ctx = input.Request.Context()
+	}
+	notes, err := tasks.BuildReleaseNotes(ctx, basicRes.GetDal(), repoId, newRef, oldRef)
+	if err != nil {
+		return nil, err
+	}
+	if format == "markdown" {
+		return &plugin.ApiResourceOutput{
+			Status: http.StatusOK,
+			File: &plugin.OutputFile{
+				ContentType: "text/markdown; charset=utf-8",
+				Data:        []byte(notes.Markdown()),
+			},
+		}, nil
+	}
+	return &plugin.ApiResourceOutput{Body: notes, Status: http.StatusOK}, nil
+}
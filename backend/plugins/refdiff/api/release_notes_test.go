@@ -0,0 +1,77 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/plugin"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/apache/incubator-devlake/plugins/refdiff/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockReleaseNotesDal serves the refs v1.0.0 and v1.0.1 of repo1, both pointing to the same commit
+func mockReleaseNotesDal(t *testing.T) {
+	db := mockdal.NewDal(t)
+	db.On("First", mock.AnythingOfType("*code.Ref"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		dst.(*code.Ref).CommitSha = "c1"
+		return nil
+	})
+	br := mockcontext.NewBasicRes(t)
+	br.On("GetDal").Return(db)
+	basicRes = br
+}
+
+func TestGetReleaseNotesBadInput(t *testing.T) {
+	for _, query := range []url.Values{
+		{"repoId": {"repo1"}, "newRef": {"v1.0.1"}},
+		{"repoId": {"repo1"}, "newRef": {"v1.0.1"}, "oldRef": {"v1.0.0"}, "format": {"html"}},
+	} {
+		_, err := GetReleaseNotes(&plugin.ApiResourceInput{Query: query})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, errors.BadInput, err.GetType())
+		}
+	}
+}
+
+func TestGetReleaseNotes(t *testing.T) {
+	mockReleaseNotesDal(t)
+	query := url.Values{"repoId": {"repo1"}, "newRef": {"v1.0.1"}, "oldRef": {"v1.0.0"}}
+	output, err := GetReleaseNotes(&plugin.ApiResourceInput{Query: query})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, output.Status)
+	notes := output.Body.(*models.ReleaseNotes)
+	assert.Equal(t, "repo1", notes.RepoId)
+	assert.Equal(t, "c1", notes.NewCommitSha)
+	assert.Equal(t, 0, notes.CommitCount)
+
+	query.Set("format", "markdown")
+	output, err = GetReleaseNotes(&plugin.ApiResourceInput{Query: query})
+	assert.Nil(t, err)
+	assert.Nil(t, output.Body)
+	assert.Equal(t, "text/markdown; charset=utf-8", output.File.ContentType)
+	assert.Equal(t, notes.Markdown(), string(output.File.Data))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
 	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
 	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
 	"github.com/apache/incubator-devlake/helpers/e2ehelper"
 	"github.com/apache/incubator-devlake/plugins/refdiff/impl"
 	"github.com/apache/incubator-devlake/plugins/refdiff/models"
@@ -59,3 +60,68 @@ func TestDeploymentCommitDiffDataFlow(t *testing.T) {
 		CSVRelPath: "./deployment_commit_diff/_tool_refdiff_finished_commits_diffs.csv",
 	})
 }
+
+func TestDeploymentChangesDataFlow(t *testing.T) {
+	var plugin impl.RefDiff
+	dataflowTester := e2ehelper.NewDataFlowTester(t, "refdiff", plugin)
+
+	taskData := &tasks.RefdiffTaskData{
+		Options: &models.RefdiffOptions{
+			ProjectName: "project2",
+		},
+	}
+
+	dataflowTester.ImportCsvIntoTabler("./deployment_commit_diff/project_mapping.csv", &crossdomain.ProjectMapping{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_commit_diff/repo_commits.csv", &code.RepoCommit{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_commit_diff/commit_parents.csv", &code.CommitParent{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_changes/cicd_deployment_commits.csv", &devops.CicdDeploymentCommit{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_changes/commits.csv", &code.Commit{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_changes/pull_requests.csv", &code.PullRequest{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_changes/pull_request_issues.csv", &crossdomain.PullRequestIssue{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_changes/issue_commits.csv", &crossdomain.IssueCommit{})
+	dataflowTester.ImportCsvIntoTabler("./deployment_changes/issues.csv", &ticket.Issue{})
+	dataflowTester.FlushTabler(&code.CommitsDiff{})
+	dataflowTester.FlushTabler(&models.FinishedCommitsDiff{})
+
+	// the failed deployment 7 is ignored, 6 follows 4 in PRODUCTION and 8 follows 5 in STAGING. pull request 8 merges
+	// commit_sha11 as well but belongs to a repo of project1
+	dataflowTester.FlushTabler(&crossdomain.DeploymentChange{})
+	dataflowTester.Subtask(tasks.CalculateDeploymentChangesMeta, taskData)
+	dataflowTester.VerifyTableWithOptions(&crossdomain.DeploymentChange{}, e2ehelper.TableOptions{
+		CSVRelPath: "./deployment_changes/deployment_changes.csv",
+		TargetFields: []string{
+			"deployment_commit_id",
+			"change_type",
+			"change_id",
+			"change_key",
+			"title",
+			"url",
+			"prev_deployment_commit_id",
+			"cicd_scope_id",
+			"repo_url",
+			"environment",
+			"commit_sha",
+			"prev_commit_sha",
+		},
+	})
+
+	taskData.Options.ProductionOnly = true
+	dataflowTester.Subtask(tasks.CalculateDeploymentChangesMeta, taskData)
+	dataflowTester.VerifyTableWithOptions(&crossdomain.DeploymentChange{}, e2ehelper.TableOptions{
+		CSVRelPath: "./deployment_changes/deployment_changes_production_only.csv",
+		TargetFields: []string{
+			"deployment_commit_id",
+			"change_type",
+			"change_id",
+			"change_key",
+			"title",
+			"url",
+			"prev_deployment_commit_id",
+			"cicd_scope_id",
+			"repo_url",
+			"environment",
+			"commit_sha",
+			"prev_commit_sha",
+		},
+	})
+}
@@ -18,10 +18,12 @@ limitations under the License.
 package impl
 
 import (
+	"github.com/apache/incubator-devlake/core/context"
 	"github.com/apache/incubator-devlake/core/dal"
 	"github.com/apache/incubator-devlake/core/errors"
 	"github.com/apache/incubator-devlake/core/plugin"
 	helper "github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+	"github.com/apache/incubator-devlake/plugins/refdiff/api"
 	"github.com/apache/incubator-devlake/plugins/refdiff/models"
 	"github.com/apache/incubator-devlake/plugins/refdiff/tasks"
 )
@@ -29,6 +31,7 @@ import (
 // make sure interface is implemented
 var _ interface {
 	plugin.PluginMeta
+	plugin.PluginInit
 	plugin.PluginTask
 	plugin.PluginApi
 	plugin.PluginModel
@@ -37,6 +40,11 @@ var _ interface {
 
 type RefDiff struct{}
 
+func (p RefDiff) Init(basicRes context.BasicRes) errors.Error {
+	api.Init(basicRes)
+	return nil
+}
+
 func (p RefDiff) Description() string {
 	return "Calculate commits diff for specified ref pairs based on `commits` and `commit_parents` tables"
 }
@@ -73,6 +81,8 @@ func (p RefDiff) SubTaskMetas() []plugin.SubTaskMeta {
 		tasks.CalculateIssuesDiffMeta,
 		tasks.CalculatePrCherryPickMeta,
 		tasks.CalculateDeploymentCommitsDiffMeta,
+		tasks.CalculateDeploymentChangesMeta,
+		tasks.GenerateReleaseNotesMeta,
 	}
 }
 
@@ -108,5 +118,9 @@ func (p RefDiff) RootPkgPath() string {
 }
 
 func (p RefDiff) ApiResources() map[string]map[string]plugin.ApiResourceHandler {
-	return nil
+	return map[string]map[string]plugin.ApiResourceHandler{
+		"release-notes": {
+			"GET": api.GetReleaseNotes,
+		},
+	}
 }
@@ -36,4 +36,6 @@ type RefdiffOptions struct {
 
 	AllPairs    RefCommitPairs // Pairs and TagsPattern Pairs
 	ProjectName string
+	// ProductionOnly limits the deployment change sets to the PRODUCTION environment
+	ProductionOnly bool `json:"productionOnly"`
 }
@@ -0,0 +1,132 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReleaseNotes is the changelog between two refs of a repo
+type ReleaseNotes struct {
+	RepoId          string                        `json:"repoId"`
+	NewRef          string                        `json:"newRef"`
+	OldRef          string                        `json:"oldRef"`
+	NewCommitSha    string                        `json:"newCommitSha"`
+	OldCommitSha    string                        `json:"oldCommitSha"`
+	CommitCount     int                           `json:"commitCount"`
+	IssueGroups     []*ReleaseNotesIssueGroup     `json:"issueGroups"`
+	PullRequests    []*ReleaseNotesPullRequest    `json:"pullRequests"`
+	Contributors    []*ReleaseNotesContributor    `json:"contributors"`
+	BreakingChanges []*ReleaseNotesBreakingChange `json:"breakingChanges"`
+}
+
+// ReleaseNotesIssueGroup holds the linked issues of a standard type, e.g. REQUIREMENT or BUG
+type ReleaseNotesIssueGroup struct {
+	Type   string               `json:"type"`
+	Issues []*ReleaseNotesIssue `json:"issues"`
+}
+
+type ReleaseNotesIssue struct {
+	Id       string `json:"id"`
+	IssueKey string `json:"issueKey"`
+	Title    string `json:"title"`
+	Url      string `json:"url"`
+	Status   string `json:"status"`
+}
+
+type ReleaseNotesPullRequest struct {
+	Id             string     `json:"id"`
+	PullRequestKey int        `json:"pullRequestKey"`
+	Title          string     `json:"title"`
+	Url            string     `json:"url"`
+	AuthorName     string     `json:"authorName"`
+	MergedDate     *time.Time `json:"mergedDate"`
+}
+
+// ReleaseNotesContributor is an author of the commits, identified by email
+type ReleaseNotesContributor struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	CommitCount int    `json:"commitCount"`
+}
+
+// ReleaseNotesBreakingChange is a commit flagged as breaking by its Conventional Commits header or its
+// `BREAKING CHANGE` trailer
+type ReleaseNotesBreakingChange struct {
+	CommitSha   string `json:"commitSha"`
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// Markdown renders the release notes the way they are published on a release page
+func (n *ReleaseNotes) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\n\n", n.NewRef)
+	if n.OldRef != "" {
+		fmt.Fprintf(&sb, "Changes since %s, %d commits.\n", n.OldRef, n.CommitCount)
+	}
+	if len(n.BreakingChanges) > 0 {
+		sb.WriteString("\n### Breaking Changes\n\n")
+		for _, change := range n.BreakingChanges {
+			if change.Scope != "" {
+				fmt.Fprintf(&sb, "- **%s**: %s (%s)\n", change.Scope, change.Description, shortSha(change.CommitSha))
+			} else {
+				fmt.Fprintf(&sb, "- %s (%s)\n", change.Description, shortSha(change.CommitSha))
+			}
+		}
+	}
+	for _, group := range n.IssueGroups {
+		fmt.Fprintf(&sb, "\n### %s\n\n", group.Type)
+		for _, issue := range group.Issues {
+			fmt.Fprintf(&sb, "- %s %s\n", markdownLink(issue.IssueKey, issue.Url), issue.Title)
+		}
+	}
+	if len(n.PullRequests) > 0 {
+		sb.WriteString("\n### Merged Pull Requests\n\n")
+		for _, pr := range n.PullRequests {
+			fmt.Fprintf(&sb, "- %s %s", markdownLink(fmt.Sprintf("#%d", pr.PullRequestKey), pr.Url), pr.Title)
+			if pr.AuthorName != "" {
+				fmt.Fprintf(&sb, " by %s", pr.AuthorName)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if len(n.Contributors) > 0 {
+		sb.WriteString("\n### Contributors\n\n")
+		for _, contributor := range n.Contributors {
+			fmt.Fprintf(&sb, "- %s (%d commits)\n", contributor.Name, contributor.CommitCount)
+		}
+	}
+	return sb.String()
+}
+
+func markdownLink(text, url string) string {
+	if url == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
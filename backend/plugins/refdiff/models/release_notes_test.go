@@ -0,0 +1,73 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseNotesMarkdown(t *testing.T) {
+	mergedDate := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	notes := &ReleaseNotes{
+		NewRef:      "v1.1.0",
+		OldRef:      "v1.0.0",
+		CommitCount: 3,
+		IssueGroups: []*ReleaseNotesIssueGroup{
+			{Type: "REQUIREMENT", Issues: []*ReleaseNotesIssue{{IssueKey: "DL-1", Title: "Add release notes", Url: "https://jira/DL-1"}}},
+			{Type: "BUG", Issues: []*ReleaseNotesIssue{{IssueKey: "DL-2", Title: "Fix crash"}}},
+		},
+		PullRequests: []*ReleaseNotesPullRequest{
+			{PullRequestKey: 12, Title: "feat: release notes", Url: "https://github.com/a/b/pull/12", AuthorName: "alice", MergedDate: &mergedDate},
+		},
+		Contributors: []*ReleaseNotesContributor{
+			{Name: "alice", Email: "alice@example.com", CommitCount: 2},
+			{Name: "bob", Email: "bob@example.com", CommitCount: 1},
+		},
+		BreakingChanges: []*ReleaseNotesBreakingChange{
+			{CommitSha: "0123456789abcdef", Scope: "api", Description: "drop v1 endpoints"},
+		},
+	}
+	assert.Equal(t, `## v1.1.0
+
+Changes since v1.0.0, 3 commits.
+
+### Breaking Changes
+
+- **api**: drop v1 endpoints (0123456)
+
+### REQUIREMENT
+
+- [DL-1](https://jira/DL-1) Add release notes
+
+### BUG
+
+- DL-2 Fix crash
+
+### Merged Pull Requests
+
+- [#12](https://github.com/a/b/pull/12) feat: release notes by alice
+
+### Contributors
+
+- alice (2 commits)
+- bob (1 commits)
+`, notes.Markdown())
+}
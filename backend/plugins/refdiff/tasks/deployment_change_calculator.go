@@ -0,0 +1,302 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/crossdomain"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+	"github.com/apache/incubator-devlake/plugins/refdiff/models"
+	"github.com/apache/incubator-devlake/plugins/refdiff/utils"
+)
+
+var CalculateDeploymentChangesMeta = plugin.SubTaskMeta{
+	Name:             "calculateDeploymentChanges",
+	EntryPoint:       CalculateDeploymentChanges,
+	EnabledByDefault: true,
+	Description:      "Calculate the commits, pull requests and issues shipped by each deployment since the previous one to the same environment in the specified project",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE, plugin.DOMAIN_TYPE_CICD, plugin.DOMAIN_TYPE_CROSS},
+	DependencyTables: []string{devops.CicdDeploymentCommit{}.TableName(), code.CommitParent{}.TableName()},
+	ProductTables:    []string{crossdomain.DeploymentChange{}.TableName(), code.CommitsDiff{}.TableName()},
+}
+
+// consecutiveDeployment is a successful deployment commit along with the previous successful one of the same repo
+// to the same environment
+type consecutiveDeployment struct {
+	Id            string
+	CicdScopeId   string
+	RepoUrl       string
+	Environment   string
+	CommitSha     string
+	FinishedDate  *time.Time
+	PrevId        string
+	PrevCommitSha string
+}
+
+// CalculateDeploymentChanges pairs every successful deployment commit with the previous successful one of the same
+// repo to the same environment, ordered by finished_date, without relying on prev_success_deployment_commit_id.
+// The commits in between are calculated like calculateDeploymentCommitsDiff does when missing, then saved to
+// deployment_changes along with the pull requests merging them and the issues linked to both. The first
+// deployment of each repo and environment has nothing to be compared with and is skipped.
+func CalculateDeploymentChanges(taskCtx plugin.SubTaskContext) errors.Error {
+	data := taskCtx.GetData().(*RefdiffTaskData)
+	db := taskCtx.GetDal()
+	ctx := taskCtx.GetContext()
+	logger := taskCtx.GetLogger()
+
+	if data.Options.ProjectName == "" {
+		return nil
+	}
+
+	deployments, err := loadConsecutiveDeployments(db, data)
+	if err != nil {
+		return err
+	}
+
+	// the change sets are recalculated as a whole since issues might have been linked since the last run
+	err = db.Exec(`
+	DELETE FROM deployment_changes
+		WHERE cicd_scope_id IN (
+			SELECT pm.row_id
+				FROM project_mapping pm
+				WHERE pm.table = 'cicd_scopes' AND pm.project_name = ?
+		)`,
+		data.Options.ProjectName,
+	)
+	if err != nil {
+		return err
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+
+	batchSave, err := api.NewBatchSave(taskCtx, reflect.TypeOf(&crossdomain.DeploymentChange{}), 500)
+	if err != nil {
+		return err
+	}
+	defer batchSave.Close()
+
+	var graph *utils.CommitNodeGraph
+	var diffBatchSave *api.BatchSave
+	taskCtx.SetProgress(0, len(deployments))
+	for _, deployment := range deployments {
+		select {
+		case <-ctx.Done():
+			return errors.Convert(ctx.Err())
+		default:
+		}
+		count, err := db.Count(
+			dal.From(&models.FinishedCommitsDiff{}),
+			dal.Where("new_commit_sha = ? AND old_commit_sha = ?", deployment.CommitSha, deployment.PrevCommitSha),
+		)
+		if err != nil {
+			return err
+		}
+		if count == 0 && deployment.CommitSha != deployment.PrevCommitSha {
+			if graph == nil {
+				// graph is expensive, only build it when some diff is missing
+				if graph, err = loadCommitGraph(ctx, db, data); err != nil {
+					return err
+				}
+				if diffBatchSave, err = api.NewBatchSave(taskCtx, reflect.TypeOf(&code.CommitsDiff{}), 1000); err != nil {
+					return err
+				}
+				defer diffBatchSave.Close()
+			}
+			if _, _, err = saveCommitsDiff(db, diffBatchSave, graph, deployment.CommitSha, deployment.PrevCommitSha); err != nil {
+				return err
+			}
+		}
+		changes, err := loadDeploymentChanges(db, data.Options.ProjectName, deployment)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			change.DeploymentCommitId = deployment.Id
+			change.PrevDeploymentCommitId = deployment.PrevId
+			change.CicdScopeId = deployment.CicdScopeId
+			change.RepoUrl = deployment.RepoUrl
+			change.Environment = deployment.Environment
+			change.CommitSha = deployment.CommitSha
+			change.PrevCommitSha = deployment.PrevCommitSha
+			change.DeployedDate = deployment.FinishedDate
+			if err = batchSave.Add(change); err != nil {
+				return err
+			}
+		}
+		logger.Debug("deployment commit %s shipped %d changes", deployment.Id, len(changes))
+		taskCtx.IncProgress(1)
+	}
+	return batchSave.Flush()
+}
+
+// loadConsecutiveDeployments returns the successful deployment commits of the project having a previous one
+func loadConsecutiveDeployments(db dal.Dal, data *RefdiffTaskData) ([]*consecutiveDeployment, errors.Error) {
+	clauses := []dal.Clause{
+		dal.Select("dc.id, dc.cicd_scope_id, dc.repo_url, dc.environment, dc.commit_sha, dc.finished_date"),
+		dal.From("cicd_deployment_commits dc"),
+		dal.Join("LEFT JOIN project_mapping pm ON (pm.table = 'cicd_scopes' AND pm.row_id = dc.cicd_scope_id)"),
+		dal.Where(`
+			pm.project_name = ?
+			AND dc.finished_date IS NOT NULL
+			AND dc.environment IS NOT NULL
+			AND dc.environment != ''
+			AND dc.repo_url IS NOT NULL
+			AND dc.repo_url != ''
+			AND dc.result = ?
+			`,
+			data.Options.ProjectName, devops.RESULT_SUCCESS,
+		),
+	}
+	if data.Options.ProductionOnly {
+		clauses = append(clauses, dal.Where("dc.environment = ?", devops.PRODUCTION))
+	}
+	clauses = append(clauses, dal.Orderby("dc.repo_url, dc.environment, dc.finished_date"))
+	var deploymentCommits []*consecutiveDeployment
+	if err := db.All(&deploymentCommits, clauses...); err != nil {
+		return nil, err
+	}
+
+	deployments := make([]*consecutiveDeployment, 0, len(deploymentCommits))
+	var prev *consecutiveDeployment
+	for _, deploymentCommit := range deploymentCommits {
+		if prev != nil && prev.RepoUrl == deploymentCommit.RepoUrl && prev.Environment == deploymentCommit.Environment {
+			deploymentCommit.PrevId = prev.Id
+			deploymentCommit.PrevCommitSha = prev.CommitSha
+			deployments = append(deployments, deploymentCommit)
+		}
+		prev = deploymentCommit
+	}
+	return deployments, nil
+}
+
+// loadDeploymentChanges returns the commits of the diff, the pull requests of the project's repos merged by or
+// containing them and the issues linked to either of them
+func loadDeploymentChanges(db dal.Dal, projectName string, deployment *consecutiveDeployment) ([]*crossdomain.DeploymentChange, errors.Error) {
+	diffCommits := dal.Where("cd.new_commit_sha = ? AND cd.old_commit_sha = ?", deployment.CommitSha, deployment.PrevCommitSha)
+	var changes []*crossdomain.DeploymentChange
+
+	var commits []*code.Commit
+	err := db.All(
+		&commits,
+		dal.Select("commits.sha, commits.message"),
+		dal.From(&code.Commit{}),
+		dal.Join("JOIN commits_diffs cd ON cd.commit_sha = commits.sha"),
+		diffCommits,
+		dal.Orderby("cd.sorting_index"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range commits {
+		changes = append(changes, &crossdomain.DeploymentChange{
+			ChangeType: crossdomain.DEPLOYMENT_CHANGE_COMMIT,
+			ChangeId:   commit.Sha,
+			ChangeKey:  commit.Sha,
+			Title:      strings.SplitN(commit.Message, "\n", 2)[0],
+		})
+	}
+
+	var pullRequests []*code.PullRequest
+	err = db.All(
+		&pullRequests,
+		dal.Select("pull_requests.id, pull_requests.pull_request_key, pull_requests.title, pull_requests.url"),
+		dal.From(&code.PullRequest{}),
+		dal.Join("JOIN project_mapping pm ON (pm.table = 'repos' AND pm.row_id = pull_requests.base_repo_id)"),
+		dal.Where(
+			`pm.project_name = ? AND pull_requests.merged_date IS NOT NULL AND (
+				pull_requests.merge_commit_sha IN (
+					SELECT cd.commit_sha FROM commits_diffs cd WHERE cd.new_commit_sha = ? AND cd.old_commit_sha = ?
+				)
+				OR pull_requests.id IN (
+					SELECT prc.pull_request_id
+						FROM pull_request_commits prc
+							JOIN commits_diffs cd ON cd.commit_sha = prc.commit_sha
+						WHERE cd.new_commit_sha = ? AND cd.old_commit_sha = ?
+				)
+			)`,
+			projectName, deployment.CommitSha, deployment.PrevCommitSha, deployment.CommitSha, deployment.PrevCommitSha,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	prIds := make([]string, 0, len(pullRequests))
+	for _, pr := range pullRequests {
+		prIds = append(prIds, pr.Id)
+		changes = append(changes, &crossdomain.DeploymentChange{
+			ChangeType: crossdomain.DEPLOYMENT_CHANGE_PULL_REQUEST,
+			ChangeId:   pr.Id,
+			ChangeKey:  fmt.Sprintf("%d", pr.PullRequestKey),
+			Title:      pr.Title,
+			Url:        pr.Url,
+		})
+	}
+
+	var issues []*ticket.Issue
+	err = db.All(
+		&issues,
+		dal.Select("DISTINCT issues.id, issues.issue_key, issues.title, issues.url"),
+		dal.From(&ticket.Issue{}),
+		dal.Join("JOIN issue_commits ic ON ic.issue_id = issues.id"),
+		dal.Join("JOIN commits_diffs cd ON cd.commit_sha = ic.commit_sha"),
+		diffCommits,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(prIds) > 0 {
+		var prIssues []*ticket.Issue
+		err = db.All(
+			&prIssues,
+			dal.Select("DISTINCT issues.id, issues.issue_key, issues.title, issues.url"),
+			dal.From(&ticket.Issue{}),
+			dal.Join("JOIN pull_request_issues pri ON pri.issue_id = issues.id"),
+			dal.Where("pri.pull_request_id IN ?", prIds),
+		)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, prIssues...)
+	}
+	seen := make(map[string]bool)
+	for _, issue := range issues {
+		if seen[issue.Id] {
+			continue
+		}
+		seen[issue.Id] = true
+		changes = append(changes, &crossdomain.DeploymentChange{
+			ChangeType: crossdomain.DEPLOYMENT_CHANGE_ISSUE,
+			ChangeId:   issue.Id,
+			ChangeKey:  issue.IssueKey,
+			Title:      issue.Title,
+			Url:        issue.Url,
+		})
+	}
+	return changes, nil
+}
@@ -96,29 +96,7 @@ func CalculateDeploymentCommitsDiff(taskCtx plugin.SubTaskContext) errors.Error
 			return errors.Convert(ctx.Err())
 		default:
 		}
-		lostSha, oldCount, newCount := graph.CalculateLostSha(pair.PrevCommitSha, pair.CommitSha)
-		for i, sha := range lostSha {
-			commitsDiff := &code.CommitsDiff{
-				NewCommitSha: pair.CommitSha,
-				OldCommitSha: pair.PrevCommitSha,
-				CommitSha:    sha,
-				SortingIndex: i + 1,
-			}
-			err = batch_save.Add(commitsDiff)
-			if err != nil {
-				return err
-			}
-		}
-		err = batch_save.Flush()
-		if err != nil {
-			return err
-		}
-		// mark commits_diff were calculated, no need to do it again in the future
-		finishedCommitsDiff := &models.FinishedCommitsDiff{
-			NewCommitSha: pair.CommitSha,
-			OldCommitSha: pair.PrevCommitSha,
-		}
-		err = db.CreateOrUpdate(finishedCommitsDiff)
+		oldCount, newCount, err := saveCommitsDiff(db, batch_save, graph, pair.CommitSha, pair.PrevCommitSha)
 		if err != nil {
 			return err
 		}
@@ -135,6 +113,39 @@ func CalculateDeploymentCommitsDiff(taskCtx plugin.SubTaskContext) errors.Error
 	return nil
 }
 
+// saveCommitsDiff saves the commits reachable from the new commit but not from the old one to commits_diffs and
+// marks the pair as finished, so it won't be calculated again in the future. It returns the number of commits
+// reachable from the old commit and the number of commits of difference.
+func saveCommitsDiff(
+	db dal.Dal,
+	batchSave *api.BatchSave,
+	graph *utils.CommitNodeGraph,
+	newCommitSha, oldCommitSha string,
+) (int, int, errors.Error) {
+	lostSha, oldCount, newCount := graph.CalculateLostSha(oldCommitSha, newCommitSha)
+	for i, sha := range lostSha {
+		commitsDiff := &code.CommitsDiff{
+			NewCommitSha: newCommitSha,
+			OldCommitSha: oldCommitSha,
+			CommitSha:    sha,
+			SortingIndex: i + 1,
+		}
+		err := batchSave.Add(commitsDiff)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	err := batchSave.Flush()
+	if err != nil {
+		return 0, 0, err
+	}
+	err = db.CreateOrUpdate(&models.FinishedCommitsDiff{
+		NewCommitSha: newCommitSha,
+		OldCommitSha: oldCommitSha,
+	})
+	return oldCount, newCount, err
+}
+
 type deploymentCommitPair struct {
 	Id            string
 	CommitSha     string
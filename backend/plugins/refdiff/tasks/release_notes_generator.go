@@ -0,0 +1,471 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/refdiff/models"
+)
+
+var GenerateReleaseNotesMeta = plugin.SubTaskMeta{
+	Name:             "generateReleaseNotes",
+	EntryPoint:       GenerateReleaseNotes,
+	EnabledByDefault: false,
+	Description:      "Generate release notes between refs and save them as the description of cicd_releases",
+	DomainTypes:      []string{plugin.DOMAIN_TYPE_CODE, plugin.DOMAIN_TYPE_CICD},
+	DependencyTables: []string{code.CommitsDiff{}.TableName(), code.PullRequest{}.TableName(), ticket.Issue{}.TableName()},
+	ProductTables:    []string{devops.CicdRelease{}.TableName()},
+}
+
+// issue types listed first in release notes, the others follow alphabetically
+var releaseNotesIssueTypes = []string{ticket.REQUIREMENT, ticket.BUG, ticket.INCIDENT}
+
+// shas are passed to IN clauses this many at a time
+const releaseNotesChunkSize = 1000
+
+func GenerateReleaseNotes(taskCtx plugin.SubTaskContext) errors.Error {
+	data := taskCtx.GetData().(*RefdiffTaskData)
+	repoId := data.Options.RepoId
+	db := taskCtx.GetDal()
+	logger := taskCtx.GetLogger()
+
+	// the project level refdiff task planned by dora has no RepoId and hence no ref pairs, it only diffs the
+	// deployments of the project. release notes are generated by the repo level tasks, same as calculateCommitsDiff
+	if data.Options.ProjectName != "" {
+		return nil
+	}
+	pairs := data.Options.AllPairs
+	taskCtx.SetProgress(0, len(pairs))
+	for _, pair := range pairs {
+		notes, err := BuildReleaseNotes(taskCtx.GetContext(), db, repoId, pair[2], pair[3])
+		if err != nil {
+			return err
+		}
+		err = SaveReleaseNotes(db, notes)
+		if err != nil {
+			return err
+		}
+		logger.Info("release notes of %s since %s generated with %d commits", notes.NewRef, notes.OldRef, notes.CommitCount)
+		taskCtx.IncProgress(1)
+	}
+	return nil
+}
+
+// SaveReleaseNotes puts the markdown release notes into the description of the release of the new ref. The release
+// is looked up by the tag name first and by the commit only if the repo has no release for the tag, it is created if
+// neither matches. The description of a release collected from the data source is kept if it was written by hand,
+// the one generated by a previous run is recognized by releaseNotesMarker and regenerated.
+func SaveReleaseNotes(db dal.Dal, notes *models.ReleaseNotes) errors.Error {
+	tagName := strings.TrimPrefix(notes.NewRef, "refs/tags/")
+	release, err := findRelease(db, notes.RepoId, tagName, notes.NewCommitSha)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		ref, err := loadRef(db, notes.RepoId, notes.NewRef)
+		if err != nil {
+			return err
+		}
+		release = &devops.CicdRelease{
+			CicdScopeId:  notes.RepoId,
+			Name:         tagName,
+			DisplayTitle: tagName,
+			RepoId:       notes.RepoId,
+			TagName:      tagName,
+			CommitSha:    notes.NewCommitSha,
+			PublishedAt:  time.Now(),
+		}
+		release.Id = releaseNotesReleaseId(notes.RepoId, notes.NewRef)
+		if ref.CreatedDate != nil {
+			release.PublishedAt = *ref.CreatedDate
+		}
+	} else if release.Id != releaseNotesReleaseId(notes.RepoId, notes.NewRef) && release.Description != "" &&
+		!strings.HasSuffix(release.Description, releaseNotesMarker) {
+		return nil
+	}
+	release.Description = releaseNotesDescription(notes)
+	return db.CreateOrUpdate(release)
+}
+
+// releaseNotesMarker ends the descriptions generated by SaveReleaseNotes to tell them from the ones written by hand,
+// it is not rendered by markdown
+const releaseNotesMarker = "<!-- generated by devlake refdiff -->"
+
+func releaseNotesDescription(notes *models.ReleaseNotes) string {
+	return notes.Markdown() + "\n" + releaseNotesMarker
+}
+
+// releaseNotesReleaseId is the id of the release created for the ref when the repo has none
+func releaseNotesReleaseId(repoId, refName string) string {
+	return fmt.Sprintf("refdiff:CicdRelease:%s:%s", repoId, refName)
+}
+
+// findRelease returns the release of the repo for the tag, or for the commit if there is none for the tag
+func findRelease(db dal.Dal, repoId, tagName, commitSha string) (*devops.CicdRelease, errors.Error) {
+	for _, where := range []dal.Clause{
+		dal.Where("repo_id = ? AND tag_name = ?", repoId, tagName),
+		dal.Where("repo_id = ? AND commit_sha = ?", repoId, commitSha),
+	} {
+		release := &devops.CicdRelease{}
+		err := db.First(release, where)
+		if err == nil {
+			return release, nil
+		}
+		if !db.IsErrorNotFound(err) {
+			return nil, errors.Default.Wrap(err, "error finding cicd_release")
+		}
+	}
+	return nil, nil
+}
+
+func loadRef(db dal.Dal, repoId, refName string) (*code.Ref, errors.Error) {
+	if refName == "" {
+		return nil, errors.BadInput.New("ref name is empty")
+	}
+	ref := &code.Ref{}
+	ref.Id = fmt.Sprintf("%s:%s", repoId, refName)
+	err := db.First(ref)
+	if err != nil {
+		if db.IsErrorNotFound(err) {
+			return nil, errors.NotFound.New(fmt.Sprintf("ref %s not found in repo %s", refName, repoId))
+		}
+		return nil, errors.Default.Wrap(err, fmt.Sprintf("failed to load Ref info for repoId:%s, refName:%s", repoId, refName))
+	}
+	return ref, nil
+}
+
+// BuildReleaseNotes collects the changes from oldRef to newRef: the linked issues grouped by type, the merged pull
+// requests, the contributors and the breaking changes. The commits in between come from commits_diffs when
+// calculateCommitsDiff has handled the pair, otherwise they are calculated from the commit graph.
+func BuildReleaseNotes(ctx context.Context, db dal.Dal, repoId, newRef, oldRef string) (*models.ReleaseNotes, errors.Error) {
+	newRefInfo, err := loadRef(db, repoId, newRef)
+	if err != nil {
+		return nil, err
+	}
+	oldRefInfo, err := loadRef(db, repoId, oldRef)
+	if err != nil {
+		return nil, err
+	}
+	notes := &models.ReleaseNotes{
+		RepoId:       repoId,
+		NewRef:       newRef,
+		OldRef:       oldRef,
+		NewCommitSha: newRefInfo.CommitSha,
+		OldCommitSha: oldRefInfo.CommitSha,
+	}
+	shas, err := diffCommitShas(ctx, db, repoId, notes.NewCommitSha, notes.OldCommitSha)
+	if err != nil {
+		return nil, err
+	}
+	notes.CommitCount = len(shas)
+	if notes.PullRequests, err = loadMergedPullRequests(db, repoId, shas); err != nil {
+		return nil, err
+	}
+	if notes.IssueGroups, err = loadIssueGroups(db, notes.PullRequests, shas); err != nil {
+		return nil, err
+	}
+	if notes.Contributors, err = loadContributors(db, shas); err != nil {
+		return nil, err
+	}
+	if notes.BreakingChanges, err = loadBreakingChanges(db, shas); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// diffCommitShas returns the commits reachable from the new commit but not from the old one
+func diffCommitShas(ctx context.Context, db dal.Dal, repoId, newSha, oldSha string) ([]string, errors.Error) {
+	if newSha == oldSha {
+		return nil, nil
+	}
+	count, err := db.Count(
+		dal.From(&models.FinishedCommitsDiff{}),
+		dal.Where("new_commit_sha = ? AND old_commit_sha = ?", newSha, oldSha),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var shas []string
+	if count > 0 {
+		err = db.Pluck(
+			"commit_sha",
+			&shas,
+			dal.From(&code.CommitsDiff{}),
+			dal.Where("new_commit_sha = ? AND old_commit_sha = ?", newSha, oldSha),
+			dal.Orderby("sorting_index"),
+		)
+		return shas, err
+	}
+	commitNodeGraph, err := LoadCommitNodeGraph(ctx, db, repoId)
+	if err != nil {
+		return nil, err
+	}
+	shas, _, _ = commitNodeGraph.CalculateLostSha(oldSha, newSha)
+	return shas, nil
+}
+
+// inChunks calls fn with the shas split into chunks small enough for IN clauses
+func inChunks(shas []string, fn func(chunk []string) errors.Error) errors.Error {
+	for start := 0; start < len(shas); start += releaseNotesChunkSize {
+		end := start + releaseNotesChunkSize
+		if end > len(shas) {
+			end = len(shas)
+		}
+		if err := fn(shas[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMergedPullRequests returns the pull requests of the repo merged by or containing one of the commits
+func loadMergedPullRequests(db dal.Dal, repoId string, shas []string) ([]*models.ReleaseNotesPullRequest, errors.Error) {
+	pullRequests := make([]*models.ReleaseNotesPullRequest, 0)
+	seen := make(map[string]bool)
+	err := inChunks(shas, func(chunk []string) errors.Error {
+		var prs []*code.PullRequest
+		err := db.All(
+			&prs,
+			dal.From(&code.PullRequest{}),
+			dal.Where(
+				`base_repo_id = ? AND merged_date IS NOT NULL AND (merge_commit_sha IN ?
+					OR id IN (SELECT pull_request_id FROM pull_request_commits WHERE commit_sha IN ?))`,
+				repoId, chunk, chunk,
+			),
+		)
+		if err != nil {
+			return err
+		}
+		for _, pr := range prs {
+			if seen[pr.Id] {
+				continue
+			}
+			seen[pr.Id] = true
+			pullRequests = append(pullRequests, &models.ReleaseNotesPullRequest{
+				Id:             pr.Id,
+				PullRequestKey: pr.PullRequestKey,
+				Title:          pr.Title,
+				Url:            pr.Url,
+				AuthorName:     pr.AuthorName,
+				MergedDate:     pr.MergedDate,
+			})
+		}
+		return nil
+	})
+	sort.SliceStable(pullRequests, func(i, j int) bool {
+		return pullRequests[i].MergedDate.Before(*pullRequests[j].MergedDate)
+	})
+	return pullRequests, err
+}
+
+// loadIssueGroups returns the issues linked to the pull requests or directly to the commits, grouped by type
+func loadIssueGroups(db dal.Dal, pullRequests []*models.ReleaseNotesPullRequest, shas []string) ([]*models.ReleaseNotesIssueGroup, errors.Error) {
+	var issues []*ticket.Issue
+	prIds := make([]string, 0, len(pullRequests))
+	for _, pr := range pullRequests {
+		prIds = append(prIds, pr.Id)
+	}
+	err := inChunks(prIds, func(chunk []string) errors.Error {
+		var prIssues []*ticket.Issue
+		err := db.All(
+			&prIssues,
+			dal.Select("DISTINCT issues.*"),
+			dal.From(&ticket.Issue{}),
+			dal.Join("JOIN pull_request_issues pri ON pri.issue_id = issues.id"),
+			dal.Where("pri.pull_request_id IN ?", chunk),
+		)
+		issues = append(issues, prIssues...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = inChunks(shas, func(chunk []string) errors.Error {
+		var commitIssues []*ticket.Issue
+		err := db.All(
+			&commitIssues,
+			dal.Select("DISTINCT issues.*"),
+			dal.From(&ticket.Issue{}),
+			dal.Join("JOIN issue_commits ic ON ic.issue_id = issues.id"),
+			dal.Where("ic.commit_sha IN ?", chunk),
+		)
+		issues = append(issues, commitIssues...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*models.ReleaseNotesIssueGroup)
+	seen := make(map[string]bool)
+	for _, issue := range issues {
+		if seen[issue.Id] {
+			continue
+		}
+		seen[issue.Id] = true
+		issueType := issue.Type
+		if issueType == "" {
+			issueType = ticket.OTHER
+		}
+		group, ok := groups[issueType]
+		if !ok {
+			group = &models.ReleaseNotesIssueGroup{Type: issueType}
+			groups[issueType] = group
+		}
+		group.Issues = append(group.Issues, &models.ReleaseNotesIssue{
+			Id:       issue.Id,
+			IssueKey: issue.IssueKey,
+			Title:    issue.Title,
+			Url:      issue.Url,
+			Status:   issue.Status,
+		})
+	}
+
+	var otherTypes []string
+	for issueType := range groups {
+		if !isReleaseNotesIssueType(issueType) {
+			otherTypes = append(otherTypes, issueType)
+		}
+	}
+	sort.Strings(otherTypes)
+	issueGroups := make([]*models.ReleaseNotesIssueGroup, 0, len(groups))
+	for _, issueType := range append(append([]string{}, releaseNotesIssueTypes...), otherTypes...) {
+		group, ok := groups[issueType]
+		if !ok {
+			continue
+		}
+		sort.Slice(group.Issues, func(i, j int) bool {
+			return group.Issues[i].IssueKey < group.Issues[j].IssueKey
+		})
+		issueGroups = append(issueGroups, group)
+	}
+	return issueGroups, nil
+}
+
+func isReleaseNotesIssueType(issueType string) bool {
+	for _, t := range releaseNotesIssueTypes {
+		if t == issueType {
+			return true
+		}
+	}
+	return false
+}
+
+// loadContributors returns the authors and co-authors of the commits, the most active first
+func loadContributors(db dal.Dal, shas []string) ([]*models.ReleaseNotesContributor, errors.Error) {
+	contributors := make([]*models.ReleaseNotesContributor, 0)
+	byEmail := make(map[string]*models.ReleaseNotesContributor)
+	add := func(name, email string) {
+		key := strings.ToLower(email)
+		if key == "" {
+			key = name
+		}
+		contributor, ok := byEmail[key]
+		if !ok {
+			contributor = &models.ReleaseNotesContributor{Name: name, Email: email}
+			byEmail[key] = contributor
+			contributors = append(contributors, contributor)
+		}
+		contributor.CommitCount++
+	}
+	err := inChunks(shas, func(chunk []string) errors.Error {
+		var commits []*code.Commit
+		err := db.All(
+			&commits,
+			dal.Select("sha, author_name, author_email"),
+			dal.From(&code.Commit{}),
+			dal.Where("sha IN ?", chunk),
+		)
+		if err != nil {
+			return err
+		}
+		for _, commit := range commits {
+			add(commit.AuthorName, commit.AuthorEmail)
+		}
+		var coAuthors []*code.CommitTrailer
+		err = db.All(
+			&coAuthors,
+			dal.From(&code.CommitTrailer{}),
+			dal.Where("commit_sha IN ? AND commit_trailers.key = ? AND email != ''", chunk, "Co-authored-by"),
+		)
+		if err != nil {
+			return err
+		}
+		for _, coAuthor := range coAuthors {
+			add(coAuthor.Name, coAuthor.Email)
+		}
+		return nil
+	})
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return contributors[i].CommitCount > contributors[j].CommitCount
+	})
+	return contributors, err
+}
+
+// loadBreakingChanges returns the commits marked breaking, described by their `BREAKING CHANGE` trailer if any
+func loadBreakingChanges(db dal.Dal, shas []string) ([]*models.ReleaseNotesBreakingChange, errors.Error) {
+	changes := make([]*models.ReleaseNotesBreakingChange, 0)
+	err := inChunks(shas, func(chunk []string) errors.Error {
+		var classifications []*code.CommitClassification
+		err := db.All(
+			&classifications,
+			dal.From(&code.CommitClassification{}),
+			dal.Where("commit_sha IN ? AND is_breaking = ?", chunk, true),
+		)
+		if err != nil {
+			return err
+		}
+		var trailers []*code.CommitTrailer
+		err = db.All(
+			&trailers,
+			dal.From(&code.CommitTrailer{}),
+			dal.Where("commit_sha IN ? AND commit_trailers.key = ?", chunk, "BREAKING CHANGE"),
+		)
+		if err != nil {
+			return err
+		}
+		descriptions := make(map[string]string)
+		for _, trailer := range trailers {
+			descriptions[trailer.CommitSha] = trailer.Value
+		}
+		for _, classification := range classifications {
+			change := &models.ReleaseNotesBreakingChange{
+				CommitSha:   classification.CommitSha,
+				Scope:       classification.Scope,
+				Description: classification.Description,
+			}
+			if description, ok := descriptions[classification.CommitSha]; ok {
+				change.Description = description
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	return changes, err
+}
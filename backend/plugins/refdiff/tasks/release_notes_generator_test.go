@@ -0,0 +1,257 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/code"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/ticket"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/apache/incubator-devlake/plugins/refdiff/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// whereParams returns the params of the where clause
+func whereParams(clauses []dal.Clause) []interface{} {
+	for _, clause := range clauses {
+		if clause.Type == dal.WhereClause {
+			return clause.Data.(dal.DalClause).Params
+		}
+	}
+	return nil
+}
+
+// joins reports whether one of the join clauses mentions the table
+func joins(clauses []dal.Clause, table string) bool {
+	for _, clause := range clauses {
+		if clause.Type == dal.JoinClause && strings.Contains(clause.Data.(dal.DalClause).Expr, table) {
+			return true
+		}
+	}
+	return false
+}
+
+// mockReleaseNotesDal serves v1.0.0 -> v1.1.0 of repo1, the pair has been handled by calculateCommitsDiff
+// and brings in the commits c1 and c2
+func mockReleaseNotesDal(t *testing.T) *mockdal.Dal {
+	mockDal := mockdal.NewDal(t)
+	refShas := map[string]string{"repo1:v1.1.0": "new", "repo1:v1.0.0": "old"}
+	mockDal.On("First", mock.AnythingOfType("*code.Ref"), mock.Anything).Return(
+		func(dst interface{}, _ ...dal.Clause) errors.Error {
+			ref := dst.(*code.Ref)
+			sha, ok := refShas[ref.Id]
+			if !ok {
+				return errors.NotFound.New("record not found")
+			}
+			ref.CommitSha = sha
+			return nil
+		},
+	)
+	mockDal.On("IsErrorNotFound", mock.Anything).Return(
+		func(err error) bool {
+			return err.(errors.Error).GetType() == errors.NotFound
+		},
+	).Maybe()
+	mockDal.On("Count", mock.Anything).Return(int64(1), nil).Maybe()
+	mockDal.On("Pluck", "commit_sha", mock.Anything, mock.Anything).Return(
+		func(_ string, dst interface{}, clauses ...dal.Clause) errors.Error {
+			assert.Equal(t, []interface{}{"new", "old"}, whereParams(clauses))
+			*dst.(*[]string) = []string{"c2", "c1"}
+			return nil
+		},
+	).Maybe()
+
+	mergedDate := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	mockDal.On("All", mock.Anything, mock.Anything).Return(
+		func(dst interface{}, clauses ...dal.Clause) errors.Error {
+			params := whereParams(clauses)
+			switch dst := dst.(type) {
+			case *[]*code.PullRequest:
+				assert.Equal(t, "repo1", params[0])
+				pr := &code.PullRequest{PullRequestKey: 12, Title: "feat: release notes", AuthorName: "alice", MergedDate: &mergedDate}
+				pr.Id = "pr1"
+				// the pr both merges c2 and contains c1, it is listed once
+				*dst = []*code.PullRequest{pr, pr}
+			case *[]*ticket.Issue:
+				bug := &ticket.Issue{DomainEntity: domainlayer.DomainEntity{Id: "i1"}, IssueKey: "DL-1", Title: "Fix crash", Type: ticket.BUG}
+				if joins(clauses, "pull_request_issues") {
+					assert.Equal(t, []interface{}{[]string{"pr1"}}, params)
+					*dst = []*ticket.Issue{bug}
+				} else {
+					assert.True(t, joins(clauses, "issue_commits"))
+					*dst = []*ticket.Issue{
+						bug,
+						{DomainEntity: domainlayer.DomainEntity{Id: "i2"}, IssueKey: "DL-2", Title: "Add release notes", Type: ticket.REQUIREMENT},
+						{DomainEntity: domainlayer.DomainEntity{Id: "i3"}, IssueKey: "DL-3", Title: "Bump deps", Type: "CHORE"},
+					}
+				}
+			case *[]*code.Commit:
+				*dst = []*code.Commit{
+					{Sha: "c1", AuthorName: "alice", AuthorEmail: "alice@example.com"},
+					{Sha: "c2", AuthorName: "bob", AuthorEmail: "bob@example.com"},
+				}
+			case *[]*code.CommitTrailer:
+				if params[1] == "Co-authored-by" {
+					*dst = []*code.CommitTrailer{{CommitSha: "c2", Key: "Co-authored-by", Name: "Alice", Email: "ALICE@example.com"}}
+				} else {
+					assert.Equal(t, "BREAKING CHANGE", params[1])
+					*dst = []*code.CommitTrailer{{CommitSha: "c2", Key: "BREAKING CHANGE", Value: "drop v1 endpoints"}}
+				}
+			case *[]*code.CommitClassification:
+				*dst = []*code.CommitClassification{{CommitSha: "c2", Scope: "api", IsBreaking: true, Description: "remove api v1"}}
+			default:
+				t.Fatalf("unexpected query for %T", dst)
+			}
+			return nil
+		},
+	).Maybe()
+	return mockDal
+}
+
+func TestBuildReleaseNotes(t *testing.T) {
+	notes, err := BuildReleaseNotes(context.Background(), mockReleaseNotesDal(t), "repo1", "v1.1.0", "v1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "new", notes.NewCommitSha)
+	assert.Equal(t, "old", notes.OldCommitSha)
+	assert.Equal(t, 2, notes.CommitCount)
+
+	if assert.Len(t, notes.PullRequests, 1) {
+		assert.Equal(t, "pr1", notes.PullRequests[0].Id)
+	}
+	var issueTypes []string
+	for _, group := range notes.IssueGroups {
+		issueTypes = append(issueTypes, group.Type)
+		assert.Len(t, group.Issues, 1)
+	}
+	assert.Equal(t, []string{ticket.REQUIREMENT, ticket.BUG, "CHORE"}, issueTypes)
+
+	// the co-author matches alice by email regardless of the case
+	assert.Equal(t, []*models.ReleaseNotesContributor{
+		{Name: "alice", Email: "alice@example.com", CommitCount: 2},
+		{Name: "bob", Email: "bob@example.com", CommitCount: 1},
+	}, notes.Contributors)
+	// the trailer takes precedence over the description of the header
+	assert.Equal(t, []*models.ReleaseNotesBreakingChange{
+		{CommitSha: "c2", Scope: "api", Description: "drop v1 endpoints"},
+	}, notes.BreakingChanges)
+}
+
+func TestBuildReleaseNotesSameCommit(t *testing.T) {
+	mockDal := mockdal.NewDal(t)
+	mockDal.On("First", mock.AnythingOfType("*code.Ref"), mock.Anything).Return(
+		func(dst interface{}, _ ...dal.Clause) errors.Error {
+			dst.(*code.Ref).CommitSha = "same"
+			return nil
+		},
+	)
+	notes, err := BuildReleaseNotes(context.Background(), mockDal, "repo1", "v1.0.1", "v1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, notes.CommitCount)
+	assert.Empty(t, notes.PullRequests)
+	assert.Empty(t, notes.IssueGroups)
+	assert.Empty(t, notes.Contributors)
+}
+
+func TestBuildReleaseNotesRefNotFound(t *testing.T) {
+	_, err := BuildReleaseNotes(context.Background(), mockReleaseNotesDal(t), "repo1", "v2.0.0", "v1.0.0")
+	assert.Equal(t, errors.NotFound, err.GetType())
+}
+
+// mockReleasesDal serves the releases of repo1 by tag name and by commit, the saved release is passed to save
+func mockReleasesDal(t *testing.T, byTag, byCommit *devops.CicdRelease, save func(release *devops.CicdRelease)) *mockdal.Dal {
+	mockDal := mockdal.NewDal(t)
+	mockDal.On("First", mock.AnythingOfType("*devops.CicdRelease"), mock.Anything).Return(
+		func(dst interface{}, clauses ...dal.Clause) errors.Error {
+			release := byCommit
+			params := whereParams(clauses)
+			if strings.Contains(clauses[0].Data.(dal.DalClause).Expr, "tag_name") {
+				assert.Equal(t, []interface{}{"repo1", "v1.1.0"}, params)
+				release = byTag
+			} else {
+				assert.Equal(t, []interface{}{"repo1", "new"}, params)
+			}
+			if release == nil {
+				return errors.NotFound.New("record not found")
+			}
+			*dst.(*devops.CicdRelease) = *release
+			return nil
+		},
+	)
+	mockDal.On("First", mock.AnythingOfType("*code.Ref"), mock.Anything).Return(nil).Maybe()
+	mockDal.On("IsErrorNotFound", mock.Anything).Return(true).Maybe()
+	mockDal.On("CreateOrUpdate", mock.Anything, mock.Anything).Return(func(entity interface{}, _ ...dal.Clause) errors.Error {
+		save(entity.(*devops.CicdRelease))
+		return nil
+	}).Maybe()
+	return mockDal
+}
+
+func TestSaveReleaseNotes(t *testing.T) {
+	notes := &models.ReleaseNotes{RepoId: "repo1", NewRef: "refs/tags/v1.1.0", NewCommitSha: "new", CommitCount: 2}
+	release := func(id, tagName, description string) *devops.CicdRelease {
+		r := &devops.CicdRelease{TagName: tagName, CommitSha: "new", Description: description}
+		r.Id = id
+		return r
+	}
+	var saved *devops.CicdRelease
+	save := func(release *devops.CicdRelease) {
+		saved = release
+	}
+
+	// the release of the tag wins over the one of the same commit
+	assert.Nil(t, SaveReleaseNotes(mockReleasesDal(t, release("github:1", "v1.1.0", ""), release("github:2", "nightly", ""), save), notes))
+	assert.Equal(t, "github:1", saved.Id)
+	assert.Equal(t, releaseNotesDescription(notes), saved.Description)
+
+	// the description written on the data source is kept
+	saved = nil
+	assert.Nil(t, SaveReleaseNotes(mockReleasesDal(t, release("github:1", "v1.1.0", "hand written"), nil, save), notes))
+	assert.Nil(t, saved)
+
+	// the notes generated before on the release of the data source are regenerated
+	previous := &models.ReleaseNotes{RepoId: "repo1", NewRef: "refs/tags/v1.1.0", OldRef: "refs/tags/v1.0.0", NewCommitSha: "new", CommitCount: 1}
+	assert.Nil(t, SaveReleaseNotes(mockReleasesDal(t, release("github:1", "v1.1.0", releaseNotesDescription(previous)), nil, save), notes))
+	assert.Equal(t, "github:1", saved.Id)
+	assert.Equal(t, releaseNotesDescription(notes), saved.Description)
+	assert.NotContains(t, saved.Description, "Changes since")
+
+	// the notes generated before on their own release are refreshed
+	own := release("refdiff:CicdRelease:repo1:refs/tags/v1.1.0", "v1.1.0", "old notes")
+	assert.Nil(t, SaveReleaseNotes(mockReleasesDal(t, own, nil, save), notes))
+	assert.Equal(t, releaseNotesDescription(notes), saved.Description)
+
+	// the release of the commit is used only if the tag has none
+	assert.Nil(t, SaveReleaseNotes(mockReleasesDal(t, nil, release("github:2", "nightly", ""), save), notes))
+	assert.Equal(t, "github:2", saved.Id)
+
+	// a release is created for the tag otherwise
+	assert.Nil(t, SaveReleaseNotes(mockReleasesDal(t, nil, nil, save), notes))
+	assert.Equal(t, "refdiff:CicdRelease:repo1:refs/tags/v1.1.0", saved.Id)
+	assert.Equal(t, "v1.1.0", saved.TagName)
+	assert.Equal(t, "v1.1.0", saved.Name)
+	assert.Equal(t, releaseNotesDescription(notes), saved.Description)
+}
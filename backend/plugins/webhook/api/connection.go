@@ -27,6 +27,7 @@ import (
 	"github.com/apache/incubator-devlake/core/errors"
 	coreModels "github.com/apache/incubator-devlake/core/models"
 	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/core/utils"
 	"github.com/apache/incubator-devlake/plugins/webhook/models"
 )
 
@@ -53,11 +54,16 @@ func PostConnections(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput,
 		}
 		return nil, err
 	}
-	logger.Info("connection: %+v", connection)
+	logger.Info("connection: %+v", connection.Sanitize())
 	name := apiKeyHelper.GenApiKeyNameForPlugin(pluginName, connection.ID)
-	allowedPath := fmt.Sprintf("/plugins/%s/connections/%d/.*", pluginName, connection.ID)
+	// the key may only push data to the endpoints of its connection, not manage the connection itself
+	scopes := &coreModels.ApiKeyScopes{
+		Methods:              []string{http.MethodPost},
+		Resources:            []string{"plugins/" + pluginName},
+		WebhookConnectionIds: []uint64{connection.ID},
+	}
 	extra := fmt.Sprintf("connectionId:%d", connection.ID)
-	apiKeyRecord, err := apiKeyHelper.CreateForPlugin(tx, input.User, name, pluginName, allowedPath, extra)
+	apiKeyRecord, err := apiKeyHelper.CreateForPlugin(tx, input.User, name, pluginName, scopes, extra)
 	if err != nil {
 		if err := tx.Rollback(); err != nil {
 			logger.Error(err, "transaction Rollback")
@@ -90,11 +96,17 @@ func PostConnections(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput,
 // @Router /plugins/webhook/connections/{connectionId} [PATCH]
 func PatchConnection(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
 	connection := &models.WebhookConnection{}
-	err := connectionHelper.Patch(connection, input)
+	err := connectionHelper.First(connection, input.Params)
+	if err != nil {
+		return nil, err
+	}
+	keepSanitizedSecret(connection, input)
+	err = connectionHelper.Patch(connection, input)
 	if err != nil {
 		return nil, err
 	}
-	return &plugin.ApiResourceOutput{Body: connection}, nil
+	sanitized := connection.Sanitize()
+	return &plugin.ApiResourceOutput{Body: &sanitized}, nil
 }
 
 // PatchConnectionByName
@@ -108,11 +120,24 @@ func PatchConnection(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput,
 // @Router /plugins/webhook/connections/by-name/{connectionName} [PATCH]
 func PatchConnectionByName(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
 	connection := &models.WebhookConnection{}
-	err := connectionHelper.PatchByName(connection, input)
+	err := connectionHelper.FirstByName(connection, input.Params)
+	if err != nil {
+		return nil, err
+	}
+	keepSanitizedSecret(connection, input)
+	err = connectionHelper.PatchByName(connection, input)
 	if err != nil {
 		return nil, err
 	}
-	return &plugin.ApiResourceOutput{Body: connection}, nil
+	sanitized := connection.Sanitize()
+	return &plugin.ApiResourceOutput{Body: &sanitized}, nil
+}
+
+// keepSanitizedSecret drops the secret from the patch body if the client sent back the sanitized value
+func keepSanitizedSecret(connection *models.WebhookConnection, input *plugin.ApiResourceInput) {
+	if secret, ok := input.Body["secret"].(string); ok && secret != "" && secret == utils.SanitizeString(connection.Secret) {
+		delete(input.Body, "secret")
+	}
 }
 
 // DeleteConnection
@@ -164,6 +189,14 @@ func deleteConnection(e error, connectionId uint64) (*plugin.ApiResourceOutput,
 		logger.Error(err, "delete connection: %d", connectionId)
 		return nil, err
 	}
+	err = tx.Delete(&models.WebhookNonce{}, dal.Where("connection_id = ?", connectionId))
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			logger.Error(err, "transaction Rollback")
+		}
+		logger.Error(err, "delete nonces of connection: %d", connectionId)
+		return nil, err
+	}
 	extra := fmt.Sprintf("connectionId:%d", connectionId)
 	err = apiKeyHelper.DeleteForPlugin(tx, pluginName, extra)
 	if err != nil {
@@ -188,6 +221,9 @@ type WebhookConnectionResponse struct {
 	PostPipelineTaskEndpoint       string             `json:"postPipelineTaskEndpoint"`
 	PostPipelineDeployTaskEndpoint string             `json:"postPipelineDeployTaskEndpoint"`
 	ClosePipelineEndpoint          string             `json:"closePipelineEndpoint"`
+	PostGithubDeploymentEndpoint   string             `json:"postGithubDeploymentEndpoint"`
+	PostGitlabDeploymentEndpoint   string             `json:"postGitlabDeploymentEndpoint"`
+	PostJenkinsDeploymentEndpoint  string             `json:"postJenkinsDeploymentEndpoint"`
 	ApiKey                         *coreModels.ApiKey `json:"apiKey,omitempty"`
 }
 
@@ -254,13 +290,16 @@ func getConnection(err errors.Error, connection *models.WebhookConnection) (*plu
 }
 
 func formatConnection(connection *models.WebhookConnection, withApiKeyInfo bool) (*WebhookConnectionResponse, errors.Error) {
-	response := &WebhookConnectionResponse{WebhookConnection: *connection}
+	response := &WebhookConnectionResponse{WebhookConnection: connection.Sanitize()}
 	response.PostIssuesEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/issues`, connection.ID)
 	response.CloseIssuesEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/issue/:issueKey/close`, connection.ID)
 	response.PostPullRequestsEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/pull_requests`, connection.ID)
 	response.PostPipelineTaskEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/cicd_tasks`, connection.ID)
 	response.PostPipelineDeployTaskEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/deployments`, connection.ID)
 	response.ClosePipelineEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/cicd_pipeline/:pipelineName/finish`, connection.ID)
+	response.PostGithubDeploymentEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/github`, connection.ID)
+	response.PostGitlabDeploymentEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/gitlab`, connection.ID)
+	response.PostJenkinsDeploymentEndpoint = fmt.Sprintf(`/rest/plugins/webhook/connections/%d/jenkins`, connection.ID)
 	if withApiKeyInfo {
 		db := basicRes.GetDal()
 		apiKeyName := apiKeyHelper.GenApiKeyNameForPlugin(pluginName, connection.ID)
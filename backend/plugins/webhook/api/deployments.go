@@ -77,10 +77,12 @@ type WebhookDeploymentCommitReq struct {
 // @Description example1: {"repo_url":"devlake","commit_sha":"015e3d3b480e417aede5a1293bd61de9b0fd051d","start_time":"2020-01-01T12:00:00+00:00","end_time":"2020-01-01T12:59:59+00:00","environment":"PRODUCTION"}<br/>
 // @Description So we suggest request before task after deployment pipeline finish.
 // @Description Both cicd_pipeline and cicd_task will be created
+// @Description When the connection has a secret, the request must carry the X-DevLake-Signature, X-DevLake-Timestamp and X-DevLake-Nonce headers
 // @Tags plugins/webhook
 // @Param body body WebhookDeploymentReq true "json body"
 // @Success 200
 // @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
 // @Failure 403  {string} errcode.Error "Forbidden"
 // @Failure 500  {string} errcode.Error "Internal Error"
 // @Router /plugins/webhook/connections/:connectionId/deployments [POST]
@@ -97,10 +99,12 @@ func PostDeployments(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput,
 // @Description example1: {"repo_url":"devlake","commit_sha":"015e3d3b480e417aede5a1293bd61de9b0fd051d","start_time":"2020-01-01T12:00:00+00:00","end_time":"2020-01-01T12:59:59+00:00","environment":"PRODUCTION"}<br/>
 // @Description So we suggest request before task after deployment pipeline finish.
 // @Description Both cicd_pipeline and cicd_task will be created
+// @Description When the connection has a secret, the request must carry the X-DevLake-Signature, X-DevLake-Timestamp and X-DevLake-Nonce headers
 // @Tags plugins/webhook
 // @Param body body WebhookDeploymentReq true "json body"
 // @Success 200
 // @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
 // @Failure 403  {string} errcode.Error "Forbidden"
 // @Failure 500  {string} errcode.Error "Internal Error"
 // @Router /plugins/webhook/connections/by-name/:connectionName/deployments [POST]
@@ -117,10 +121,12 @@ func PostDeploymentsByName(input *plugin.ApiResourceInput) (*plugin.ApiResourceO
 // @Description example1: {"repo_url":"devlake","commit_sha":"015e3d3b480e417aede5a1293bd61de9b0fd051d","start_time":"2020-01-01T12:00:00+00:00","end_time":"2020-01-01T12:59:59+00:00","environment":"PRODUCTION"}<br/>
 // @Description So we suggest request before task after deployment pipeline finish.
 // @Description Both cicd_pipeline and cicd_task will be created
+// @Description When the connection has a secret, the request must carry the X-DevLake-Signature, X-DevLake-Timestamp and X-DevLake-Nonce headers
 // @Tags plugins/webhook
 // @Param body body WebhookDeploymentReq true "json body"
 // @Success 200
 // @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
 // @Failure 403  {string} errcode.Error "Forbidden"
 // @Failure 500  {string} errcode.Error "Internal Error"
 // @Router /projects/:projectName/deployments [POST]
@@ -214,22 +220,29 @@ func postDeployments(input *plugin.ApiResourceInput, connection *models.WebhookC
 	if err != nil {
 		return nil, err
 	}
+	if err = verifyDevlakeSignature(connection, input); err != nil {
+		return nil, err
+	}
 	// get request
 	request := &WebhookDeploymentReq{}
 	err = api.DecodeMapStruct(input.Body, request, true)
 	if err != nil {
 		return &plugin.ApiResourceOutput{Body: err.Error(), Status: http.StatusBadRequest}, nil
 	}
+	return saveDeployment(connection, request)
+}
+
+func saveDeployment(connection *models.WebhookConnection, request *WebhookDeploymentReq) (*plugin.ApiResourceOutput, errors.Error) {
 	// validate
 	vld = validator.New()
-	err = errors.Convert(vld.Struct(request))
+	err := errors.Convert(vld.Struct(request))
 	if err != nil {
 		return nil, errors.BadInput.Wrap(vld.Struct(request), `input json error`)
 	}
 	txHelper := dbhelper.NewTxHelper(basicRes, &err)
 	defer txHelper.End()
 	tx := txHelper.Begin()
-	if err := CreateDeploymentAndDeploymentCommits(connection, request, tx, logger); err != nil {
+	if err = CreateDeploymentAndDeploymentCommits(connection, request, tx, logger); err != nil {
 		logger.Error(err, "create deployments")
 		return nil, err
 	}
@@ -0,0 +1,162 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/webhook/models"
+)
+
+const githubEventHeader = "X-GitHub-Event"
+
+var githubDeploymentResultRule = &devops.ResultRule{
+	Success: []string{"success"},
+	Failure: []string{"failure", "error"},
+}
+
+type githubDeploymentStatusEvent struct {
+	Action           string `json:"action"`
+	DeploymentStatus struct {
+		Id          int64      `json:"id"`
+		State       string     `json:"state"`
+		Environment string     `json:"environment"`
+		Description string     `json:"description"`
+		CreatedAt   *time.Time `json:"created_at"`
+		UpdatedAt   *time.Time `json:"updated_at"`
+	} `json:"deployment_status"`
+	Deployment struct {
+		Id                    int64      `json:"id"`
+		Sha                   string     `json:"sha"`
+		Ref                   string     `json:"ref"`
+		Task                  string     `json:"task"`
+		Environment           string     `json:"environment"`
+		Description           string     `json:"description"`
+		ProductionEnvironment bool       `json:"production_environment"`
+		CreatedAt             *time.Time `json:"created_at"`
+	} `json:"deployment"`
+	Repository struct {
+		Id       int64  `json:"id"`
+		FullName string `json:"full_name"`
+		HtmlUrl  string `json:"html_url"`
+	} `json:"repository"`
+}
+
+// PostGithubDeployments
+// @Summary create deployment by github webhook
+// @Description Create deployment from the `deployment_status` event sent by a github webhook.<br/>
+// @Description The `X-Hub-Signature-256` header is verified when the connection has a secret.
+// @Description No api key is needed then, the secret is required without one.
+// @Description Only the final states (success, failure and error) create a deployment, other events are ignored.
+// @Tags plugins/webhook
+// @Param environment query string false "override the environment classified from the payload"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/webhook/connections/:connectionId/github [POST]
+func PostGithubDeployments(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	connection := &models.WebhookConnection{}
+	err := connectionHelper.First(connection, input.Params)
+
+	return postNativeDeployments(input, connection, err, verifyGithubSignature, convertGithubDeploymentStatus)
+}
+
+// PostGithubDeploymentsByName
+// @Summary create deployment by github webhook and connection name
+// @Description Create deployment from the `deployment_status` event sent by a github webhook.<br/>
+// @Description The `X-Hub-Signature-256` header is verified when the connection has a secret.
+// @Description No api key is needed then, the secret is required without one.
+// @Description Only the final states (success, failure and error) create a deployment, other events are ignored.
+// @Tags plugins/webhook
+// @Param environment query string false "override the environment classified from the payload"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/webhook/connections/by-name/:connectionName/github [POST]
+func PostGithubDeploymentsByName(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	connection := &models.WebhookConnection{}
+	err := connectionHelper.FirstByName(connection, input.Params)
+
+	return postNativeDeployments(input, connection, err, verifyGithubSignature, convertGithubDeploymentStatus)
+}
+
+func convertGithubDeploymentStatus(input *plugin.ApiResourceInput) (*WebhookDeploymentReq, errors.Error) {
+	if event := getHeader(input, githubEventHeader); event != "" && event != "deployment_status" {
+		return nil, nil
+	}
+	event := &githubDeploymentStatusEvent{}
+	if err := json.Unmarshal(input.RawBody, event); err != nil {
+		return nil, errors.BadInput.Wrap(err, "invalid github deployment_status payload")
+	}
+	result := devops.GetResult(githubDeploymentResultRule, event.DeploymentStatus.State)
+	if result == devops.RESULT_DEFAULT {
+		return nil, nil
+	}
+	if event.Deployment.Sha == "" || event.Repository.HtmlUrl == "" {
+		return nil, errors.BadInput.New("deployment sha and repository url are required")
+	}
+
+	originalEnvironment := event.Deployment.Environment
+	if event.DeploymentStatus.Environment != "" {
+		originalEnvironment = event.DeploymentStatus.Environment
+	}
+	environment := classifyEnvironment(originalEnvironment)
+	if event.Deployment.ProductionEnvironment {
+		environment = devops.PRODUCTION
+	}
+	startedDate := event.Deployment.CreatedAt
+	finishedDate := event.DeploymentStatus.UpdatedAt
+	if finishedDate == nil {
+		finishedDate = event.DeploymentStatus.CreatedAt
+	}
+	displayTitle := event.Deployment.Description
+	if displayTitle == "" {
+		displayTitle = fmt.Sprintf("deploy %s to %s", event.Deployment.Ref, originalEnvironment)
+	}
+
+	return &WebhookDeploymentReq{
+		Id:                  fmt.Sprintf("github:%d", event.Deployment.Id),
+		DisplayTitle:        displayTitle,
+		Result:              result,
+		Environment:         environment,
+		OriginalEnvironment: originalEnvironment,
+		Name:                fmt.Sprintf("%s:%s", event.Repository.FullName, event.Deployment.Task),
+		DeploymentCommits: []WebhookDeploymentCommitReq{
+			{
+				DisplayTitle: displayTitle,
+				RepoUrl:      event.Repository.HtmlUrl,
+				Name:         event.Repository.FullName,
+				RefName:      event.Deployment.Ref,
+				CommitSha:    event.Deployment.Sha,
+				Result:       result,
+				StartedDate:  startedDate,
+				FinishedDate: finishedDate,
+			},
+		},
+		CreatedDate:  startedDate,
+		StartedDate:  startedDate,
+		FinishedDate: finishedDate,
+	}, nil
+}
@@ -0,0 +1,82 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/stretchr/testify/assert"
+)
+
+const githubDeploymentStatusPayload = `{
+	"action": "created",
+	"deployment_status": {"id": 2, "state": "%s", "environment": "%s", "created_at": "2024-03-01T10:00:00Z", "updated_at": "2024-03-01T10:05:00Z"},
+	"deployment": {"id": 1, "sha": "abc123", "ref": "main", "task": "deploy", "environment": "staging", "production_environment": %t, "created_at": "2024-03-01T09:59:00Z"},
+	"repository": {"id": 3, "full_name": "apache/devlake", "html_url": "https://github.com/apache/devlake"}
+}`
+
+func TestConvertGithubDeploymentStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		event       string
+		body        string
+		ignored     bool
+		fails       bool
+		result      string
+		environment string
+	}{
+		{name: "success", body: fmt.Sprintf(githubDeploymentStatusPayload, "success", "production", false), result: devops.RESULT_SUCCESS, environment: devops.PRODUCTION},
+		{name: "error", body: fmt.Sprintf(githubDeploymentStatusPayload, "error", "qa", false), result: devops.RESULT_FAILURE, environment: devops.TESTING},
+		{name: "production flag wins", body: fmt.Sprintf(githubDeploymentStatusPayload, "success", "blue", true), result: devops.RESULT_SUCCESS, environment: devops.PRODUCTION},
+		{name: "in progress", body: fmt.Sprintf(githubDeploymentStatusPayload, "in_progress", "production", false), ignored: true},
+		{name: "other event", event: "push", body: `{}`, ignored: true},
+		{name: "missing sha", body: `{"deployment_status": {"state": "success"}}`, fails: true},
+		{name: "invalid json", body: `{`, fails: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.event != "" {
+				headers[githubEventHeader] = tc.event
+			}
+			request, err := convertGithubDeploymentStatus(newNativeInput(tc.body, headers))
+			if tc.fails {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if tc.ignored {
+				assert.Nil(t, request)
+				return
+			}
+			assert.Equal(t, "github:1", request.Id)
+			assert.Equal(t, tc.result, request.Result)
+			assert.Equal(t, tc.environment, request.Environment)
+			assert.Equal(t, "apache/devlake:deploy", request.Name)
+			assert.Equal(t, "2024-03-01T09:59:00Z", request.StartedDate.Format(time.RFC3339))
+			assert.Equal(t, "2024-03-01T10:05:00Z", request.FinishedDate.Format(time.RFC3339))
+			if assert.Len(t, request.DeploymentCommits, 1) {
+				assert.Equal(t, "abc123", request.DeploymentCommits[0].CommitSha)
+				assert.Equal(t, "main", request.DeploymentCommits[0].RefName)
+				assert.Equal(t, "https://github.com/apache/devlake", request.DeploymentCommits[0].RepoUrl)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/webhook/models"
+)
+
+var gitlabDeploymentResultRule = &devops.ResultRule{
+	Success: []string{"success"},
+	Failure: []string{"failed", "canceled"},
+}
+
+type gitlabProject struct {
+	Id                int64  `json:"id"`
+	WebUrl            string `json:"web_url"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type gitlabEnvironment struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+type gitlabPipelineEvent struct {
+	ObjectKind       string `json:"object_kind"`
+	ObjectAttributes struct {
+		Id         int64  `json:"id"`
+		Name       string `json:"name"`
+		Ref        string `json:"ref"`
+		Sha        string `json:"sha"`
+		Status     string `json:"status"`
+		CreatedAt  string `json:"created_at"`
+		FinishedAt string `json:"finished_at"`
+	} `json:"object_attributes"`
+	Project gitlabProject `json:"project"`
+	Commit  struct {
+		Id      string `json:"id"`
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"commit"`
+	Builds []struct {
+		Id          int64              `json:"id"`
+		Name        string             `json:"name"`
+		Stage       string             `json:"stage"`
+		Status      string             `json:"status"`
+		StartedAt   string             `json:"started_at"`
+		FinishedAt  string             `json:"finished_at"`
+		Environment *gitlabEnvironment `json:"environment"`
+	} `json:"builds"`
+}
+
+type gitlabDeploymentEvent struct {
+	ObjectKind      string        `json:"object_kind"`
+	Status          string        `json:"status"`
+	StatusChangedAt string        `json:"status_changed_at"`
+	DeploymentId    int64         `json:"deployment_id"`
+	Environment     string        `json:"environment"`
+	Project         gitlabProject `json:"project"`
+	Ref             string        `json:"ref"`
+	ShortSha        string        `json:"short_sha"`
+	CommitUrl       string        `json:"commit_url"`
+	CommitTitle     string        `json:"commit_title"`
+}
+
+// PostGitlabDeployments
+// @Summary create deployment by gitlab webhook
+// @Description Create deployment from the `pipeline` or `deployment` event sent by a gitlab webhook.<br/>
+// @Description The `X-Gitlab-Token` header is verified when the connection has a secret.
+// @Description No api key is needed then, the secret is required without one.
+// @Description Pipelines without any job targeting an environment and unfinished events are ignored.
+// @Tags plugins/webhook
+// @Param environment query string false "override the environment classified from the payload"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/webhook/connections/:connectionId/gitlab [POST]
+func PostGitlabDeployments(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	connection := &models.WebhookConnection{}
+	err := connectionHelper.First(connection, input.Params)
+
+	return postNativeDeployments(input, connection, err, verifyGitlabToken, convertGitlabEvent)
+}
+
+// PostGitlabDeploymentsByName
+// @Summary create deployment by gitlab webhook and connection name
+// @Description Create deployment from the `pipeline` or `deployment` event sent by a gitlab webhook.<br/>
+// @Description The `X-Gitlab-Token` header is verified when the connection has a secret.
+// @Description No api key is needed then, the secret is required without one.
+// @Description Pipelines without any job targeting an environment and unfinished events are ignored.
+// @Tags plugins/webhook
+// @Param environment query string false "override the environment classified from the payload"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/webhook/connections/by-name/:connectionName/gitlab [POST]
+func PostGitlabDeploymentsByName(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	connection := &models.WebhookConnection{}
+	err := connectionHelper.FirstByName(connection, input.Params)
+
+	return postNativeDeployments(input, connection, err, verifyGitlabToken, convertGitlabEvent)
+}
+
+func convertGitlabEvent(input *plugin.ApiResourceInput) (*WebhookDeploymentReq, errors.Error) {
+	switch input.Body["object_kind"] {
+	case "pipeline":
+		return convertGitlabPipeline(input)
+	case "deployment":
+		return convertGitlabDeployment(input)
+	}
+	return nil, nil
+}
+
+func convertGitlabPipeline(input *plugin.ApiResourceInput) (*WebhookDeploymentReq, errors.Error) {
+	event := &gitlabPipelineEvent{}
+	if err := json.Unmarshal(input.RawBody, event); err != nil {
+		return nil, errors.BadInput.Wrap(err, "invalid gitlab pipeline payload")
+	}
+	result := devops.GetResult(gitlabDeploymentResultRule, event.ObjectAttributes.Status)
+	if result == devops.RESULT_DEFAULT {
+		return nil, nil
+	}
+	// a pipeline is a deployment only if one of its jobs starts an environment
+	var originalEnvironment string
+	for _, build := range event.Builds {
+		if build.Environment != nil && (build.Environment.Action == "" || build.Environment.Action == "start") {
+			originalEnvironment = build.Environment.Name
+			break
+		}
+	}
+	if originalEnvironment == "" {
+		return nil, nil
+	}
+	sha := event.ObjectAttributes.Sha
+	if sha == "" {
+		sha = event.Commit.Id
+	}
+	if sha == "" || event.Project.WebUrl == "" {
+		return nil, errors.BadInput.New("pipeline sha and project web_url are required")
+	}
+	startedDate := parseNativeTime(event.ObjectAttributes.CreatedAt)
+	finishedDate := parseNativeTime(event.ObjectAttributes.FinishedAt)
+	if finishedDate == nil {
+		finishedDate = startedDate
+	}
+	displayTitle := event.Commit.Title
+	if displayTitle == "" {
+		displayTitle = fmt.Sprintf("deploy %s to %s", event.ObjectAttributes.Ref, originalEnvironment)
+	}
+
+	return &WebhookDeploymentReq{
+		Id:                  fmt.Sprintf("gitlab:pipeline:%d", event.ObjectAttributes.Id),
+		DisplayTitle:        displayTitle,
+		Result:              result,
+		Environment:         classifyEnvironment(originalEnvironment),
+		OriginalEnvironment: originalEnvironment,
+		Name:                event.ObjectAttributes.Name,
+		DeploymentCommits: []WebhookDeploymentCommitReq{
+			{
+				DisplayTitle: displayTitle,
+				RepoUrl:      event.Project.WebUrl,
+				Name:         event.Project.PathWithNamespace,
+				RefName:      event.ObjectAttributes.Ref,
+				CommitSha:    sha,
+				CommitMsg:    event.Commit.Message,
+				Result:       result,
+				StartedDate:  startedDate,
+				FinishedDate: finishedDate,
+			},
+		},
+		CreatedDate:  startedDate,
+		StartedDate:  startedDate,
+		FinishedDate: finishedDate,
+	}, nil
+}
+
+func convertGitlabDeployment(input *plugin.ApiResourceInput) (*WebhookDeploymentReq, errors.Error) {
+	event := &gitlabDeploymentEvent{}
+	if err := json.Unmarshal(input.RawBody, event); err != nil {
+		return nil, errors.BadInput.Wrap(err, "invalid gitlab deployment payload")
+	}
+	result := devops.GetResult(gitlabDeploymentResultRule, event.Status)
+	if result == devops.RESULT_DEFAULT {
+		return nil, nil
+	}
+	// the deployment event only carries the short sha, the full one is the last segment of the commit url
+	sha := path.Base(event.CommitUrl)
+	if event.CommitUrl == "" || sha == "" {
+		return nil, errors.BadInput.New("commit_url is required")
+	}
+	if event.Project.WebUrl == "" {
+		return nil, errors.BadInput.New("project web_url is required")
+	}
+	// gitlab only reports when the status changed, which is the best approximation of both dates
+	finishedDate := parseNativeTime(event.StatusChangedAt)
+	displayTitle := event.CommitTitle
+	if displayTitle == "" {
+		displayTitle = fmt.Sprintf("deploy %s to %s", event.Ref, event.Environment)
+	}
+
+	return &WebhookDeploymentReq{
+		Id:                  fmt.Sprintf("gitlab:deployment:%d", event.DeploymentId),
+		DisplayTitle:        displayTitle,
+		Result:              result,
+		Environment:         classifyEnvironment(event.Environment),
+		OriginalEnvironment: event.Environment,
+		DeploymentCommits: []WebhookDeploymentCommitReq{
+			{
+				DisplayTitle: displayTitle,
+				RepoUrl:      event.Project.WebUrl,
+				Name:         event.Project.PathWithNamespace,
+				RefName:      event.Ref,
+				CommitSha:    sha,
+				Result:       result,
+				StartedDate:  finishedDate,
+				FinishedDate: finishedDate,
+			},
+		},
+		CreatedDate:  finishedDate,
+		StartedDate:  finishedDate,
+		FinishedDate: finishedDate,
+	}, nil
+}
@@ -0,0 +1,157 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertGitlabPipeline(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		body        string
+		ignored     bool
+		fails       bool
+		result      string
+		environment string
+		finished    string
+	}{
+		{
+			name: "deploying pipeline",
+			body: `{"object_kind": "pipeline",
+				"object_attributes": {"id": 7, "ref": "main", "sha": "abc123", "status": "success", "created_at": "2024-03-01 10:00:00 UTC", "finished_at": "2024-03-01 10:05:00 UTC"},
+				"project": {"web_url": "https://gitlab.com/g/p", "path_with_namespace": "g/p"},
+				"builds": [{"name": "test"}, {"name": "deploy", "environment": {"name": "production", "action": "start"}}]}`,
+			result:      devops.RESULT_SUCCESS,
+			environment: devops.PRODUCTION,
+			finished:    "2024-03-01T10:05:00Z",
+		},
+		{
+			name: "unfinished pipeline takes the creation date",
+			body: `{"object_kind": "pipeline",
+				"object_attributes": {"id": 7, "ref": "main", "status": "failed", "created_at": "2024-03-01 10:00:00 UTC"},
+				"commit": {"id": "abc123"},
+				"project": {"web_url": "https://gitlab.com/g/p"},
+				"builds": [{"name": "deploy", "environment": {"name": "staging"}}]}`,
+			result:      devops.RESULT_FAILURE,
+			environment: devops.STAGING,
+			finished:    "2024-03-01T10:00:00Z",
+		},
+		{
+			name:    "no environment",
+			body:    `{"object_attributes": {"status": "success"}, "builds": [{"name": "deploy", "environment": {"name": "production", "action": "stop"}}]}`,
+			ignored: true,
+		},
+		{
+			name:    "running",
+			body:    `{"object_attributes": {"status": "running"}}`,
+			ignored: true,
+		},
+		{
+			name:  "missing sha",
+			body:  `{"object_attributes": {"status": "success"}, "project": {"web_url": "https://gitlab.com/g/p"}, "builds": [{"environment": {"name": "production"}}]}`,
+			fails: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := convertGitlabPipeline(newNativeInput(tc.body, nil))
+			if tc.fails {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if tc.ignored {
+				assert.Nil(t, request)
+				return
+			}
+			assert.Equal(t, "gitlab:pipeline:7", request.Id)
+			assert.Equal(t, tc.result, request.Result)
+			assert.Equal(t, tc.environment, request.Environment)
+			assert.Equal(t, "2024-03-01T10:00:00Z", request.StartedDate.Format(time.RFC3339))
+			assert.Equal(t, tc.finished, request.FinishedDate.Format(time.RFC3339))
+			if assert.Len(t, request.DeploymentCommits, 1) {
+				assert.Equal(t, "abc123", request.DeploymentCommits[0].CommitSha)
+				assert.Equal(t, "https://gitlab.com/g/p", request.DeploymentCommits[0].RepoUrl)
+			}
+		})
+	}
+}
+
+func TestConvertGitlabDeployment(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		body    string
+		ignored bool
+		fails   bool
+		result  string
+	}{
+		{
+			name: "success",
+			body: `{"object_kind": "deployment", "status": "success", "status_changed_at": "2024-03-01 10:05:00 +0000", "deployment_id": 9,
+				"environment": "prod", "project": {"web_url": "https://gitlab.com/g/p", "path_with_namespace": "g/p"},
+				"ref": "main", "short_sha": "abc", "commit_url": "https://gitlab.com/g/p/-/commit/abc123"}`,
+			result: devops.RESULT_SUCCESS,
+		},
+		{
+			name: "canceled",
+			body: `{"object_kind": "deployment", "status": "canceled", "status_changed_at": "2024-03-01 10:05:00 +0000", "deployment_id": 9,
+				"environment": "prod", "project": {"web_url": "https://gitlab.com/g/p"}, "commit_url": "https://gitlab.com/g/p/-/commit/abc123"}`,
+			result: devops.RESULT_FAILURE,
+		},
+		{
+			name:    "running",
+			body:    `{"object_kind": "deployment", "status": "running"}`,
+			ignored: true,
+		},
+		{
+			name:  "missing commit url",
+			body:  `{"object_kind": "deployment", "status": "success", "project": {"web_url": "https://gitlab.com/g/p"}}`,
+			fails: true,
+		},
+		{
+			name:  "missing project",
+			body:  `{"object_kind": "deployment", "status": "success", "commit_url": "https://gitlab.com/g/p/-/commit/abc123"}`,
+			fails: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := convertGitlabDeployment(newNativeInput(tc.body, nil))
+			if tc.fails {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if tc.ignored {
+				assert.Nil(t, request)
+				return
+			}
+			assert.Equal(t, "gitlab:deployment:9", request.Id)
+			assert.Equal(t, tc.result, request.Result)
+			assert.Equal(t, devops.PRODUCTION, request.Environment)
+			assert.Equal(t, "prod", request.OriginalEnvironment)
+			assert.Equal(t, "2024-03-01T10:05:00Z", request.FinishedDate.UTC().Format(time.RFC3339))
+			if assert.Len(t, request.DeploymentCommits, 1) {
+				assert.Equal(t, "abc123", request.DeploymentCommits[0].CommitSha)
+			}
+		})
+	}
+}
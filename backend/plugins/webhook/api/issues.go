@@ -85,6 +85,7 @@ func saveIncidentRelatedRecordsFromIssue(db dal.Transaction, logger log.Logger,
 // PostIssue
 // @Summary receive a record as defined and save it
 // @Description receive a record as follow and save it, example: {"url":"","issue_key":"DLK-1234","title":"a feature from DLK","description":"","epic_key":"","type":"BUG","status":"TODO","original_status":"created","story_point":0,"resolution_date":null,"created_date":"2020-01-01T12:00:00+00:00","updated_date":null,"lead_time_minutes":0,"parent_issue_key":"DLK-1200","priority":"","original_estimate_minutes":0,"time_spent_minutes":0,"time_remaining_minutes":0,"creator_id":"user1131","creator_name":"Nick name 1","assignee_id":"user1132","assignee_name":"Nick name 2","severity":"","component":""}
+// @Description When the connection has a secret, the request must carry the X-DevLake-Signature, X-DevLake-Timestamp and X-DevLake-Nonce headers
 // @Tags plugins/webhook
 // @Param body body WebhookIssueRequest true "json body"
 // @Success 200  {string} noResponse ""
@@ -100,6 +101,7 @@ func PostIssue(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, error
 // PostIssueByName
 // @Summary receive a record as defined and save it
 // @Description receive a record as follow and save it, example: {"url":"","issue_key":"DLK-1234","title":"a feature from DLK","description":"","epic_key":"","type":"BUG","status":"TODO","original_status":"created","story_point":0,"resolution_date":null,"created_date":"2020-01-01T12:00:00+00:00","updated_date":null,"lead_time_minutes":0,"parent_issue_key":"DLK-1200","priority":"","original_estimate_minutes":0,"time_spent_minutes":0,"time_remaining_minutes":0,"creator_id":"user1131","creator_name":"Nick name 1","assignee_id":"user1132","assignee_name":"Nick name 2","severity":"","component":""}
+// @Description When the connection has a secret, the request must carry the X-DevLake-Signature, X-DevLake-Timestamp and X-DevLake-Nonce headers
 // @Tags plugins/webhook
 // @Param body body WebhookIssueRequest true "json body"
 // @Success 200  {string} noResponse ""
@@ -116,6 +118,9 @@ func postIssue(input *plugin.ApiResourceInput, err errors.Error, connection *mod
 	if err != nil {
 		return nil, err
 	}
+	if err = verifyDevlakeSignature(connection, input); err != nil {
+		return nil, err
+	}
 	// get request
 	request := &WebhookIssueRequest{}
 	err = helper.DecodeMapStruct(input.Body, request, true)
@@ -252,6 +257,9 @@ func closeIssue(input *plugin.ApiResourceInput, err errors.Error, connection *mo
 	if err != nil {
 		return nil, err
 	}
+	if err = verifyDevlakeSignature(connection, input); err != nil {
+		return nil, err
+	}
 
 	txHelper := dbhelper.NewTxHelper(basicRes, &err)
 	defer txHelper.End()
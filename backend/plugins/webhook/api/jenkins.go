@@ -0,0 +1,151 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/webhook/models"
+	"github.com/spf13/cast"
+)
+
+// jenkinsEnvironmentParameter is the build parameter read to find out the target environment of a job
+const jenkinsEnvironmentParameter = "ENVIRONMENT"
+
+var jenkinsDeploymentResultRule = &devops.ResultRule{
+	Success: []string{"SUCCESS"},
+	Failure: []string{"FAILURE", "UNSTABLE", "ABORTED"},
+}
+
+type jenkinsNotificationEvent struct {
+	Name  string `json:"name"`
+	Url   string `json:"url"`
+	Build struct {
+		FullUrl string `json:"full_url"`
+		Number  int64  `json:"number"`
+		Phase   string `json:"phase"`
+		Status  string `json:"status"`
+		Scm     struct {
+			Url    string `json:"url"`
+			Branch string `json:"branch"`
+			Commit string `json:"commit"`
+		} `json:"scm"`
+		Parameters map[string]interface{} `json:"parameters"`
+		// Timestamp and Duration are in milliseconds
+		Timestamp int64 `json:"timestamp"`
+		Duration  int64 `json:"duration"`
+	} `json:"build"`
+}
+
+// PostJenkinsDeployments
+// @Summary create deployment by jenkins notification
+// @Description Create deployment from the payload sent by the jenkins notification plugin.<br/>
+// @Description The `X-Jenkins-Token` header is verified when the connection has a secret.
+// @Description No api key is needed then, the secret is required without one.
+// @Description The environment is read from the `ENVIRONMENT` build parameter, builds which are not completed are ignored.
+// @Tags plugins/webhook
+// @Param environment query string false "override the environment read from the build parameters"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/webhook/connections/:connectionId/jenkins [POST]
+func PostJenkinsDeployments(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	connection := &models.WebhookConnection{}
+	err := connectionHelper.First(connection, input.Params)
+
+	return postNativeDeployments(input, connection, err, verifyJenkinsToken, convertJenkinsNotification)
+}
+
+// PostJenkinsDeploymentsByName
+// @Summary create deployment by jenkins notification and connection name
+// @Description Create deployment from the payload sent by the jenkins notification plugin.<br/>
+// @Description The `X-Jenkins-Token` header is verified when the connection has a secret.
+// @Description No api key is needed then, the secret is required without one.
+// @Description The environment is read from the `ENVIRONMENT` build parameter, builds which are not completed are ignored.
+// @Tags plugins/webhook
+// @Param environment query string false "override the environment read from the build parameters"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 401  {string} errcode.Error "Unauthorized"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /plugins/webhook/connections/by-name/:connectionName/jenkins [POST]
+func PostJenkinsDeploymentsByName(input *plugin.ApiResourceInput) (*plugin.ApiResourceOutput, errors.Error) {
+	connection := &models.WebhookConnection{}
+	err := connectionHelper.FirstByName(connection, input.Params)
+
+	return postNativeDeployments(input, connection, err, verifyJenkinsToken, convertJenkinsNotification)
+}
+
+func convertJenkinsNotification(input *plugin.ApiResourceInput) (*WebhookDeploymentReq, errors.Error) {
+	event := &jenkinsNotificationEvent{}
+	if err := json.Unmarshal(input.RawBody, event); err != nil {
+		return nil, errors.BadInput.Wrap(err, "invalid jenkins notification payload")
+	}
+	if event.Build.Phase != "COMPLETED" && event.Build.Phase != "FINALIZED" {
+		return nil, nil
+	}
+	result := devops.GetResult(jenkinsDeploymentResultRule, event.Build.Status)
+	if result == devops.RESULT_DEFAULT {
+		return nil, nil
+	}
+	if event.Build.Scm.Commit == "" || event.Build.Scm.Url == "" {
+		return nil, errors.BadInput.New("build.scm.commit and build.scm.url are required")
+	}
+	if event.Build.Timestamp <= 0 {
+		return nil, errors.BadInput.New("build.timestamp is required")
+	}
+	// the duration is not always reported by the time the notification is sent, which leaves the
+	// finished date unknown, the start is used then rather than the delivery time of the notification
+	startedDate := time.UnixMilli(event.Build.Timestamp)
+	finishedDate := startedDate
+	if event.Build.Duration > 0 {
+		finishedDate = startedDate.Add(time.Duration(event.Build.Duration) * time.Millisecond)
+	}
+	originalEnvironment := cast.ToString(event.Build.Parameters[jenkinsEnvironmentParameter])
+	displayTitle := fmt.Sprintf("%s #%d", event.Name, event.Build.Number)
+
+	return &WebhookDeploymentReq{
+		Id:                  fmt.Sprintf("jenkins:%s:%d", event.Name, event.Build.Number),
+		DisplayTitle:        displayTitle,
+		Result:              result,
+		Environment:         classifyEnvironment(originalEnvironment),
+		OriginalEnvironment: originalEnvironment,
+		Name:                event.Name,
+		DeploymentCommits: []WebhookDeploymentCommitReq{
+			{
+				DisplayTitle: displayTitle,
+				RepoUrl:      event.Build.Scm.Url,
+				Name:         event.Name,
+				RefName:      event.Build.Scm.Branch,
+				CommitSha:    event.Build.Scm.Commit,
+				Result:       result,
+				StartedDate:  &startedDate,
+				FinishedDate: &finishedDate,
+			},
+		},
+		CreatedDate:  &startedDate,
+		StartedDate:  &startedDate,
+		FinishedDate: &finishedDate,
+	}, nil
+}
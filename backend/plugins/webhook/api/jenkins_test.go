@@ -0,0 +1,95 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertJenkinsNotification(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		body        string
+		ignored     bool
+		fails       bool
+		result      string
+		environment string
+		finished    string
+	}{
+		{
+			name: "completed",
+			body: `{"name": "deploy", "build": {"number": 12, "phase": "COMPLETED", "status": "SUCCESS", "timestamp": 1709287200000, "duration": 300000,
+				"scm": {"url": "https://github.com/apache/devlake", "branch": "main", "commit": "abc123"}, "parameters": {"ENVIRONMENT": "staging"}}}`,
+			result:      devops.RESULT_SUCCESS,
+			environment: devops.STAGING,
+			finished:    "2024-03-01T10:05:00Z",
+		},
+		{
+			name: "unknown duration",
+			body: `{"name": "deploy", "build": {"number": 12, "phase": "FINALIZED", "status": "UNSTABLE", "timestamp": 1709287200000,
+				"scm": {"url": "https://github.com/apache/devlake", "commit": "abc123"}}}`,
+			result:   devops.RESULT_FAILURE,
+			finished: "2024-03-01T10:00:00Z",
+		},
+		{
+			name:    "started",
+			body:    `{"name": "deploy", "build": {"phase": "STARTED"}}`,
+			ignored: true,
+		},
+		{
+			name:    "not built",
+			body:    `{"name": "deploy", "build": {"phase": "COMPLETED", "status": "NOT_BUILT"}}`,
+			ignored: true,
+		},
+		{
+			name:  "missing scm",
+			body:  `{"name": "deploy", "build": {"phase": "COMPLETED", "status": "SUCCESS", "timestamp": 1709287200000}}`,
+			fails: true,
+		},
+		{
+			name:  "missing timestamp",
+			body:  `{"name": "deploy", "build": {"phase": "COMPLETED", "status": "SUCCESS", "scm": {"url": "https://github.com/apache/devlake", "commit": "abc123"}}}`,
+			fails: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := convertJenkinsNotification(newNativeInput(tc.body, nil))
+			if tc.fails {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if tc.ignored {
+				assert.Nil(t, request)
+				return
+			}
+			assert.Equal(t, "jenkins:deploy:12", request.Id)
+			assert.Equal(t, tc.result, request.Result)
+			assert.Equal(t, tc.environment, request.Environment)
+			assert.Equal(t, "2024-03-01T10:00:00Z", request.StartedDate.UTC().Format(time.RFC3339))
+			assert.Equal(t, tc.finished, request.FinishedDate.UTC().Format(time.RFC3339))
+			if assert.Len(t, request.DeploymentCommits, 1) {
+				assert.Equal(t, "abc123", request.DeploymentCommits[0].CommitSha)
+			}
+		})
+	}
+}
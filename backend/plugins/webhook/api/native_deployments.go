@@ -0,0 +1,109 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/webhook/models"
+)
+
+// nativeDeploymentConverter translates a payload sent by a third-party CI/CD tool into a WebhookDeploymentReq,
+// a nil request means the event carries no finished deployment and should be ignored
+type nativeDeploymentConverter func(input *plugin.ApiResourceInput) (*WebhookDeploymentReq, errors.Error)
+
+var (
+	productionEnvPattern  = regexp.MustCompile(`(?i)prod`)
+	stagingEnvPattern     = regexp.MustCompile(`(?i)stag|pre-?prod|uat`)
+	testingEnvPattern     = regexp.MustCompile(`(?i)test|qa`)
+	developmentEnvPattern = regexp.MustCompile(`(?i)dev`)
+)
+
+var nativeTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+}
+
+func postNativeDeployments(
+	input *plugin.ApiResourceInput,
+	connection *models.WebhookConnection,
+	err errors.Error,
+	verify signatureVerifier,
+	convert nativeDeploymentConverter,
+) (*plugin.ApiResourceOutput, errors.Error) {
+	if err != nil {
+		return nil, err
+	}
+	// the requests without an api key or a user are only authenticated by the connection secret
+	if input.User == nil && connection.Secret == "" {
+		return nil, errors.Unauthorized.New("the connection has no secret to verify the request, set one or send an api key")
+	}
+	if err = verify(connection, input); err != nil {
+		return nil, err
+	}
+	request, err := convert(input)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		logger.Debug("ignore webhook event for connection %d: no finished deployment", connection.ID)
+		return &plugin.ApiResourceOutput{Body: nil, Status: http.StatusOK}, nil
+	}
+	if environment := input.Query.Get("environment"); environment != "" {
+		request.Environment = environment
+	}
+	return saveDeployment(connection, request)
+}
+
+// classifyEnvironment maps the free-form environment name used by CI/CD tools onto the devlake environments,
+// an empty string is returned when nothing matches so the default environment applies
+func classifyEnvironment(originalEnvironment string) string {
+	switch {
+	case productionEnvPattern.MatchString(originalEnvironment) && !stagingEnvPattern.MatchString(originalEnvironment):
+		return devops.PRODUCTION
+	case stagingEnvPattern.MatchString(originalEnvironment):
+		return devops.STAGING
+	case testingEnvPattern.MatchString(originalEnvironment):
+		return devops.TESTING
+	case developmentEnvPattern.MatchString(originalEnvironment):
+		return devops.DEVELOPMENT
+	}
+	return ""
+}
+
+func parseNativeTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range nativeTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	if t, err := common.ConvertStringToTime(value); err == nil {
+		return &t
+	}
+	return nil
+}
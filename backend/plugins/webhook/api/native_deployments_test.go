@@ -0,0 +1,79 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyEnvironment(t *testing.T) {
+	for environment, expected := range map[string]string{
+		"production":       devops.PRODUCTION,
+		"prod-eu":          devops.PRODUCTION,
+		"Pre-Prod":         devops.STAGING,
+		"preprod":          devops.STAGING,
+		"staging":          devops.STAGING,
+		"uat":              devops.STAGING,
+		"qa":               devops.TESTING,
+		"integration-test": devops.TESTING,
+		"dev":              devops.DEVELOPMENT,
+		"Development":      devops.DEVELOPMENT,
+		"sandbox":          "",
+		"":                 "",
+	} {
+		assert.Equal(t, expected, classifyEnvironment(environment), environment)
+	}
+}
+
+func TestParseNativeTime(t *testing.T) {
+	expected := time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		value    string
+		expected *time.Time
+	}{
+		{"2024-03-01T10:20:30Z", &expected},
+		{"2024-03-01T12:20:30+02:00", &expected},
+		{"2024-03-01 10:20:30 UTC", &expected},
+		{"2024-03-01 12:20:30 +0200", &expected},
+		{"", nil},
+		{"yesterday", nil},
+	} {
+		actual := parseNativeTime(tc.value)
+		if tc.expected == nil {
+			assert.Nil(t, actual, tc.value)
+			continue
+		}
+		if assert.NotNil(t, actual, tc.value) {
+			assert.True(t, tc.expected.Equal(*actual), tc.value)
+		}
+	}
+}
+
+func newNativeInput(body string, headers map[string]string) *plugin.ApiResourceInput {
+	request, _ := http.NewRequest(http.MethodPost, "/", nil)
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+	return &plugin.ApiResourceInput{RawBody: []byte(body), Request: request}
+}
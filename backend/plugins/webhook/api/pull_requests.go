@@ -108,6 +108,9 @@ func postPullRequests(input *plugin.ApiResourceInput, connection *models.Webhook
 	if err != nil {
 		return nil, err
 	}
+	if err = verifyDevlakeSignature(connection, input); err != nil {
+		return nil, err
+	}
 	// get request
 	request := &WebhookPullRequestReq{}
 	err = api.DecodeMapStruct(input.Body, request, true)
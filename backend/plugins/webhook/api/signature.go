@@ -0,0 +1,157 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/plugins/webhook/models"
+)
+
+const (
+	devlakeSignatureHeader = "X-DevLake-Signature"
+	devlakeTimestampHeader = "X-DevLake-Timestamp"
+	devlakeNonceHeader     = "X-DevLake-Nonce"
+	githubSignatureHeader  = "X-Hub-Signature-256"
+	gitlabTokenHeader      = "X-Gitlab-Token"
+	jenkinsTokenHeader     = "X-Jenkins-Token"
+)
+
+// signatureTolerance is how far the signed timestamp may drift from the server clock
+const signatureTolerance = 5 * time.Minute
+
+const maxNonceLength = 100
+
+// signatureVerifier checks that the incoming request was sent by the holder of the connection secret
+type signatureVerifier func(connection *models.WebhookConnection, input *plugin.ApiResourceInput) errors.Error
+
+// verifyDevlakeSignature checks the `X-DevLake-Signature` header of the requests sent to the devlake shaped endpoints.
+// The signature is the hex encoded HMAC-SHA256 of `<timestamp>.<nonce>.<raw body>` keyed by the connection secret,
+// the timestamp must be within the tolerance window and every nonce is accepted only once.
+func verifyDevlakeSignature(connection *models.WebhookConnection, input *plugin.ApiResourceInput) errors.Error {
+	if connection.Secret == "" {
+		return nil
+	}
+	signature := strings.TrimPrefix(getHeader(input, devlakeSignatureHeader), "sha256=")
+	timestamp := getHeader(input, devlakeTimestampHeader)
+	nonce := getHeader(input, devlakeNonceHeader)
+	if signature == "" || timestamp == "" || nonce == "" {
+		return errors.Unauthorized.New(fmt.Sprintf("%s, %s and %s are required", devlakeSignatureHeader, devlakeTimestampHeader, devlakeNonceHeader))
+	}
+	if len(nonce) > maxNonceLength {
+		return errors.BadInput.New(fmt.Sprintf("%s must not be longer than %d", devlakeNonceHeader, maxNonceLength))
+	}
+	unixTime, parseErr := strconv.ParseInt(timestamp, 10, 64)
+	if parseErr != nil {
+		return errors.Unauthorized.Wrap(parseErr, "invalid "+devlakeTimestampHeader)
+	}
+	drift := time.Since(time.Unix(unixTime, 0))
+	if drift > signatureTolerance || drift < -signatureTolerance {
+		return errors.Unauthorized.New(devlakeTimestampHeader + " is out of the accepted window")
+	}
+	payload := append([]byte(fmt.Sprintf("%s.%s.", timestamp, nonce)), input.RawBody...)
+	if !hmac.Equal([]byte(signature), []byte(hmacSha256Hex(connection.Secret, payload))) {
+		return errors.Unauthorized.New("invalid " + devlakeSignatureHeader)
+	}
+	return rememberNonce(connection.ID, nonce)
+}
+
+// rememberNonce rejects the nonce if it was seen before and purges the ones older than the tolerance window,
+// which could not pass the timestamp check anymore
+func rememberNonce(connectionId uint64, nonce string) errors.Error {
+	db := basicRes.GetDal()
+	now := time.Now()
+	err := db.Delete(&models.WebhookNonce{}, dal.Where("created_at < ?", now.Add(-2*signatureTolerance)))
+	if err != nil {
+		return err
+	}
+	err = db.Create(&models.WebhookNonce{
+		ConnectionId: connectionId,
+		Nonce:        nonce,
+		CreatedAt:    now,
+	})
+	if err != nil {
+		if db.IsDuplicationError(err) {
+			return errors.Unauthorized.New("request has been replayed")
+		}
+		return err
+	}
+	return nil
+}
+
+// verifyGithubSignature checks the `X-Hub-Signature-256` header, which is the hex encoded HMAC-SHA256 of the raw body
+func verifyGithubSignature(connection *models.WebhookConnection, input *plugin.ApiResourceInput) errors.Error {
+	if connection.Secret == "" {
+		return nil
+	}
+	signature := strings.TrimPrefix(getHeader(input, githubSignatureHeader), "sha256=")
+	if signature == "" {
+		return errors.Unauthorized.New("missing " + githubSignatureHeader)
+	}
+	if !hmac.Equal([]byte(signature), []byte(hmacSha256Hex(connection.Secret, input.RawBody))) {
+		return errors.Unauthorized.New("invalid " + githubSignatureHeader)
+	}
+	return nil
+}
+
+// verifyGitlabToken checks the `X-Gitlab-Token` header, which carries the secret token as is
+func verifyGitlabToken(connection *models.WebhookConnection, input *plugin.ApiResourceInput) errors.Error {
+	return verifyToken(connection, input, gitlabTokenHeader)
+}
+
+// verifyJenkinsToken checks the `X-Jenkins-Token` header, the notification plugin has no signing so the secret is sent as is
+func verifyJenkinsToken(connection *models.WebhookConnection, input *plugin.ApiResourceInput) errors.Error {
+	return verifyToken(connection, input, jenkinsTokenHeader)
+}
+
+func verifyToken(connection *models.WebhookConnection, input *plugin.ApiResourceInput, header string) errors.Error {
+	if connection.Secret == "" {
+		return nil
+	}
+	token := getHeader(input, header)
+	if token == "" {
+		return errors.Unauthorized.New("missing " + header)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(connection.Secret)) != 1 {
+		return errors.Unauthorized.New("invalid " + header)
+	}
+	return nil
+}
+
+func hmacSha256Hex(secret string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func getHeader(input *plugin.ApiResourceInput, key string) string {
+	if input.Request == nil {
+		return ""
+	}
+	return input.Request.Header.Get(key)
+}
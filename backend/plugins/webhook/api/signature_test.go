@@ -0,0 +1,171 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/apache/incubator-devlake/plugins/webhook/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockNonceStore keeps the nonces in memory, failing like a unique key on duplicates
+func mockNonceStore(t *testing.T) {
+	nonces := map[string]bool{}
+	duplicated := errors.Default.New("duplicated nonce")
+	db := mockdal.NewDal(t)
+	db.On("Delete", mock.Anything, mock.Anything).Return(nil).Maybe()
+	db.On("Create", mock.Anything, mock.Anything).Return(func(entity interface{}, _ ...dal.Clause) errors.Error {
+		nonce := entity.(*models.WebhookNonce)
+		key := fmt.Sprintf("%d:%s", nonce.ConnectionId, nonce.Nonce)
+		if nonces[key] {
+			return duplicated
+		}
+		nonces[key] = true
+		return nil
+	}).Maybe()
+	db.On("IsDuplicationError", mock.Anything).Return(func(err error) bool {
+		return err == duplicated
+	}).Maybe()
+	br := mockcontext.NewBasicRes(t)
+	br.On("GetDal").Return(db).Maybe()
+	basicRes = br
+}
+
+func signDevlakeRequest(secret, body, nonce string, timestamp time.Time) map[string]string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return map[string]string{
+		devlakeSignatureHeader: "sha256=" + hmacSha256Hex(secret, []byte(ts+"."+nonce+"."+body)),
+		devlakeTimestampHeader: ts,
+		devlakeNonceHeader:     nonce,
+	}
+}
+
+func TestVerifyDevlakeSignature(t *testing.T) {
+	body := `{"id":"1"}`
+	now := time.Now()
+	for _, tc := range []struct {
+		name    string
+		secret  string
+		body    string
+		headers map[string]string
+		valid   bool
+	}{
+		{name: "no secret", body: body, valid: true},
+		{name: "valid", secret: "s3cr3t", body: body, headers: signDevlakeRequest("s3cr3t", body, "n1", now), valid: true},
+		{name: "tampered body", secret: "s3cr3t", body: `{"id":"2"}`, headers: signDevlakeRequest("s3cr3t", body, "n2", now)},
+		{name: "other secret", secret: "s3cr3t", body: body, headers: signDevlakeRequest("other", body, "n3", now)},
+		{name: "expired", secret: "s3cr3t", body: body, headers: signDevlakeRequest("s3cr3t", body, "n4", now.Add(-signatureTolerance-time.Minute))},
+		{name: "from the future", secret: "s3cr3t", body: body, headers: signDevlakeRequest("s3cr3t", body, "n5", now.Add(signatureTolerance+time.Minute))},
+		{name: "missing headers", secret: "s3cr3t", body: body},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockNonceStore(t)
+			connection := &models.WebhookConnection{Secret: tc.secret}
+			err := verifyDevlakeSignature(connection, newNativeInput(tc.body, tc.headers))
+			if tc.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyDevlakeSignatureReplay(t *testing.T) {
+	mockNonceStore(t)
+	body := `{"id":"1"}`
+	headers := signDevlakeRequest("s3cr3t", body, "n1", time.Now())
+	connection := &models.WebhookConnection{Secret: "s3cr3t"}
+	connection.ID = 1
+	assert.Nil(t, verifyDevlakeSignature(connection, newNativeInput(body, headers)))
+	err := verifyDevlakeSignature(connection, newNativeInput(body, headers))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "replayed")
+	}
+	// nonces are tracked per connection
+	other := &models.WebhookConnection{Secret: "s3cr3t"}
+	other.ID = 2
+	assert.Nil(t, verifyDevlakeSignature(other, newNativeInput(body, headers)))
+}
+
+func TestVerifyGithubSignature(t *testing.T) {
+	body := `{"action":"created"}`
+	for _, tc := range []struct {
+		name      string
+		secret    string
+		signature string
+		valid     bool
+	}{
+		{name: "no secret", valid: true},
+		{name: "valid", secret: "s3cr3t", signature: "sha256=" + hmacSha256Hex("s3cr3t", []byte(body)), valid: true},
+		{name: "other secret", secret: "s3cr3t", signature: "sha256=" + hmacSha256Hex("other", []byte(body))},
+		{name: "tampered body", secret: "s3cr3t", signature: "sha256=" + hmacSha256Hex("s3cr3t", []byte(body+" "))},
+		{name: "missing", secret: "s3cr3t"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			connection := &models.WebhookConnection{Secret: tc.secret}
+			headers := map[string]string{}
+			if tc.signature != "" {
+				headers[githubSignatureHeader] = tc.signature
+			}
+			err := verifyGithubSignature(connection, newNativeInput(body, headers))
+			if tc.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		secret string
+		token  string
+		valid  bool
+	}{
+		{name: "no secret", valid: true},
+		{name: "valid", secret: "s3cr3t", token: "s3cr3t", valid: true},
+		{name: "invalid", secret: "s3cr3t", token: "s3cr3"},
+		{name: "missing", secret: "s3cr3t"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			connection := &models.WebhookConnection{Secret: tc.secret}
+			headers := map[string]string{}
+			if tc.token != "" {
+				headers[gitlabTokenHeader] = tc.token
+			}
+			err := verifyToken(connection, newNativeInput(`{}`, headers), gitlabTokenHeader)
+			if tc.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
@@ -57,6 +57,7 @@ func (p Webhook) Init(basicRes context.BasicRes) errors.Error {
 func (p Webhook) GetTablesInfo() []dal.Tabler {
 	return []dal.Tabler{
 		&models.WebhookConnection{},
+		&models.WebhookNonce{},
 	}
 }
 
@@ -90,6 +91,15 @@ func (p Webhook) ApiResources() map[string]map[string]plugin.ApiResourceHandler
 		"connections/:connectionId/deployments": {
 			"POST": api.PostDeployments,
 		},
+		"connections/:connectionId/github": {
+			"POST": api.PostGithubDeployments,
+		},
+		"connections/:connectionId/gitlab": {
+			"POST": api.PostGitlabDeployments,
+		},
+		"connections/:connectionId/jenkins": {
+			"POST": api.PostJenkinsDeployments,
+		},
 		"connections/:connectionId/pull_requests": {
 			"POST": api.PostPullRequests,
 		},
@@ -119,6 +129,15 @@ func (p Webhook) ApiResources() map[string]map[string]plugin.ApiResourceHandler
 		"connections/by-name/:connectionName/deployments": {
 			"POST": api.PostDeploymentsByName,
 		},
+		"connections/by-name/:connectionName/github": {
+			"POST": api.PostGithubDeploymentsByName,
+		},
+		"connections/by-name/:connectionName/gitlab": {
+			"POST": api.PostGitlabDeploymentsByName,
+		},
+		"connections/by-name/:connectionName/jenkins": {
+			"POST": api.PostJenkinsDeploymentsByName,
+		},
 		"connections/by-name/:connectionName/pull_requests": {
 			"POST": api.PostPullRequestsByName,
 		},
@@ -18,13 +18,21 @@ limitations under the License.
 package models
 
 import (
+	"github.com/apache/incubator-devlake/core/utils"
 	helper "github.com/apache/incubator-devlake/helpers/pluginhelper/api"
 )
 
 type WebhookConnection struct {
 	helper.BaseConnection `mapstructure:",squash"`
+	// Secret is optional, it is used to verify the signature of the incoming payloads
+	Secret string `mapstructure:"secret" json:"secret" gorm:"type:text;serializer:encdec"`
 }
 
 func (WebhookConnection) TableName() string {
 	return "_tool_webhook_connections"
 }
+
+func (connection WebhookConnection) Sanitize() WebhookConnection {
+	connection.Secret = utils.SanitizeString(connection.Secret)
+	return connection
+}
@@ -0,0 +1,56 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrationscripts
+
+import (
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/migrationhelper"
+)
+
+var _ plugin.MigrationScript = (*addNonces)(nil)
+
+type webhookNonce20261018 struct {
+	ConnectionId uint64    `gorm:"primaryKey"`
+	Nonce        string    `gorm:"primaryKey;type:varchar(100)"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+func (webhookNonce20261018) TableName() string {
+	return "_tool_webhook_nonces"
+}
+
+type addNonces struct{}
+
+func (*addNonces) Up(basicRes context.BasicRes) errors.Error {
+	return migrationhelper.AutoMigrateTables(
+		basicRes,
+		&webhookNonce20261018{},
+	)
+}
+
+func (*addNonces) Version() uint64 {
+	return 20261018000002
+}
+
+func (*addNonces) Name() string {
+	return "add _tool_webhook_nonces table for replay protection"
+}
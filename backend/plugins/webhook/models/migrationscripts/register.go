@@ -26,5 +26,7 @@ func All() []plugin.MigrationScript {
 	return []plugin.MigrationScript{
 		new(addInitTables),
 		new(addApiKeys),
+		new(addSecretToConnections),
+		new(addNonces),
 	}
 }
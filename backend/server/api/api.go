@@ -105,14 +105,20 @@ func CreateApiServer() *gin.Engine {
 	router.GET("/health", ping.Health)
 	router.GET("/version", version.Get)
 
-	// Auth chain order matters: REST API key first (its own short-circuit),
-	// then OIDC session, then oauth2-proxy header (only sets USER if not yet
-	// set), then the terminal 401 gate, finally CSRF on unsafe methods.
+	// Auth chain order matters: REST API key first (its own short-circuit, which
+	// records the requests it rejects), then the audit log so that the requests
+	// rejected by the middlewares below are recorded too, then OIDC
+	// session, then oauth2-proxy header (only sets USER if not yet set), then the
+	// terminal 401 gate, CSRF on unsafe methods, the role check on the matched
+	// route, and finally the read of the state the audited request changes.
 	router.Use(RestAuthentication(router, basicRes))
+	router.Use(AuditLog(router, basicRes))
 	router.Use(auth.OIDCAuthentication())
 	router.Use(OAuth2ProxyAuthentication(basicRes))
 	router.Use(auth.RequireAuth())
 	router.Use(auth.CSRFProtect())
+	router.Use(auth.RequirePermission())
+	router.Use(ReadAuditedResource(router))
 
 	return router
 }
@@ -0,0 +1,228 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/context"
+	"github.com/apache/incubator-devlake/core/log"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/helpers/apikeyhelper"
+	"github.com/apache/incubator-devlake/server/api/shared"
+	"github.com/apache/incubator-devlake/server/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditedBodySize bounds the response bodies kept as the after state of audit logs
+const maxAuditedBodySize = 1 << 20
+
+// auditResponseWriter keeps a copy of the response body for the audit log
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(data []byte) {
+	if w.overflow || w.body.Len()+len(data) > maxAuditedBodySize {
+		w.overflow = true
+		return
+	}
+	w.body.Write(data)
+}
+
+// saveAuditLog is replaced by the tests
+var saveAuditLog = services.SaveAuditLog
+
+const (
+	// auditReadKey holds the GET handler of the resource targeted by the audited request
+	auditReadKey = "auditRead"
+	// auditBeforeKey holds the state of the resource read by that handler
+	auditBeforeKey = "auditBefore"
+)
+
+// AuditLog records every request but reads, with its actor and result. The state of
+// the target before the request is read by ReadAuditedResource when the route addresses
+// a single resource which can be read, the state after is the response of the request.
+func AuditLog(router *gin.Engine, basicRes context.BasicRes) gin.HandlerFunc {
+	logger := basicRes.GetLogger()
+	var getHandlers map[string]gin.HandlerFunc
+	var getHandlersOnce sync.Once
+	return func(c *gin.Context) {
+		if !audited(c) {
+			c.Next()
+			return
+		}
+		// routes are all registered by the time requests are served
+		getHandlersOnce.Do(func() {
+			getHandlers = make(map[string]gin.HandlerFunc)
+			for _, r := range router.Routes() {
+				if r.Method == http.MethodGet {
+					getHandlers[r.Path] = r.HandlerFunc
+				}
+			}
+		})
+		start := time.Now()
+		route := c.FullPath()
+		if handler, ok := getHandlers[route]; ok && addressesResource(route) {
+			c.Set(auditReadKey, handler)
+		}
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		auditLog := newAuditLog(c, start, writer)
+		if before, ok := c.Get(auditBeforeKey); ok {
+			auditLog.Before = before.(json.RawMessage)
+		}
+		if err := saveAuditLog(auditLog); err != nil {
+			logger.Error(err, "save audit log of %s %s", auditLog.Method, auditLog.Path)
+		}
+	}
+}
+
+// ReadAuditedResource reads the state of the resource targeted by the request AuditLog
+// records, once the request went through the authentication and permission checks, by
+// calling the GET handler of the route in process with the same caller
+func ReadAuditedResource(router *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handler, ok := c.Get(auditReadKey); ok {
+			c.Set(auditBeforeKey, readResource(router, c, handler.(gin.HandlerFunc)))
+		}
+		c.Next()
+	}
+}
+
+// auditRejected records a request RestAuthentication rejected before routing it, its
+// response having been captured by the writer
+func auditRejected(c *gin.Context, logger log.Logger, start time.Time, writer *auditResponseWriter) {
+	if !audited(c) {
+		return
+	}
+	auditLog := newAuditLog(c, start, writer)
+	if err := saveAuditLog(auditLog); err != nil {
+		logger.Error(err, "save audit log of %s %s", auditLog.Method, auditLog.Path)
+	}
+}
+
+func audited(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// addressesResource tells whether the route ends with the parameter identifying a single
+// resource, the GET of the other routes lists a collection, i.e. the one a POST adds to
+func addressesResource(route string) bool {
+	return strings.Contains(route[strings.LastIndex(route, "/")+1:], ":")
+}
+
+func newAuditLog(c *gin.Context, start time.Time, writer *auditResponseWriter) *models.AuditLog {
+	route := c.FullPath()
+	auditLog := &models.AuditLog{
+		CreatedAt:    start,
+		ActorType:    models.AUDIT_ACTOR_ANONYMOUS,
+		ClientIp:     c.ClientIP(),
+		Method:       c.Request.Method,
+		Path:         truncate(c.Request.URL.Path, 255),
+		Route:        route,
+		ResourceType: apikeyhelper.RouteResource(route),
+		Status:       writer.Status(),
+		Success:      writer.Status() < http.StatusBadRequest,
+		DurationMs:   time.Since(start).Milliseconds(),
+	}
+	var params []string
+	for _, p := range c.Params {
+		params = append(params, p.Key+"="+p.Value)
+	}
+	auditLog.ResourceId = truncate(strings.Join(params, ","), 255)
+	if apiKey, ok := shared.GetApiKey(c); ok {
+		auditLog.ActorType = models.AUDIT_ACTOR_API_KEY
+		auditLog.ApiKeyId = apiKey.ID
+		auditLog.Actor = apiKey.Name
+		auditLog.ActorEmail = apiKey.CreatorEmail
+	} else if user, ok := shared.GetUser(c); ok && user != nil {
+		auditLog.ActorType = models.AUDIT_ACTOR_USER
+		auditLog.Actor = user.Name
+		auditLog.ActorEmail = user.Email
+	}
+	if !writer.overflow {
+		if auditLog.Success {
+			if c.Request.Method != http.MethodDelete {
+				auditLog.After = writer.body.Bytes()
+			}
+		} else {
+			body := &shared.ApiBody{}
+			if json.Unmarshal(writer.body.Bytes(), body) == nil {
+				auditLog.Message = body.Message
+			}
+		}
+	}
+	return auditLog
+}
+
+// readResource calls the GET handler on a context of its own, sharing the request
+// context and the keys of the caller's, returns nil when the resource can't be read
+func readResource(router *gin.Engine, c *gin.Context, handler gin.HandlerFunc) json.RawMessage {
+	req := c.Request.Clone(c.Request.Context())
+	req.Method = http.MethodGet
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	req.Header.Del("Content-Type")
+	w := httptest.NewRecorder()
+	getCtx := gin.CreateTestContextOnly(w, router)
+	getCtx.Request = req
+	getCtx.Params = c.Params
+	for key, value := range c.Keys {
+		if key != auditReadKey {
+			getCtx.Set(key, value)
+		}
+	}
+	handler(getCtx)
+	if w.Code != http.StatusOK || w.Body.Len() == 0 || w.Body.Len() > maxAuditedBodySize {
+		return nil
+	}
+	return w.Body.Bytes()
+}
+
+func truncate(s string, size int) string {
+	if len(s) > size {
+		return s[:size]
+	}
+	return s
+}
@@ -0,0 +1,188 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/apache/incubator-devlake/server/api/shared"
+	"github.com/apache/incubator-devlake/server/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newAuditLogTestRouter serves projects the way the real routes do, authenticating the
+// requests with the given middleware after RestAuthentication, which looks the api keys
+// up in db, and collects the audit logs instead of saving them. The GETs respond with
+// the actor they were made by and are counted.
+func newAuditLogTestRouter(t *testing.T, authenticate gin.HandlerFunc, db *mockdal.Dal) (*gin.Engine, *[]*models.AuditLog, *int) {
+	t.Setenv("ENCRYPTION_SECRET", "audit log test")
+	var auditLogs []*models.AuditLog
+	saveAuditLog = func(auditLog *models.AuditLog) errors.Error {
+		auditLogs = append(auditLogs, auditLog)
+		return nil
+	}
+	t.Cleanup(func() { saveAuditLog = services.SaveAuditLog })
+	basicRes := mockcontext.NewBasicRes(t)
+	basicRes.On("GetLogger").Return(unithelper.DummyLogger()).Maybe()
+	basicRes.On("GetDal").Return(db).Maybe()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RestAuthentication(r, basicRes))
+	r.Use(authenticate)
+	r.Use(AuditLog(r, basicRes))
+	r.Use(ReadAuditedResource(r))
+	reads := 0
+	actor := func(c *gin.Context) string {
+		if apiKey, ok := shared.GetApiKey(c); ok {
+			return apiKey.Name
+		}
+		if user, ok := shared.GetUser(c); ok {
+			return user.Name
+		}
+		return ""
+	}
+	r.GET("/projects", func(c *gin.Context) {
+		reads++
+		c.JSON(http.StatusOK, gin.H{"count": 1, "projects": []gin.H{{"name": "a"}}})
+	})
+	r.POST("/projects", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"name": "b"})
+	})
+	r.GET("/projects/:projectName", func(c *gin.Context) {
+		reads++
+		c.JSON(http.StatusOK, gin.H{"name": c.Param("projectName"), "description": "old", "readBy": actor(c)})
+	})
+	r.PATCH("/projects/:projectName", func(c *gin.Context) {
+		if c.Param("projectName") == "missing" {
+			shared.ApiOutputError(c, errors.NotFound.New("project missing not found"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"name": c.Param("projectName"), "description": "new"})
+	})
+	return r, &auditLogs, &reads
+}
+
+func TestAuditLog(t *testing.T) {
+	user := &common.User{Name: "alice", Email: "alice@example.com"}
+	r, auditLogs, reads := newAuditLogTestRouter(t, func(c *gin.Context) {
+		c.Set(common.USER, user)
+	}, new(mockdal.Dal))
+	serve := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w.Code
+	}
+
+	// reads aren't recorded
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/projects/a", ""))
+	assert.Empty(t, *auditLogs)
+
+	// the target is read beforehand on behalf of the same user
+	*reads = 0
+	assert.Equal(t, http.StatusOK, serve(http.MethodPatch, "/projects/a", `{"description":"new"}`))
+	assert.Equal(t, 1, *reads)
+	assert.Len(t, *auditLogs, 1)
+	auditLog := (*auditLogs)[0]
+	assert.Equal(t, models.AUDIT_ACTOR_USER, auditLog.ActorType)
+	assert.Equal(t, "alice", auditLog.Actor)
+	assert.Equal(t, "alice@example.com", auditLog.ActorEmail)
+	assert.Equal(t, "/projects/:projectName", auditLog.Route)
+	assert.Equal(t, "projects", auditLog.ResourceType)
+	assert.Equal(t, "projectName=a", auditLog.ResourceId)
+	assert.True(t, auditLog.Success)
+	assert.JSONEq(t, `{"name":"a","description":"old","readBy":"alice"}`, string(auditLog.Before))
+	assert.JSONEq(t, `{"name":"a","description":"new"}`, string(auditLog.After))
+
+	// creations don't read the listing of the collection
+	*reads = 0
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/projects", `{"name":"b"}`))
+	assert.Equal(t, 0, *reads)
+	auditLog = (*auditLogs)[1]
+	assert.Nil(t, auditLog.Before)
+	assert.JSONEq(t, `{"name":"b"}`, string(auditLog.After))
+
+	// failures keep the message, not the response
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPatch, "/projects/missing", `{}`))
+	auditLog = (*auditLogs)[2]
+	assert.False(t, auditLog.Success)
+	assert.Equal(t, http.StatusNotFound, auditLog.Status)
+	assert.Equal(t, "project missing not found (404)", auditLog.Message)
+	assert.Nil(t, auditLog.After)
+}
+
+func TestAuditLogApiKey(t *testing.T) {
+	apiKey := &models.ApiKey{
+		Model:   common.Model{ID: 3},
+		Creator: common.Creator{Creator: "bob", CreatorEmail: "bob@example.com"},
+		Name:    "ci",
+		Scopes:  &models.ApiKeyScopes{All: true},
+	}
+	db := new(mockdal.Dal)
+	db.On("First", mock.AnythingOfType("*models.ApiKey"), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.ApiKey) = *apiKey
+	}).Return(nil)
+	db.On("UpdateColumns", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	r, auditLogs, reads := newAuditLogTestRouter(t, func(c *gin.Context) {}, db)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/rest/projects/a", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, *reads)
+	assert.Len(t, *auditLogs, 1)
+	auditLog := (*auditLogs)[0]
+	assert.Equal(t, models.AUDIT_ACTOR_API_KEY, auditLog.ActorType)
+	assert.Equal(t, uint64(3), auditLog.ApiKeyId)
+	assert.Equal(t, "ci", auditLog.Actor)
+	assert.Equal(t, "bob@example.com", auditLog.ActorEmail)
+	// the GET handler is called on behalf of the key too
+	before := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(auditLog.Before, &before))
+	assert.Equal(t, "ci", before["readBy"])
+}
+
+func TestAuditLogRejectedRestRequest(t *testing.T) {
+	r, auditLogs, _ := newAuditLogTestRouter(t, func(c *gin.Context) {}, new(mockdal.Dal))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rest/projects", strings.NewReader(`{"name":"b"}`)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Len(t, *auditLogs, 1)
+	auditLog := (*auditLogs)[0]
+	assert.Equal(t, models.AUDIT_ACTOR_ANONYMOUS, auditLog.ActorType)
+	assert.Equal(t, "/rest/projects", auditLog.Path)
+	assert.False(t, auditLog.Success)
+	assert.Equal(t, "token is missing", auditLog.Message)
+
+	// rejected reads aren't recorded either
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rest/projects", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Len(t, *auditLogs, 1)
+}
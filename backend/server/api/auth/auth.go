@@ -303,13 +303,13 @@ func (s *Service) Callback(c *gin.Context) {
 		return
 	}
 
-	sub, email, name, err := extractUser(idTok)
+	sub, email, name, groups, err := extractUser(idTok, s.cfg.RBAC.GroupsClaim)
 	if err != nil {
 		fail(c, http.StatusBadGateway, "extract claims", err)
 		return
 	}
 	jti := uuid.NewString()
-	jwt, expiresAt, err := oidchelper.IssueSession(s.cfg, jti, state.Provider, sub, email, name)
+	jwt, expiresAt, err := oidchelper.IssueSession(s.cfg, jti, state.Provider, sub, email, name, groups)
 	if err != nil {
 		fail(c, http.StatusInternalServerError, "issue session", err)
 		return
@@ -381,9 +381,11 @@ func (s *Service) Logout(c *gin.Context) {
 }
 
 type userInfoResponse struct {
-	Authenticated bool   `json:"authenticated"`
-	Name          string `json:"name"`
-	Email         string `json:"email"`
+	Authenticated bool               `json:"authenticated"`
+	Name          string             `json:"name"`
+	Email         string             `json:"email"`
+	Groups        []string           `json:"groups,omitempty"`
+	Grants        []oidchelper.Grant `json:"grants,omitempty"`
 }
 
 func UserInfo(c *gin.Context) { defaultService.UserInfo(c) }
@@ -401,11 +403,17 @@ func (s *Service) UserInfo(c *gin.Context) {
 		shared.ApiOutputSuccess(c, userInfoResponse{Authenticated: false}, http.StatusOK)
 		return
 	}
-	shared.ApiOutputSuccess(c, userInfoResponse{
+	out := userInfoResponse{
 		Authenticated: true,
 		Name:          u.Name,
 		Email:         u.Email,
-	}, http.StatusOK)
+		Groups:        u.Groups,
+	}
+	// let the UI hide what the user is not allowed to do
+	if s.cfg != nil && s.cfg.RBAC.Enabled {
+		out.Grants = s.cfg.RBAC.GrantsFor(u.Groups)
+	}
+	shared.ApiOutputSuccess(c, out, http.StatusOK)
 }
 
 // pickProvider resolves the requested provider name. Empty names are allowed
@@ -489,8 +497,9 @@ func safeReturnURL(raw string) string {
 // extractUser pulls user identity out of the verified ID token. Email is
 // taken strictly from the `email` claim; we never coerce a username into
 // the email column. Display name falls back to preferred_username, then
-// email, so the UI always has *something* to render.
-func extractUser(tok *oidc.IDToken) (sub, email, name string, err error) {
+// email, so the UI always has *something* to render. Groups are read from
+// the configured claim, which IdPs send either as a list or a string.
+func extractUser(tok *oidc.IDToken, groupsClaim string) (sub, email, name string, groups []string, err error) {
 	var claims struct {
 		Sub               string `json:"sub"`
 		Email             string `json:"email"`
@@ -498,10 +507,24 @@ func extractUser(tok *oidc.IDToken) (sub, email, name string, err error) {
 		Name              string `json:"name"`
 	}
 	if err := tok.Claims(&claims); err != nil {
-		return "", "", "", err
+		return "", "", "", nil, err
 	}
 	if claims.Sub == "" {
-		return "", "", "", fmt.Errorf("id_token missing sub claim")
+		return "", "", "", nil, fmt.Errorf("id_token missing sub claim")
+	}
+	var raw map[string]any
+	if err := tok.Claims(&raw); err != nil {
+		return "", "", "", nil, err
+	}
+	switch v := raw[groupsClaim].(type) {
+	case []any:
+		for _, g := range v {
+			if s, ok := g.(string); ok && s != "" {
+				groups = append(groups, s)
+			}
+		}
+	case string:
+		groups = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
 	}
 	name = claims.Name
 	if name == "" {
@@ -510,7 +533,7 @@ func extractUser(tok *oidc.IDToken) (sub, email, name string, err error) {
 	if name == "" {
 		name = claims.Email
 	}
-	return claims.Sub, claims.Email, name, nil
+	return claims.Sub, claims.Email, name, groups, nil
 }
 
 func pkceChallenge(verifier string) string {
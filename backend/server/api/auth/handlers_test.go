@@ -421,6 +421,27 @@ func TestLogoutPublicWhenNoSession(t *testing.T) {
 	}
 }
 
+func TestRequireAuthLetsSignedWebhooksThrough(t *testing.T) {
+	idp := newFakeIdP(t)
+	s, _ := newTestService(t, idp)
+	r := newTestRouter(s)
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.POST("/plugins/webhook/connections/:connectionId/github", ok)
+	r.POST("/plugins/webhook/connections/:connectionId/deployments", ok)
+
+	// the webhook plugin verifies the signature of the native routes itself
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/plugins/webhook/connections/1/github", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected the native webhook route to be reachable, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/plugins/webhook/connections/1/deployments", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 on the other webhook routes, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
 // Compile-time assertion that the testify mock satisfies the dal.Dal
 // interface so the tests catch any new method that gets added.
 var _ dal.Dal = (*mockdal.Dal)(nil)
@@ -20,6 +20,7 @@ package auth
 import (
 	"crypto/subtle"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -46,6 +47,16 @@ var publicPaths = map[string]struct{}{
 	PathUserInfo:            {},
 }
 
+// signedWebhookPaths are the native webhook routes GitHub, GitLab and Jenkins post
+// to. They can't send an api key or a session, the webhook plugin authenticates them
+// by the signature or token of the connection secret instead.
+var signedWebhookPaths = regexp.MustCompile(`^/plugins/webhook/connections/(\d+|by-name/[^/]+)/(github|gitlab|jenkins)$`)
+
+// IsSignedWebhook tells whether the request is posted to a native webhook route
+func IsSignedWebhook(method, path string) bool {
+	return method == http.MethodPost && signedWebhookPaths.MatchString(path)
+}
+
 func OIDCAuthentication() gin.HandlerFunc { return defaultService.OIDCAuthentication() }
 
 func RequireAuth() gin.HandlerFunc { return defaultService.RequireAuth() }
@@ -84,8 +95,9 @@ func (s *Service) OIDCAuthentication() gin.HandlerFunc {
 			return
 		}
 		c.Set(common.USER, &common.User{
-			Name:  claims.Name,
-			Email: claims.Email,
+			Name:   claims.Name,
+			Email:  claims.Email,
+			Groups: claims.Groups,
 		})
 		s.bumpLastSeen(claims.ID)
 		c.Next()
@@ -100,7 +112,7 @@ func (s *Service) RequireAuth() gin.HandlerFunc {
 			c.Next()
 			return
 		}
-		if isPublicPath(c.Request.URL.Path) {
+		if isPublicPath(c.Request.URL.Path) || IsSignedWebhook(c.Request.Method, c.Request.URL.Path) {
 			c.Next()
 			return
 		}
@@ -0,0 +1,341 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/helpers/apikeyhelper"
+	"github.com/apache/incubator-devlake/helpers/oidchelper"
+	"github.com/apache/incubator-devlake/server/api/shared"
+)
+
+// adminRoutes are reserved to admins whatever the method: they hold credentials
+// or settings shared by every project.
+var adminRoutes = regexp.MustCompile(`^/(api-keys|audit-logs|encryption-secret|notification-channels)(/|$)`)
+
+// connectionRoutes are the plugin connections themselves, by id or by name (not
+// their scopes, scope configs or webhook endpoints): reading them is sensitive,
+// changing them is admin only. Testing a saved connection is too, as the body
+// is merged into it and may send its credentials to another endpoint.
+var connectionRoutes = regexp.MustCompile(`^/plugins/[^/]+/connections(/:[^/]+|/by-name/:[^/]+|/:connectionId/test)?$`)
+
+// sensitiveRoutes may reveal how DevLake is wired to the data sources.
+var sensitiveRoutes = regexp.MustCompile(`^(/plugins/[^/]+/connections|/notifications|/store)(/|$)`)
+
+func RequirePermission() gin.HandlerFunc { return defaultService.RequirePermission() }
+
+// RequirePermission enforces the roles mapped from the user's groups on the
+// matched route, plugin ApiResources included. Runs after RequireAuth, no-op
+// unless RBAC_ENABLED=true.
+func (s *Service) RequirePermission() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.cfg == nil || !s.cfg.AuthEnabled || !s.cfg.RBAC.Enabled {
+			c.Next()
+			return
+		}
+		// unknown routes end up with 404 anyway
+		if isPublicPath(c.Request.URL.Path) || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		apiKey, isApiKey := shared.GetApiKey(c)
+		user, ok := shared.GetUser(c)
+		if !isApiKey && (!ok || user == nil) {
+			c.Next()
+			return
+		}
+		perm := RoutePermission(c)
+		if perm == oidchelper.PermissionWrite {
+			custom, err := WritesCustomPlan(s.db, c)
+			if err != nil {
+				shared.ApiOutputError(c, err)
+				c.Abort()
+				return
+			}
+			if custom {
+				perm = oidchelper.PermissionAdmin
+			}
+		}
+		// api keys are limited by their own scopes, which must name the resource
+		// of the routes reserved to admins
+		if isApiKey {
+			if perm == oidchelper.PermissionAdmin {
+				scopes := apiKey.Scopes
+				if scopes == nil {
+					scopes = &models.ApiKeyScopes{}
+				}
+				err := apikeyhelper.CheckScopes(scopes, &apikeyhelper.ScopedRequest{
+					Method:   c.Request.Method,
+					Resource: apikeyhelper.RouteResource(c.FullPath()),
+					Admin:    true,
+				})
+				if err != nil {
+					s.logger.Info("rbac: %s %s denied to api key %s", c.Request.Method, c.FullPath(), apiKey.Name)
+					shared.ApiOutputError(c, err)
+					c.Abort()
+					return
+				}
+			}
+			c.Next()
+			return
+		}
+		grants := s.cfg.RBAC.GrantsFor(user.Groups)
+		allowed := oidchelper.Allowed(grants, perm)
+		// project scoped grants need to know which projects are involved
+		if !allowed && perm == oidchelper.PermissionWrite {
+			projects, err := RouteProjects(s.db, c)
+			if err != nil {
+				shared.ApiOutputError(c, err)
+				c.Abort()
+				return
+			}
+			allowed = oidchelper.Allowed(grants, perm, projects...)
+		}
+		if !allowed {
+			s.logger.Info("rbac: %s %s denied to %s", c.Request.Method, c.FullPath(), user.Name)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "forbidden",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RoutePermission classifies the matched route
+func RoutePermission(c *gin.Context) oidchelper.Permission {
+	route := c.FullPath()
+	method := c.Request.Method
+	if adminRoutes.MatchString(route) {
+		return oidchelper.PermissionAdmin
+	}
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		if sensitiveRoutes.MatchString(route) {
+			return oidchelper.PermissionReadSensitive
+		}
+		return oidchelper.PermissionRead
+	}
+	if connectionRoutes.MatchString(route) {
+		return oidchelper.PermissionAdmin
+	}
+	switch route {
+	case "/push/:tableName", "/pipelines":
+		// writing domain tables or running arbitrary plans bypasses projects
+		return oidchelper.PermissionAdmin
+	}
+	return oidchelper.PermissionWrite
+}
+
+// RouteProjects resolves the projects the matched route acts on, from the path
+// parameters identifying a project, blueprint, pipeline or task, and from the
+// project named in the body of creations and updates. A resource which doesn't
+// exist or belongs to no project resolves to the empty project name.
+func RouteProjects(db dal.Dal, c *gin.Context) ([]string, errors.Error) {
+	var projects []string
+	if c.Request.Method != http.MethodGet {
+		field := ""
+		switch c.FullPath() {
+		case "/projects", "/projects/:projectName":
+			field = "name"
+		case "/blueprints", "/blueprints/:blueprintId":
+			field = "projectName"
+		}
+		if field != "" {
+			project, err := projectInBody(c, field)
+			if err != nil {
+				return nil, err
+			}
+			if project != "" {
+				projects = append(projects, project)
+			}
+		}
+	}
+	for _, param := range c.Params {
+		var project string
+		var err errors.Error
+		switch param.Key {
+		case "projectName":
+			project = param.Value
+		case "blueprintId":
+			project, err = blueprintProject(db, param.Value)
+		case "pipelineId":
+			project, err = pipelineProject(db, param.Value)
+		case "taskId":
+			task := &models.Task{}
+			err = db.First(task, dal.Select("pipeline_id"), dal.Where("id = ?", param.Value))
+			if err == nil {
+				project, err = pipelineProject(db, task.PipelineId)
+			}
+			err = ignoreNotFound(db, err)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+func pipelineProject(db dal.Dal, pipelineId any) (string, errors.Error) {
+	pipeline := &models.Pipeline{}
+	err := db.First(pipeline, dal.Select("blueprint_id"), dal.Where("id = ?", pipelineId))
+	if err != nil {
+		return "", ignoreNotFound(db, err)
+	}
+	return blueprintProject(db, pipeline.BlueprintId)
+}
+
+func blueprintProject(db dal.Dal, blueprintId any) (string, errors.Error) {
+	blueprint := &models.Blueprint{}
+	err := db.First(blueprint, dal.Select("project_name"), dal.Where("id = ?", blueprintId))
+	if err != nil {
+		return "", ignoreNotFound(db, err)
+	}
+	return blueprint.ProjectName, nil
+}
+
+// ignoreNotFound lets missing resources through as belonging to no project, the
+// handler responds 404 to those allowed to act outside projects.
+func ignoreNotFound(db dal.Dal, err errors.Error) errors.Error {
+	if err != nil && db.IsErrorNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// WritesCustomPlan tells whether the write sets a plan of its own: an advanced
+// mode blueprint, or the before and after plans of a normal one, created alone
+// or along with a project. Such plans may run any task against any connection
+// and scope, beyond the projects, so they are reserved to admins like
+// /pipelines. Writing an existing advanced mode blueprint is reserved too,
+// triggering it is not.
+func WritesCustomPlan(db dal.Dal, c *gin.Context) (bool, errors.Error) {
+	var body map[string]any
+	var current *models.Blueprint
+	switch c.FullPath() {
+	case "/projects":
+		projectBody, err := jsonBody(c)
+		if err != nil {
+			return false, err
+		}
+		body, _ = projectBody["blueprint"].(map[string]any)
+	case "/blueprints":
+		var err errors.Error
+		if body, err = jsonBody(c); err != nil {
+			return false, err
+		}
+	case "/blueprints/:blueprintId":
+		current = &models.Blueprint{}
+		err := db.First(current, dal.Select("mode, before_plan, after_plan"), dal.Where("id = ?", c.Param("blueprintId")))
+		if err != nil {
+			if db.IsErrorNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if current.Mode == models.BLUEPRINT_MODE_ADVANCED {
+			return true, nil
+		}
+		if body, err = jsonBody(c); err != nil {
+			return false, err
+		}
+	default:
+		return false, nil
+	}
+	if body == nil {
+		return false, nil
+	}
+	if mode, _ := body["mode"].(string); mode == models.BLUEPRINT_MODE_ADVANCED {
+		return true, nil
+	}
+	if current == nil {
+		current = &models.Blueprint{}
+	}
+	// the ui sends the whole blueprint back, so unchanged plans are fine
+	return changesPlan(body["beforePlan"], current.BeforePlan) || changesPlan(body["afterPlan"], current.AfterPlan), nil
+}
+
+func changesPlan(value any, current models.PipelinePlan) bool {
+	if value == nil {
+		return false
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return true
+	}
+	var plan models.PipelinePlan
+	if json.Unmarshal(raw, &plan) != nil {
+		// malformed plans are rejected by the handler, to admins
+		return true
+	}
+	if planTaskCount(plan) == 0 && planTaskCount(current) == 0 {
+		return false
+	}
+	expected, _ := json.Marshal(current)
+	actual, _ := json.Marshal(plan)
+	return !bytes.Equal(expected, actual)
+}
+
+func planTaskCount(plan models.PipelinePlan) int {
+	count := 0
+	for _, stage := range plan {
+		count += len(stage)
+	}
+	return count
+}
+
+// projectInBody peeks the project named by `field` in the JSON body
+func projectInBody(c *gin.Context, field string) (string, errors.Error) {
+	body, err := jsonBody(c)
+	if err != nil {
+		return "", err
+	}
+	project, _ := body[field].(string)
+	return project, nil
+}
+
+// jsonBody peeks the JSON body and puts it back for the handler, malformed
+// bodies result in nil as the handler rejects them anyway
+func jsonBody(c *gin.Context) (map[string]any, errors.Error) {
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, errors.BadInput.Wrap(err, "failed to read request body")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	body := map[string]any{}
+	if json.Unmarshal(raw, &body) != nil {
+		return nil, nil
+	}
+	return body, nil
+}
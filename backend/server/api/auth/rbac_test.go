@@ -0,0 +1,182 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/helpers/oidchelper"
+	"github.com/apache/incubator-devlake/impls/logruslog"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/apache/incubator-devlake/server/api/shared"
+)
+
+// newRBACTestRouter serves the routes with a fixed user, the way the session
+// middleware would have set it, and blueprints 1 and 2 belonging to project-a,
+// 2 being in advanced mode.
+func newRBACTestRouter(groups ...string) *gin.Engine {
+	return newRBACTestRouterAs(func(c *gin.Context) {
+		c.Set(common.USER, &common.User{Name: "alice", Groups: groups})
+	})
+}
+
+func newRBACTestRouterAs(authenticate gin.HandlerFunc) *gin.Engine {
+	mappings, _ := oidchelper.ParseRoleMappings("admins=admin;maintainers=project-maintainer;team-a=project-maintainer:project-a;staff=viewer")
+	db := &mockdal.Dal{}
+	db.On("First", mock.AnythingOfType("*models.Blueprint"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		blueprint := args.Get(0).(*models.Blueprint)
+		blueprint.ProjectName = "project-a"
+		blueprint.Mode = models.BLUEPRINT_MODE_NORMAL
+		for _, clause := range args.Get(1).([]dal.Clause) {
+			if clause.Type == dal.WhereClause && clause.Data.(dal.DalClause).Params[0] == "2" {
+				blueprint.Mode = models.BLUEPRINT_MODE_ADVANCED
+			}
+		}
+	}).Return(nil)
+	s := &Service{
+		cfg: &oidchelper.Config{
+			AuthEnabled: true,
+			RBAC:        oidchelper.RBACConfig{Enabled: true, GroupsClaim: "groups", Mappings: mappings},
+		},
+		logger:   logruslog.Global,
+		db:       db,
+		revoked:  newRevocationCache(),
+		lastSeen: map[string]time.Time{},
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(authenticate)
+	r.Use(s.RequirePermission())
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.GET("/projects", ok)
+	r.POST("/projects", ok)
+	r.DELETE("/projects/:projectName", ok)
+	r.POST("/blueprints", ok)
+	r.PATCH("/blueprints/:blueprintId", ok)
+	r.POST("/blueprints/:blueprintId/trigger", ok)
+	r.GET("/api-keys", ok)
+	r.GET("/plugins/github/connections", ok)
+	r.DELETE("/plugins/github/connections/:connectionId", ok)
+	r.PUT("/plugins/github/connections/:connectionId/scopes", ok)
+	r.POST("/plugins/github/connections/:connectionId/test", ok)
+	r.PATCH("/plugins/webhook/connections/by-name/:connectionName", ok)
+	r.DELETE("/plugins/webhook/connections/by-name/:connectionName", ok)
+	r.POST("/plugins/webhook/connections/by-name/:connectionName/deployments", ok)
+	return r
+}
+
+func TestRequirePermission(t *testing.T) {
+	cases := []struct {
+		groups []string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{[]string{"admins"}, http.MethodGet, "/api-keys", "", http.StatusNoContent},
+		{[]string{"admins"}, http.MethodDelete, "/plugins/github/connections/1", "", http.StatusNoContent},
+		{[]string{"staff"}, http.MethodGet, "/projects", "", http.StatusNoContent},
+		{[]string{"staff"}, http.MethodGet, "/plugins/github/connections", "", http.StatusForbidden},
+		{[]string{"staff"}, http.MethodDelete, "/projects/project-a", "", http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodGet, "/plugins/github/connections", "", http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodGet, "/api-keys", "", http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodDelete, "/plugins/github/connections/1", "", http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodPut, "/plugins/github/connections/1/scopes", "", http.StatusForbidden},
+		// the body of a test is merged into the saved connection, credentials included
+		{[]string{"maintainers"}, http.MethodPost, "/plugins/github/connections/1/test", `{"endpoint":"https://attacker"}`, http.StatusForbidden},
+		{[]string{"admins"}, http.MethodPost, "/plugins/github/connections/1/test", `{}`, http.StatusNoContent},
+		// the webhook connections are changed by name as well, unlike pushing to them
+		{[]string{"maintainers"}, http.MethodPatch, "/plugins/webhook/connections/by-name/hook", `{"name":"renamed"}`, http.StatusForbidden},
+		{[]string{"maintainers"}, http.MethodDelete, "/plugins/webhook/connections/by-name/hook", "", http.StatusForbidden},
+		{[]string{"maintainers"}, http.MethodPost, "/plugins/webhook/connections/by-name/hook/deployments", `{}`, http.StatusNoContent},
+		{[]string{"admins"}, http.MethodPatch, "/plugins/webhook/connections/by-name/hook", `{"name":"renamed"}`, http.StatusNoContent},
+		{[]string{"admins"}, http.MethodDelete, "/plugins/webhook/connections/by-name/hook", "", http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodDelete, "/projects/project-a", "", http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodDelete, "/projects/project-b", "", http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodPost, "/projects", `{"name":"project-a"}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPost, "/projects", `{"name":"project-b"}`, http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodPatch, "/blueprints/1", `{"enable":false}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPatch, "/blueprints/1", `{"projectName":"project-b"}`, http.StatusForbidden},
+		// advanced mode and before/after plans may run anything, beyond the projects
+		{[]string{"team-a"}, http.MethodPost, "/blueprints", `{"projectName":"project-a","mode":"NORMAL"}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPost, "/blueprints", `{"projectName":"project-a","mode":"ADVANCED","plan":[[{"plugin":"github"}]]}`, http.StatusForbidden},
+		{[]string{"admins"}, http.MethodPost, "/blueprints", `{"projectName":"project-a","mode":"ADVANCED","plan":[[{"plugin":"github"}]]}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPatch, "/blueprints/1", `{"enable":true,"beforePlan":[],"afterPlan":null}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPatch, "/blueprints/1", `{"beforePlan":[[{"plugin":"github","options":{"connectionId":9}}]]}`, http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodPatch, "/blueprints/1", `{"mode":"ADVANCED"}`, http.StatusForbidden},
+		{[]string{"team-a"}, http.MethodPatch, "/blueprints/2", `{"enable":false}`, http.StatusForbidden},
+		{[]string{"admins"}, http.MethodPatch, "/blueprints/2", `{"enable":false}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPost, "/blueprints/2/trigger", `{}`, http.StatusNoContent},
+		{[]string{"team-a"}, http.MethodPost, "/projects", `{"name":"project-a","blueprint":{"mode":"ADVANCED"}}`, http.StatusForbidden},
+		{nil, http.MethodGet, "/projects", "", http.StatusForbidden},
+		{[]string{"staff"}, http.MethodGet, "/unknown", "", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		r := newRBACTestRouter(c.groups...)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if w.Code != c.want {
+			t.Errorf("%v %s %s: expected %d, got %d body=%s", c.groups, c.method, c.path, c.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequirePermissionApiKey(t *testing.T) {
+	cases := []struct {
+		scopes *models.ApiKeyScopes
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{&models.ApiKeyScopes{All: true}, http.MethodDelete, "/projects/project-b", "", http.StatusNoContent},
+		// admin routes must be named by the scopes
+		{&models.ApiKeyScopes{All: true}, http.MethodGet, "/api-keys", "", http.StatusForbidden},
+		{&models.ApiKeyScopes{Methods: []string{http.MethodGet}}, http.MethodGet, "/api-keys", "", http.StatusForbidden},
+		{&models.ApiKeyScopes{Resources: []string{"api-keys"}}, http.MethodGet, "/api-keys", "", http.StatusNoContent},
+		{&models.ApiKeyScopes{All: true}, http.MethodDelete, "/plugins/github/connections/1", "", http.StatusForbidden},
+		{&models.ApiKeyScopes{Resources: []string{"plugins/github"}}, http.MethodDelete, "/plugins/github/connections/1", "", http.StatusNoContent},
+		{&models.ApiKeyScopes{All: true}, http.MethodPatch, "/blueprints/2", `{"enable":false}`, http.StatusForbidden},
+		{&models.ApiKeyScopes{Resources: []string{"blueprints"}}, http.MethodPatch, "/blueprints/2", `{"enable":false}`, http.StatusNoContent},
+		// keys created before scopes only have their path
+		{nil, http.MethodGet, "/api-keys", "", http.StatusForbidden},
+		{nil, http.MethodGet, "/projects", "", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		apiKey := &models.ApiKey{Name: "key", AllowedPath: ".*", Scopes: c.scopes}
+		r := newRBACTestRouterAs(func(c *gin.Context) {
+			shared.SetApiKey(c, apiKey)
+			c.Set(common.USER, &common.User{Name: "alice"})
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if w.Code != c.want {
+			t.Errorf("%+v %s %s: expected %d, got %d body=%s", c.scopes, c.method, c.path, c.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"net/http"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/server/api/shared"
+	"github.com/apache/incubator-devlake/server/services"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Rotate the encryption secret
+// @Description Re-encrypt every stored secret (connection tokens and passwords, pipeline plans, etc.) with the current
+// @Description ENCRYPTION_SECRET inside a single transaction. The values encrypted with the previous secret can be
+// @Description decrypted as long as it is listed in ENCRYPTION_SECRET_PREVIOUS, which can be emptied afterwards.
+// @Tags framework/encryption
+// @Success 200  {object} services.EncryptionRotationResult
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /encryption-secret/rotate [post]
+func PostRotate(c *gin.Context) {
+	result, err := services.RotateEncryptionSecret()
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error rotating encryption secret"))
+		return
+	}
+	shared.ApiOutputSuccess(c, result, http.StatusOK)
+}
@@ -21,16 +21,23 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/apache/incubator-devlake/core/log"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/apache/incubator-devlake/core/config"
 	"github.com/apache/incubator-devlake/core/context"
 	"github.com/apache/incubator-devlake/core/dal"
 	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
 	"github.com/apache/incubator-devlake/core/models/common"
 	"github.com/apache/incubator-devlake/helpers/apikeyhelper"
+	"github.com/apache/incubator-devlake/server/api/auth"
+	"github.com/apache/incubator-devlake/server/api/shared"
 	"github.com/gin-gonic/gin"
 )
 
@@ -40,9 +47,16 @@ func getOAuthUserInfo(c *gin.Context) (*common.User, error) {
 	}
 	user := c.GetHeader("X-Forwarded-User")
 	email := c.GetHeader("X-Forwarded-Email")
+	var groups []string
+	for _, g := range strings.Split(c.GetHeader("X-Forwarded-Groups"), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
 	return &common.User{
-		Name:  user,
-		Email: email,
+		Name:   user,
+		Email:  email,
+		Groups: groups,
 	}, nil
 }
 
@@ -72,11 +86,84 @@ func getBasicAuthUserInfo(c *gin.Context, basicRes context.BasicRes) (*common.Us
 	}, nil
 }
 
+// trustedProxy is the authenticating reverse proxy, i.e. oauth2-proxy, DevLake is deployed behind. Only the
+// requests coming from it may tell who the user is with the X-Forwarded-* or basic auth headers, anybody could
+// forge them otherwise.
+type trustedProxy struct {
+	networks []*net.IPNet
+}
+
+// loadTrustedProxy reads AUTH_PROXY_ENABLED and AUTH_PROXY_TRUSTED_CIDRS, nil is returned when disabled. The
+// headers name the actors of the audit logs even while AUTH_ENABLED=false, so they are never trusted from any peer.
+func loadTrustedProxy(cfg config.ConfigReader) (*trustedProxy, error) {
+	if !cfg.GetBool("AUTH_PROXY_ENABLED") {
+		return nil, nil
+	}
+	proxy := &trustedProxy{}
+	for _, cidr := range strings.Split(cfg.GetString("AUTH_PROXY_TRUSTED_CIDRS"), ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTH_PROXY_TRUSTED_CIDRS entry %q: %w", cidr, err)
+		}
+		proxy.networks = append(proxy.networks, network)
+	}
+	if len(proxy.networks) == 0 {
+		return nil, fmt.Errorf("AUTH_PROXY_ENABLED=true but AUTH_PROXY_TRUSTED_CIDRS is not set")
+	}
+	return proxy, nil
+}
+
+// trusts tells whether the request was sent by the proxy, judging by the address of the peer and never by the
+// X-Forwarded-For header
+func (p *trustedProxy) trusts(c *gin.Context) bool {
+	if p == nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		host = c.Request.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuth2ProxyAuthentication sets the user authenticated by the trusted proxy, the headers of the requests coming
+// from anywhere else are ignored. Disabled unless AUTH_PROXY_ENABLED=true.
 func OAuth2ProxyAuthentication(basicRes context.BasicRes) gin.HandlerFunc {
 	logger := basicRes.GetLogger()
+	proxy, err := loadTrustedProxy(basicRes.GetConfigReader())
+	if err != nil {
+		panic(err)
+	}
+	var ignoredOnce sync.Once
 	return func(c *gin.Context) {
 		_, exist := c.Get(common.USER)
-		if !exist {
+		if !exist && proxy == nil && c.GetHeader("X-Forwarded-User") != "" {
+			// the deployment is likely behind a proxy which hasn't been configured since the upgrade
+			ignoredOnce.Do(func() {
+				logger.Warn(nil, "X-Forwarded-User headers are ignored, set AUTH_PROXY_ENABLED and AUTH_PROXY_TRUSTED_CIDRS to trust the proxy sending them")
+			})
+		}
+		if !exist && proxy.trusts(c) {
 			user, err := getOAuthUserInfo(c)
 			if err != nil {
 				logger.Error(err, "getOAuthUserInfo")
@@ -109,18 +196,44 @@ func RestAuthentication(router *gin.Engine, basicRes context.BasicRes) gin.Handl
 		panic(fmt.Errorf("db is not initialised"))
 	}
 	apiKeyHelper := apikeyhelper.NewApiKeyHelper(basicRes, logger)
+	rateLimiter := apikeyhelper.NewRateLimiter()
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 		// Only open api needs to check api key
 		if !strings.HasPrefix(path, "/rest") {
+			// requests authenticated below come back here once routed, which is
+			// when the scopes of their api key can be checked against the route
+			if apiKey, ok := shared.GetApiKey(c); ok {
+				if !CheckApiKeyScopes(c, logger, db, apiKey) {
+					c.Abort()
+					return
+				}
+				c.Set(common.USER, &common.User{
+					Name:  apiKey.Creator.Creator,
+					Email: apiKey.Creator.CreatorEmail,
+				})
+			}
 			logger.Debug("path %s will continue", path)
 			c.Next()
 			return
 		}
 		path = strings.TrimPrefix(path, "/rest")
 		authHeader := c.GetHeader("Authorization")
-		ok := CheckAuthorizationHeader(c, logger, db, apiKeyHelper, authHeader, path)
+		// third-party webhooks sign their payloads with the connection secret instead
+		if authHeader == "" && auth.IsSignedWebhook(c.Request.Method, path) {
+			c.Request.URL.Path = path
+			router.HandleContext(c)
+			c.Abort()
+			return
+		}
+		// the rejected requests never reach AuditLog, record them here
+		start := time.Now()
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		ok := CheckAuthorizationHeader(c, logger, db, apiKeyHelper, rateLimiter, authHeader, path)
+		c.Writer = writer.ResponseWriter
 		if !ok {
+			auditRejected(c, logger, start, writer)
 			c.Abort()
 			return
 		} else {
@@ -131,7 +244,7 @@ func RestAuthentication(router *gin.Engine, basicRes context.BasicRes) gin.Handl
 	}
 }
 
-func CheckAuthorizationHeader(c *gin.Context, logger log.Logger, db dal.Dal, apiKeyHelper *apikeyhelper.ApiKeyHelper, authHeader, path string) bool {
+func CheckAuthorizationHeader(c *gin.Context, logger log.Logger, db dal.Dal, apiKeyHelper *apikeyhelper.ApiKeyHelper, rateLimiter *apikeyhelper.RateLimiter, authHeader, path string) bool {
 	if authHeader == "" {
 		c.Abort()
 		c.JSON(http.StatusUnauthorized, &apiBody{
@@ -187,29 +300,98 @@ func CheckAuthorizationHeader(c *gin.Context, logger log.Logger, db dal.Dal, api
 		})
 		return false
 	}
-	matched, matchErr := regexp.MatchString(apiKey.AllowedPath, path)
-	if matchErr != nil {
-		logger.Error(err, "regexp match path error")
-		c.Abort()
-		c.JSON(http.StatusInternalServerError, &apiBody{
-			Success: false,
-			Message: matchErr.Error(),
-		})
-		return false
+	// keys created before scopes existed are still restricted by their path regex
+	if apiKey.Scopes == nil {
+		matched, matchErr := regexp.MatchString(apiKey.AllowedPath, path)
+		if matchErr != nil {
+			logger.Error(err, "regexp match path error")
+			c.Abort()
+			c.JSON(http.StatusInternalServerError, &apiBody{
+				Success: false,
+				Message: matchErr.Error(),
+			})
+			return false
+		}
+		if !matched {
+			c.JSON(http.StatusForbidden, &apiBody{
+				Success: false,
+				Message: "path doesn't match api key's scope",
+			})
+			return false
+		}
 	}
-	if !matched {
-		c.JSON(http.StatusForbidden, &apiBody{
+	if allowed, retryAfter := rateLimiter.Allow(apiKey); !allowed {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, &apiBody{
 			Success: false,
-			Message: "path doesn't match api key's scope",
+			Message: "api key's rate limit exceeded",
 		})
 		return false
 	}
+	apiKeyHelper.RecordUsage(apiKey, c.ClientIP())
 
 	logger.Info("redirect path: %s to: %s", c.Request.URL.Path, path)
 	c.Request.URL.Path = path
-	c.Set(common.USER, &common.User{
-		Name:  apiKey.Creator.Creator,
-		Email: apiKey.Creator.CreatorEmail,
-	})
+	shared.SetApiKey(c, apiKey)
+	return true
+}
+
+// CheckApiKeyScopes verifies the routed request against the scopes of the api key it was authenticated by
+func CheckApiKeyScopes(c *gin.Context, logger log.Logger, db dal.Dal, apiKey *models.ApiKey) bool {
+	// unknown routes end up with 404 anyway
+	if c.FullPath() == "" {
+		return true
+	}
+	// keys without scopes were checked by path, the admin resources stay out of their reach
+	scopes := apiKey.Scopes
+	if scopes == nil {
+		scopes = &models.ApiKeyScopes{}
+	}
+	req := &apikeyhelper.ScopedRequest{
+		Method:   c.Request.Method,
+		Resource: apikeyhelper.RouteResource(c.FullPath()),
+	}
+	var err errors.Error
+	if len(scopes.Projects) > 0 {
+		req.Projects, err = auth.RouteProjects(db, c)
+	}
+	if err == nil && len(scopes.WebhookConnectionIds) > 0 && req.Resource == "plugins/webhook" {
+		req.WebhookConnectionId, err = webhookConnectionId(db, c)
+	}
+	if err == nil {
+		err = apikeyhelper.CheckScopes(scopes, req)
+	}
+	if err != nil {
+		if err.GetType() != errors.Forbidden {
+			logger.Error(err, "check api key scopes")
+		}
+		shared.ApiOutputError(c, err)
+		return false
+	}
 	return true
 }
+
+// webhookConnectionId resolves the connection of the webhook endpoints, the routes of
+// the connections themselves don't count as its endpoints
+func webhookConnectionId(db dal.Dal, c *gin.Context) (uint64, errors.Error) {
+	switch c.FullPath() {
+	case "/plugins/webhook/connections/:connectionId", "/plugins/webhook/connections/by-name/:connectionName":
+		return 0, nil
+	}
+	if id := c.Param("connectionId"); id != "" {
+		connectionId, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return 0, errors.BadInput.Wrap(err, "invalid connectionId")
+		}
+		return connectionId, nil
+	}
+	if name := c.Param("connectionName"); name != "" {
+		var ids []uint64
+		err := db.Pluck("id", &ids, dal.From("_tool_webhook_connections"), dal.Where("name = ?", name))
+		if err != nil || len(ids) == 0 {
+			return 0, err
+		}
+		return ids[0], nil
+	}
+	return 0, nil
+}
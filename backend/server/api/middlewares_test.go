@@ -0,0 +1,257 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/core/models/domainlayer/devops"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	webhookImpl "github.com/apache/incubator-devlake/plugins/webhook/impl"
+	webhookModels "github.com/apache/incubator-devlake/plugins/webhook/models"
+	"github.com/apache/incubator-devlake/server/services"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newProxyAuthTestRouter responds with the user set by OAuth2ProxyAuthentication, 401 when there is none
+func newProxyAuthTestRouter(t *testing.T, settings map[string]interface{}) *gin.Engine {
+	v := viper.New()
+	for key, value := range settings {
+		v.Set(key, value)
+	}
+	logger := unithelper.DummyLogger()
+	logger.On("Warn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	basicRes := mockcontext.NewBasicRes(t)
+	basicRes.On("GetLogger").Return(logger).Maybe()
+	basicRes.On("GetConfigReader").Return(v)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(OAuth2ProxyAuthentication(basicRes))
+	r.GET("/whoami", func(c *gin.Context) {
+		user, ok := c.Get(common.USER)
+		if !ok {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	})
+	return r
+}
+
+func proxyAuthRequest(r *gin.Engine, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestOAuth2ProxyAuthentication(t *testing.T) {
+	forged := map[string]string{
+		"X-Forwarded-User":   "mallory",
+		"X-Forwarded-Groups": "devlake-admins",
+		"X-Forwarded-For":    "10.0.0.1",
+	}
+	basicAuth := map[string]string{"Authorization": "Basic YWxpY2U6c2VjcmV0"}
+
+	// the headers are ignored unless the proxy is trusted explicitly, with or without authentication as they name
+	// the actors of the audit logs
+	for _, authEnabled := range []bool{false, true} {
+		r := newProxyAuthTestRouter(t, map[string]interface{}{"AUTH_ENABLED": authEnabled})
+		assert.Equal(t, http.StatusUnauthorized, proxyAuthRequest(r, "10.0.0.1:4180", forged).Code)
+		assert.Equal(t, http.StatusUnauthorized, proxyAuthRequest(r, "10.0.0.1:4180", basicAuth).Code)
+	}
+
+	r := newProxyAuthTestRouter(t, map[string]interface{}{
+		"AUTH_PROXY_ENABLED":       true,
+		"AUTH_PROXY_TRUSTED_CIDRS": "10.0.0.1, 172.16.0.0/12",
+	})
+	// a client reaching DevLake directly can't pass for the proxy, X-Forwarded-For included
+	assert.Equal(t, http.StatusUnauthorized, proxyAuthRequest(r, "192.168.1.5:52100", forged).Code)
+	assert.Equal(t, http.StatusUnauthorized, proxyAuthRequest(r, "192.168.1.5:52100", basicAuth).Code)
+
+	w := proxyAuthRequest(r, "10.0.0.1:4180", map[string]string{
+		"X-Forwarded-User":   "alice",
+		"X-Forwarded-Email":  "alice@example.com",
+		"X-Forwarded-Groups": "devlake-admins, team-a",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Name":"alice","Email":"alice@example.com","Groups":["devlake-admins","team-a"]}`, w.Body.String())
+	w = proxyAuthRequest(r, "172.20.3.4:4180", basicAuth)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Name":"alice","Email":"","Groups":null}`, w.Body.String())
+}
+
+func TestOAuth2ProxyAuthenticationConfig(t *testing.T) {
+	assert.Panics(t, func() {
+		newProxyAuthTestRouter(t, map[string]interface{}{"AUTH_PROXY_ENABLED": true})
+	})
+	assert.Panics(t, func() {
+		newProxyAuthTestRouter(t, map[string]interface{}{"AUTH_PROXY_ENABLED": true, "AUTH_PROXY_TRUSTED_CIDRS": "proxy.local"})
+	})
+}
+
+func TestCheckApiKeyScopes(t *testing.T) {
+	// the scopes given to the keys generated for webhook connections
+	webhookKey := &models.ApiKey{Scopes: &models.ApiKeyScopes{
+		Methods:              []string{http.MethodPost},
+		Resources:            []string{"plugins/webhook"},
+		WebhookConnectionIds: []uint64{1},
+	}}
+	legacyKey := &models.ApiKey{AllowedPath: ".*"}
+	gin.SetMode(gin.TestMode)
+	newRouter := func(apiKey *models.ApiKey) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if !CheckApiKeyScopes(c, unithelper.DummyLogger(), nil, apiKey) {
+				c.Abort()
+			}
+		})
+		ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+		r.POST("/plugins/webhook/connections", ok)
+		r.GET("/plugins/webhook/connections/:connectionId", ok)
+		r.PATCH("/plugins/webhook/connections/:connectionId", ok)
+		r.DELETE("/plugins/webhook/connections/:connectionId", ok)
+		r.POST("/plugins/webhook/connections/:connectionId/deployments", ok)
+		r.GET("/projects", ok)
+		r.GET("/api-keys", ok)
+		return r
+	}
+	cases := []struct {
+		apiKey *models.ApiKey
+		method string
+		path   string
+		want   int
+	}{
+		{webhookKey, http.MethodPost, "/plugins/webhook/connections/1/deployments", http.StatusNoContent},
+		{webhookKey, http.MethodPost, "/plugins/webhook/connections/2/deployments", http.StatusForbidden},
+		{webhookKey, http.MethodPost, "/plugins/webhook/connections", http.StatusForbidden},
+		{webhookKey, http.MethodGet, "/plugins/webhook/connections/1", http.StatusForbidden},
+		{webhookKey, http.MethodPatch, "/plugins/webhook/connections/1", http.StatusForbidden},
+		{webhookKey, http.MethodDelete, "/plugins/webhook/connections/1", http.StatusForbidden},
+		{legacyKey, http.MethodGet, "/projects", http.StatusNoContent},
+		{legacyKey, http.MethodGet, "/api-keys", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		newRouter(c.apiKey).ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		assert.Equal(t, c.want, w.Code, "%s %s", c.method, c.path)
+	}
+}
+
+// newSignedWebhookTestRouter serves the routes of the webhook plugin behind RestAuthentication the way the
+// server does, with connection 1 holding the given secret, and collects the deployments saved
+func newSignedWebhookTestRouter(t *testing.T, secret string) (*gin.Engine, *[]*devops.CICDDeployment) {
+	t.Setenv("ENCRYPTION_SECRET", "signed webhook test")
+	saveAuditLog = func(*models.AuditLog) errors.Error { return nil }
+	t.Cleanup(func() { saveAuditLog = services.SaveAuditLog })
+	var deployments []*devops.CICDDeployment
+	tx := new(mockdal.Transaction)
+	tx.On("CreateOrUpdate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if deployment, ok := args.Get(0).(*devops.CICDDeployment); ok {
+			deployments = append(deployments, deployment)
+		}
+	}).Return(nil)
+	tx.On("UnlockTables").Return(nil)
+	tx.On("Commit").Return(nil)
+	tx.On("Rollback").Return(nil).Maybe()
+	db := new(mockdal.Dal)
+	db.On("First", mock.AnythingOfType("*models.WebhookConnection"), mock.Anything).Run(func(args mock.Arguments) {
+		connection := args.Get(0).(*webhookModels.WebhookConnection)
+		connection.ID = 1
+		connection.Name = "ci"
+		connection.Secret = secret
+	}).Return(nil)
+	db.On("Begin").Return(tx)
+	basicRes := mockcontext.NewBasicRes(t)
+	basicRes.On("GetLogger").Return(unithelper.DummyLogger()).Maybe()
+	basicRes.On("GetDal").Return(db).Maybe()
+	basicRes.On("GetConfig", plugin.EncodeKeyEnvStr).Return("").Maybe()
+	webhook := webhookImpl.Webhook{}
+	assert.Nil(t, webhook.Init(basicRes))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RestAuthentication(r, basicRes))
+	registerPluginEndpoints(r, basicRes, "webhook", webhook.ApiResources())
+	return r, &deployments
+}
+
+func TestRestAuthenticationSignedWebhook(t *testing.T) {
+	body := `{
+		"action": "created",
+		"deployment_status": {"id": 2, "state": "success", "environment": "production", "created_at": "2024-03-01T10:00:00Z", "updated_at": "2024-03-01T10:05:00Z"},
+		"deployment": {"id": 1, "sha": "abc123", "ref": "main", "environment": "production", "created_at": "2024-03-01T09:59:00Z"},
+		"repository": {"id": 3, "full_name": "apache/devlake", "html_url": "https://github.com/apache/devlake"}
+	}`
+	sign := func(secret string) string {
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(h.Sum(nil))
+	}
+	post := func(r *gin.Engine, path string, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "deployment_status")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// github sends no api key, the signature is what authenticates it
+	r, deployments := newSignedWebhookTestRouter(t, "s3cr3t")
+	assert.Equal(t, http.StatusOK, post(r, "/rest/plugins/webhook/connections/1/github", map[string]string{
+		"X-Hub-Signature-256": sign("s3cr3t"),
+	}))
+	if assert.Len(t, *deployments, 1) {
+		assert.Equal(t, "webhook:1", (*deployments)[0].CicdScopeId)
+		assert.Equal(t, devops.RESULT_SUCCESS, (*deployments)[0].Result)
+	}
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/rest/plugins/webhook/connections/1/github", map[string]string{
+		"X-Hub-Signature-256": sign("other"),
+	}))
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/rest/plugins/webhook/connections/1/github", nil))
+	// the other routes still require an api key
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/rest/plugins/webhook/connections/1/deployments", nil))
+	assert.Len(t, *deployments, 1)
+
+	// nothing authenticates the requests to a connection without secret
+	r, deployments = newSignedWebhookTestRouter(t, "")
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/rest/plugins/webhook/connections/1/github", nil))
+	assert.Empty(t, *deployments)
+}
@@ -0,0 +1,297 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifications
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/server/api/shared"
+	"github.com/apache/incubator-devlake/server/services"
+	"github.com/gin-gonic/gin"
+)
+
+type PaginatedNotificationChannels struct {
+	Channels []*models.NotificationChannel `json:"channels"`
+	Count    int64                         `json:"count"`
+}
+
+type PaginatedNotifications struct {
+	Notifications []*models.Notification `json:"notifications"`
+	Count         int64                  `json:"count"`
+}
+
+// @Summary Get list of notification channels
+// @Description GET /notification-channels?page=1&pageSize=10
+// @Tags framework/notifications
+// @Param page query int false "query"
+// @Param pageSize query int false "query"
+// @Success 200  {object} PaginatedNotificationChannels
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels [get]
+func GetChannels(c *gin.Context) {
+	var query services.NotificationChannelQuery
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		shared.ApiOutputError(c, errors.BadInput.Wrap(err, shared.BadRequestBody))
+		return
+	}
+	channels, count, err := services.GetNotificationChannels(&query)
+	if err != nil {
+		shared.ApiOutputAbort(c, errors.Default.Wrap(err, "error getting notification channels"))
+		return
+	}
+	shared.ApiOutputSuccess(c, PaginatedNotificationChannels{
+		Channels: channels,
+		Count:    count,
+	}, http.StatusOK)
+}
+
+// @Summary Create a notification channel
+// @Description Create a slack, teams, http or smtp notification channel
+// @Tags framework/notifications
+// @Accept application/json
+// @Param channel body models.NotificationChannel true "json"
+// @Success 201  {object} models.NotificationChannel
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels [post]
+func PostChannel(c *gin.Context) {
+	channel := &models.NotificationChannel{}
+	err := c.ShouldBind(channel)
+	if err != nil {
+		shared.ApiOutputError(c, errors.BadInput.Wrap(err, shared.BadRequestBody))
+		return
+	}
+	err = services.CreateNotificationChannel(channel)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error creating notification channel"))
+		return
+	}
+	shared.ApiOutputSuccess(c, channel, http.StatusCreated)
+}
+
+// @Summary Get a notification channel
+// @Description Get a notification channel
+// @Tags framework/notifications
+// @Param channelId path int true "channelId"
+// @Success 200  {object} models.NotificationChannel
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId} [get]
+func GetChannel(c *gin.Context) {
+	id, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	channel, err := services.GetNotificationChannel(id)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error getting notification channel"))
+		return
+	}
+	shared.ApiOutputSuccess(c, channel, http.StatusOK)
+}
+
+// @Summary Patch a notification channel
+// @Description Patch a notification channel
+// @Tags framework/notifications
+// @Accept application/json
+// @Param channelId path int true "channelId"
+// @Param channel body models.NotificationChannel true "json"
+// @Success 200  {object} models.NotificationChannel
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId} [patch]
+func PatchChannel(c *gin.Context) {
+	id, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	var body map[string]interface{}
+	err := c.ShouldBind(&body)
+	if err != nil {
+		shared.ApiOutputError(c, errors.BadInput.Wrap(err, shared.BadRequestBody))
+		return
+	}
+	channel, err := services.PatchNotificationChannel(id, body)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error patching notification channel"))
+		return
+	}
+	shared.ApiOutputSuccess(c, channel, http.StatusOK)
+}
+
+// @Summary Delete a notification channel
+// @Description Delete a notification channel and its subscriptions
+// @Tags framework/notifications
+// @Param channelId path int true "channelId"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId} [delete]
+func DeleteChannel(c *gin.Context) {
+	id, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	err := services.DeleteNotificationChannel(id)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error deleting notification channel"))
+		return
+	}
+	shared.ApiOutputSuccess(c, nil, http.StatusOK)
+}
+
+// @Summary Test a notification channel
+// @Description Send a sample notification to the channel and return the delivery log
+// @Tags framework/notifications
+// @Param channelId path int true "channelId"
+// @Success 200  {object} models.Notification
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId}/test [post]
+func TestChannel(c *gin.Context) {
+	id, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	notification, err := services.TestNotificationChannel(c.Request.Context(), id)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error testing notification channel"))
+		return
+	}
+	shared.ApiOutputSuccess(c, notification, http.StatusOK)
+}
+
+// @Summary Get subscriptions of a notification channel
+// @Description Get subscriptions of a notification channel
+// @Tags framework/notifications
+// @Param channelId path int true "channelId"
+// @Success 200  {object} []models.NotificationSubscription
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId}/subscriptions [get]
+func GetSubscriptions(c *gin.Context) {
+	id, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	subscriptions, err := services.GetNotificationSubscriptions(id)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error getting notification subscriptions"))
+		return
+	}
+	shared.ApiOutputSuccess(c, subscriptions, http.StatusOK)
+}
+
+// @Summary Subscribe a notification channel
+// @Description Subscribe a notification channel to the pipelines of a project or a blueprint, optionally filtered by status
+// @Tags framework/notifications
+// @Accept application/json
+// @Param channelId path int true "channelId"
+// @Param subscription body models.NotificationSubscription true "json"
+// @Success 201  {object} models.NotificationSubscription
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId}/subscriptions [post]
+func PostSubscription(c *gin.Context) {
+	id, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	subscription := &models.NotificationSubscription{}
+	err := c.ShouldBind(subscription)
+	if err != nil {
+		shared.ApiOutputError(c, errors.BadInput.Wrap(err, shared.BadRequestBody))
+		return
+	}
+	err = services.CreateNotificationSubscription(id, subscription)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error creating notification subscription"))
+		return
+	}
+	shared.ApiOutputSuccess(c, subscription, http.StatusCreated)
+}
+
+// @Summary Delete a subscription of a notification channel
+// @Description Delete a subscription of a notification channel
+// @Tags framework/notifications
+// @Param channelId path int true "channelId"
+// @Param subscriptionId path int true "subscriptionId"
+// @Success 200
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notification-channels/{channelId}/subscriptions/{subscriptionId} [delete]
+func DeleteSubscription(c *gin.Context) {
+	channelId, ok := parseId(c, "channelId")
+	if !ok {
+		return
+	}
+	subscriptionId, ok := parseId(c, "subscriptionId")
+	if !ok {
+		return
+	}
+	err := services.DeleteNotificationSubscription(channelId, subscriptionId)
+	if err != nil {
+		shared.ApiOutputError(c, errors.Default.Wrap(err, "error deleting notification subscription"))
+		return
+	}
+	shared.ApiOutputSuccess(c, nil, http.StatusOK)
+}
+
+// @Summary Get the notification delivery log
+// @Description GET /notifications?channelId=1&pipelineId=1&status=FAILED&page=1&pageSize=10
+// @Tags framework/notifications
+// @Param channelId query int false "query"
+// @Param pipelineId query int false "query"
+// @Param status query string false "DELIVERED or FAILED"
+// @Param page query int false "query"
+// @Param pageSize query int false "query"
+// @Success 200  {object} PaginatedNotifications
+// @Failure 400  {string} errcode.Error "Bad Request"
+// @Failure 500  {string} errcode.Error "Internal Error"
+// @Router /notifications [get]
+func GetNotifications(c *gin.Context) {
+	var query services.NotificationQuery
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		shared.ApiOutputError(c, errors.BadInput.Wrap(err, shared.BadRequestBody))
+		return
+	}
+	notifications, count, err := services.GetNotifications(&query)
+	if err != nil {
+		shared.ApiOutputAbort(c, errors.Default.Wrap(err, "error getting notifications"))
+		return
+	}
+	shared.ApiOutputSuccess(c, PaginatedNotifications{
+		Notifications: notifications,
+		Count:         count,
+	}, http.StatusOK)
+}
+
+func parseId(c *gin.Context, param string) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		shared.ApiOutputError(c, errors.BadInput.Wrap(err, "bad "+param+" format supplied"))
+		return 0, false
+	}
+	return id, true
+}
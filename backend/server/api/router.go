@@ -18,7 +18,9 @@ limitations under the License.
 package api
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
@@ -26,12 +28,15 @@ import (
 	"github.com/apache/incubator-devlake/core/errors"
 	"github.com/apache/incubator-devlake/impls/logruslog"
 	"github.com/apache/incubator-devlake/server/api/apikeys"
+	"github.com/apache/incubator-devlake/server/api/auditlogs"
 	"github.com/apache/incubator-devlake/server/api/auth"
 	"github.com/apache/incubator-devlake/server/api/store"
 
 	"github.com/apache/incubator-devlake/core/plugin"
 	"github.com/apache/incubator-devlake/server/api/blueprints"
 	"github.com/apache/incubator-devlake/server/api/domainlayer"
+	"github.com/apache/incubator-devlake/server/api/encryption"
+	"github.com/apache/incubator-devlake/server/api/notifications"
 	"github.com/apache/incubator-devlake/server/api/pipelines"
 	"github.com/apache/incubator-devlake/server/api/plugininfo"
 	"github.com/apache/incubator-devlake/server/api/project"
@@ -87,6 +92,24 @@ func RegisterRouter(r *gin.Engine, basicRes context.BasicRes) {
 	r.PUT("/api-keys/:apiKeyId", apikeys.PutApiKey)
 	r.DELETE("/api-keys/:apiKeyId", apikeys.DeleteApiKey)
 
+	// audit logs api
+	r.GET("/audit-logs", auditlogs.GetAuditLogs)
+
+	// encryption api
+	r.POST("/encryption-secret/rotate", encryption.PostRotate)
+
+	// notification channels api
+	r.GET("/notification-channels", notifications.GetChannels)
+	r.POST("/notification-channels", notifications.PostChannel)
+	r.GET("/notification-channels/:channelId", notifications.GetChannel)
+	r.PATCH("/notification-channels/:channelId", notifications.PatchChannel)
+	r.DELETE("/notification-channels/:channelId", notifications.DeleteChannel)
+	r.POST("/notification-channels/:channelId/test", notifications.TestChannel)
+	r.GET("/notification-channels/:channelId/subscriptions", notifications.GetSubscriptions)
+	r.POST("/notification-channels/:channelId/subscriptions", notifications.PostSubscription)
+	r.DELETE("/notification-channels/:channelId/subscriptions/:subscriptionId", notifications.DeleteSubscription)
+	r.GET("/notifications", notifications.GetNotifications)
+
 	// auth (OIDC user login)
 	r.GET(auth.PathMethods, auth.GetMethods)
 	r.GET(auth.PathLogin, auth.LoginInit)
@@ -139,6 +162,14 @@ func handlePluginCall(basicRes context.BasicRes, pluginName string, handler plug
 			if strings.HasPrefix(c.Request.Header.Get("Content-Type"), "multipart/form-data;") {
 				input.Request = c.Request
 			} else {
+				rawBody, readErr := io.ReadAll(c.Request.Body)
+				if readErr != nil {
+					shared.ApiOutputError(c, readErr)
+					return
+				}
+				c.Request.Body = io.NopCloser(bytes.NewReader(rawBody))
+				input.RawBody = rawBody
+				input.Request = c.Request
 				shouldBindJSONErr := c.ShouldBindJSON(&input.Body)
 				if shouldBindJSONErr != nil && shouldBindJSONErr.Error() != "EOF" {
 					shared.ApiOutputError(c, shouldBindJSONErr)
@@ -18,6 +18,9 @@ limitations under the License.
 package shared
 
 import (
+	"context"
+
+	"github.com/apache/incubator-devlake/core/models"
 	"github.com/apache/incubator-devlake/core/models/common"
 	"github.com/gin-gonic/gin"
 )
@@ -30,3 +33,18 @@ func GetUser(c *gin.Context) (*common.User, bool) {
 	user := userObj.(*common.User)
 	return user, true
 }
+
+type apiKeyContextKey struct{}
+
+// SetApiKey marks the request as authenticated by the api key. It is kept in the
+// request's context rather than gin's keys so it survives router.HandleContext,
+// which is how /rest/* requests are dispatched once authenticated.
+func SetApiKey(c *gin.Context, apiKey *models.ApiKey) {
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), apiKeyContextKey{}, apiKey))
+}
+
+// GetApiKey returns the api key the request was authenticated by
+func GetApiKey(c *gin.Context) (*models.ApiKey, bool) {
+	apiKey, ok := c.Request.Context().Value(apiKeyContextKey{}).(*models.ApiKey)
+	return apiKey, ok
+}
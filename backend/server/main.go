@@ -18,10 +18,13 @@ limitations under the License.
 package main
 
 import (
+	"os"
+
 	"github.com/apache/incubator-devlake/core/config"
 	"github.com/apache/incubator-devlake/core/plugin"
 	_ "github.com/apache/incubator-devlake/core/version"
 	"github.com/apache/incubator-devlake/server/api"
+	"github.com/apache/incubator-devlake/server/services"
 )
 
 func main() {
@@ -30,5 +33,19 @@ func main() {
 	if encryptionSecret == "" {
 		panic("ENCRYPTION_SECRET must be set in environment variable or .env file")
 	}
+	// rotate-encryption-secret: re-encrypt the stored secrets with ENCRYPTION_SECRET and exit
+	if len(os.Args) > 1 && os.Args[1] == "rotate-encryption-secret" {
+		services.RunEncryptionSecretRotation()
+		return
+	}
+	// worker mode: execute the tasks dispatched by the server sharing the same database
+	if v.GetBool("WORKER_MODE") {
+		services.InitWorker()
+		// the worker was evicted by the server, exit right away to abandon the running tasks
+		if err := services.RunWorker(); err != nil {
+			panic(err)
+		}
+		return
+	}
 	api.CreateAndRunApiServer()
 }
@@ -100,16 +100,19 @@ func CreateApiKey(user *common.User, apiKeyInput *models.ApiInputApiKey) (*model
 		logger.Error(err, "verify: %+v", apiKeyInput)
 		return nil, err
 	}
+	if apiKeyInput.AllowedPath != nil {
+		return nil, errors.BadInput.New("allowedPath is no longer supported, restrict the api key by its scopes instead")
+	}
 
 	apiKeyHelper := apikeyhelper.NewApiKeyHelper(basicRes, logger)
 	tx := basicRes.GetDal().Begin()
-	apiKey, err := apiKeyHelper.Create(tx, user, apiKeyInput.Name, apiKeyInput.ExpiredAt, apiKeyInput.AllowedPath, apiKeyInput.Type, "")
+	apiKey, err := apiKeyHelper.Create(tx, user, apiKeyInput.Name, apiKeyInput.ExpiredAt, apiKeyInput.Scopes, apiKeyInput.RateLimitPerMinute, apiKeyInput.Type, "")
 	if err != nil {
 		if err := tx.Rollback(); err != nil {
 			logger.Error(err, "transaction Rollback")
 		}
 		logger.Error(err, "api key helper create")
-		return nil, errors.Default.Wrap(err, "random letters")
+		return nil, err
 	}
 	if err := tx.Commit(); err != nil {
 		logger.Info("transaction commit: %s", err)
@@ -0,0 +1,41 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateApiKeyRejectsAllowedPath(t *testing.T) {
+	vld = validator.New()
+	logger = unithelper.DummyLogger()
+	allowedPath := ".*"
+	_, err := CreateApiKey(nil, &models.ApiInputApiKey{Name: "key", Type: "devlake", Scopes: &models.ApiKeyScopes{All: true}, AllowedPath: &allowedPath})
+	assert.NotNil(t, err)
+	assert.Equal(t, errors.BadInput, err.GetType())
+
+	_, err = CreateApiKey(nil, &models.ApiInputApiKey{Name: "key", Type: "devlake"})
+	assert.NotNil(t, err)
+	assert.Equal(t, errors.BadInput, err.GetType())
+}
@@ -0,0 +1,238 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+)
+
+// redactedValue replaces secrets in the before/after JSON of audit logs
+const redactedValue = "******"
+
+// secretKeys matches the JSON keys holding credentials, i.e. token, password, secretKey, apiKey, privateKey
+var secretKeys = regexp.MustCompile(`(?i)(token|password|secret|api_?key|private_?key|credential|authorization|cookie)`)
+
+// AuditLogsQuery used to filter the audit logs
+type AuditLogsQuery struct {
+	Pagination
+	Actor        string     `form:"actor"`
+	ActorType    string     `form:"actorType"`
+	ApiKeyId     uint64     `form:"apiKeyId"`
+	Method       string     `form:"method"`
+	ResourceType string     `form:"resourceType"`
+	ResourceId   string     `form:"resourceId"`
+	Success      *bool      `form:"success"`
+	From         *time.Time `form:"from"`
+	To           *time.Time `form:"to"`
+}
+
+// GetAuditLogs returns a paginated list of audit logs, the most recent first
+func GetAuditLogs(query *AuditLogsQuery) ([]*models.AuditLog, int64, errors.Error) {
+	clauses := []dal.Clause{
+		dal.From(&models.AuditLog{}),
+	}
+	if query.Actor != "" {
+		clauses = append(clauses, dal.Where("actor = ? OR actor_email = ?", query.Actor, query.Actor))
+	}
+	if query.ActorType != "" {
+		clauses = append(clauses, dal.Where("actor_type = ?", query.ActorType))
+	}
+	if query.ApiKeyId != 0 {
+		clauses = append(clauses, dal.Where("api_key_id = ?", query.ApiKeyId))
+	}
+	if query.Method != "" {
+		clauses = append(clauses, dal.Where("method = ?", query.Method))
+	}
+	if query.ResourceType != "" {
+		clauses = append(clauses, dal.Where("resource_type = ?", query.ResourceType))
+	}
+	if query.ResourceId != "" {
+		clauses = append(clauses, dal.Where("resource_id = ?", query.ResourceId))
+	}
+	if query.Success != nil {
+		clauses = append(clauses, dal.Where("success = ?", *query.Success))
+	}
+	if query.From != nil {
+		clauses = append(clauses, dal.Where("created_at >= ?", *query.From))
+	}
+	if query.To != nil {
+		clauses = append(clauses, dal.Where("created_at < ?", *query.To))
+	}
+	count, err := db.Count(clauses...)
+	if err != nil {
+		return nil, 0, errors.Default.Wrap(err, "error getting DB count of audit logs")
+	}
+	clauses = append(clauses,
+		dal.Orderby("id DESC"),
+		dal.Offset(query.GetSkip()),
+		dal.Limit(query.GetPageSize()),
+	)
+	auditLogs := make([]*models.AuditLog, 0)
+	err = db.All(&auditLogs, clauses...)
+	if err != nil {
+		return nil, 0, errors.Default.Wrap(err, "error finding DB audit logs")
+	}
+	return auditLogs, count, nil
+}
+
+// SaveAuditLog redacts the secrets out of the before/after JSON, computes the diff and saves the log
+func SaveAuditLog(auditLog *models.AuditLog) errors.Error {
+	// diff first so that a changed secret still shows up, masked. Failed requests changed nothing.
+	if auditLog.Success {
+		auditLog.Diff = DiffJson(auditLog.Before, auditLog.After)
+	}
+	// changed objects and lists, i.e. the root of a creation, hold their secrets nested
+	for i := range auditLog.Diff {
+		change := &auditLog.Diff[i]
+		if secretKeys.MatchString(lastKey(change.Path)) {
+			change.Before = redactSecret(change.Before)
+			change.After = redactSecret(change.After)
+		}
+		change.Before = redact(change.Before)
+		change.After = redact(change.After)
+	}
+	auditLog.Before = RedactSecrets(auditLog.Before)
+	auditLog.After = RedactSecrets(auditLog.After)
+	if err := db.Create(auditLog); err != nil {
+		return errors.Default.Wrap(err, "error saving audit log")
+	}
+	return nil
+}
+
+// RedactSecrets masks the values of the keys looking like credentials, returns nil for invalid JSON
+func RedactSecrets(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var value interface{}
+	if json.Unmarshal(raw, &value) != nil {
+		return nil
+	}
+	redacted, err := json.Marshal(redact(value))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if _, ok := item.(string); ok && secretKeys.MatchString(key) {
+				v[key] = redactSecret(item)
+				continue
+			}
+			v[key] = redact(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+// redactSecret masks non-empty strings, telling whether a secret is set without revealing it
+func redactSecret(value interface{}) interface{} {
+	if s, ok := value.(string); ok && s != "" {
+		return redactedValue
+	}
+	return value
+}
+
+// lastKey returns the key of the innermost object of a diff path, i.e. `token` for `connection.tokens[0].token`
+func lastKey(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+	if i := strings.Index(path, "["); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// DiffJson lists the fields whose values differ between the two JSON documents
+func DiffJson(before, after json.RawMessage) []models.AuditLogChange {
+	var b, a interface{}
+	if len(before) > 0 {
+		_ = json.Unmarshal(before, &b)
+	}
+	if len(after) > 0 {
+		_ = json.Unmarshal(after, &a)
+	}
+	var changes []models.AuditLogChange
+	diffValue("", b, a, &changes)
+	return changes
+}
+
+func diffValue(path string, before, after interface{}, changes *[]models.AuditLogChange) {
+	bm, bIsMap := before.(map[string]interface{})
+	am, aIsMap := after.(map[string]interface{})
+	if bIsMap && aIsMap {
+		keys := make([]string, 0, len(bm)+len(am))
+		for k := range bm {
+			keys = append(keys, k)
+		}
+		for k := range am {
+			if _, ok := bm[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffValue(joinPath(path, k), bm[k], am[k], changes)
+		}
+		return
+	}
+	bl, bIsList := before.([]interface{})
+	al, aIsList := after.([]interface{})
+	if bIsList && aIsList {
+		for i := 0; i < len(bl) || i < len(al); i++ {
+			var bi, ai interface{}
+			if i < len(bl) {
+				bi = bl[i]
+			}
+			if i < len(al) {
+				ai = al[i]
+			}
+			diffValue(fmt.Sprintf("%s[%d]", path, i), bi, ai, changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, models.AuditLogChange{Path: path, Before: before, After: after})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
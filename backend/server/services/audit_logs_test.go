@@ -0,0 +1,74 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/models"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	redacted := RedactSecrets(json.RawMessage(`{"name":"gh","token":"ghp_x","password":"","extra":{"privateKey":"k","apiKey":1},"apps":[{"clientSecret":"s"}]}`))
+	assert.JSONEq(t, `{"name":"gh","token":"`+redactedValue+`","password":"","extra":{"privateKey":"`+redactedValue+`","apiKey":1},"apps":[{"clientSecret":"`+redactedValue+`"}]}`, string(redacted))
+	assert.Nil(t, RedactSecrets(json.RawMessage(`not json`)))
+	assert.Nil(t, RedactSecrets(nil))
+}
+
+func TestDiffJson(t *testing.T) {
+	changes := DiffJson(
+		json.RawMessage(`{"name":"a","rateLimit":0,"scopes":[{"id":1}],"removed":true}`),
+		json.RawMessage(`{"name":"b","rateLimit":0,"scopes":[{"id":1},{"id":2}],"added":"x"}`),
+	)
+	assert.Equal(t, []models.AuditLogChange{
+		{Path: "added", Before: nil, After: "x"},
+		{Path: "name", Before: "a", After: "b"},
+		{Path: "removed", Before: true, After: nil},
+		{Path: "scopes[1]", Before: nil, After: map[string]interface{}{"id": float64(2)}},
+	}, changes)
+	assert.Empty(t, DiffJson(json.RawMessage(`{"a":1}`), json.RawMessage(`{"a":1}`)))
+	assert.Equal(t, "token", lastKey("connection.tokens[0].token"))
+	assert.Equal(t, "tokens", lastKey("connection.tokens[0]"))
+}
+
+func TestSaveAuditLogRedactsNestedSecrets(t *testing.T) {
+	mockDal := new(mockdal.Dal)
+	mockDal.On("Create", mock.Anything, mock.Anything).Return(nil)
+	db = mockDal
+	// POST /plugins/webhook/connections: the GET of the route lists the connections while the
+	// response is the new connection along with its api key, the whole document changes
+	auditLog := &models.AuditLog{
+		Success: true,
+		Before:  json.RawMessage(`[{"id":1,"name":"a"}]`),
+		After:   json.RawMessage(`{"id":2,"name":"b","apiKey":{"name":"webhook-2","apiKey":"plaintext"}}`),
+	}
+	assert.Nil(t, SaveAuditLog(auditLog))
+	assert.Len(t, auditLog.Diff, 1)
+	assert.Equal(t, "", auditLog.Diff[0].Path)
+	assert.Equal(t, map[string]interface{}{
+		"id":     float64(2),
+		"name":   "b",
+		"apiKey": map[string]interface{}{"name": "webhook-2", "apiKey": redactedValue},
+	}, auditLog.Diff[0].After)
+	assert.NotContains(t, string(auditLog.After), "plaintext")
+	mockDal.AssertCalled(t, "Create", auditLog, mock.Anything)
+}
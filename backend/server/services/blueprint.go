@@ -168,6 +168,9 @@ func validateBlueprintAndMakePlan(blueprint *models.Blueprint) errors.Error {
 		if len(blueprint.Plan) == 0 {
 			return errors.BadInput.New("invalid plan")
 		}
+		if _, e := blueprint.Plan.ToDag(); e != nil {
+			return e
+		}
 	} else if blueprint.Mode == models.BLUEPRINT_MODE_NORMAL {
 		var e errors.Error
 		blueprint.Plan, e = MakePlanForBlueprint(blueprint, &blueprint.SyncPolicy)
@@ -378,7 +381,63 @@ func MakePlanForBlueprint(blueprint *models.Blueprint, syncPolicy *models.SyncPo
 	if err != nil {
 		return nil, err
 	}
-	return SequentializePipelinePlans(blueprint.BeforePlan, plan, blueprint.AfterPlan), nil
+	return SequentializePipelinePlans(blueprint.BeforePlan, plan, waitForPipelinePlan(blueprint.AfterPlan, plan)), nil
+}
+
+// waitForPipelinePlan makes the first stage of the next plan depend on all the sinks of the plan, since its
+// scopes don't end in the same stage. A copy of the next plan is returned, the tasks already having
+// dependencies or sinks without id leave it unchanged.
+func waitForPipelinePlan(next models.PipelinePlan, plan models.PipelinePlan) models.PipelinePlan {
+	sinks := PipelinePlanSinks(plan)
+	if len(sinks) == 0 {
+		return next
+	}
+	for i, stage := range next {
+		if len(stage) == 0 {
+			continue
+		}
+		copied := make(models.PipelinePlan, len(next))
+		copy(copied, next)
+		copied[i] = make(models.PipelineStage, len(stage))
+		for j, task := range stage {
+			copied[i][j] = task
+			if task != nil && len(task.DependsOn) == 0 {
+				waiting := *task
+				waiting.DependsOn = sinks
+				copied[i][j] = &waiting
+			}
+		}
+		return copied
+	}
+	return next
+}
+
+// PipelinePlanSinks returns the ids of the tasks of the plan no other task waits for, by dependsOn or by
+// waiting for their stage. Nil is returned when one of them has no id.
+func PipelinePlanSinks(plan models.PipelinePlan) []string {
+	dag, err := plan.ToDag()
+	if err != nil {
+		return nil
+	}
+	waited := make(map[models.PipelineTaskPosition]bool)
+	for _, deps := range dag {
+		for _, dep := range deps {
+			waited[dep] = true
+		}
+	}
+	var sinks []string
+	for i, stage := range plan {
+		for j, task := range stage {
+			if task == nil || waited[models.PipelineTaskPosition{Row: i + 1, Col: j + 1}] {
+				continue
+			}
+			if task.Id == "" {
+				return nil
+			}
+			sinks = append(sinks, task.Id)
+		}
+	}
+	return sinks
 }
 
 // ParallelizePipelinePlans merges multiple pipelines into one unified plan
@@ -410,6 +469,127 @@ func SequentializePipelinePlans(plans ...models.PipelinePlan) models.PipelinePla
 	return merged
 }
 
+// scopeOptions are the task options identifying the scope a task works on. The tasks of a plan
+// sharing one of their values, e.g. the github, gitextractor and refdiff tasks of a repo, form
+// the lineage of a scope.
+var scopeOptions = []string{"repoId", "fullName", "name", "projectId", "boardId", "scopeId"}
+
+// ChainPipelinePlan assigns ids to the tasks of the plan, makes each task depend on the previous tasks of its
+// scope lineage (or on upstream) and returns the ids of the leaf tasks for the downstream plans to depend on.
+func ChainPipelinePlan(plan models.PipelinePlan, prefix string, upstream []string) []string {
+	lineages := scopeLineages(plan)
+	var previous, unscoped []string
+	lineageIds := make(map[int][]string)
+	var tasks []*models.PipelineTask
+	for i, stage := range plan {
+		ids := make([]string, 0, len(stage))
+		stageUnscoped := make([]string, 0)
+		stageLineageIds := make(map[int][]string)
+		for j, task := range stage {
+			if task == nil {
+				continue
+			}
+			if task.Id == "" {
+				task.Id = fmt.Sprintf("%s-%d-%d", prefix, i+1, j+1)
+			}
+			lineage, scoped := lineages[task]
+			if len(task.DependsOn) == 0 {
+				var dependsOn []string
+				if scoped {
+					dependsOn = append(append(dependsOn, lineageIds[lineage]...), unscoped...)
+				} else {
+					dependsOn = append(dependsOn, previous...)
+				}
+				if len(dependsOn) == 0 {
+					dependsOn = upstream
+				}
+				task.DependsOn = append([]string(nil), dependsOn...)
+			}
+			if scoped {
+				stageLineageIds[lineage] = append(stageLineageIds[lineage], task.Id)
+			} else {
+				stageUnscoped = append(stageUnscoped, task.Id)
+			}
+			ids = append(ids, task.Id)
+			tasks = append(tasks, task)
+		}
+		if len(ids) > 0 {
+			previous = ids
+		}
+		if len(stageUnscoped) > 0 {
+			unscoped = stageUnscoped
+		}
+		for lineage, ids := range stageLineageIds {
+			lineageIds[lineage] = ids
+		}
+	}
+	dependedOn := make(map[string]bool)
+	for _, task := range tasks {
+		for _, id := range task.DependsOn {
+			dependedOn[id] = true
+		}
+	}
+	sinks := make([]string, 0)
+	for _, task := range tasks {
+		if !dependedOn[task.Id] {
+			sinks = append(sinks, task.Id)
+		}
+	}
+	if len(sinks) == 0 {
+		return upstream
+	}
+	return sinks
+}
+
+// scopeLineages groups the tasks of the plan sharing the value of a scope option, the tasks
+// without any scope option are left out
+func scopeLineages(plan models.PipelinePlan) map[*models.PipelineTask]int {
+	// union-find over the tasks, joined by the values of their scope options
+	parents := make(map[*models.PipelineTask]*models.PipelineTask)
+	var find func(task *models.PipelineTask) *models.PipelineTask
+	find = func(task *models.PipelineTask) *models.PipelineTask {
+		if parents[task] != task {
+			parents[task] = find(parents[task])
+		}
+		return parents[task]
+	}
+	owners := make(map[string]*models.PipelineTask)
+	var scoped []*models.PipelineTask
+	for _, stage := range plan {
+		for _, task := range stage {
+			if task == nil {
+				continue
+			}
+			for _, option := range scopeOptions {
+				value, ok := task.Options[option]
+				if !ok || value == nil || fmt.Sprint(value) == "" {
+					continue
+				}
+				if _, ok := parents[task]; !ok {
+					parents[task] = task
+					scoped = append(scoped, task)
+				}
+				key := option + "=" + fmt.Sprint(value)
+				if owner, ok := owners[key]; ok {
+					parents[find(task)] = find(owner)
+				} else {
+					owners[key] = task
+				}
+			}
+		}
+	}
+	lineages := make(map[*models.PipelineTask]int)
+	roots := make(map[*models.PipelineTask]int)
+	for _, task := range scoped {
+		root := find(task)
+		if _, ok := roots[root]; !ok {
+			roots[root] = len(roots)
+		}
+		lineages[task] = roots[root]
+	}
+	return lineages
+}
+
 // TriggerBlueprint triggers blueprint immediately
 func TriggerBlueprint(id uint64, triggerSyncPolicy *models.TriggerSyncPolicy, shouldSanitize bool) (*models.Pipeline, errors.Error) {
 	// load record from db
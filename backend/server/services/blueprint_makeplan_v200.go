@@ -132,6 +132,16 @@ func GeneratePlanJsonV200(
 			}
 		}
 	}
+	// connections and their scopes don't wait for each other but for the project mapping, while metric
+	// plugins need the data of all of them
+	mappingTaskIds := ChainPipelinePlan(planForProjectMapping, "mapping", nil)
+	var sourceTaskIds []string
+	for i, plan := range sourcePlans {
+		sourceTaskIds = append(sourceTaskIds, ChainPipelinePlan(plan, fmt.Sprintf("source-%d", i+1), mappingTaskIds)...)
+	}
+	for i, plan := range metricPlans {
+		ChainPipelinePlan(plan, fmt.Sprintf("metric-%d", i+1), sourceTaskIds)
+	}
 	plan := SequentializePipelinePlans(
 		planForProjectMapping,
 		ParallelizePipelinePlans(sourcePlans...),
@@ -43,11 +43,12 @@ func TestMakePlanV200(t *testing.T) {
 	githubOutputPlan := coreModels.PipelinePlan{
 		{
 			{Plugin: githubName, Options: map[string]interface{}{"name": "apache/incubator-devlake"}},
-			{Plugin: "gitextractor", Options: map[string]interface{}{"url": "http://gihub.com/apache/incubator-devlake.git"}},
+			{Plugin: "gitextractor", Options: map[string]interface{}{"url": "http://gihub.com/apache/incubator-devlake.git", "name": "apache/incubator-devlake", "repoId": "github:GithubRepo:1:123"}},
 		},
 		{
+			{Plugin: "refdiff", Options: map[string]interface{}{"repoId": "github:GithubRepo:1:123"}},
 			{Plugin: githubName, Options: map[string]interface{}{"name": "apache/incubator-devlake-website"}},
-			{Plugin: "gitextractor", Options: map[string]interface{}{"url": "http://gihub.com/apache/incubator-devlake-website.git"}},
+			{Plugin: "gitextractor", Options: map[string]interface{}{"url": "http://gihub.com/apache/incubator-devlake-website.git", "name": "apache/incubator-devlake-website", "repoId": "github:GithubRepo:1:321"}},
 		},
 	}
 	githubOutputScopes := []plugin.Scope{
@@ -100,4 +101,15 @@ func TestMakePlanV200(t *testing.T) {
 	assert.Nil(t, err)
 
 	assert.Equal(t, expectedPlan, plan)
+	// each scope waits for its own previous tasks only, the metric plugins wait for the last tasks of all scopes
+	assert.Empty(t, plan[0][0].DependsOn)
+	assert.Equal(t, []string{"mapping-1-1"}, plan[1][0].DependsOn)
+	assert.Equal(t, []string{"mapping-1-1"}, plan[1][1].DependsOn)
+	assert.Equal(t, []string{"source-1-1-1", "source-1-1-2"}, plan[2][0].DependsOn)
+	assert.Equal(t, []string{"mapping-1-1"}, plan[2][1].DependsOn)
+	assert.Equal(t, []string{"mapping-1-1"}, plan[2][2].DependsOn)
+	assert.Equal(t, []string{"source-1-2-1", "source-1-2-2", "source-1-2-3"}, plan[3][0].DependsOn)
+	assert.Equal(t, []string{"source-1-2-1", "source-1-2-2", "source-1-2-3"}, plan[3][1].DependsOn)
+	_, err = plan.ToDag()
+	assert.Nil(t, err)
 }
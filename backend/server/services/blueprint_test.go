@@ -93,6 +93,98 @@ func TestParallelizePipelineTasks(t *testing.T) {
 	)
 }
 
+func TestChainPipelinePlan(t *testing.T) {
+	slow := coreModels.PipelinePlan{
+		{{Plugin: "github"}, {Plugin: "gitextractor"}},
+		{},
+		{{Plugin: "refdiff"}},
+	}
+	fast := coreModels.PipelinePlan{
+		{{Plugin: "jira"}},
+	}
+	metric := coreModels.PipelinePlan{
+		{{Plugin: "dora"}},
+		{{Plugin: "dora", Id: "custom"}},
+	}
+	sinks := append(ChainPipelinePlan(slow, "source-1", nil), ChainPipelinePlan(fast, "source-2", nil)...)
+	assert.Equal(t, []string{"source-1-3-1", "source-2-1-1"}, sinks)
+	assert.Equal(t, []string{"custom"}, ChainPipelinePlan(metric, "metric-1", sinks))
+
+	// the empty stage is skipped and the first stage of a plan waits for the upstream only
+	assert.Equal(t, []string{"source-1-1-1", "source-1-1-2"}, slow[2][0].DependsOn)
+	assert.Empty(t, fast[0][0].DependsOn)
+	assert.Equal(t, sinks, metric[0][0].DependsOn)
+	assert.Equal(t, []string{"metric-1-1-1"}, metric[1][0].DependsOn)
+
+	// the jira task doesn't wait for the refdiff task of the other connection once merged
+	dag, err := SequentializePipelinePlans(ParallelizePipelinePlans(slow, fast), metric).ToDag()
+	assert.Nil(t, err)
+	assert.Empty(t, dag[coreModels.PipelineTaskPosition{Row: 1, Col: 3}])
+	assert.Equal(t, []coreModels.PipelineTaskPosition{{Row: 3, Col: 1}, {Row: 1, Col: 3}}, dag[coreModels.PipelineTaskPosition{Row: 4, Col: 1}])
+}
+
+func TestChainPipelinePlanScopes(t *testing.T) {
+	// scope a is slow to collect, the refdiff of a lands in the stage of scope b
+	plan := coreModels.PipelinePlan{
+		{
+			{Plugin: "github", Options: map[string]interface{}{"name": "a"}},
+			{Plugin: "gitextractor", Options: map[string]interface{}{"name": "a", "repoId": "repo-a"}},
+		},
+		{
+			{Plugin: "refdiff", Options: map[string]interface{}{"repoId": "repo-a"}},
+			{Plugin: "github", Options: map[string]interface{}{"name": "b"}},
+			{Plugin: "gitextractor", Options: map[string]interface{}{"name": "b", "repoId": "repo-b"}},
+		},
+		{
+			{Plugin: "refdiff", Options: map[string]interface{}{"repoId": "repo-b"}},
+		},
+	}
+	metric := coreModels.PipelinePlan{
+		{{Plugin: "dora"}},
+	}
+	mapping := coreModels.PipelinePlan{
+		{{Plugin: "org"}},
+	}
+	mappingIds := ChainPipelinePlan(mapping, "mapping", nil)
+	sinks := ChainPipelinePlan(plan, "source-1", mappingIds)
+	assert.Equal(t, []string{"source-1-2-1", "source-1-3-1"}, sinks)
+	assert.Equal(t, []string{"metric-1-1-1"}, ChainPipelinePlan(metric, "metric-1", sinks))
+
+	// the tasks of b don't wait for a, the refdiff tasks wait for their own repo
+	assert.Equal(t, []string{"mapping-1-1"}, plan[0][0].DependsOn)
+	assert.Equal(t, []string{"source-1-1-1", "source-1-1-2"}, plan[1][0].DependsOn)
+	assert.Equal(t, []string{"mapping-1-1"}, plan[1][1].DependsOn)
+	assert.Equal(t, []string{"mapping-1-1"}, plan[1][2].DependsOn)
+	assert.Equal(t, []string{"source-1-2-2", "source-1-2-3"}, plan[2][0].DependsOn)
+	// dora waits for the last task of each scope
+	assert.Equal(t, sinks, metric[0][0].DependsOn)
+
+	merged := SequentializePipelinePlans(mapping, plan, metric)
+	dag, err := merged.ToDag()
+	assert.Nil(t, err)
+	// the github task of b only waits for the project mapping while the one of a is still running
+	assert.Equal(t, []coreModels.PipelineTaskPosition{{Row: 1, Col: 1}}, dag[coreModels.PipelineTaskPosition{Row: 3, Col: 2}])
+
+	// the plan after waits for the last task of each scope too, not for the last stage only
+	after := coreModels.PipelinePlan{{{Plugin: "custom"}}}
+	waiting := waitForPipelinePlan(after, merged)
+	assert.Equal(t, []string{"metric-1-1-1"}, waiting[0][0].DependsOn)
+	assert.Empty(t, after[0][0].DependsOn)
+	assert.Equal(t, sinks, PipelinePlanSinks(SequentializePipelinePlans(mapping, plan)))
+}
+
+// unscoped tasks keep waiting for the whole previous stage and are waited for by the scoped ones
+func TestChainPipelinePlanUnscoped(t *testing.T) {
+	plan := coreModels.PipelinePlan{
+		{{Plugin: "github", Options: map[string]interface{}{"name": "a"}}, {Plugin: "github", Options: map[string]interface{}{"name": "b"}}},
+		{{Plugin: "custom"}},
+		{{Plugin: "refdiff", Options: map[string]interface{}{"name": "a"}}},
+	}
+	assert.Equal(t, []string{"source-3-1"}, ChainPipelinePlan(plan, "source", []string{"mapping"}))
+	assert.Equal(t, []string{"source-1-1", "source-1-2"}, plan[1][0].DependsOn)
+	assert.Equal(t, []string{"source-1-1", "source-2-1"}, plan[2][0].DependsOn)
+}
+
 func TestRemoveCollectorTasks(t *testing.T) {
 	plan1 := coreModels.PipelinePlan{
 		{
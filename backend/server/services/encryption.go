@@ -0,0 +1,213 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/core/runner"
+	"github.com/apache/incubator-devlake/impls/dalgorm"
+)
+
+// EncryptionRotationResult tells how many values of each encdec column were re-encrypted
+type EncryptionRotationResult struct {
+	KeyId  string                     `json:"keyId"`
+	Tables []*EncryptionRotationTable `json:"tables"`
+}
+
+type EncryptionRotationTable struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Values  int      `json:"values"`
+}
+
+// encryptedTable is a table with encdec serialized columns
+type encryptedTable struct {
+	name        string
+	primaryKeys []string
+	columns     []string
+}
+
+// RotateEncryptionSecret re-encrypts every value of the encdec serialized columns with the current
+// ENCRYPTION_SECRET, the values encrypted with a secret of ENCRYPTION_SECRET_PREVIOUS or without key id included.
+// All of them are updated inside a single transaction, nothing is changed if any of them fails to be decrypted.
+func RotateEncryptionSecret() (*EncryptionRotationResult, errors.Error) {
+	keyring := plugin.NewEncryptionKeyring(
+		cfg.GetString(plugin.EncodeKeyEnvStr),
+		plugin.ParseEncryptionSecrets(cfg.GetString(plugin.EncodeKeyPreviousEnvStr))...,
+	)
+	if keyring.CurrentSecret() == "" {
+		return nil, errors.BadInput.New("ENCRYPTION_SECRET must be set")
+	}
+	tables, err := getEncryptedTables()
+	if err != nil {
+		return nil, err
+	}
+	result := &EncryptionRotationResult{KeyId: keyring.CurrentKeyId()}
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				logger.Error(rollbackErr, "failed to rollback the encryption secret rotation")
+			}
+		}
+	}()
+	for _, table := range tables {
+		var values int
+		values, err = reencryptTable(tx, keyring, table)
+		if err != nil {
+			return nil, err
+		}
+		result.Tables = append(result.Tables, &EncryptionRotationTable{
+			Table:   table.name,
+			Columns: table.columns,
+			Values:  values,
+		})
+		logger.Info("re-encrypted %d values of %s with key %s", values, table.name, keyring.CurrentKeyId())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getEncryptedTables returns the existing tables of the framework and the plugins having encdec columns
+func getEncryptedTables() ([]*encryptedTable, errors.Error) {
+	tablers := []dal.Tabler{
+		&models.Blueprint{},
+		&models.Pipeline{},
+		&models.Task{},
+		&models.NotificationChannel{},
+	}
+	for _, pluginMeta := range plugin.AllPlugins() {
+		if pluginModel, ok := pluginMeta.(plugin.PluginModel); ok {
+			tablers = append(tablers, pluginModel.GetTablesInfo()...)
+		}
+	}
+	var tables []*encryptedTable
+	seen := make(map[string]bool)
+	for _, tabler := range tablers {
+		name := tabler.TableName()
+		if seen[name] || !db.HasTable(name) {
+			continue
+		}
+		primaryKeys, columns, err := dalgorm.GetEncDecColumns(tabler)
+		if err != nil {
+			return nil, errors.Default.Wrap(err, "failed to parse the model of "+name)
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		if len(primaryKeys) == 0 {
+			return nil, errors.Default.New(fmt.Sprintf("table %s has encrypted columns but no primary key", name))
+		}
+		seen[name] = true
+		tables = append(tables, &encryptedTable{name: name, primaryKeys: primaryKeys, columns: columns})
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].name < tables[j].name
+	})
+	return tables, nil
+}
+
+// reencryptTable updates the values of the table which are not encrypted with the current secret yet
+func reencryptTable(tx dal.Transaction, keyring *plugin.EncryptionKeyring, table *encryptedTable) (int, errors.Error) {
+	cursor, err := tx.Cursor(
+		dal.Select(strings.Join(append(append([]string{}, table.primaryKeys...), table.columns...), ", ")),
+		dal.From(table.name),
+	)
+	if err != nil {
+		return 0, err
+	}
+	type update struct {
+		keys []interface{}
+		set  []dal.DalSet
+	}
+	var updates []update
+	for cursor.Next() {
+		keys := make([]interface{}, len(table.primaryKeys))
+		values := make([]sql.NullString, len(table.columns))
+		dest := make([]interface{}, 0, len(keys)+len(values))
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if scanErr := cursor.Scan(dest...); scanErr != nil {
+			cursor.Close()
+			return 0, errors.Convert(scanErr)
+		}
+		var set []dal.DalSet
+		for i, value := range values {
+			if !value.Valid || keyring.IsCurrent(value.String) {
+				continue
+			}
+			decrypted, err := keyring.Decrypt(value.String)
+			if err != nil {
+				cursor.Close()
+				return 0, errors.Default.Wrap(err, fmt.Sprintf("failed to decrypt %s.%s of %v", table.name, table.columns[i], keys))
+			}
+			encrypted, err := keyring.Encrypt(decrypted)
+			if err != nil {
+				cursor.Close()
+				return 0, err
+			}
+			set = append(set, dal.DalSet{ColumnName: table.columns[i], Value: encrypted})
+		}
+		if len(set) > 0 {
+			updates = append(updates, update{keys: keys, set: set})
+		}
+	}
+	cursor.Close()
+
+	where := strings.Join(table.primaryKeys, " = ? AND ") + " = ?"
+	values := 0
+	for _, u := range updates {
+		err = tx.UpdateColumns(table.name, u.set, dal.Where(where, u.keys...))
+		if err != nil {
+			return 0, err
+		}
+		values += len(u.set)
+	}
+	return values, nil
+}
+
+// RunEncryptionSecretRotation is the `lake rotate-encryption-secret` command, it loads the plugins to find their
+// encrypted columns and rotates the secret without starting the api server
+func RunEncryptionSecretRotation() {
+	InitResources()
+	errors.Must(runner.LoadPlugins(basicRes))
+	result, err := RotateEncryptionSecret()
+	if err != nil {
+		panic(err)
+	}
+	for _, table := range result.Tables {
+		fmt.Printf("%s: %d values of %s re-encrypted\n", table.Table, table.Values, strings.Join(table.Columns, ", "))
+	}
+	fmt.Printf("all encrypted values are now under key %s\n", result.KeyId)
+}
@@ -0,0 +1,147 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	"github.com/apache/incubator-devlake/impls/dalgorm"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockEncryptedRows serves the rows of _devlake_blueprints as (id, plan, before_plan, after_plan), nil is NULL
+func mockEncryptedRows(t *testing.T, rows [][]interface{}) *mockdal.Rows {
+	cursor := mockdal.NewRows(t)
+	next := 0
+	cursor.On("Next").Return(func() bool {
+		next++
+		return next <= len(rows)
+	})
+	cursor.On("Scan", mock.Anything).Return(func(dest ...interface{}) error {
+		row := rows[next-1]
+		*dest[0].(*interface{}) = row[0]
+		for i, value := range row[1:] {
+			if value != nil {
+				*dest[i+1].(*sql.NullString) = sql.NullString{String: value.(string), Valid: true}
+			}
+		}
+		return nil
+	})
+	cursor.On("Close").Return(nil)
+	return cursor
+}
+
+// mockEncryptionRotation rotates the secret from old-secret to new-secret, _devlake_blueprints is the only existing
+// table having encrypted columns
+func mockEncryptionRotation(t *testing.T, rows [][]interface{}) *mockdal.Transaction {
+	v := viper.New()
+	v.Set(plugin.EncodeKeyEnvStr, "new-secret")
+	v.Set(plugin.EncodeKeyPreviousEnvStr, "old-secret")
+	cfg = v
+	logger = unithelper.DummyLogger()
+	// the encdec columns are found by parsing the models, which requires the serializer
+	dalgorm.Init(plugin.NewEncryptionKeyring("new-secret"))
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("HasTable", mock.Anything).Return(func(table interface{}) bool {
+		return table == models.Blueprint{}.TableName()
+	})
+	tx := mockdal.NewTransaction(t)
+	tx.On("Cursor", mock.Anything).Return(func(clauses ...dal.Clause) dal.Rows {
+		assert.Equal(t, "id, plan, before_plan, after_plan", clauses[0].Data.(dal.DalClause).Expr)
+		return mockEncryptedRows(t, rows)
+	}, nil)
+	mockDb.On("Begin").Return(tx)
+	db = mockDb
+	return tx
+}
+
+func TestRotateEncryptionSecret(t *testing.T) {
+	oldKeyring := plugin.NewEncryptionKeyring("old-secret")
+	keyring := plugin.NewEncryptionKeyring("new-secret")
+	legacy, err := plugin.Encrypt("old-secret", "[[]]")
+	assert.Nil(t, err)
+	withOldKey, err := oldKeyring.Encrypt("[]")
+	assert.Nil(t, err)
+	withNewKey, err := keyring.Encrypt("[[{}]]")
+	assert.Nil(t, err)
+
+	tx := mockEncryptionRotation(t, [][]interface{}{
+		{int64(1), legacy, withOldKey, nil},
+		{int64(2), withNewKey, withNewKey, withNewKey},
+	})
+	updated := make(map[interface{}][]dal.DalSet)
+	tx.On("UpdateColumns", models.Blueprint{}.TableName(), mock.Anything, mock.Anything).Return(
+		func(_ interface{}, set []dal.DalSet, clauses ...dal.Clause) errors.Error {
+			where := clauses[0].Data.(dal.DalClause)
+			assert.Equal(t, "id = ?", where.Expr)
+			updated[where.Params[0]] = set
+			return nil
+		},
+	)
+	tx.On("Commit").Return(nil)
+
+	result, err := RotateEncryptionSecret()
+	assert.Nil(t, err)
+	assert.Equal(t, keyring.CurrentKeyId(), result.KeyId)
+	assert.Equal(t, []*EncryptionRotationTable{
+		{Table: models.Blueprint{}.TableName(), Columns: []string{"plan", "before_plan", "after_plan"}, Values: 2},
+	}, result.Tables)
+
+	// the values under the current key and the NULL ones are left alone
+	assert.Len(t, updated, 1)
+	set := updated[int64(1)]
+	if assert.Len(t, set, 2) {
+		assert.Equal(t, "plan", set[0].ColumnName)
+		assert.Equal(t, "before_plan", set[1].ColumnName)
+		for i, expected := range []string{"[[]]", "[]"} {
+			encrypted := set[i].Value.(string)
+			assert.True(t, keyring.IsCurrent(encrypted))
+			decrypted, err := keyring.Decrypt(encrypted)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, decrypted)
+		}
+	}
+}
+
+func TestRotateEncryptionSecretUnknownKey(t *testing.T) {
+	withUnknownKey, err := plugin.NewEncryptionKeyring("lost-secret").Encrypt("[]")
+	assert.Nil(t, err)
+	withOldKey, err := plugin.NewEncryptionKeyring("old-secret").Encrypt("[]")
+	assert.Nil(t, err)
+
+	// nothing is updated once a value can't be decrypted, even the ones read before it
+	tx := mockEncryptionRotation(t, [][]interface{}{
+		{int64(1), withOldKey, nil, nil},
+		{int64(2), withUnknownKey, nil, nil},
+	})
+	tx.On("Rollback").Return(nil)
+
+	_, err = RotateEncryptionSecret()
+	assert.NotNil(t, err)
+	tx.AssertNotCalled(t, "UpdateColumns", mock.Anything, mock.Anything, mock.Anything)
+	tx.AssertNotCalled(t, "Commit")
+}
@@ -0,0 +1,276 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/utils"
+	helper "github.com/apache/incubator-devlake/helpers/pluginhelper/api"
+)
+
+// NotificationChannelQuery used to query notification channels
+type NotificationChannelQuery struct {
+	Pagination
+}
+
+// NotificationQuery used to query the notification delivery log
+type NotificationQuery struct {
+	Pagination
+	ChannelId  uint64 `form:"channelId"`
+	PipelineId uint64 `form:"pipelineId"`
+	Status     string `form:"status"`
+}
+
+// GetNotificationChannels returns a paginated list of notification channels
+func GetNotificationChannels(query *NotificationChannelQuery) ([]*models.NotificationChannel, int64, errors.Error) {
+	clauses := []dal.Clause{dal.From(&models.NotificationChannel{})}
+	count, err := db.Count(clauses...)
+	if err != nil {
+		return nil, 0, errors.Default.Wrap(err, "error getting DB count of notification channels")
+	}
+	clauses = append(clauses,
+		dal.Orderby("id DESC"),
+		dal.Offset(query.GetSkip()),
+		dal.Limit(query.GetPageSize()),
+	)
+	channels := make([]*models.NotificationChannel, 0)
+	err = db.All(&channels, clauses...)
+	if err != nil {
+		return nil, 0, errors.Default.Wrap(err, "error finding DB notification channels")
+	}
+	for _, channel := range channels {
+		sanitizeNotificationChannel(channel)
+	}
+	return channels, count, nil
+}
+
+// GetNotificationChannel returns the sanitized notification channel
+func GetNotificationChannel(id uint64) (*models.NotificationChannel, errors.Error) {
+	channel, err := getNotificationChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	sanitizeNotificationChannel(channel)
+	return channel, nil
+}
+
+// CreateNotificationChannel accepts a notification channel instance and insert it to database
+func CreateNotificationChannel(channel *models.NotificationChannel) errors.Error {
+	channel.ID = 0
+	if err := verifyNotificationChannel(channel); err != nil {
+		return err
+	}
+	if err := db.Create(channel); err != nil {
+		if db.IsDuplicationError(err) {
+			return errors.BadInput.New(fmt.Sprintf("notification channel [%s] already exists", channel.Name))
+		}
+		return errors.Default.Wrap(err, "error creating DB notification channel")
+	}
+	sanitizeNotificationChannel(channel)
+	return nil
+}
+
+// PatchNotificationChannel updates a notification channel, the secret and endpoint are kept if their sanitized values are sent back
+func PatchNotificationChannel(id uint64, body map[string]interface{}) (*models.NotificationChannel, errors.Error) {
+	channel, err := getNotificationChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	if secret, ok := body["secret"].(string); ok && secret != "" && secret == utils.SanitizeString(channel.Secret) {
+		delete(body, "secret")
+	}
+	if endpoint, ok := body["endpoint"].(string); ok && endpoint != "" && endpoint == sanitizeNotificationEndpoint(channel) {
+		delete(body, "endpoint")
+	}
+	err = helper.DecodeMapStruct(body, channel, true)
+	if err != nil {
+		return nil, err
+	}
+	channel.ID = id
+	if err := verifyNotificationChannel(channel); err != nil {
+		return nil, err
+	}
+	if err := db.Update(channel); err != nil {
+		if db.IsDuplicationError(err) {
+			return nil, errors.BadInput.New(fmt.Sprintf("notification channel [%s] already exists", channel.Name))
+		}
+		return nil, errors.Default.Wrap(err, "error updating DB notification channel")
+	}
+	sanitizeNotificationChannel(channel)
+	return channel, nil
+}
+
+// DeleteNotificationChannel removes a notification channel along with its subscriptions
+func DeleteNotificationChannel(id uint64) errors.Error {
+	if _, err := getNotificationChannel(id); err != nil {
+		return err
+	}
+	tx := db.Begin()
+	err := tx.Delete(&models.NotificationSubscription{}, dal.Where("channel_id = ?", id))
+	if err == nil {
+		err = tx.Delete(&models.NotificationChannel{}, dal.Where("id = ?", id))
+	}
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			logger.Error(err, "transaction Rollback")
+		}
+		return errors.Default.Wrap(err, "error deleting DB notification channel")
+	}
+	return tx.Commit()
+}
+
+// TestNotificationChannel delivers a sample notification synchronously, in a single attempt, and returns the delivery log
+func TestNotificationChannel(ctx context.Context, id uint64) (*models.Notification, errors.Error) {
+	channel, err := getNotificationChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, testNotificationTimeout)
+	defer cancel()
+	return deliverNotification(ctx, channel, PipelineNotificationParam{
+		ProjectName: "test",
+		Status:      models.TASK_COMPLETED,
+	}, 0), nil
+}
+
+// GetNotificationSubscriptions returns all subscriptions of a notification channel
+func GetNotificationSubscriptions(channelId uint64) ([]*models.NotificationSubscription, errors.Error) {
+	subscriptions := make([]*models.NotificationSubscription, 0)
+	err := db.All(&subscriptions, dal.Where("channel_id = ?", channelId), dal.Orderby("id"))
+	if err != nil {
+		return nil, errors.Default.Wrap(err, "error finding DB notification subscriptions")
+	}
+	return subscriptions, nil
+}
+
+// CreateNotificationSubscription subscribes a notification channel to a project or a blueprint
+func CreateNotificationSubscription(channelId uint64, subscription *models.NotificationSubscription) errors.Error {
+	if _, err := getNotificationChannel(channelId); err != nil {
+		return err
+	}
+	if subscription.ProjectName == "" && subscription.BlueprintId == 0 {
+		return errors.BadInput.New("either projectName or blueprintId is required")
+	}
+	for i, status := range subscription.Statuses {
+		status = strings.ToUpper(status)
+		if !strings.HasPrefix(status, "TASK_") {
+			status = "TASK_" + status
+		}
+		if !utils.StringsContains(models.FinishedTaskStatus, status) && !utils.StringsContains(models.PendingTaskStatus, status) {
+			return errors.BadInput.New(fmt.Sprintf("unknown pipeline status: %s", subscription.Statuses[i]))
+		}
+		subscription.Statuses[i] = status
+	}
+	subscription.ID = 0
+	subscription.ChannelId = channelId
+	if err := db.Create(subscription); err != nil {
+		return errors.Default.Wrap(err, "error creating DB notification subscription")
+	}
+	return nil
+}
+
+// DeleteNotificationSubscription removes a subscription from a notification channel
+func DeleteNotificationSubscription(channelId, subscriptionId uint64) errors.Error {
+	err := db.Delete(&models.NotificationSubscription{}, dal.Where("id = ? AND channel_id = ?", subscriptionId, channelId))
+	if err != nil {
+		return errors.Default.Wrap(err, "error deleting DB notification subscription")
+	}
+	return nil
+}
+
+// GetNotifications returns a paginated list of the notification delivery log
+func GetNotifications(query *NotificationQuery) ([]*models.Notification, int64, errors.Error) {
+	clauses := []dal.Clause{dal.From(&models.Notification{})}
+	if query.ChannelId != 0 {
+		clauses = append(clauses, dal.Where("channel_id = ?", query.ChannelId))
+	}
+	if query.PipelineId != 0 {
+		clauses = append(clauses, dal.Where("pipeline_id = ?", query.PipelineId))
+	}
+	if query.Status != "" {
+		clauses = append(clauses, dal.Where("status = ?", query.Status))
+	}
+	count, err := db.Count(clauses...)
+	if err != nil {
+		return nil, 0, errors.Default.Wrap(err, "error getting DB count of notifications")
+	}
+	clauses = append(clauses,
+		dal.Orderby("id DESC"),
+		dal.Offset(query.GetSkip()),
+		dal.Limit(query.GetPageSize()),
+	)
+	notifications := make([]*models.Notification, 0)
+	err = db.All(&notifications, clauses...)
+	if err != nil {
+		return nil, 0, errors.Default.Wrap(err, "error finding DB notifications")
+	}
+	return notifications, count, nil
+}
+
+func getNotificationChannel(id uint64) (*models.NotificationChannel, errors.Error) {
+	channel := &models.NotificationChannel{}
+	err := db.First(channel, dal.Where("id = ?", id))
+	if err != nil {
+		if db.IsErrorNotFound(err) {
+			return nil, errors.NotFound.New(fmt.Sprintf("notification channel(id: %d) not found", id))
+		}
+		return nil, errors.Default.Wrap(err, "error getting DB notification channel")
+	}
+	return channel, nil
+}
+
+func verifyNotificationChannel(channel *models.NotificationChannel) errors.Error {
+	if err := VerifyStruct(channel); err != nil {
+		return err
+	}
+	if _, ok := getNotificationChannelSender(channel.Type); !ok {
+		return errors.BadInput.New(fmt.Sprintf("unsupported notification channel type: %s", channel.Type))
+	}
+	if channel.Template != "" {
+		if _, err := renderNotification(channel, PipelineNotificationParam{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sanitizeNotificationChannel(channel *models.NotificationChannel) {
+	channel.Secret = utils.SanitizeString(channel.Secret)
+	channel.Endpoint = sanitizeNotificationEndpoint(channel)
+}
+
+// sanitizeNotificationEndpoint masks what follows the host of the slack and teams incoming webhooks,
+// the other endpoints hold no credentials
+func sanitizeNotificationEndpoint(channel *models.NotificationChannel) string {
+	if channel.Type != models.NOTIFICATION_CHANNEL_SLACK && channel.Type != models.NOTIFICATION_CHANNEL_TEAMS {
+		return channel.Endpoint
+	}
+	u, err := url.Parse(channel.Endpoint)
+	if err != nil || u.Host == "" {
+		return utils.SanitizeString(channel.Endpoint)
+	}
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+	return origin + utils.SanitizeString(strings.TrimPrefix(channel.Endpoint, origin))
+}
@@ -0,0 +1,377 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/utils"
+)
+
+const defaultNotificationMaxRetries = 3
+const notificationRetryBaseDelay = 2 * time.Second
+const defaultNotificationTemplate = `DevLake pipeline #{{.PipelineID}}{{if .ProjectName}} of project {{.ProjectName}}{{end}} is {{.Status}}`
+
+// testNotificationTimeout bounds the delivery of a test notification, which is sent while the api request waits
+const testNotificationTimeout = 10 * time.Second
+
+var notificationHttpClient = &http.Client{Timeout: 30 * time.Second}
+
+// NotificationMessage is a rendered notification ready to be delivered
+type NotificationMessage struct {
+	Subject string
+	Body    []byte
+	// Nonce is `<notification id>-<random letters>`, the same as the one sent by the DefaultPipelineNotificationService
+	Nonce string
+}
+
+// NotificationChannelSender delivers a rendered message to a kind of channel and returns the response of the remote end
+type NotificationChannelSender func(ctx context.Context, channel *models.NotificationChannel, message *NotificationMessage) (int, string, errors.Error)
+
+var notificationChannelSenders = map[string]NotificationChannelSender{
+	models.NOTIFICATION_CHANNEL_SLACK: sendSlackNotification,
+	models.NOTIFICATION_CHANNEL_TEAMS: sendTeamsNotification,
+	models.NOTIFICATION_CHANNEL_HTTP:  sendHttpNotification,
+	models.NOTIFICATION_CHANNEL_SMTP:  sendSmtpNotification,
+}
+var notificationChannelSendersLock sync.RWMutex
+
+// RegisterNotificationChannelSender makes a new type of notification channel available
+func RegisterNotificationChannelSender(channelType string, sender NotificationChannelSender) {
+	notificationChannelSendersLock.Lock()
+	defer notificationChannelSendersLock.Unlock()
+	notificationChannelSenders[channelType] = sender
+}
+
+func getNotificationChannelSender(channelType string) (NotificationChannelSender, bool) {
+	notificationChannelSendersLock.RLock()
+	defer notificationChannelSendersLock.RUnlock()
+	sender, ok := notificationChannelSenders[channelType]
+	return sender, ok
+}
+
+// ChannelPipelineNotificationService notifies the channels subscribed to the project or the blueprint of a pipeline
+type ChannelPipelineNotificationService struct{}
+
+// NewChannelPipelineNotificationService creates a new ChannelPipelineNotificationService
+func NewChannelPipelineNotificationService() *ChannelPipelineNotificationService {
+	return &ChannelPipelineNotificationService{}
+}
+
+// PipelineStatusChanged delivers the notification to every subscribed channel in the background
+func (n *ChannelPipelineNotificationService) PipelineStatusChanged(params PipelineNotificationParam) errors.Error {
+	channels, err := findSubscribedNotificationChannels(params)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		maxRetries := channel.MaxRetries
+		if maxRetries == 0 {
+			maxRetries = defaultNotificationMaxRetries
+		}
+		go deliverNotification(context.Background(), channel, params, maxRetries)
+	}
+	return nil
+}
+
+func findSubscribedNotificationChannels(params PipelineNotificationParam) ([]*models.NotificationChannel, errors.Error) {
+	if params.ProjectName == "" && params.BlueprintId == 0 {
+		return nil, nil
+	}
+	var subscriptions []*models.NotificationSubscription
+	err := db.All(
+		&subscriptions,
+		dal.Where(
+			"(project_name = ? AND project_name != '') OR (blueprint_id = ? AND blueprint_id != 0)",
+			params.ProjectName, params.BlueprintId,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var channelIds []uint64
+	for _, subscription := range subscriptions {
+		if len(subscription.Statuses) == 0 || utils.StringsContains(subscription.Statuses, params.Status) {
+			channelIds = append(channelIds, subscription.ChannelId)
+		}
+	}
+	if len(channelIds) == 0 {
+		return nil, nil
+	}
+	var channels []*models.NotificationChannel
+	err = db.All(&channels, dal.Where("id IN ?", channelIds))
+	if err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// deliverNotification sends the notification with retries and records the outcome in the delivery log
+func deliverNotification(ctx context.Context, channel *models.NotificationChannel, params PipelineNotificationParam, maxRetries int) *models.Notification {
+	record := &models.Notification{
+		Type:       models.NotificationPipelineStatusChanged,
+		Endpoint:   notificationEndpoint(channel),
+		ChannelId:  channel.ID,
+		PipelineId: params.PipelineID,
+		Status:     models.NOTIFICATION_PENDING,
+	}
+	message := &NotificationMessage{
+		Subject: fmt.Sprintf("[DevLake] pipeline #%d %s", params.PipelineID, params.Status),
+	}
+	var err errors.Error
+	message.Body, err = renderNotification(channel, params)
+	if err == nil {
+		record.Data = string(message.Body)
+		record.Nonce, err = utils.RandLetterBytes(16)
+	}
+	if err == nil {
+		// the record is saved upfront for its id to be part of the nonce
+		if err := db.Create(record); err != nil {
+			globalPipelineLog.Error(err, "failed to save notification delivery log of channel %s", channel.Name)
+		}
+		message.Nonce = fmt.Sprintf("%d-%s", record.ID, record.Nonce)
+		sender, ok := getNotificationChannelSender(channel.Type)
+		if !ok {
+			err = errors.BadInput.New(fmt.Sprintf("unsupported notification channel type: %s", channel.Type))
+		}
+		for attempt := 0; ok && attempt <= maxRetries; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(notificationRetryBaseDelay << (attempt - 1)):
+				}
+			}
+			if ctx.Err() != nil {
+				err = errors.Convert(ctx.Err())
+				break
+			}
+			record.Attempts = attempt + 1
+			record.ResponseCode, record.Response, err = sender(ctx, channel, message)
+			if err == nil {
+				break
+			}
+			globalPipelineLog.Warn(err, "failed to deliver notification to channel %s, attempt %d", channel.Name, record.Attempts)
+		}
+	}
+	if err != nil {
+		record.Status = models.NOTIFICATION_FAILED
+		record.Error = err.Error()
+	} else {
+		record.Status = models.NOTIFICATION_DELIVERED
+	}
+	if err := db.CreateOrUpdate(record); err != nil {
+		globalPipelineLog.Error(err, "failed to save notification delivery log of channel %s", channel.Name)
+	}
+	return record
+}
+
+// notificationEndpoint describes where a channel delivers to without leaking its credentials,
+// the path of the slack and teams incoming webhooks is a secret on its own
+func notificationEndpoint(channel *models.NotificationChannel) string {
+	if channel.Type == models.NOTIFICATION_CHANNEL_SMTP {
+		return channel.Endpoint
+	}
+	u, err := url.Parse(channel.Endpoint)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	endpoint := &url.URL{Scheme: u.Scheme, Host: u.Host}
+	if channel.Type == models.NOTIFICATION_CHANNEL_HTTP {
+		endpoint.Path = u.Path
+	}
+	return endpoint.String()
+}
+
+// signNotification computes the signature of a notification the way DefaultPipelineNotificationService does,
+// so that the receivers of both verify them alike
+func signNotification(data, secret, nonce string) string {
+	sum := sha256.Sum256([]byte(data + secret + nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// renderNotification executes the channel template, the http channels post the params as json when there is no template
+func renderNotification(channel *models.NotificationChannel, params PipelineNotificationParam) ([]byte, errors.Error) {
+	text := channel.Template
+	if text == "" {
+		if channel.Type == models.NOTIFICATION_CHANNEL_HTTP {
+			body, err := json.Marshal(params)
+			return body, errors.Convert(err)
+		}
+		text = defaultNotificationTemplate
+	}
+	tpl, err := template.New(channel.Name).Parse(text)
+	if err != nil {
+		return nil, errors.BadInput.Wrap(err, "invalid notification template")
+	}
+	buf := &bytes.Buffer{}
+	if err := tpl.Execute(buf, params); err != nil {
+		return nil, errors.BadInput.Wrap(err, "failed to render notification template")
+	}
+	return buf.Bytes(), nil
+}
+
+func sendSlackNotification(ctx context.Context, channel *models.NotificationChannel, message *NotificationMessage) (int, string, errors.Error) {
+	payload, err := json.Marshal(map[string]string{"text": string(message.Body)})
+	if err != nil {
+		return 0, "", errors.Convert(err)
+	}
+	return postNotification(ctx, channel.Endpoint, payload)
+}
+
+func sendTeamsNotification(ctx context.Context, channel *models.NotificationChannel, message *NotificationMessage) (int, string, errors.Error) {
+	payload, err := json.Marshal(map[string]string{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  message.Subject,
+		"title":    message.Subject,
+		"text":     string(message.Body),
+	})
+	if err != nil {
+		return 0, "", errors.Convert(err)
+	}
+	return postNotification(ctx, channel.Endpoint, payload)
+}
+
+// sendHttpNotification posts the rendered body, signed with the `nouce` and `sign` query parameters if the channel has a secret
+func sendHttpNotification(ctx context.Context, channel *models.NotificationChannel, message *NotificationMessage) (int, string, errors.Error) {
+	endpoint := channel.Endpoint
+	if channel.Secret != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return 0, "", errors.BadInput.Wrap(err, "invalid notification endpoint")
+		}
+		query := u.Query()
+		query.Set("nouce", message.Nonce)
+		query.Set("sign", signNotification(string(message.Body), channel.Secret, message.Nonce))
+		u.RawQuery = query.Encode()
+		endpoint = u.String()
+	}
+	return postNotification(ctx, endpoint, message.Body)
+}
+
+func sendSmtpNotification(ctx context.Context, channel *models.NotificationChannel, message *NotificationMessage) (int, string, errors.Error) {
+	if len(channel.Recipients) == 0 {
+		return 0, "", errors.BadInput.New("smtp channel has no recipients")
+	}
+	host, _, err := net.SplitHostPort(channel.Endpoint)
+	if err != nil {
+		return 0, "", errors.BadInput.Wrap(err, "smtp endpoint should be host:port")
+	}
+	var auth smtp.Auth
+	if channel.Username != "" {
+		auth = smtp.PlainAuth("", channel.Username, channel.Secret, host)
+	}
+	mail := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		channel.From, strings.Join(channel.Recipients, ", "), message.Subject, message.Body,
+	)
+	// smtp.SendMail has no timeout, the connection is dialed here to be bound to the context
+	conn, err := (&net.Dialer{Timeout: notificationHttpClient.Timeout}).DialContext(ctx, "tcp", channel.Endpoint)
+	if err != nil {
+		return 0, "", errors.Default.Wrap(err, "failed to connect to the smtp server")
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(notificationHttpClient.Timeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return 0, "", errors.Convert(err)
+	}
+	if err = sendMail(conn, host, auth, channel.From, channel.Recipients, []byte(mail)); err != nil {
+		return 0, "", errors.Default.Wrap(err, "failed to send mail")
+	}
+	return 0, "", nil
+}
+
+// sendMail does what smtp.SendMail does over an established connection
+func sendMail(conn net.Conn, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func postNotification(ctx context.Context, endpoint string, payload []byte) (int, string, errors.Error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", errors.BadInput.Wrap(err, "invalid notification endpoint")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := notificationHttpClient.Do(req)
+	if err != nil {
+		return 0, "", errors.Convert(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, "", errors.Convert(err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode, string(respBody), errors.HttpStatus(resp.StatusCode).New(fmt.Sprintf("notification endpoint responded %d", resp.StatusCode))
+	}
+	return resp.StatusCode, string(respBody), nil
+}
@@ -0,0 +1,211 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	coreModels "github.com/apache/incubator-devlake/core/models"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRenderNotification(t *testing.T) {
+	params := PipelineNotificationParam{
+		ProjectName: "devlake",
+		PipelineID:  42,
+		Status:      coreModels.TASK_FAILED,
+	}
+
+	slack := &coreModels.NotificationChannel{Name: "slack", Type: coreModels.NOTIFICATION_CHANNEL_SLACK}
+	body, err := renderNotification(slack, params)
+	assert.Nil(t, err)
+	assert.Equal(t, "DevLake pipeline #42 of project devlake is TASK_FAILED", string(body))
+
+	custom := &coreModels.NotificationChannel{Name: "custom", Type: coreModels.NOTIFICATION_CHANNEL_HTTP, Template: `{"id":{{.PipelineID}}}`}
+	body, err = renderNotification(custom, params)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":42}`, string(body))
+
+	raw := &coreModels.NotificationChannel{Name: "raw", Type: coreModels.NOTIFICATION_CHANNEL_HTTP}
+	body, err = renderNotification(raw, params)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `"PipelineID":42`)
+
+	invalid := &coreModels.NotificationChannel{Name: "invalid", Type: coreModels.NOTIFICATION_CHANNEL_TEAMS, Template: "{{.Unknown"}
+	_, err = renderNotification(invalid, params)
+	assert.NotNil(t, err)
+}
+
+// mockNotificationDb collects the delivery logs saved by deliverNotification
+func mockNotificationDb(t *testing.T) *[]coreModels.Notification {
+	var saved []coreModels.Notification
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("Create", mock.AnythingOfType("*models.Notification"), mock.Anything).Return(func(entity interface{}, _ ...dal.Clause) errors.Error {
+		entity.(*coreModels.Notification).ID = 7
+		return nil
+	})
+	mockDb.On("CreateOrUpdate", mock.AnythingOfType("*models.Notification"), mock.Anything).Return(func(entity interface{}, _ ...dal.Clause) errors.Error {
+		saved = append(saved, *entity.(*coreModels.Notification))
+		return nil
+	})
+	db = mockDb
+	return &saved
+}
+
+func TestDeliverHttpNotification(t *testing.T) {
+	saved := mockNotificationDb(t)
+	var calls int
+	var query map[string]string
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		query = map[string]string{"nouce": r.URL.Query().Get("nouce"), "sign": r.URL.Query().Get("sign")}
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	channel := &coreModels.NotificationChannel{Name: "hook", Type: coreModels.NOTIFICATION_CHANNEL_HTTP, Endpoint: server.URL + "/hook?token=x", Secret: "s3cr3t"}
+	record := deliverNotification(context.Background(), channel, PipelineNotificationParam{PipelineID: 42, Status: coreModels.TASK_FAILED}, 1)
+
+	assert.Equal(t, coreModels.NOTIFICATION_DELIVERED, record.Status)
+	assert.Equal(t, 2, record.Attempts)
+	assert.Equal(t, "ok", record.Response)
+	assert.Equal(t, server.URL+"/hook", record.Endpoint)
+	nonce := fmt.Sprintf("7-%s", record.Nonce)
+	assert.Equal(t, nonce, query["nouce"])
+	assert.Equal(t, signNotification(body, "s3cr3t", nonce), query["sign"])
+	assert.Equal(t, []coreModels.Notification{*record}, *saved)
+}
+
+func TestDeliverNotificationGivesUp(t *testing.T) {
+	saved := mockNotificationDb(t)
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	channel := &coreModels.NotificationChannel{Name: "slack", Type: coreModels.NOTIFICATION_CHANNEL_SLACK, Endpoint: server.URL + "/services/T0/B0/secret"}
+	record := deliverNotification(context.Background(), channel, PipelineNotificationParam{PipelineID: 42}, 0)
+	assert.Equal(t, coreModels.NOTIFICATION_FAILED, record.Status)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusInternalServerError, record.ResponseCode)
+	// the path of slack webhooks is a credential
+	assert.Equal(t, server.URL, record.Endpoint)
+	assert.Len(t, *saved, 1)
+
+	// retries stop as soon as the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	record = deliverNotification(ctx, channel, PipelineNotificationParam{PipelineID: 42}, 3)
+	assert.Equal(t, coreModels.NOTIFICATION_FAILED, record.Status)
+	assert.Equal(t, 1, record.Attempts)
+	assert.Less(t, time.Since(start), notificationRetryBaseDelay)
+}
+
+func TestRegisterNotificationChannelSender(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		channelType := fmt.Sprintf("custom-%d", i)
+		t.Cleanup(func() {
+			delete(notificationChannelSenders, channelType)
+		})
+		go func() {
+			defer wg.Done()
+			RegisterNotificationChannelSender(channelType, sendHttpNotification)
+		}()
+		go func() {
+			defer wg.Done()
+			getNotificationChannelSender(coreModels.NOTIFICATION_CHANNEL_HTTP)
+		}()
+	}
+	wg.Wait()
+	_, ok := getNotificationChannelSender("custom-9")
+	assert.True(t, ok)
+}
+
+func TestSanitizeNotificationChannel(t *testing.T) {
+	slack := &coreModels.NotificationChannel{
+		Type:     coreModels.NOTIFICATION_CHANNEL_SLACK,
+		Endpoint: "https://hooks.slack.com/services/T000/B000/XXXXXXXX",
+		Secret:   "",
+	}
+	sanitizeNotificationChannel(slack)
+	assert.Equal(t, "https://hooks.slack.com/s************************XX", slack.Endpoint)
+
+	http := &coreModels.NotificationChannel{
+		Type:     coreModels.NOTIFICATION_CHANNEL_HTTP,
+		Endpoint: "https://example.com/notify",
+		Secret:   "secret",
+	}
+	sanitizeNotificationChannel(http)
+	assert.Equal(t, "https://example.com/notify", http.Endpoint)
+	assert.Equal(t, "se**et", http.Secret)
+}
+
+func TestPatchNotificationChannelKeepsEndpoint(t *testing.T) {
+	vld = validator.New()
+	const endpoint = "https://hooks.slack.com/services/T000/B000/XXXXXXXX"
+	mockDal := new(mockdal.Dal)
+	mockDal.On("First", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*coreModels.NotificationChannel) = coreModels.NotificationChannel{
+			Name:     "team",
+			Type:     coreModels.NOTIFICATION_CHANNEL_SLACK,
+			Endpoint: endpoint,
+		}
+	}).Return(nil)
+	var updated coreModels.NotificationChannel
+	mockDal.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		updated = *args.Get(0).(*coreModels.NotificationChannel)
+	}).Return(nil)
+	db = mockDal
+
+	channel, err := PatchNotificationChannel(1, map[string]interface{}{
+		"name":     "renamed",
+		"endpoint": "https://hooks.slack.com/s************************XX",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", updated.Name)
+	assert.Equal(t, endpoint, updated.Endpoint)
+	assert.NotEqual(t, endpoint, channel.Endpoint)
+
+	_, err = PatchNotificationChannel(1, map[string]interface{}{"endpoint": "https://hooks.slack.com/services/T000/B000/YYYYYYYY"})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://hooks.slack.com/services/T000/B000/YYYYYYYY", updated.Endpoint)
+}
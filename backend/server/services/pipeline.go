@@ -88,6 +88,7 @@ func pipelineServiceInit() {
 	if strings.TrimSpace(notificationEndpoint) != "" {
 		defaultNotificationService = NewDefaultPipelineNotificationService(notificationEndpoint, notificationSecret)
 	}
+	RegisterPipelineNotificationService(NewChannelPipelineNotificationService())
 
 	// standalone mode: reset pipeline status
 	if cfg.GetBool("RESUME_PIPELINES") {
@@ -111,6 +112,10 @@ func pipelineServiceInit() {
 	if cfg.GetBool("CONSUME_PIPELINES") {
 		go RunPipelineInQueue(pipelineMaxParallel)
 	}
+	// distributed mode: tasks are executed by the workers, take care of the dead ones
+	if isDistributed() {
+		go reassignTasksOfDeadWorkers()
+	}
 }
 
 func markInterruptedPipelineAs(status string) {
@@ -396,10 +401,10 @@ func getProjectName(pipeline *models.Pipeline) (string, errors.Error) {
 	return dbBlueprint.ProjectName, nil
 }
 
-// NotifyExternal FIXME ...
+// NotifyExternal sends the pipeline status to all notification services
 func NotifyExternal(pipelineId uint64) errors.Error {
-	notification := GetPipelineNotificationService()
-	if notification == nil {
+	notificationServices := GetPipelineNotificationServices()
+	if len(notificationServices) == 0 {
 		return nil
 	}
 	// send notification to an external web endpoint
@@ -411,20 +416,25 @@ func NotifyExternal(pipelineId uint64) errors.Error {
 	if err != nil {
 		return err
 	}
-	err = notification.PipelineStatusChanged(PipelineNotificationParam{
+	params := PipelineNotificationParam{
 		ProjectName: projectName,
+		BlueprintId: pipeline.BlueprintId,
 		PipelineID:  pipeline.ID,
 		CreatedAt:   pipeline.CreatedAt,
 		UpdatedAt:   pipeline.UpdatedAt,
 		BeganAt:     pipeline.BeganAt,
 		FinishedAt:  pipeline.FinishedAt,
 		Status:      pipeline.Status,
-	})
-	if err != nil {
-		globalPipelineLog.Error(err, "failed to send notification: %v", err)
-		return err
 	}
-	return nil
+	var lastErr errors.Error
+	for _, notification := range notificationServices {
+		err = notification.PipelineStatusChanged(params)
+		if err != nil {
+			globalPipelineLog.Error(err, "failed to send notification: %v", err)
+			lastErr = err
+		}
+	}
+	return lastErr
 }
 
 // CancelPipeline FIXME ...
@@ -454,6 +464,9 @@ func CancelPipeline(pipelineId uint64) errors.Error {
 		// the target pipeline is pending, no running, no need to perform the actual cancel operation
 		return nil
 	}
+	if isDistributed() {
+		cancelDistributedPipeline(pipelineId)
+	}
 	pendingTasks, count, err := GetTasks(&TaskQuery{PipelineId: pipelineId, Pending: 1, Pagination: Pagination{PageSize: -1}})
 	if err != nil {
 		return errors.Convert(err)
@@ -484,6 +497,10 @@ func cancelRunningTasks(taskIds []uint64) int {
 	for _, taskId := range taskIds {
 		if err := CancelTask(taskId); err != nil {
 			if err.GetType() == errors.NotFound {
+				// the task might be running on a worker, which picks the cancellation up from the database
+				if isDistributed() {
+					failCount += cancelWorkerTaskInDB(taskId)
+				}
 				continue // task no longer tracked in-memory (finished or context lost after restart)
 			}
 			globalPipelineLog.Error(err, "failed to cancel running task #%d", taskId)
@@ -493,6 +510,21 @@ func cancelRunningTasks(taskIds []uint64) int {
 	return failCount
 }
 
+// cancelWorkerTaskInDB marks the task held by a worker as cancelled, the worker would stop it on the next heartbeat.
+// Returns the number of tasks that failed to update.
+func cancelWorkerTaskInDB(taskId uint64) int {
+	err := db.UpdateColumn(
+		&models.Task{},
+		"status", models.TASK_CANCELLED,
+		dal.Where("id = ? AND status = ? AND worker_id <> ''", taskId, models.TASK_RUNNING),
+	)
+	if err != nil {
+		globalPipelineLog.Error(err, "failed to cancel task #%d held by worker", taskId)
+		return 1
+	}
+	return 0
+}
+
 // cancelPendingTasksInDB marks non-running pending tasks as cancelled directly
 // in the database. Returns the number of tasks that failed to update.
 func cancelPendingTasksInDB(taskIds []uint64) int {
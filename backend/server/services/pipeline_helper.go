@@ -32,6 +32,9 @@ var createDbPipelineLock sync.Mutex
 
 // CreateDbPipeline returns a NewPipeline
 func CreateDbPipeline(newPipeline *models.NewPipeline) (pipeline *models.Pipeline, err errors.Error) {
+	if _, err = newPipeline.Plan.ToDag(); err != nil {
+		return nil, err
+	}
 	createDbPipelineLock.Lock()
 	defer createDbPipelineLock.Unlock()
 	pipeline = &models.Pipeline{}
@@ -24,6 +24,7 @@ import (
 
 type PipelineNotificationParam struct {
 	ProjectName string // can be an empty string, if pipeline is created and triggered by API
+	BlueprintId uint64 // can be 0, if pipeline is created and triggered by API
 	PipelineID  uint64
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -37,6 +38,21 @@ type PipelineNotificationService interface {
 }
 
 var customPipelineNotificationService PipelineNotificationService
+var registeredPipelineNotificationServices []PipelineNotificationService
+
+// RegisterPipelineNotificationService adds a service to be notified along with the default or the custom one
+func RegisterPipelineNotificationService(service PipelineNotificationService) {
+	registeredPipelineNotificationServices = append(registeredPipelineNotificationServices, service)
+}
+
+// GetPipelineNotificationServices returns all services to be notified when a pipeline status changed
+func GetPipelineNotificationServices() []PipelineNotificationService {
+	notificationServices := make([]PipelineNotificationService, 0, len(registeredPipelineNotificationServices)+1)
+	if notificationService := GetPipelineNotificationService(); notificationService != nil {
+		notificationServices = append(notificationServices, notificationService)
+	}
+	return append(notificationServices, registeredPipelineNotificationServices...)
+}
 
 func GetPipelineNotificationService() PipelineNotificationService {
 	if customPipelineNotificationService != nil {
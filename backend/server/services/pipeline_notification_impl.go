@@ -18,8 +18,6 @@ limitations under the License.
 package services
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/apache/incubator-devlake/core/errors"
@@ -70,7 +68,7 @@ func (n *DefaultPipelineNotificationService) sendNotification(notificationType m
 		return errors.Convert(err)
 	}
 
-	sign := n.signature(notification.Data, fmt.Sprintf("%d-%s", notification.ID, nonce))
+	sign := signNotification(notification.Data, n.Secret, fmt.Sprintf("%d-%s", notification.ID, nonce))
 	url := fmt.Sprintf("%s?nouce=%d-%s&sign=%s", n.EndPoint, notification.ID, nonce, sign)
 
 	resp, err := http.Post(url, "application/json", strings.NewReader(notification.Data))
@@ -86,8 +84,3 @@ func (n *DefaultPipelineNotificationService) sendNotification(notificationType m
 	notification.Response = string(respBody)
 	return db.Update(notification)
 }
-
-func (n *DefaultPipelineNotificationService) signature(input, nouce string) string {
-	sum := sha256.Sum256([]byte(input + n.Secret + nouce))
-	return hex.EncodeToString(sum[:])
-}
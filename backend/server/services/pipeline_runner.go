@@ -44,6 +44,26 @@ func (p *pipelineRunner) runPipelineStandalone() errors.Error {
 	)
 }
 
+func (p *pipelineRunner) runPipelineDistributed() errors.Error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	distributedPipelines.mu.Lock()
+	distributedPipelines.cancels[p.pipeline.ID] = cancel
+	distributedPipelines.mu.Unlock()
+	defer func() {
+		distributedPipelines.mu.Lock()
+		delete(distributedPipelines.cancels, p.pipeline.ID)
+		distributedPipelines.mu.Unlock()
+	}()
+	return runner.RunPipeline(
+		basicRes.ReplaceLogger(p.logger),
+		p.pipeline.ID,
+		func(taskIds []uint64) errors.Error {
+			return RunTasksDistributed(ctx, p.logger, taskIds)
+		},
+	)
+}
+
 // GetPipelineLogger returns logger for the pipeline
 func GetPipelineLogger(pipeline *models.Pipeline) log.Logger {
 	pipelineLogger := globalPipelineLog.Nested(
@@ -73,7 +93,11 @@ func runPipeline(pipelineId uint64) errors.Error {
 		pipeline: ppl,
 	}
 	// run
-	err = pipelineRun.runPipelineStandalone()
+	if isDistributed() {
+		err = pipelineRun.runPipelineDistributed()
+	} else {
+		err = pipelineRun.runPipelineStandalone()
+	}
 	isCancelled := errors.Is(err, context.Canceled)
 	if err != nil {
 		err = errors.Default.Wrap(err, fmt.Sprintf("Error running pipeline %d.", pipelineId))
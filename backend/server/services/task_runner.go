@@ -63,6 +63,9 @@ func (rt *RunningTask) FillProgressDetailToTasks(tasks []*models.Task) {
 		taskId := task.ID
 		if task, ok := rt.tasks[taskId]; ok {
 			tasks[index].ProgressDetail = task.ProgressDetail
+		} else if tasks[index].Status == models.TASK_RUNNING && tasks[index].WorkerProgress != nil {
+			// the task is running on a worker
+			tasks[index].ProgressDetail = tasks[index].WorkerProgress
 		}
 	}
 }
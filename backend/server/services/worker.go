@@ -0,0 +1,501 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/log"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/core/plugin"
+	"github.com/apache/incubator-devlake/core/runner"
+	"github.com/apache/incubator-devlake/core/version"
+	"github.com/apache/incubator-devlake/helpers/dbhelper"
+	"github.com/apache/incubator-devlake/impls/logruslog"
+	"github.com/google/uuid"
+	"golang.org/x/sync/semaphore"
+)
+
+var workerLog = logruslog.Global.Nested("worker")
+
+// workerProgressInterval is how often the workers store the progress of their running tasks
+var workerProgressInterval = 5 * time.Second
+
+// workerPollInterval is how often the workers look for new tasks and the server checks the dispatched ones
+var workerPollInterval = time.Second
+
+var pendingTaskStatusForWorkers = []string{models.TASK_CREATED, models.TASK_RERUN, models.TASK_RESUME}
+
+var unfinishedTaskStatusForWorkers = append([]string{models.TASK_RUNNING}, pendingTaskStatusForWorkers...)
+
+// isDistributed tells whether the tasks should be dispatched to the workers instead of running inside the server
+func isDistributed() bool {
+	return cfg.GetBool("DISTRIBUTED_WORKERS")
+}
+
+func getWorkerTimeout() time.Duration {
+	timeout := cfg.GetDuration("WORKER_TIMEOUT")
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return timeout
+}
+
+// getWorkerClaimTimeout is how long a dispatched task may wait for a worker to claim it
+func getWorkerClaimTimeout() time.Duration {
+	timeout := cfg.GetDuration("WORKER_CLAIM_TIMEOUT")
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	return timeout
+}
+
+// distributedPipelines holds the cancel functions of the pipelines running their tasks on the workers
+var distributedPipelines = struct {
+	mu      sync.Mutex
+	cancels map[uint64]context.CancelFunc
+}{cancels: make(map[uint64]context.CancelFunc)}
+
+// cancelDistributedPipeline stops the server waiting for the tasks of the pipeline
+func cancelDistributedPipeline(pipelineId uint64) {
+	distributedPipelines.mu.Lock()
+	defer distributedPipelines.mu.Unlock()
+	if cancel, ok := distributedPipelines.cancels[pipelineId]; ok {
+		cancel()
+	}
+}
+
+// InitWorker initializes the services module for a worker process, workers never migrate the database
+// so the server must be started first
+func InitWorker() {
+	InitResources()
+	errors.Must(runner.LoadPlugins(basicRes))
+	registerPluginsMigrationScripts()
+	if migrator.HasPendingScripts() || !db.HasTable(&models.Worker{}) {
+		panic(errors.Default.New("the database has pending migration scripts, please start or upgrade the devlake server first"))
+	}
+	plugin.InitPlugins(basicRes)
+	serviceStatus = SERVICE_STATUS_READY
+}
+
+// RunWorker registers current process as a worker and executes the tasks dispatched by the server, it returns only
+// when the worker was evicted by the server. The running tasks are not cancelled since the server has given them to
+// other workers already, updating their status would corrupt the data, the caller is expected to exit right away
+func RunWorker() errors.Error {
+	maxParallel := cfg.GetInt("WORKER_MAX_PARALLEL")
+	if maxParallel <= 0 {
+		maxParallel = 4
+	}
+	worker, err := registerWorker(maxParallel)
+	if err != nil {
+		return err
+	}
+	workerLog.Info("worker %s registered, max parallel: %d", worker.Id, maxParallel)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	evicted := make(chan errors.Error, 1)
+	go func() {
+		evicted <- keepWorkerAlive(worker)
+		cancel()
+	}()
+
+	sema := semaphore.NewWeighted(int64(maxParallel))
+	for {
+		if sema.Acquire(ctx, 1) != nil {
+			return <-evicted
+		}
+		task := waitForTask(ctx, worker.Id)
+		if task == nil {
+			return <-evicted
+		}
+		go func(task *models.Task) {
+			defer sema.Release(1)
+			workerLog.Info("worker %s claimed task #%d of pipeline #%d", worker.Id, task.ID, task.PipelineId)
+			var parentLogger log.Logger = workerLog
+			if pipeline, err := GetDbPipeline(task.PipelineId); err == nil {
+				parentLogger = GetPipelineLogger(pipeline)
+			}
+			if err := runTaskOnWorker(parentLogger, task.ID); err != nil {
+				workerLog.Error(err, "task #%d failed", task.ID)
+			}
+		}(task)
+	}
+}
+
+// runTaskOnWorker runs the task like the server does and stores its progress periodically, so the server could
+// serve the progress of the task it isn't running
+func runTaskOnWorker(parentLogger log.Logger, taskId uint64) errors.Error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		var saved models.TaskProgressDetail
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(workerProgressInterval):
+			}
+			saveTaskProgress(taskId, &saved)
+		}
+	}()
+	return runTaskStandalone(parentLogger, taskId)
+}
+
+// saveTaskProgress stores the progress detail of the running task into the task row if it changed since saved
+func saveTaskProgress(taskId uint64, saved *models.TaskProgressDetail) {
+	data := getRunningTaskById(taskId)
+	if data == nil {
+		return
+	}
+	runningTasks.mu.Lock()
+	current := *data.ProgressDetail
+	runningTasks.mu.Unlock()
+	if current == *saved {
+		return
+	}
+	err := db.UpdateAllColumn(&models.Task{Model: common.Model{ID: taskId}, WorkerProgress: &current})
+	if err != nil {
+		workerLog.Error(err, "failed to save progress of task #%d", taskId)
+		return
+	}
+	*saved = current
+}
+
+// waitForTask claims a task for the worker, it returns nil if ctx was done before any task was claimed
+func waitForTask(ctx context.Context, workerId string) *models.Task {
+	for {
+		task, err := claimTask(workerId)
+		if err != nil {
+			workerLog.Error(err, "claim task failed")
+		}
+		if task != nil {
+			return task
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(workerPollInterval):
+		}
+	}
+}
+
+func registerWorker(maxParallel int) (*models.Worker, errors.Error) {
+	hostName, e := os.Hostname()
+	if e != nil {
+		return nil, errors.Convert(e)
+	}
+	worker := &models.Worker{
+		Id:          fmt.Sprintf("%s-%s", hostName, uuid.New().String()),
+		HostName:    hostName,
+		Pid:         os.Getpid(),
+		Version:     version.Version,
+		Status:      models.WORKER_ONLINE,
+		MaxParallel: maxParallel,
+		HeartbeatAt: time.Now(),
+	}
+	return worker, db.Create(worker)
+}
+
+// keepWorkerAlive updates the heartbeat of the worker periodically until the worker was evicted by the server
+func keepWorkerAlive(worker *models.Worker) errors.Error {
+	interval := getWorkerTimeout() / 4
+	for {
+		time.Sleep(interval)
+		if err := heartbeatWorker(worker); err != nil {
+			return err
+		}
+	}
+}
+
+// heartbeatWorker updates the heartbeat of the worker and cancels the tasks which were cancelled or failed by the server,
+// an error is returned only if the worker was evicted, other failures are logged and retried on next heartbeat
+func heartbeatWorker(worker *models.Worker) errors.Error {
+	current := &models.Worker{}
+	if err := db.First(current, dal.Where("id = ?", worker.Id)); err != nil {
+		workerLog.Error(err, "failed to load worker %s", worker.Id)
+		return nil
+	}
+	// the server had given our tasks to others, running them any further would corrupt the data
+	if current.Status != models.WORKER_ONLINE {
+		return errors.Default.New(fmt.Sprintf("worker %s was marked as %s by the server", worker.Id, current.Status))
+	}
+	runningTasks.mu.Lock()
+	taskIds := make([]uint64, 0, len(runningTasks.tasks))
+	for taskId := range runningTasks.tasks {
+		taskIds = append(taskIds, taskId)
+	}
+	runningTasks.mu.Unlock()
+	err := db.UpdateColumns(worker, []dal.DalSet{
+		{ColumnName: "heartbeat_at", Value: time.Now()},
+		{ColumnName: "running_tasks", Value: len(taskIds)},
+	})
+	if err != nil {
+		workerLog.Error(err, "failed to update heartbeat of worker %s", worker.Id)
+	}
+	if len(taskIds) == 0 {
+		return nil
+	}
+	var cancelledTaskIds []uint64
+	err = db.Pluck("id", &cancelledTaskIds, dal.From(&models.Task{}), dal.Where("id IN ? AND status IN ?", taskIds, []string{models.TASK_CANCELLED, models.TASK_FAILED}))
+	if err != nil {
+		workerLog.Error(err, "failed to check cancelled tasks")
+		return nil
+	}
+	for _, taskId := range cancelledTaskIds {
+		if err := CancelTask(taskId); err != nil && err.GetType() != errors.NotFound {
+			workerLog.Error(err, "failed to cancel task #%d", taskId)
+		}
+	}
+	return nil
+}
+
+// claimTask picks the earliest dispatched task nobody holds, the row lock makes sure every task goes to one worker only
+func claimTask(workerId string) (task *models.Task, err errors.Error) {
+	txHelper := dbhelper.NewTxHelper(basicRes, &err)
+	defer txHelper.End()
+	tx := txHelper.Begin()
+	task = &models.Task{}
+	err = tx.First(
+		task,
+		dal.Where(
+			"dispatched_at IS NOT NULL AND (worker_id IS NULL OR worker_id = '') AND status IN ?",
+			pendingTaskStatusForWorkers,
+		),
+		dal.Orderby("dispatched_at ASC, id ASC"),
+		dal.LockSkipLocked(true),
+	)
+	if err != nil {
+		if tx.IsErrorNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = tx.UpdateColumns(task, []dal.DalSet{
+		{ColumnName: "worker_id", Value: workerId},
+		{ColumnName: "status", Value: models.TASK_RUNNING},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// RunTasksDistributed dispatches the tasks to the workers and waits until all of them are finished or ctx is done.
+// The tasks nobody claims, the ones held by a dead worker and the ones running too long are failed.
+func RunTasksDistributed(ctx context.Context, parentLogger log.Logger, taskIds []uint64) errors.Error {
+	if len(taskIds) == 0 {
+		return nil
+	}
+	err := db.UpdateColumns(
+		&models.Task{},
+		[]dal.DalSet{
+			{ColumnName: "dispatched_at", Value: time.Now()},
+			{ColumnName: "worker_id", Value: ""},
+		},
+		dal.Where("id IN ?", taskIds),
+	)
+	if err != nil {
+		return err
+	}
+	parentLogger.Info("tasks %v were dispatched to workers", taskIds)
+	unclaimedSince := make(map[uint64]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			// the workers stop the cancelled tasks on their next heartbeat
+			err = db.UpdateColumn(
+				&models.Task{},
+				"status", models.TASK_CANCELLED,
+				dal.Where("id IN ? AND status IN ?", taskIds, unfinishedTaskStatusForWorkers),
+			)
+			if err != nil {
+				parentLogger.Error(err, "failed to cancel the dispatched tasks")
+			}
+			return errors.Default.Wrap(ctx.Err(), fmt.Sprintf("tasks %v were cancelled", taskIds))
+		case <-time.After(workerPollInterval):
+		}
+		var tasks []models.Task
+		err = db.All(&tasks, dal.Where("id IN ?", taskIds))
+		if err != nil {
+			return err
+		}
+		err = failStuckTasks(parentLogger, tasks, unclaimedSince, time.Now())
+		if err != nil {
+			return err
+		}
+		finished := true
+		for _, task := range tasks {
+			if !isTaskFinished(task.Status) {
+				finished = false
+				break
+			}
+		}
+		if !finished {
+			continue
+		}
+		var sb strings.Builder
+		for _, task := range tasks {
+			if task.Status == models.TASK_CANCELLED {
+				parentLogger.Info("task canceled")
+				return errors.Default.Wrap(context.Canceled, fmt.Sprintf("task %d was cancelled", task.ID))
+			}
+			if task.Status == models.TASK_FAILED {
+				_, _ = sb.WriteString(fmt.Sprintf("Error running task %d on worker %s.\n%s\n", task.ID, task.WorkerId, task.Message))
+			}
+		}
+		if sb.Len() > 0 {
+			// RunTask swallows the failures of the pipelines with SkipOnFail, the workers can't tell us so we check it here
+			pipeline := &models.Pipeline{}
+			err = db.First(pipeline, dal.Where("id = ?", tasks[0].PipelineId))
+			if err != nil {
+				return err
+			}
+			if pipeline.SkipOnFail {
+				parentLogger.Warn(errors.Default.New(sb.String()), "skipping the failed tasks since the pipeline skips on failure")
+				return nil
+			}
+			return errors.Default.New(sb.String())
+		}
+		return nil
+	}
+}
+
+// failStuckTasks fails the unfinished tasks nobody claimed for WORKER_CLAIM_TIMEOUT, the ones held by a worker
+// which missed its heartbeats for twice WORKER_TIMEOUT, as it should have been reassigned by then, and the ones
+// running longer than WORKER_TASK_TIMEOUT if set. unclaimedSince keeps when the tasks were seen unclaimed first.
+func failStuckTasks(parentLogger log.Logger, tasks []models.Task, unclaimedSince map[uint64]time.Time, now time.Time) errors.Error {
+	var workerIds []string
+	for _, task := range tasks {
+		if task.Status == models.TASK_RUNNING && task.WorkerId != "" {
+			workerIds = append(workerIds, task.WorkerId)
+		}
+	}
+	heartbeats := make(map[string]time.Time)
+	if len(workerIds) > 0 {
+		var workers []models.Worker
+		err := db.All(&workers, dal.Where("id IN ?", workerIds))
+		if err != nil {
+			return err
+		}
+		for _, worker := range workers {
+			heartbeats[worker.Id] = worker.HeartbeatAt
+		}
+	}
+	taskTimeout := cfg.GetDuration("WORKER_TASK_TIMEOUT")
+	for i := range tasks {
+		task := &tasks[i]
+		if isTaskFinished(task.Status) {
+			delete(unclaimedSince, task.ID)
+			continue
+		}
+		var reason string
+		if task.WorkerId == "" {
+			since, ok := unclaimedSince[task.ID]
+			if !ok {
+				unclaimedSince[task.ID] = now
+				continue
+			}
+			if now.Sub(since) > getWorkerClaimTimeout() {
+				reason = fmt.Sprintf("no worker claimed the task in %s", getWorkerClaimTimeout())
+			}
+		} else {
+			delete(unclaimedSince, task.ID)
+			heartbeatAt, ok := heartbeats[task.WorkerId]
+			if task.Status == models.TASK_RUNNING && (!ok || now.Sub(heartbeatAt) > 2*getWorkerTimeout()) {
+				reason = fmt.Sprintf("worker %s stopped sending heartbeats", task.WorkerId)
+			} else if taskTimeout > 0 && task.BeganAt != nil && now.Sub(*task.BeganAt) > taskTimeout {
+				reason = fmt.Sprintf("the task ran longer than %s", taskTimeout)
+			}
+		}
+		if reason == "" {
+			continue
+		}
+		parentLogger.Warn(nil, "failing task #%d: %s", task.ID, reason)
+		// the worker still holding the task stops it on its next heartbeat
+		err := db.UpdateColumns(
+			&models.Task{},
+			[]dal.DalSet{
+				{ColumnName: "status", Value: models.TASK_FAILED},
+				{ColumnName: "message", Value: reason},
+				{ColumnName: "finished_at", Value: now},
+			},
+			dal.Where("id = ? AND status IN ?", task.ID, unfinishedTaskStatusForWorkers),
+		)
+		if err != nil {
+			return err
+		}
+		task.Status = models.TASK_FAILED
+		task.Message = reason
+	}
+	return nil
+}
+
+func isTaskFinished(status string) bool {
+	for _, s := range models.FinishedTaskStatus {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// reassignTasksOfDeadWorkers marks the workers without heartbeat as offline and puts their tasks back to the queue
+func reassignTasksOfDeadWorkers() {
+	timeout := getWorkerTimeout()
+	for {
+		time.Sleep(timeout / 2)
+		reassignDeadWorkerTasks(timeout)
+	}
+}
+
+// reassignDeadWorkerTasks reassigns the tasks of the workers whose last heartbeat is older than timeout
+func reassignDeadWorkerTasks(timeout time.Duration) {
+	var workers []models.Worker
+	err := db.All(&workers, dal.Where("status = ? AND heartbeat_at < ?", models.WORKER_ONLINE, time.Now().Add(-timeout)))
+	if err != nil {
+		workerLog.Error(err, "failed to load dead workers")
+		return
+	}
+	for _, worker := range workers {
+		workerLog.Warn(nil, "worker %s lost heartbeat since %s, reassigning its tasks", worker.Id, worker.HeartbeatAt)
+		err = db.UpdateColumn(&models.Worker{}, "status", models.WORKER_OFFLINE, dal.Where("id = ?", worker.Id))
+		if err != nil {
+			workerLog.Error(err, "failed to mark worker %s offline", worker.Id)
+			continue
+		}
+		err = db.UpdateColumns(
+			&models.Task{},
+			[]dal.DalSet{
+				{ColumnName: "status", Value: models.TASK_RESUME},
+				{ColumnName: "worker_id", Value: ""},
+			},
+			dal.Where("worker_id = ? AND status = ?", worker.Id, models.TASK_RUNNING),
+		)
+		if err != nil {
+			workerLog.Error(err, "failed to reassign tasks of worker %s", worker.Id)
+		}
+	}
+}
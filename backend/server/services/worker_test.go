@@ -0,0 +1,349 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-devlake/core/dal"
+	"github.com/apache/incubator-devlake/core/errors"
+	"github.com/apache/incubator-devlake/core/models"
+	"github.com/apache/incubator-devlake/core/models/common"
+	"github.com/apache/incubator-devlake/helpers/unithelper"
+	mockcontext "github.com/apache/incubator-devlake/mocks/core/context"
+	mockdal "github.com/apache/incubator-devlake/mocks/core/dal"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var errWorkerNotFound = errors.NotFound.New("not found")
+
+// mockWorkerTx makes claimTask run inside the returned transaction
+func mockWorkerTx(t *testing.T) *mockdal.Transaction {
+	tx := mockdal.NewTransaction(t)
+	tx.On("UnlockTables").Return(nil).Maybe()
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("Begin").Return(tx)
+	mockRes := mockcontext.NewBasicRes(t)
+	mockRes.On("GetDal").Return(mockDb)
+	mockRes.On("GetLogger").Return(unithelper.DummyLogger())
+	basicRes = mockRes
+	return tx
+}
+
+func TestClaimTask(t *testing.T) {
+	tx := mockWorkerTx(t)
+	tx.On("First", mock.AnythingOfType("*models.Task"), mock.Anything).Return(func(dst interface{}, clauses ...dal.Clause) errors.Error {
+		// the row must be locked without waiting for the ones claimed by other workers
+		var lock []bool
+		for _, c := range clauses {
+			if c.Type == dal.LockClause {
+				lock = c.Data.([]bool)
+			}
+		}
+		assert.Equal(t, []bool{true, false, true}, lock)
+		dst.(*models.Task).ID = 5
+		return nil
+	})
+	var claimed []dal.DalSet
+	tx.On("UpdateColumns", mock.AnythingOfType("*models.Task"), mock.Anything, mock.Anything).Return(func(_ interface{}, set []dal.DalSet, _ ...dal.Clause) errors.Error {
+		claimed = set
+		return nil
+	})
+	tx.On("Commit").Return(nil)
+
+	task, err := claimTask("worker-1")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), task.ID)
+	assert.Equal(t, []dal.DalSet{
+		{ColumnName: "worker_id", Value: "worker-1"},
+		{ColumnName: "status", Value: models.TASK_RUNNING},
+	}, claimed)
+}
+
+func TestClaimTaskNothingToClaim(t *testing.T) {
+	tx := mockWorkerTx(t)
+	tx.On("First", mock.Anything, mock.Anything).Return(errWorkerNotFound)
+	tx.On("IsErrorNotFound", errWorkerNotFound).Return(true)
+	tx.On("Commit").Return(nil)
+
+	task, err := claimTask("worker-1")
+	assert.Nil(t, err)
+	assert.Nil(t, task)
+}
+
+func TestClaimTaskFailed(t *testing.T) {
+	tx := mockWorkerTx(t)
+	tx.On("First", mock.Anything, mock.Anything).Return(nil)
+	tx.On("UpdateColumns", mock.Anything, mock.Anything, mock.Anything).Return(errors.Default.New("deadlock"))
+	tx.On("Rollback").Return(nil)
+
+	task, err := claimTask("worker-1")
+	assert.NotNil(t, err)
+	assert.Nil(t, task)
+}
+
+func TestReassignDeadWorkerTasks(t *testing.T) {
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("All", mock.AnythingOfType("*[]models.Worker"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		*dst.(*[]models.Worker) = []models.Worker{{Id: "dead", Status: models.WORKER_ONLINE}}
+		return nil
+	})
+	mockDb.On("UpdateColumn", mock.Anything, "status", models.WORKER_OFFLINE, mock.Anything).Return(func(_ interface{}, _ string, _ interface{}, clauses ...dal.Clause) errors.Error {
+		assert.Equal(t, []interface{}{"dead"}, clauses[0].Data.(dal.DalClause).Params)
+		return nil
+	}).Once()
+	mockDb.On("UpdateColumns", mock.AnythingOfType("*models.Task"), []dal.DalSet{
+		{ColumnName: "status", Value: models.TASK_RESUME},
+		{ColumnName: "worker_id", Value: ""},
+	}, mock.Anything).Return(func(_ interface{}, _ []dal.DalSet, clauses ...dal.Clause) errors.Error {
+		assert.Equal(t, []interface{}{"dead", models.TASK_RUNNING}, clauses[0].Data.(dal.DalClause).Params)
+		return nil
+	}).Once()
+	db = mockDb
+
+	reassignDeadWorkerTasks(time.Minute)
+}
+
+func TestHeartbeatWorker(t *testing.T) {
+	status := models.WORKER_ONLINE
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("First", mock.AnythingOfType("*models.Worker"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		dst.(*models.Worker).Status = status
+		return nil
+	})
+	mockDb.On("UpdateColumns", mock.AnythingOfType("*models.Worker"), mock.Anything, mock.Anything).Return(nil).Once()
+	mockDb.On("Pluck", "id", mock.Anything, mock.Anything).Return(func(_ string, dest interface{}, _ ...dal.Clause) errors.Error {
+		*dest.(*[]uint64) = []uint64{9}
+		return nil
+	}).Once()
+	db = mockDb
+
+	// the task cancelled by the server is cancelled on the worker
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Nil(t, runningTasks.Add(9, cancel))
+	worker := &models.Worker{Id: "worker-1"}
+	assert.Nil(t, heartbeatWorker(worker))
+	assert.NotNil(t, ctx.Err())
+
+	// the worker stops once evicted
+	status = models.WORKER_OFFLINE
+	assert.NotNil(t, heartbeatWorker(worker))
+}
+
+// mockWorkerConfig sets the config read by the worker functions
+func mockWorkerConfig(settings map[string]interface{}) {
+	v := viper.New()
+	for key, value := range settings {
+		v.Set(key, value)
+	}
+	cfg = v
+}
+
+// mockDispatchedTasks returns the statuses one after another to the polling of RunTasksDistributed, the workers
+// holding them are alive
+func mockDispatchedTasks(t *testing.T, polls ...[]models.Task) *mockdal.Dal {
+	workerPollInterval = time.Millisecond
+	mockWorkerConfig(nil)
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("All", mock.AnythingOfType("*[]models.Worker"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		*dst.(*[]models.Worker) = []models.Worker{{Id: "worker-1", HeartbeatAt: time.Now()}}
+		return nil
+	}).Maybe()
+	mockDb.On("UpdateColumns", mock.AnythingOfType("*models.Task"), mock.Anything, mock.Anything).Return(nil).Once()
+	poll := 0
+	mockDb.On("All", mock.AnythingOfType("*[]models.Task"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		*dst.(*[]models.Task) = polls[poll]
+		poll++
+		return nil
+	}).Times(len(polls))
+	db = mockDb
+	return mockDb
+}
+
+// mockSkipOnFail makes the pipeline of the dispatched tasks skip the failed tasks or not
+func mockSkipOnFail(mockDb *mockdal.Dal, skipOnFail bool) {
+	mockDb.On("First", mock.AnythingOfType("*models.Pipeline"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		dst.(*models.Pipeline).SkipOnFail = skipOnFail
+		return nil
+	}).Once()
+}
+
+func TestRunTasksDistributed(t *testing.T) {
+	task := func(id uint64, status string) models.Task {
+		return models.Task{Model: common.Model{ID: id}, Status: status, WorkerId: "worker-1", Message: "boom"}
+	}
+	logger := unithelper.DummyLogger()
+	logger.On("Warn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	mockDispatchedTasks(t,
+		[]models.Task{task(1, models.TASK_RUNNING), task(2, models.TASK_CREATED)},
+		[]models.Task{task(1, models.TASK_COMPLETED), task(2, models.TASK_COMPLETED)},
+	)
+	assert.Nil(t, RunTasksDistributed(context.Background(), logger, []uint64{1, 2}))
+
+	mockSkipOnFail(mockDispatchedTasks(t,
+		[]models.Task{task(1, models.TASK_FAILED), task(2, models.TASK_COMPLETED)},
+	), false)
+	err := RunTasksDistributed(context.Background(), logger, []uint64{1, 2})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Error running task 1 on worker worker-1")
+
+	// the failures are skipped just like RunTask does
+	mockSkipOnFail(mockDispatchedTasks(t,
+		[]models.Task{task(1, models.TASK_FAILED), task(2, models.TASK_COMPLETED)},
+	), true)
+	assert.Nil(t, RunTasksDistributed(context.Background(), logger, []uint64{1, 2}))
+
+	// the cancellation is detected by the pipeline runner
+	mockDispatchedTasks(t,
+		[]models.Task{task(1, models.TASK_CANCELLED), task(2, models.TASK_FAILED)},
+	)
+	err = RunTasksDistributed(context.Background(), logger, []uint64{1, 2})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestRunTasksDistributedCancelled(t *testing.T) {
+	workerPollInterval = time.Millisecond
+	mockWorkerConfig(nil)
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("UpdateColumns", mock.AnythingOfType("*models.Task"), mock.Anything, mock.Anything).Return(nil).Once()
+	mockDb.On("All", mock.AnythingOfType("*[]models.Task"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		*dst.(*[]models.Task) = []models.Task{{Model: common.Model{ID: 1}, Status: models.TASK_CREATED}}
+		return nil
+	}).Maybe()
+	// the unfinished tasks are cancelled for the workers to stop them
+	mockDb.On("UpdateColumn", mock.AnythingOfType("*models.Task"), "status", models.TASK_CANCELLED, mock.Anything).Return(nil).Once()
+	db = mockDb
+
+	// nobody claims the task, only the cancellation of the pipeline ends the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err := RunTasksDistributed(ctx, unithelper.DummyLogger(), []uint64{1})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestRunTasksDistributedNoWorker(t *testing.T) {
+	logger := unithelper.DummyLogger()
+	logger.On("Warn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockDb := mockDispatchedTasks(t,
+		[]models.Task{{Model: common.Model{ID: 1}, Status: models.TASK_CREATED}},
+		[]models.Task{{Model: common.Model{ID: 1}, Status: models.TASK_CREATED}},
+	)
+	mockWorkerConfig(map[string]interface{}{"WORKER_CLAIM_TIMEOUT": time.Nanosecond})
+	var failed []dal.DalSet
+	mockDb.On("UpdateColumns", mock.AnythingOfType("*models.Task"), mock.Anything, mock.Anything).Return(func(_ interface{}, set []dal.DalSet, _ ...dal.Clause) errors.Error {
+		failed = set
+		return nil
+	}).Once()
+	mockSkipOnFail(mockDb, false)
+
+	err := RunTasksDistributed(context.Background(), logger, []uint64{1})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no worker claimed the task")
+	assert.Equal(t, models.TASK_FAILED, failed[0].Value)
+}
+
+func TestFailStuckTasks(t *testing.T) {
+	now := time.Now()
+	began := now.Add(-2 * time.Hour)
+	logger := unithelper.DummyLogger()
+	logger.On("Warn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockWorkerConfig(map[string]interface{}{
+		"WORKER_TIMEOUT":       time.Minute,
+		"WORKER_CLAIM_TIMEOUT": 10 * time.Minute,
+		"WORKER_TASK_TIMEOUT":  time.Hour,
+	})
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("All", mock.AnythingOfType("*[]models.Worker"), mock.Anything).Return(func(dst interface{}, _ ...dal.Clause) errors.Error {
+		*dst.(*[]models.Worker) = []models.Worker{
+			{Id: "alive", HeartbeatAt: now.Add(-30 * time.Second)},
+			{Id: "dead", HeartbeatAt: now.Add(-5 * time.Minute)},
+		}
+		return nil
+	})
+	var failedIds []interface{}
+	mockDb.On("UpdateColumns", mock.AnythingOfType("*models.Task"), mock.Anything, mock.Anything).Return(func(_ interface{}, _ []dal.DalSet, clauses ...dal.Clause) errors.Error {
+		failedIds = append(failedIds, clauses[0].Data.(dal.DalClause).Params[0])
+		return nil
+	})
+	db = mockDb
+
+	tasks := []models.Task{
+		{Model: common.Model{ID: 1}, Status: models.TASK_RUNNING, WorkerId: "alive"},
+		{Model: common.Model{ID: 2}, Status: models.TASK_RUNNING, WorkerId: "dead"},
+		{Model: common.Model{ID: 3}, Status: models.TASK_RUNNING, WorkerId: "gone"},
+		{Model: common.Model{ID: 4}, Status: models.TASK_RUNNING, WorkerId: "alive", BeganAt: &began},
+		{Model: common.Model{ID: 5}, Status: models.TASK_CREATED},
+		{Model: common.Model{ID: 6}, Status: models.TASK_RESUME},
+		{Model: common.Model{ID: 7}, Status: models.TASK_COMPLETED, WorkerId: "gone"},
+	}
+	// task 6 has waited for a worker since long ago, task 5 is seen unclaimed for the first time
+	unclaimedSince := map[uint64]time.Time{6: now.Add(-time.Hour)}
+	assert.Nil(t, failStuckTasks(logger, tasks, unclaimedSince, now))
+	assert.Equal(t, []interface{}{uint64(2), uint64(3), uint64(4), uint64(6)}, failedIds)
+	for _, task := range tasks {
+		failed := task.ID == 2 || task.ID == 3 || task.ID == 4 || task.ID == 6
+		assert.Equal(t, failed, task.Status == models.TASK_FAILED, "task #%d", task.ID)
+	}
+	assert.Contains(t, tasks[1].Message, "worker dead stopped sending heartbeats")
+	assert.Contains(t, tasks[3].Message, "the task ran longer than 1h0m0s")
+	assert.Contains(t, unclaimedSince, uint64(5))
+}
+
+func TestSaveTaskProgress(t *testing.T) {
+	var savedTasks []*models.Task
+	mockDb := mockdal.NewDal(t)
+	mockDb.On("UpdateAllColumn", mock.AnythingOfType("*models.Task"), mock.Anything).Return(func(entity interface{}, _ ...dal.Clause) errors.Error {
+		savedTasks = append(savedTasks, entity.(*models.Task))
+		return nil
+	})
+	db = mockDb
+
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Nil(t, runningTasks.Add(7, cancel))
+	defer func() {
+		_, _ = runningTasks.Remove(7)
+	}()
+	var saved models.TaskProgressDetail
+	// nothing has been reported yet
+	saveTaskProgress(7, &saved)
+	assert.Empty(t, savedTasks)
+
+	runningTasks.mu.Lock()
+	runningTasks.tasks[7].ProgressDetail.SubTaskName = "collectIssues"
+	runningTasks.tasks[7].ProgressDetail.FinishedRecords = 10
+	runningTasks.mu.Unlock()
+	saveTaskProgress(7, &saved)
+	// unchanged progress isn't saved again
+	saveTaskProgress(7, &saved)
+	assert.Len(t, savedTasks, 1)
+	assert.Equal(t, uint64(7), savedTasks[0].ID)
+	assert.Equal(t, "collectIssues", savedTasks[0].WorkerProgress.SubTaskName)
+	assert.Equal(t, 10, savedTasks[0].WorkerProgress.FinishedRecords)
+
+	// the server serves the progress saved by the worker
+	_, _ = runningTasks.Remove(7)
+	tasks := []*models.Task{{Model: common.Model{ID: 7}, Status: models.TASK_RUNNING, WorkerProgress: savedTasks[0].WorkerProgress}}
+	runningTasks.FillProgressDetailToTasks(tasks)
+	assert.Equal(t, 10, tasks[0].ProgressDetail.FinishedRecords)
+}
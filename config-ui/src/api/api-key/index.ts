@@ -29,7 +29,7 @@ export const list = (data?: Pagination): Promise<ListRes> =>
     data,
   });
 
-type CreateForm = Pick<IApiKey, 'name' | 'expiredAt' | 'allowedPath'>;
+type CreateForm = Pick<IApiKey, 'name' | 'expiredAt' | 'scopes'>;
 
 export const create = (data: CreateForm): Promise<IApiKey> =>
   request('/api-keys', {
@@ -18,17 +18,17 @@
 
 import { useState, useMemo } from 'react';
 import { PlusOutlined } from '@ant-design/icons';
-import { Flex, Table, Modal, Input, Select, Button, Tag } from 'antd';
+import { Flex, Table, Modal, Input, Select, Button, Tag, Checkbox } from 'antd';
 import dayjs from 'dayjs';
 
 import API from '@/api';
 import { PageHeader, Block, ExternalLink, CopyText, Message } from '@/components';
 import { PATHS } from '@/config';
+import type { IApiKey, IApiKeyScopes } from '@/types';
 import { useRefreshData } from '@/hooks';
 import { operator, formatTime } from '@/utils';
 
 import * as C from './constant';
-import * as S from './styled';
 
 export const ApiKeys = () => {
   const [version, setVersion] = useState(1);
@@ -41,18 +41,21 @@ export const ApiKeys = () => {
   const [form, setForm] = useState<{
     name: string;
     expiredAt?: string;
-    allowedPath: string;
+    scopes: IApiKeyScopes;
   }>({
     name: '',
     expiredAt: C.timeOptions[1].value,
-    allowedPath: '.*',
+    scopes: { methods: ['GET'] },
   });
 
   const { data, ready } = useRefreshData(() => API.apiKey.list({ page, pageSize }), [version, page, pageSize]);
 
   const prefix = useMemo(() => `${window.location.origin}/api/rest/`, []);
   const [dataSource, total] = useMemo(() => [data?.apikeys ?? [], data?.count ?? 0], [data]);
-  const hasError = useMemo(() => !form.name || !form.allowedPath, [form]);
+  const hasError = useMemo(() => {
+    const { all, methods, resources, projects } = form.scopes;
+    return !form.name || (!all && !methods?.length && !resources?.length && !projects?.length);
+  }, [form]);
 
   const timeSelectedValue = useMemo(() => {
     return C.timeOptions.find((it) => it.value === form.expiredAt || !it.value)?.value;
@@ -74,7 +77,7 @@ export const ApiKeys = () => {
       setForm({
         name: '',
         expiredAt: C.timeOptions[1].value,
-        allowedPath: '.*',
+        scopes: { methods: ['GET'] },
       });
     }
   };
@@ -125,10 +128,25 @@ export const ApiKeys = () => {
             ),
           },
           {
-            title: 'Allowed Path',
-            dataIndex: 'allowedPath',
-            key: 'allowedPath',
-            render: (val) => `${prefix}${val}`,
+            title: 'Scopes',
+            dataIndex: 'scopes',
+            key: 'scopes',
+            render: (val: IApiKeyScopes | undefined, row: IApiKey) => {
+              if (!val) {
+                return `${prefix}${row.allowedPath ?? ''}`;
+              }
+              if (val.all) {
+                return 'All except the admin APIs';
+              }
+              return [
+                val.methods?.length && `Methods: ${val.methods.join(', ')}`,
+                val.resources?.length && `Resources: ${val.resources.join(', ')}`,
+                val.projects?.length && `Projects: ${val.projects.join(', ')}`,
+                val.webhookConnectionIds?.length && `Webhooks: ${val.webhookConnectionIds.join(', ')}`,
+              ]
+                .filter(Boolean)
+                .join('; ');
+            },
           },
           {
             title: '',
@@ -194,24 +212,51 @@ export const ApiKeys = () => {
             />
           </Block>
           <Block
-            title="Allowed Path"
+            title="Scopes"
             description={
               <p>
-                Enter a Regular Expression that matches the API URL(s) from the{' '}
-                <ExternalLink link="/api/swagger/index.html">DevLake API docs</ExternalLink>. The default Regular
-                Expression is set to all APIs.
+                Restrict what the API key may do on the APIs from the{' '}
+                <ExternalLink link="/api/swagger/index.html">DevLake API docs</ExternalLink>, a request must match every
+                scope set. The admin APIs (api-keys, audit-logs, encryption-secret and notification-channels) are only
+                allowed when selected in the resources.
               </p>
             }
             required
           >
-            <S.InputContainer>
-              <span>{prefix}</span>
-              <Input
-                placeholder=""
-                value={form.allowedPath}
-                onChange={(e) => setForm({ ...form, allowedPath: e.target.value })}
-              />
-            </S.InputContainer>
+            <Checkbox
+              checked={form.scopes.all}
+              onChange={(e) => setForm({ ...form, scopes: e.target.checked ? { all: true } : { methods: ['GET'] } })}
+            >
+              Allow all the APIs except the admin APIs
+            </Checkbox>
+            {!form.scopes.all && (
+              <Flex vertical gap="small" style={{ marginTop: 8 }}>
+                <Select
+                  mode="multiple"
+                  style={{ width: 386 }}
+                  placeholder="Methods, all if empty"
+                  options={C.methodOptions}
+                  value={form.scopes.methods}
+                  onChange={(methods) => setForm({ ...form, scopes: { ...form.scopes, methods } })}
+                />
+                <Select
+                  mode="tags"
+                  style={{ width: 386 }}
+                  placeholder="Resources, i.e. projects or plugins/github, all but the admin ones if empty"
+                  options={C.resourceOptions}
+                  value={form.scopes.resources}
+                  onChange={(resources) => setForm({ ...form, scopes: { ...form.scopes, resources } })}
+                />
+                <Select
+                  mode="tags"
+                  style={{ width: 386 }}
+                  placeholder="Project names, any project if empty"
+                  open={false}
+                  value={form.scopes.projects}
+                  onChange={(projects) => setForm({ ...form, scopes: { ...form.scopes, projects } })}
+                />
+              </Flex>
+            )}
           </Block>
         </Modal>
       )}
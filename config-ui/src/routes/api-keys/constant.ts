@@ -24,3 +24,22 @@ export const timeOptions = [
   { label: '90 days', value: dayjs().add(90, 'd').toISOString() },
   { label: 'Never', value: '' },
 ];
+
+export const methodOptions = ['GET', 'POST', 'PUT', 'PATCH', 'DELETE'].map((it) => ({ label: it, value: it }));
+
+// plugins/<plugin name> may be typed in as well
+export const resourceOptions = [
+  'blueprints',
+  'domainlayer',
+  'pipelines',
+  'plugininfo',
+  'plugins',
+  'projects',
+  'push',
+  'store',
+  'tasks',
+  'api-keys',
+  'audit-logs',
+  'encryption-secret',
+  'notification-channels',
+].map((it) => ({ label: it, value: it }));
@@ -16,9 +16,19 @@
  *
  */
 
+export interface IApiKeyScopes {
+  all?: boolean;
+  methods?: string[];
+  resources?: string[];
+  projects?: string[];
+  webhookConnectionIds?: number[];
+}
+
 export interface IApiKey {
   name: string;
   expiredAt?: string;
-  allowedPath: string;
+  // only set on the keys created before scopes
+  allowedPath?: string;
+  scopes?: IApiKeyScopes;
   creator: string;
 }